@@ -0,0 +1,191 @@
+// Package allowlist restricts where an API key may be used from, by the IP address of the client and by the origin of
+// the page making the request.
+//
+// IP addresses are allowed by single addresses and CIDR ranges, in IPv4 or IPv6 notation. Origins are allowed by
+// their scheme, host and port, as in "https://app.example.com" or "http://localhost:3000". The leftmost label of a
+// host may be a wildcard, so "https://*.example.com" allows every subdomain of example.com but not example.com
+// itself. A key without allowed IP addresses or origins is not restricted by them.
+package allowlist
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/payloadops/lanyard/app/ipblock"
+)
+
+// MaxEntries is the maximum number of IP addresses or origins allowed for a single API key.
+const MaxEntries = 64
+
+// wildcardLabel is the leftmost label of hosts that allow every subdomain.
+const wildcardLabel = "*."
+
+var (
+	// ErrIPAddressRequired is returned when a key restricted to IP addresses is used without a client IP address.
+	ErrIPAddressRequired = errors.New("client IP address is required")
+	// ErrIPAddressNotAllowed is returned when a key is used from an IP address it is not allowed from.
+	ErrIPAddressNotAllowed = errors.New("client IP address is not allowed")
+	// ErrOriginRequired is returned when a key restricted to origins is used without an origin or referrer.
+	ErrOriginRequired = errors.New("origin is required")
+	// ErrOriginNotAllowed is returned when a key is used from an origin it is not allowed from.
+	ErrOriginNotAllowed = errors.New("origin is not allowed")
+)
+
+// NormalizeCIDRs validates a list of IP addresses and CIDR ranges and returns them in their canonical form, removing
+// duplicates while keeping their order.
+func NormalizeCIDRs(values []string) ([]string, error) {
+	if len(values) > MaxEntries {
+		return nil, fmt.Errorf("no more than %d allowed CIDR ranges may be set", MaxEntries)
+	}
+
+	return normalizeAll(values, ipblock.Normalize)
+}
+
+// NormalizeOrigins validates a list of origins and returns them in their canonical form, removing duplicates while
+// keeping their order.
+func NormalizeOrigins(values []string) ([]string, error) {
+	if len(values) > MaxEntries {
+		return nil, fmt.Errorf("no more than %d allowed origins may be set", MaxEntries)
+	}
+
+	return normalizeAll(values, normalizeOrigin)
+}
+
+// normalizeAll normalizes each value, removing duplicates while keeping their order.
+func normalizeAll(values []string, normalize func(string) (string, error)) ([]string, error) {
+	normalized := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		canonical, err := normalize(value)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[canonical] {
+			seen[canonical] = true
+			normalized = append(normalized, canonical)
+		}
+	}
+
+	return normalized, nil
+}
+
+// normalizeOrigin validates an allowed origin and returns it as a lower case scheme, host and port, without the
+// default port of the scheme.
+func normalizeOrigin(value string) (string, error) {
+	u, err := url.Parse(strings.ToLower(strings.TrimSpace(value)))
+	if err != nil || u.Host == "" || u.Opaque != "" {
+		return "", fmt.Errorf("invalid origin '%s'", value)
+	}
+	if u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("origin '%s' must only have a scheme, host and port", value)
+	}
+
+	host := strings.TrimPrefix(u.Hostname(), wildcardLabel)
+	if host == "" || strings.Contains(host, "*") {
+		return "", fmt.Errorf("origin '%s' may only have a wildcard as the leftmost label of its host", value)
+	}
+
+	origin, ok := canonicalOrigin(u)
+	if !ok {
+		return "", fmt.Errorf("origin '%s' must use http or https", value)
+	}
+	return origin, nil
+}
+
+// canonicalOrigin returns the origin of a URL as a scheme, host and port, without the default port of the scheme.
+func canonicalOrigin(u *url.URL) (string, bool) {
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	switch {
+	case scheme == "http" && port == "80", scheme == "https" && port == "443":
+		port = ""
+	case scheme != "http" && scheme != "https":
+		return "", false
+	}
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host, true
+}
+
+// Check reports whether a key with the given allowed IP addresses and origins may be used by a client. The client IP
+// address may carry a port, and the origin may be either the Origin or the Referer header of the client. Both allowed
+// lists are expected to be normalized. A nil error means the key may be used.
+func Check(allowedCIDRs, allowedOrigins []string, clientIP, origin string) error {
+	if len(allowedCIDRs) > 0 {
+		if strings.TrimSpace(clientIP) == "" {
+			return ErrIPAddressRequired
+		}
+		if !matchIP(allowedCIDRs, clientIP) {
+			return ErrIPAddressNotAllowed
+		}
+	}
+
+	if len(allowedOrigins) > 0 {
+		if strings.TrimSpace(origin) == "" {
+			return ErrOriginRequired
+		}
+		if !matchOrigin(allowedOrigins, origin) {
+			return ErrOriginNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// matchIP reports whether an IP address is within any of the allowed IP addresses and CIDR ranges. Allowed values
+// that cannot be parsed allow nothing.
+func matchIP(allowedCIDRs []string, clientIP string) bool {
+	addr, err := ipblock.ParseAddr(strings.TrimSpace(clientIP))
+	if err != nil {
+		return false
+	}
+
+	for _, cidr := range allowedCIDRs {
+		prefix, err := ipblock.ParsePrefix(cidr)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchOrigin reports whether the origin of a URL matches any of the allowed origins. Opaque origins such as "null"
+// match nothing.
+func matchOrigin(allowedOrigins []string, origin string) bool {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	canonical, ok := canonicalOrigin(u)
+	if !ok {
+		return false
+	}
+
+	for _, allowed := range allowedOrigins {
+		if allowed == canonical {
+			return true
+		}
+
+		// "https://*.example.com" matches "https://app.example.com" but not "https://example.com"
+		prefix, suffix, ok := strings.Cut(allowed, "://"+wildcardLabel)
+		if ok && strings.HasPrefix(canonical, prefix+"://") {
+			host := strings.TrimPrefix(canonical, prefix+"://")
+			if strings.HasSuffix(host, "."+suffix) && !strings.HasPrefix(host, ".") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
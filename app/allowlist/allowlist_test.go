@@ -0,0 +1,98 @@
+package allowlist_test
+
+import (
+	"testing"
+
+	"github.com/payloadops/lanyard/app/allowlist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeCIDRs(t *testing.T) {
+	normalized, err := allowlist.NormalizeCIDRs([]string{"203.0.113.7/24", "203.0.113.0/24", "2001:DB8::1", "198.51.100.7/32"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"203.0.113.0/24", "2001:db8::1", "198.51.100.7"}, normalized)
+
+	_, err = allowlist.NormalizeCIDRs([]string{"203.0.113.0/33"})
+	assert.Error(t, err)
+
+	_, err = allowlist.NormalizeCIDRs(make([]string, allowlist.MaxEntries+1))
+	assert.Error(t, err)
+}
+
+func TestNormalizeOrigins(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		valid    bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{" HTTPS://App.Example.com/ ", "https://app.example.com", true},
+		{"https://app.example.com:443", "https://app.example.com", true},
+		{"http://localhost:3000", "http://localhost:3000", true},
+		{"http://[::1]:8080", "http://[::1]:8080", true},
+		{"https://*.example.com", "https://*.example.com", true},
+		{"", "", false},
+		{"app.example.com", "", false},
+		{"ftp://app.example.com", "", false},
+		{"https://app.example.com/login", "", false},
+		{"https://app.example.com?next=1", "", false},
+		{"https://user@app.example.com", "", false},
+		{"https://*", "", false},
+		{"https://app.*.example.com", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			normalized, err := allowlist.NormalizeOrigins([]string{tt.value})
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tt.expected}, normalized)
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cidrs := []string{"203.0.113.0/24", "2001:db8::/32"}
+	origins := []string{"https://app.example.com", "https://*.example.org", "http://localhost:3000"}
+
+	tests := []struct {
+		name     string
+		cidrs    []string
+		origins  []string
+		clientIP string
+		origin   string
+		expected error
+	}{
+		{"Unrestricted key", nil, nil, "", "", nil},
+		{"Address in range", cidrs, nil, "203.0.113.7", "", nil},
+		{"Address with port", cidrs, nil, "203.0.113.7:4321", "", nil},
+		{"IPv4-mapped address", cidrs, nil, "::ffff:203.0.113.7", "", nil},
+		{"IPv6 address", cidrs, nil, "2001:db8::1", "", nil},
+		{"Address outside of ranges", cidrs, nil, "198.51.100.7", "", allowlist.ErrIPAddressNotAllowed},
+		{"Invalid address", cidrs, nil, "unknown", "", allowlist.ErrIPAddressNotAllowed},
+		{"Missing address", cidrs, nil, "", "", allowlist.ErrIPAddressRequired},
+		{"Allowed origin", nil, origins, "", "https://app.example.com", nil},
+		{"Allowed origin with default port", nil, origins, "", "https://app.example.com:443", nil},
+		{"Referrer of allowed origin", nil, origins, "", "https://app.example.com/settings?tab=keys", nil},
+		{"Subdomain of wildcard origin", nil, origins, "", "https://www.example.org", nil},
+		{"Nested subdomain of wildcard origin", nil, origins, "", "https://a.b.example.org", nil},
+		{"Apex of wildcard origin", nil, origins, "", "https://example.org", allowlist.ErrOriginNotAllowed},
+		{"Lookalike of wildcard origin", nil, origins, "", "https://badexample.org", allowlist.ErrOriginNotAllowed},
+		{"Other scheme", nil, origins, "", "http://app.example.com", allowlist.ErrOriginNotAllowed},
+		{"Other port", nil, origins, "", "http://localhost:3001", allowlist.ErrOriginNotAllowed},
+		{"Opaque origin", nil, origins, "", "null", allowlist.ErrOriginNotAllowed},
+		{"Missing origin", nil, origins, "", "", allowlist.ErrOriginRequired},
+		{"Both allowed", cidrs, origins, "203.0.113.7", "https://app.example.com", nil},
+		{"Address checked before origin", cidrs, origins, "198.51.100.7", "https://evil.example.com", allowlist.ErrIPAddressNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, allowlist.Check(tt.cidrs, tt.origins, tt.clientIP, tt.origin))
+		})
+	}
+}
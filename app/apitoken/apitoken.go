@@ -0,0 +1,150 @@
+// Package apitoken defines the format of API key tokens, which carry the ID and secret of an API key in a single
+// string that can be recognized by secret scanning tools:
+//
+//	token       = prefix "_" environment "_" key-id "_" secret "_" checksum
+//	prefix      = lower 1*15( lower / digit )
+//	environment = lower 1*15( lower / digit )
+//
+// For example:
+//
+//	lny_live_2V7ZyKcNwQ1iGp2nnGiAvEDhXk8_dsFnVXFSyTDYWIny9xs8MzwDBExcJD0BsBNg02P9vzF_1GFyBc
+//
+// The prefix identifies the issuer of the key and can be configured per service, and the environment separates keys of
+// different deployments. The key ID is a KSUID, the secret is a random base62 string and the checksum is the CRC32 of
+// everything before it, encoded as six base62 characters. The checksum lets tokens be rejected without looking up
+// their key, and lets scanners discard strings that merely look like tokens.
+package apitoken
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math/big"
+	"strings"
+)
+
+// SecretLength is the number of base62 characters of the secret of a token, which carries about 256 bits.
+const SecretLength = 43
+
+// MaxPrefixLength is the maximum length of the prefix and environment of a token.
+const MaxPrefixLength = 16
+
+// checksumLength is the number of base62 characters encoding the CRC32 checksum of a token.
+const checksumLength = 6
+
+// separator separates the parts of a token. It never appears within a part.
+const separator = "_"
+
+// alphabet is the base62 alphabet used for secrets and checksums.
+const alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var (
+	// ErrMalformed is returned when a string does not have the structure of a token.
+	ErrMalformed = errors.New("malformed API key token")
+	// ErrChecksum is returned when the checksum of a token does not match its content.
+	ErrChecksum = errors.New("invalid API key token checksum")
+)
+
+// Token is a parsed API key token.
+type Token struct {
+	Prefix      string
+	Environment string
+	KeyID       string
+	Secret      string
+}
+
+// ValidatePrefix validates the prefix or environment of a token.
+func ValidatePrefix(value string) error {
+	if len(value) < 2 || len(value) > MaxPrefixLength {
+		return fmt.Errorf("token prefix '%s' must be between 2 and %d characters", value, MaxPrefixLength)
+	}
+	if value[0] < 'a' || value[0] > 'z' {
+		return fmt.Errorf("token prefix '%s' must start with a lower case letter", value)
+	}
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return fmt.Errorf("token prefix '%s' may only contain lower case letters and digits", value)
+		}
+	}
+
+	return nil
+}
+
+// GenerateSecret generates a cryptographically secure random secret for a token.
+func GenerateSecret() (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+	secret := make([]byte, SecretLength)
+	for i := range secret {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random bytes: %v", err)
+		}
+		secret[i] = alphabet[n.Int64()]
+	}
+
+	return string(secret), nil
+}
+
+// String formats the token, appending its checksum.
+func (t Token) String() string {
+	body := strings.Join([]string{t.Prefix, t.Environment, t.KeyID, t.Secret}, separator)
+	return body + separator + checksum(body)
+}
+
+// Parse parses a token and verifies its checksum. It does not look up the key of the token, so a token that parses
+// may still be invalid.
+func Parse(value string) (Token, error) {
+	parts := strings.Split(value, separator)
+	if len(parts) != 5 {
+		return Token{}, ErrMalformed
+	}
+
+	token := Token{
+		Prefix:      parts[0],
+		Environment: parts[1],
+		KeyID:       parts[2],
+		Secret:      parts[3],
+	}
+	if ValidatePrefix(token.Prefix) != nil || ValidatePrefix(token.Environment) != nil {
+		return Token{}, ErrMalformed
+	}
+	if token.KeyID == "" || !isBase62(token.KeyID) {
+		return Token{}, ErrMalformed
+	}
+	if len(token.Secret) != SecretLength || !isBase62(token.Secret) {
+		return Token{}, ErrMalformed
+	}
+	if len(parts[4]) != checksumLength || !isBase62(parts[4]) {
+		return Token{}, ErrMalformed
+	}
+
+	body := value[:strings.LastIndex(value, separator)]
+	if checksum(body) != parts[4] {
+		return Token{}, ErrChecksum
+	}
+
+	return token, nil
+}
+
+// checksum returns the CRC32 of a value encoded as base62, padded to a fixed length.
+func checksum(value string) string {
+	sum := crc32.ChecksumIEEE([]byte(value))
+	encoded := make([]byte, checksumLength)
+	for i := checksumLength - 1; i >= 0; i-- {
+		encoded[i] = alphabet[sum%uint32(len(alphabet))]
+		sum /= uint32(len(alphabet))
+	}
+
+	return string(encoded)
+}
+
+// isBase62 reports whether a value only contains base62 characters.
+func isBase62(value string) bool {
+	for _, c := range value {
+		if !strings.ContainsRune(alphabet, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package apitoken_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken_RoundTrip(t *testing.T) {
+	secret, err := apitoken.GenerateSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, apitoken.SecretLength)
+
+	token := apitoken.Token{Prefix: "lny", Environment: "live", KeyID: "2V7ZyKcNwQ1iGp2nnGiAvEDhXk8", Secret: secret}
+	value := token.String()
+	assert.True(t, strings.HasPrefix(value, "lny_live_2V7ZyKcNwQ1iGp2nnGiAvEDhXk8_"+secret+"_"))
+
+	parsed, err := apitoken.Parse(value)
+	require.NoError(t, err)
+	assert.Equal(t, token, parsed)
+}
+
+func TestParse(t *testing.T) {
+	secret := strings.Repeat("a", apitoken.SecretLength)
+	valid := apitoken.Token{Prefix: "acme2", Environment: "test", KeyID: "key1", Secret: secret}.String()
+	checksum := valid[strings.LastIndex(valid, "_")+1:]
+
+	tests := []struct {
+		name     string
+		value    string
+		expected error
+	}{
+		{"Valid token", valid, nil},
+		{"Empty", "", apitoken.ErrMalformed},
+		{"Legacy secret", "3q2-7wX8n5mMCv9f0ZPxVvP4u5sJ4g0dT0J0J3Nz3hE=", apitoken.ErrMalformed},
+		{"JWT", "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig", apitoken.ErrMalformed},
+		{"Missing checksum", strings.TrimSuffix(valid, "_"+checksum), apitoken.ErrMalformed},
+		{"Upper case prefix", strings.Replace(valid, "acme2", "Acme2", 1), apitoken.ErrMalformed},
+		{"Short secret", strings.Replace(valid, secret, secret[1:], 1), apitoken.ErrMalformed},
+		{"Secret with symbols", strings.Replace(valid, secret, "-"+secret[1:], 1), apitoken.ErrMalformed},
+		{"Changed secret", strings.Replace(valid, secret, "b"+secret[1:], 1), apitoken.ErrChecksum},
+		{"Changed key ID", strings.Replace(valid, "key1", "key2", 1), apitoken.ErrChecksum},
+		{"Changed environment", strings.Replace(valid, "_test_", "_live_", 1), apitoken.ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := apitoken.Parse(tt.value)
+			assert.Equal(t, tt.expected, err)
+		})
+	}
+}
+
+func TestValidatePrefix(t *testing.T) {
+	for _, value := range []string{"lny", "acme2", "live", strings.Repeat("a", apitoken.MaxPrefixLength)} {
+		assert.NoError(t, apitoken.ValidatePrefix(value), value)
+	}
+
+	for _, value := range []string{"", "a", "2fa", "Lny", "my_co", "my-co", strings.Repeat("a", apitoken.MaxPrefixLength+1)} {
+		assert.Error(t, apitoken.ValidatePrefix(value), value)
+	}
+}
@@ -7,55 +7,141 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/payloadops/lanyard/app/allowlist"
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/clientip"
 	"github.com/payloadops/lanyard/app/config"
 	"github.com/payloadops/lanyard/app/dal"
-	"github.com/payloadops/lanyard/app/utils"
+	"github.com/payloadops/lanyard/app/ipblock"
 )
 
+// SessionTTL is how long a session token minted by NewSessionToken is valid for.
+const SessionTTL = time.Hour
+
+// SecretVersionHeader is the response header reporting which secret of an API key authenticated the request.
+const SecretVersionHeader = "X-Api-Key-Secret"
+
 // Claims represents the JWT claims containing the standard claims, user ID, and organization ID.
 type Claims struct {
 	jwt.StandardClaims
-	OrgID string `json:"org"`
+	// Audience replaces the aud claim of the standard claims, which cannot hold an array of audiences.
+	Audience Audience `json:"aud,omitempty"`
+	OrgID    string   `json:"org"`
+	Role     string   `json:"role,omitempty"`
+}
+
+// NewSessionToken mints a JWT for a user with a role in an organization, signed with the configured JWT secret. The
+// token carries the first configured issuer and the configured audience, so that it passes the checks of TokenVerifier.
+func NewSessionToken(cfg *config.Config, orgID, userID string, role Role) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		OrgID: orgID,
+		Role:  string(role),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   userID,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(SessionTTL).Unix(),
+		},
+	}
+	if len(cfg.JWT.Issuers) > 0 {
+		claims.Issuer = cfg.JWT.Issuers[0]
+	}
+	if cfg.JWT.Audience != "" {
+		claims.Audience = Audience{cfg.JWT.Audience}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWTSecret))
+}
+
+// ErrorWriter writes the response to a request that the middlewares of this package reject with a status. The
+// detail of the error is shown to the client unless the status is a server error.
+type ErrorWriter func(w http.ResponseWriter, r *http.Request, err error, status int)
+
+var (
+	errMissingAuthorization       = errors.New("missing Authorization header")
+	errInvalidAuthorization       = errors.New("invalid Authorization header")
+	errInvalidAuthorizationFormat = errors.New("invalid Authorization header format")
+	errInvalidBase64              = errors.New("invalid base64 encoding")
+	errInvalidAPIKey              = errors.New("invalid API key")
+	errExpiredAPIKey              = errors.New("API key has expired")
+	errQuarantinedAPIKey          = errors.New("API key is quarantined")
+	errBlockedIP                  = errors.New("IP address is blocked")
+	errInvalidToken               = errors.New("invalid token")
+)
+
+// IsAPIKeyAuthorization reports whether an Authorization header carries API key credentials, either as Basic auth or
+// as a bearer API key token. Bearer JWTs always contain dots, which API key tokens never do.
+func IsAPIKeyAuthorization(authHeader string) bool {
+	if strings.HasPrefix(authHeader, "Basic ") {
+		return true
+	}
+
+	return strings.HasPrefix(authHeader, "Bearer ") && !strings.Contains(authHeader, ".")
 }
 
-func APIKeyAuthMiddleware(cfg *config.Config, logger *zap.Logger, apiKeyManager dal.APIKeyManager) func(http.Handler) http.Handler {
+// APIKeyAuthMiddleware returns a middleware function that validates the API key from the Authorization header, given
+// either as a bearer API key token or as Basic auth with the key ID and secret.
+// Keys of deleted services are as invalid as deleted keys.
+// Requests from IP addresses blocked for the service of the key are rejected, and the blocklist may be nil.
+// Requests from IP addresses or origins the key is not allowed from are rejected as well.
+// It sets the organization ID and service ID in the request context if the key is valid, and writes rejections with
+// writeError.
+func APIKeyAuthMiddleware(cfg *config.Config, logger *zap.Logger, writeError ErrorWriter, apiKeyManager dal.APIKeyManager, serviceManager dal.ServiceManager, blocklist *ipblock.Matcher) func(http.Handler) http.Handler {
+	verifier := NewSecretVerifier(cfg, logger, apiKeyManager)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			requestID := middleware.GetReqID(r.Context())
 			// Extract the token from the Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+				writeError(w, r, errMissingAuthorization, http.StatusUnauthorized)
 				return
 			}
 
-			// Check if the Authorization header is using Basic Auth
-			if !strings.HasPrefix(authHeader, "Basic ") {
-				http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
-				return
-			}
+			var clientID, clientSecret string
+			switch {
+			case strings.HasPrefix(authHeader, "Bearer "):
+				// Malformed tokens and tokens of other deployments are rejected before any lookup
+				token, err := apitoken.Parse(strings.TrimPrefix(authHeader, "Bearer "))
+				if err != nil || token.Environment != cfg.APIKeys.TokenEnvironment {
+					logger.Warn("invalid API key token",
+						zap.String("requestID", requestID),
+					)
 
-			// Decode the Base64 encoded credentials
-			base64Credentials := strings.TrimPrefix(authHeader, "Basic ")
-			decodedCredentials, err := base64.StdEncoding.DecodeString(base64Credentials)
-			if err != nil {
-				http.Error(w, "Invalid base64 encoding", http.StatusUnauthorized)
-				return
-			}
+					writeError(w, r, errInvalidAPIKey, http.StatusUnauthorized)
+					return
+				}
+
+				clientID, clientSecret = token.KeyID, token.Secret
+			case strings.HasPrefix(authHeader, "Basic "):
+				// Decode the Base64 encoded credentials
+				base64Credentials := strings.TrimPrefix(authHeader, "Basic ")
+				decodedCredentials, err := base64.StdEncoding.DecodeString(base64Credentials)
+				if err != nil {
+					writeError(w, r, errInvalidBase64, http.StatusUnauthorized)
+					return
+				}
 
-			// Split the credentials into clientID and clientSecret
-			credentials := strings.SplitN(string(decodedCredentials), ":", 2)
-			if len(credentials) != 2 {
-				http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+				// Split the credentials into clientID and clientSecret
+				credentials := strings.SplitN(string(decodedCredentials), ":", 2)
+				if len(credentials) != 2 {
+					writeError(w, r, errInvalidAuthorizationFormat, http.StatusUnauthorized)
+					return
+				}
+
+				clientID, clientSecret = credentials[0], credentials[1]
+			default:
+				writeError(w, r, errInvalidAuthorization, http.StatusUnauthorized)
 				return
 			}
 
-			clientID, clientSecret := credentials[0], credentials[1]
 			key, err := apiKeyManager.GetAPIKey(r.Context(), clientID)
 			if err != nil {
 				logger.Error("failed to get API key",
@@ -63,34 +149,121 @@ func APIKeyAuthMiddleware(cfg *config.Config, logger *zap.Logger, apiKeyManager
 					zap.Error(err),
 				)
 
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				// Throttled lookups can be retried, like the requests of the handlers
+				status := http.StatusInternalServerError
+				if errors.Is(err, dal.ErrThrottled) {
+					status = http.StatusServiceUnavailable
+				}
+				writeError(w, r, err, status)
 				return
 			}
 
 			if key == nil {
-				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				writeError(w, r, errInvalidAPIKey, http.StatusUnauthorized)
 				return
 			}
 
 			if key.Deleted {
 				logger.Warn("use of deleted API key", zap.String("requestID", requestID))
-				http.Error(w, "Invalid API key", http.StatusUnauthorized)
+				writeError(w, r, errInvalidAPIKey, http.StatusUnauthorized)
+				return
+			}
+
+			// Reject blocked callers before verifying the secret, so that they cannot guess it
+			clientIP := clientip.FromRequest(r)
+			if block := blocklist.Match(key.ServiceID, clientIP, time.Now()); block != nil {
+				logger.Warn("request from blocked IP address",
+					zap.String("requestID", requestID),
+					zap.String("serviceID", key.ServiceID),
+					zap.String("clientIP", clientIP),
+					zap.String("blockedIP", block.IPAddress),
+					zap.String("reason", block.Reason),
+				)
+
+				writeError(w, r, errBlockedIP, http.StatusForbidden)
 				return
 			}
 
-			if !utils.SecureCompare(clientSecret, key.Secret) {
+			secretVersion, ok := verifier.Verify(r.Context(), key, clientSecret)
+			if !ok {
 				logger.Warn("invalid API key secret",
 					zap.String("requestID", requestID),
+				)
+
+				writeError(w, r, errInvalidAPIKey, http.StatusUnauthorized)
+				return
+			}
+
+			expired, err := key.Expired(time.Now())
+			if err != nil {
+				logger.Error("failed to check API key expiry",
+					zap.String("requestID", requestID),
+					zap.Error(err),
+				)
+
+				writeError(w, r, err, http.StatusInternalServerError)
+				return
+			}
+
+			if expired {
+				logger.Warn("use of expired API key", zap.String("requestID", requestID))
+				writeError(w, r, errExpiredAPIKey, http.StatusUnauthorized)
+				return
+			}
+
+			if key.Status == dal.APIKeyStatusQuarantined {
+				logger.Warn("use of quarantined API key", zap.String("requestID", requestID))
+				writeError(w, r, errQuarantinedAPIKey, http.StatusUnauthorized)
+				return
+			}
+
+			// The keys of a deleted service may outlive it, when they are created while it is being deleted
+			service, err := serviceManager.GetService(r.Context(), key.OrgID, key.ServiceID)
+			if err != nil {
+				logger.Error("failed to get service",
+					zap.String("requestID", requestID),
 					zap.Error(err),
 				)
 
-				http.Error(w, "Invalid API Key", http.StatusUnauthorized)
+				status := http.StatusInternalServerError
+				if errors.Is(err, dal.ErrThrottled) {
+					status = http.StatusServiceUnavailable
+				}
+				writeError(w, r, err, status)
+				return
+			}
+
+			if service == nil {
+				logger.Warn("use of API key of deleted service", zap.String("requestID", requestID))
+				writeError(w, r, errInvalidAPIKey, http.StatusUnauthorized)
+				return
+			}
+
+			// Keys restricted to IP addresses or origins apply the same restrictions to calls to this API
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				origin = r.Referer()
+			}
+			if err := allowlist.Check(key.AllowedCIDRs, key.AllowedOrigins, clientIP, origin); err != nil {
+				logger.Warn("use of API key from a disallowed client",
+					zap.String("requestID", requestID),
+					zap.String("clientIP", clientIP),
+					zap.String("origin", origin),
+					zap.Error(err),
+				)
+				writeError(w, r, err, http.StatusForbidden)
 				return
 			}
 
+			// Report which secret was used, so that clients can tell whether they still rely on a rotated secret
+			w.Header().Set(SecretVersionHeader, string(secretVersion))
+
 			// Set the user and org context
 			ctx := context.WithValue(r.Context(), "orgID", key.OrgID)
 			ctx = context.WithValue(ctx, "serviceID", key.ServiceID)
+			ctx = context.WithValue(ctx, "apiKeyID", key.APIKeyID)
+			ctx = context.WithValue(ctx, "actorID", key.ActorID)
+			ctx = context.WithValue(ctx, "secretVersion", secretVersion)
 
 			// Call the next handler with the new context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -99,57 +272,59 @@ func APIKeyAuthMiddleware(cfg *config.Config, logger *zap.Logger, apiKeyManager
 }
 
 // JWTAuthMiddleware returns a middleware function that validates the JWT token from the Authorization header.
-// It sets the user ID and organization ID in the request context if the token is valid.
-func JWTAuthMiddleware(cfg *config.Config, logger *zap.Logger) func(http.Handler) http.Handler {
+// HMAC tokens are verified with the JWT secrets and asymmetric tokens with the keys of the JWKS, which may be nil.
+// It sets the user ID, organization ID and role in the request context if the token is valid, and writes rejections
+// with writeError.
+func JWTAuthMiddleware(cfg *config.Config, logger *zap.Logger, writeError ErrorWriter, jwks *JWKS) func(http.Handler) http.Handler {
+	return jwtAuthMiddleware(cfg, logger, writeError, jwks, true)
+}
+
+// JWTSubjectAuthMiddleware returns a middleware function like JWTAuthMiddleware that also accepts tokens without an
+// organization, such as those of an operator creating their first organization. The organization ID is only set in
+// the request context when the token has one.
+func JWTSubjectAuthMiddleware(cfg *config.Config, logger *zap.Logger, writeError ErrorWriter, jwks *JWKS) func(http.Handler) http.Handler {
+	return jwtAuthMiddleware(cfg, logger, writeError, jwks, false)
+}
+
+// jwtAuthMiddleware validates the JWT token from the Authorization header, optionally requiring an organization.
+func jwtAuthMiddleware(cfg *config.Config, logger *zap.Logger, writeError ErrorWriter, jwks *JWKS, requireOrg bool) func(http.Handler) http.Handler {
+	verifier := NewTokenVerifier(cfg, jwks)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			requestID := middleware.GetReqID(r.Context())
 			// Extract the token from the Authorization header
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Missing Authorization header", http.StatusUnauthorized)
+				writeError(w, r, errMissingAuthorization, http.StatusUnauthorized)
 				return
 			}
 
 			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 			if tokenString == authHeader {
-				http.Error(w, "Invalid Authorization header", http.StatusUnauthorized)
+				writeError(w, r, errInvalidAuthorization, http.StatusUnauthorized)
 				return
 			}
 
 			// Parse and validate the token
-			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				// Ensure the token method conforms to "alg" expected value
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					logger.Warn("unexpected signing method",
-						zap.String("requestID", requestID),
-					)
-
-					return nil, errors.New("unexpected signing method")
-				}
-
-				return []byte(cfg.JWTSecret), nil
-			})
-
-			if err != nil || !token.Valid {
+			claims, err := verifier.Verify(r.Context(), tokenString)
+			if err != nil {
 				logger.Warn("invalid token",
 					zap.String("requestID", requestID),
 					zap.Error(err),
 				)
 
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				writeError(w, r, errInvalidToken, http.StatusUnauthorized)
 				return
 			}
 
-			if claims.OrgID == "" {
+			if requireOrg && claims.OrgID == "" {
 				err := fmt.Errorf("required field '%s' is empty value", "org")
 				logger.Error("failed to parse org from claims",
 					zap.String("requestID", requestID),
 					zap.Error(err),
 				)
 
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				writeError(w, r, errInvalidToken, http.StatusUnauthorized)
 				return
 			}
 
@@ -160,13 +335,27 @@ func JWTAuthMiddleware(cfg *config.Config, logger *zap.Logger) func(http.Handler
 					zap.Error(err),
 				)
 
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+				writeError(w, r, errInvalidToken, http.StatusUnauthorized)
 				return
 			}
 
-			// Set the user and org context
-			ctx := context.WithValue(r.Context(), "orgID", claims.OrgID)
-			ctx = context.WithValue(ctx, "userID", claims.Subject)
+			role, err := ParseRole(claims.Role)
+			if err != nil {
+				logger.Warn("failed to parse role from claims",
+					zap.String("requestID", requestID),
+					zap.Error(err),
+				)
+
+				writeError(w, r, errInvalidToken, http.StatusUnauthorized)
+				return
+			}
+
+			// Set the user, org and role context
+			ctx := context.WithValue(r.Context(), "userID", claims.Subject)
+			ctx = context.WithValue(ctx, "role", role)
+			if claims.OrgID != "" {
+				ctx = context.WithValue(ctx, "orgID", claims.OrgID)
+			}
 
 			// Call the next handler with the new context
 			next.ServeHTTP(w, r.WithContext(ctx))
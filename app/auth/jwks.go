@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// jwksFetchTimeout bounds how long fetching a JWKS document over HTTP may take.
+const jwksFetchTimeout = 10 * time.Second
+
+// jwksMinRefreshInterval is the minimum time between refreshes triggered by tokens signed with an unknown key, so
+// that tokens with made up key IDs cannot be used to flood the JWKS endpoint.
+const jwksMinRefreshInterval = time.Minute
+
+// ErrUnknownKey is returned when a token is signed with a key that is not in the JWKS document.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// jsonWebKey is a public key of a JWKS document, as described in RFC 7517.
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwk is a parsed public key together with the algorithm it is restricted to, if any.
+type jwk struct {
+	key interface{}
+	alg string
+}
+
+// JWKS caches the public keys of a JSON Web Key Set loaded from a local file or an HTTP URL. The keys are refreshed
+// periodically by Run, and on demand when a token is signed with a key that is not cached yet.
+type JWKS struct {
+	source string
+	client *http.Client
+	logger *zap.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]jwk
+	lastRefresh time.Time
+}
+
+// NewJWKS creates a new JWKS for a file path or an HTTP(S) URL. The keys are not loaded until Refresh is called.
+func NewJWKS(source string, logger *zap.Logger) *JWKS {
+	return &JWKS{
+		source: source,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		logger: logger,
+		keys:   map[string]jwk{},
+	}
+}
+
+// Refresh reloads the keys from the source. The cached keys are kept if the source cannot be loaded.
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	j.lastRefresh = time.Now()
+	j.mu.Unlock()
+
+	return j.refresh(ctx)
+}
+
+// refresh reloads the keys from the source without recording the time of the refresh.
+func (j *JWKS) refresh(ctx context.Context) error {
+	data, err := j.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS: %v", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse JWKS: %v", err)
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the keys at every interval until the context is done.
+func (j *JWKS) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Refresh(ctx); err != nil {
+				j.logger.Error("failed to refresh JWKS", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Key returns the public key with the given key ID and the algorithm it is restricted to, which is empty when the
+// key does not restrict it. Keys that are not cached trigger a refresh, at most once per jwksMinRefreshInterval.
+func (j *JWKS) Key(ctx context.Context, kid string) (interface{}, string, error) {
+	// Claim the refresh while holding the lock, so that concurrent requests do not refresh at the same time
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	refresh := !ok && time.Since(j.lastRefresh) >= jwksMinRefreshInterval
+	if refresh {
+		j.lastRefresh = time.Now()
+	}
+	j.mu.Unlock()
+
+	if refresh {
+		if err := j.refresh(ctx); err != nil {
+			j.logger.Error("failed to refresh JWKS", zap.Error(err))
+		}
+
+		j.mu.RLock()
+		key, ok = j.keys[kid]
+		j.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, "", ErrUnknownKey
+	}
+
+	return key.key, key.alg, nil
+}
+
+// load reads the JWKS document from the source.
+func (j *JWKS) load(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(j.source, "http://") && !strings.HasPrefix(j.source, "https://") {
+		return os.ReadFile(strings.TrimPrefix(j.source, "file://"))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// parseJWKS parses the signing keys of a JWKS document by key ID. Keys of unsupported types are skipped.
+func parseJWKS(data []byte) (map[string]jwk, error) {
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := json.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwk, len(document.Keys))
+	for _, webKey := range document.Keys {
+		if webKey.Use != "" && webKey.Use != "sig" {
+			continue
+		}
+
+		key, err := webKey.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key '%s': %v", webKey.Kid, err)
+		}
+		if key == nil {
+			continue
+		}
+
+		keys[webKey.Kid] = jwk{key: key, alg: webKey.Alg}
+	}
+
+	return keys, nil
+}
+
+// publicKey decodes the public key of a JWK. It returns nil if the key type or curve is not supported.
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer.
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// jwksFixture serves a JWKS document that tests can change, like the JWKS endpoint of an identity provider.
+type jwksFixture struct {
+	mu       sync.Mutex
+	keys     []map[string]string
+	requests int
+}
+
+func (f *jwksFixture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": f.keys})
+}
+
+func (f *jwksFixture) setKeys(keys ...map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = keys
+}
+
+func (f *jwksFixture) requestCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid,
+		"kty": "RSA",
+		"alg": "RS256",
+		"use": "sig",
+		"n":   encodeBigInt(key.N),
+		"e":   encodeBigInt(big.NewInt(int64(key.E))),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid,
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   encodeBigInt(key.X),
+		"y":   encodeBigInt(key.Y),
+	}
+}
+
+func ed25519JWK(kid string, key ed25519.PublicKey) map[string]string {
+	return map[string]string{
+		"kid": kid,
+		"kty": "OKP",
+		"crv": "Ed25519",
+		"x":   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
+
+func TestJWKS_Key(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	fixture := &jwksFixture{}
+	fixture.setKeys(
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("ec", &ecKey.PublicKey),
+		ed25519JWK("ed", edKey),
+		map[string]string{"kid": "enc", "kty": "RSA", "use": "enc"},
+		map[string]string{"kid": "sym", "kty": "oct", "k": "c2VjcmV0"},
+	)
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, zap.NewNop())
+	require.NoError(t, jwks.Refresh(context.Background()))
+
+	key, alg, err := jwks.Key(context.Background(), "rsa")
+	assert.NoError(t, err)
+	assert.Equal(t, "RS256", alg)
+	assert.True(t, rsaKey.PublicKey.Equal(key))
+
+	key, _, err = jwks.Key(context.Background(), "ec")
+	assert.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key))
+
+	key, _, err = jwks.Key(context.Background(), "ed")
+	assert.NoError(t, err)
+	assert.True(t, edKey.Equal(key))
+
+	// Encryption and symmetric keys are never used to verify tokens
+	_, _, err = jwks.Key(context.Background(), "enc")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, _, err = jwks.Key(context.Background(), "sym")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestJWKS_RefreshOnUnknownKey(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	fixture := &jwksFixture{}
+	fixture.setKeys(rsaJWK("old", &oldKey.PublicKey))
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, zap.NewNop())
+	require.NoError(t, jwks.Refresh(context.Background()))
+
+	// The issuer rotates its key, which is not picked up until the minimum refresh interval has passed
+	fixture.setKeys(rsaJWK("old", &oldKey.PublicKey), rsaJWK("new", &newKey.PublicKey))
+	_, _, err = jwks.Key(context.Background(), "new")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	assert.Equal(t, 1, fixture.requestCount())
+
+	jwks.lastRefresh = jwks.lastRefresh.Add(-jwksMinRefreshInterval)
+	key, _, err := jwks.Key(context.Background(), "new")
+	assert.NoError(t, err)
+	assert.True(t, newKey.PublicKey.Equal(key))
+	assert.Equal(t, 2, fixture.requestCount())
+}
+
+func TestJWKS_RefreshKeepsKeysOnFailure(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	fixture := &jwksFixture{}
+	fixture.setKeys(rsaJWK("kid", &key.PublicKey))
+	server := httptest.NewServer(fixture)
+
+	jwks := NewJWKS(server.URL, zap.NewNop())
+	require.NoError(t, jwks.Refresh(context.Background()))
+
+	server.Close()
+	assert.Error(t, jwks.Refresh(context.Background()))
+
+	_, _, err = jwks.Key(context.Background(), "kid")
+	assert.NoError(t, err)
+}
+
+func TestJWKS_File(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{rsaJWK("kid", &key.PublicKey)}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	jwks := NewJWKS(path, zap.NewNop())
+	require.NoError(t, jwks.Refresh(context.Background()))
+
+	_, _, err = jwks.Key(context.Background(), "kid")
+	assert.NoError(t, err)
+}
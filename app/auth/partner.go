@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/payloadops/lanyard/app/config"
+)
+
+const (
+	// PartnerHeader is the request header naming the partner that signed a request.
+	PartnerHeader = "X-Lanyard-Partner"
+	// PartnerTimestampHeader is the request header with the Unix time at which a partner signed a request.
+	PartnerTimestampHeader = "X-Lanyard-Timestamp"
+	// PartnerSignatureHeader is the request header with the signature of a partner request.
+	PartnerSignatureHeader = "X-Lanyard-Signature"
+)
+
+// partnerSignatureVersion prefixes the hex encoded signatures of partner requests.
+const partnerSignatureVersion = "v1="
+
+var (
+	errMissingPartnerSignature = errors.New("missing partner signature")
+	errInvalidPartnerSignature = errors.New("invalid partner signature")
+	errInvalidPartnerTimestamp = errors.New("invalid partner signature timestamp")
+	errExpiredPartnerSignature = errors.New("partner signature has expired")
+	errReadBody                = errors.New("failed to read request body")
+	errBodyTooLarge            = errors.New("request body is too large")
+)
+
+// maxPartnerBodySize is the largest request body a partner may sign, since it is read in full before the handler.
+const maxPartnerBodySize = 1 << 20
+
+// SignPartnerRequest signs the body of a partner request at the given time. The signature is the HMAC-SHA256 of the
+// Unix timestamp and the body joined by a dot, keyed with the secret of the partner.
+func SignPartnerRequest(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return partnerSignatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PartnerSignatureMiddleware returns a middleware function that validates the signature of a request made by a
+// partner, such as a secret scanning service. Requests from unknown partners, with signatures that do not match
+// their body, or signed too long ago are rejected, so that captured requests cannot be replayed later.
+// It sets the partner ID in the request context if the signature is valid, and writes rejections with writeError.
+func PartnerSignatureMiddleware(cfg *config.Config, logger *zap.Logger, writeError ErrorWriter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := middleware.GetReqID(r.Context())
+			partnerID := r.Header.Get(PartnerHeader)
+			signature := r.Header.Get(PartnerSignatureHeader)
+			if partnerID == "" || signature == "" {
+				writeError(w, r, errMissingPartnerSignature, http.StatusUnauthorized)
+				return
+			}
+
+			secret, ok := cfg.Leaks.PartnerSecrets[partnerID]
+			if !ok || secret == "" {
+				logger.Warn("request from unknown partner",
+					zap.String("requestID", requestID),
+					zap.String("partnerID", partnerID),
+				)
+
+				writeError(w, r, errInvalidPartnerSignature, http.StatusUnauthorized)
+				return
+			}
+
+			unix, err := strconv.ParseInt(r.Header.Get(PartnerTimestampHeader), 10, 64)
+			if err != nil {
+				writeError(w, r, errInvalidPartnerTimestamp, http.StatusUnauthorized)
+				return
+			}
+
+			timestamp := time.Unix(unix, 0)
+			if skew := time.Since(timestamp); skew > cfg.Leaks.SignatureTolerance || -skew > cfg.Leaks.SignatureTolerance {
+				logger.Warn("partner signature outside of tolerance",
+					zap.String("requestID", requestID),
+					zap.String("partnerID", partnerID),
+					zap.Time("timestamp", timestamp),
+				)
+
+				writeError(w, r, errExpiredPartnerSignature, http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxPartnerBodySize+1))
+			if err != nil {
+				writeError(w, r, errReadBody, http.StatusBadRequest)
+				return
+			}
+			if len(body) > maxPartnerBodySize {
+				writeError(w, r, errBodyTooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			expected := SignPartnerRequest(secret, timestamp, body)
+			if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+				logger.Warn("invalid partner signature",
+					zap.String("requestID", requestID),
+					zap.String("partnerID", partnerID),
+				)
+
+				writeError(w, r, errInvalidPartnerSignature, http.StatusUnauthorized)
+				return
+			}
+
+			// The body was consumed to verify it, so hand the handler a copy
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Set the partner context
+			ctx := context.WithValue(r.Context(), "partnerID", partnerID)
+
+			// Call the next handler with the new context
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartnerSignatureMiddleware(t *testing.T) {
+	cfg := &config.Config{
+		Leaks: config.LeaksConfig{
+			PartnerSecrets:     map[string]string{"scanner": "scanner-secret"},
+			SignatureTolerance: 5 * time.Minute,
+		},
+	}
+
+	body := `{"tokens":[{"token":"lny_live_key1_secret_checksum"}]}`
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		partner        string
+		timestamp      time.Time
+		signature      string
+		expectedStatus int
+	}{
+		{"Valid signature", "scanner", now, SignPartnerRequest("scanner-secret", now, []byte(body)), http.StatusOK},
+		{"Upper case signature", "scanner", now, strings.ToUpper(SignPartnerRequest("scanner-secret", now, []byte(body))), http.StatusOK},
+		{"Missing signature", "scanner", now, "", http.StatusUnauthorized},
+		{"Unknown partner", "other", now, SignPartnerRequest("scanner-secret", now, []byte(body)), http.StatusUnauthorized},
+		{"Wrong secret", "scanner", now, SignPartnerRequest("other-secret", now, []byte(body)), http.StatusUnauthorized},
+		{"Other body", "scanner", now, SignPartnerRequest("scanner-secret", now, []byte(`{"tokens":[]}`)), http.StatusUnauthorized},
+		{"Other timestamp", "scanner", now, SignPartnerRequest("scanner-secret", now.Add(-time.Second), []byte(body)), http.StatusUnauthorized},
+		{"Replayed request", "scanner", now.Add(-10 * time.Minute), SignPartnerRequest("scanner-secret", now.Add(-10*time.Minute), []byte(body)), http.StatusUnauthorized},
+		{"Request from the future", "scanner", now.Add(10 * time.Minute), SignPartnerRequest("scanner-secret", now.Add(10*time.Minute), []byte(body)), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var partnerID, receivedBody string
+			handler := PartnerSignatureMiddleware(cfg, zap.NewNop(), writeTextError)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				partnerID, _ = r.Context().Value("partnerID").(string)
+				b, _ := io.ReadAll(r.Body)
+				receivedBody = string(b)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/leaks", strings.NewReader(body))
+			req.Header.Set(PartnerHeader, tt.partner)
+			req.Header.Set(PartnerTimestampHeader, strconv.FormatInt(tt.timestamp.Unix(), 10))
+			if tt.signature != "" {
+				req.Header.Set(PartnerSignatureHeader, tt.signature)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatus, rr.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.partner, partnerID)
+				assert.Equal(t, body, receivedBody)
+			}
+		})
+	}
+}
+
+func TestSignPartnerRequest(t *testing.T) {
+	// Partners sign the timestamp and body joined by a dot, so the signature can be reproduced with standard tools
+	signature := SignPartnerRequest("secret", time.Unix(1700000000, 0), []byte("{}"))
+	assert.Equal(t, "v1=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163", signature)
+}
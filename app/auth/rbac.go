@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"fmt"
+
+	"github.com/payloadops/lanyard/app/scope"
+)
+
+// Role is the role of a user in an organization, carried by the role claim of their JWT.
+type Role string
+
+const (
+	// RoleOwner can do everything, including deleting the organization.
+	RoleOwner Role = "owner"
+	// RoleAdmin manages the organization, its services and their pricing tiers, and reads the audit log.
+	RoleAdmin Role = "admin"
+	// RoleDeveloper manages the API keys and actors of services.
+	RoleDeveloper Role = "developer"
+	// RoleViewer can only read.
+	RoleViewer Role = "viewer"
+)
+
+// DefaultRole is the role of tokens without a role claim, which are given the least privilege.
+const DefaultRole = RoleViewer
+
+// Permission is an action on a kind of resource of the management API.
+type Permission string
+
+const (
+	PermissionOrganizationRead   Permission = "organizations:read"
+	PermissionOrganizationWrite  Permission = "organizations:write"
+	PermissionOrganizationDelete Permission = "organizations:delete"
+	PermissionServicesRead       Permission = "services:read"
+	PermissionServicesWrite      Permission = "services:write"
+	PermissionAPIKeysRead        Permission = "keys:read"
+	PermissionAPIKeysWrite       Permission = "keys:write"
+	PermissionActorsRead         Permission = "actors:read"
+	PermissionActorsWrite        Permission = "actors:write"
+	PermissionPricingTiersRead   Permission = "pricing-tiers:read"
+	PermissionPricingTiersWrite  Permission = "pricing-tiers:write"
+	PermissionUsageRead          Permission = "usage:read"
+	PermissionAuditRead          Permission = "audit:read"
+)
+
+// rolePermissions is the permission matrix of the roles. Permissions follow the scope grammar, so a role may be
+// granted all the permissions of a kind of resource with a wildcard.
+var rolePermissions = map[Role][]string{
+	RoleOwner: {scope.Wildcard},
+	RoleAdmin: {
+		"organizations:read",
+		"organizations:write",
+		"services:*",
+		"keys:*",
+		"actors:*",
+		"pricing-tiers:*",
+		"usage:*",
+		"audit:read",
+	},
+	RoleDeveloper: {
+		"organizations:read",
+		"services:read",
+		"keys:*",
+		"actors:*",
+		"pricing-tiers:read",
+		"usage:read",
+	},
+	RoleViewer: {
+		"organizations:read",
+		"services:read",
+		"keys:read",
+		"actors:read",
+		"pricing-tiers:read",
+		"usage:read",
+	},
+}
+
+// ParseRole parses the role claim of a token, defaulting to DefaultRole when it is empty.
+func ParseRole(value string) (Role, error) {
+	if value == "" {
+		return DefaultRole, nil
+	}
+
+	role := Role(value)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role '%s'", value)
+	}
+	return role, nil
+}
+
+// Can reports whether the role is granted a permission.
+func (r Role) Can(permission Permission) bool {
+	return len(scope.Missing(rolePermissions[r], []string{string(permission)})) == 0
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRole(t *testing.T) {
+	role, err := ParseRole("admin")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleAdmin, role)
+
+	// Tokens without a role get the least privilege
+	role, err = ParseRole("")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, role)
+
+	_, err = ParseRole("root")
+	assert.Error(t, err)
+}
+
+func TestRole_Can(t *testing.T) {
+	tests := []struct {
+		permission Permission
+		allowed    []Role
+	}{
+		{PermissionServicesRead, []Role{RoleOwner, RoleAdmin, RoleDeveloper, RoleViewer}},
+		{PermissionUsageRead, []Role{RoleOwner, RoleAdmin, RoleDeveloper, RoleViewer}},
+		{PermissionAPIKeysWrite, []Role{RoleOwner, RoleAdmin, RoleDeveloper}},
+		{PermissionActorsWrite, []Role{RoleOwner, RoleAdmin, RoleDeveloper}},
+		{PermissionServicesWrite, []Role{RoleOwner, RoleAdmin}},
+		{PermissionPricingTiersWrite, []Role{RoleOwner, RoleAdmin}},
+		{PermissionOrganizationWrite, []Role{RoleOwner, RoleAdmin}},
+		{PermissionAuditRead, []Role{RoleOwner, RoleAdmin}},
+		{PermissionOrganizationDelete, []Role{RoleOwner}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.permission), func(t *testing.T) {
+			for _, role := range []Role{RoleOwner, RoleAdmin, RoleDeveloper, RoleViewer} {
+				assert.Equal(t, contains(tt.allowed, role), role.Can(tt.permission), "role %s", role)
+			}
+		})
+	}
+
+	assert.False(t, Role("root").Can(PermissionServicesRead))
+}
+
+func contains(roles []Role, role Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/utils"
+)
+
+// SecretVerifier checks presented secrets against the hashes stored on API keys. When secret migration is enabled
+// it also accepts legacy plaintext secrets and replaces them with a hash on the first successful verification.
+type SecretVerifier struct {
+	pepper        string
+	migrate       bool
+	apiKeyManager dal.APIKeyManager
+	logger        *zap.Logger
+}
+
+// NewSecretVerifier creates a new SecretVerifier.
+func NewSecretVerifier(cfg *config.Config, logger *zap.Logger, apiKeyManager dal.APIKeyManager) *SecretVerifier {
+	return &SecretVerifier{
+		pepper:        cfg.APIKeys.SecretPepper,
+		migrate:       cfg.APIKeys.SecretMigration,
+		apiKeyManager: apiKeyManager,
+		logger:        logger,
+	}
+}
+
+// HashSecret hashes a newly generated secret for storage.
+func (v *SecretVerifier) HashSecret(secret string) (string, error) {
+	return utils.HashSecret(secret, v.pepper)
+}
+
+// SecretVersion identifies which of the secrets of an API key a request presented.
+type SecretVersion string
+
+const (
+	// SecretCurrent is the secret generated by the last rotation, or when the key was generated.
+	SecretCurrent SecretVersion = "current"
+	// SecretPrevious is the secret replaced by the last rotation, which is accepted until its grace period ends.
+	SecretPrevious SecretVersion = "previous"
+)
+
+// Verify reports whether the secret matches one of the secrets of the API key, and which one it matches.
+func (v *SecretVerifier) Verify(ctx context.Context, key *dal.APIKey, secret string) (SecretVersion, bool) {
+	if v.verifyCurrent(ctx, key, secret) {
+		return SecretCurrent, true
+	}
+
+	if v.verifyPrevious(ctx, key, secret) {
+		v.logger.Info("API key authenticated with previous secret",
+			zap.String("requestID", middleware.GetReqID(ctx)),
+			zap.String("keyID", key.APIKeyID),
+			zap.String("previousSecretExpiry", key.PreviousSecretExpiry),
+		)
+		return SecretPrevious, true
+	}
+
+	return "", false
+}
+
+// verifyPrevious reports whether the secret matches the previous secret of a rotated API key during its grace period.
+func (v *SecretVerifier) verifyPrevious(ctx context.Context, key *dal.APIKey, secret string) bool {
+	if key.PreviousSecret == "" || key.PreviousSecretExpiry == "" {
+		return false
+	}
+
+	expiry, err := utils.ParseTimestamp(key.PreviousSecretExpiry)
+	if err != nil {
+		v.logger.Error("failed to parse timestamp",
+			zap.String("requestID", middleware.GetReqID(ctx)),
+			zap.Error(err),
+		)
+		return false
+	}
+	if !time.Now().Before(expiry) {
+		return false
+	}
+
+	// A legacy plaintext secret that was rotated before it was rehashed is not worth rehashing anymore
+	if utils.IsSecretHash(key.PreviousSecret) {
+		return utils.VerifySecretHash(secret, key.PreviousSecret, v.pepper)
+	}
+	return v.migrate && utils.SecureCompare(secret, key.PreviousSecret)
+}
+
+// verifyCurrent reports whether the secret matches the current secret of the API key.
+func (v *SecretVerifier) verifyCurrent(ctx context.Context, key *dal.APIKey, secret string) bool {
+	if utils.IsSecretHash(key.Secret) {
+		return utils.VerifySecretHash(secret, key.Secret, v.pepper)
+	}
+
+	requestID := middleware.GetReqID(ctx)
+	if !v.migrate {
+		v.logger.Warn("rejected API key with plaintext secret",
+			zap.String("requestID", requestID),
+			zap.String("keyID", key.APIKeyID),
+		)
+		return false
+	}
+
+	if !utils.SecureCompare(secret, key.Secret) {
+		return false
+	}
+
+	// The secret is valid, so failing to rehash it must not fail the request; it is retried on the next use
+	hash, err := v.HashSecret(secret)
+	if err != nil {
+		v.logger.Error("failed to hash API key secret",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return true
+	}
+
+	updated, err := v.apiKeyManager.UpdateAPIKeySecret(ctx, key.APIKeyID, key.Secret, hash)
+	if err != nil {
+		v.logger.Error("failed to rehash API key secret",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return true
+	}
+	if !updated {
+		// The key was rotated or deleted since it was read, which the rehash must not undo
+		return true
+	}
+
+	key.Secret = hash
+	return true
+}
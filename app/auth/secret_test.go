@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// verify reports whether the secret matches any of the secrets of the key.
+func verify(verifier *SecretVerifier, key *dal.APIKey, secret string) bool {
+	_, ok := verifier.Verify(context.Background(), key, secret)
+	return ok
+}
+
+func TestSecretVerifier(t *testing.T) {
+	cfg := &config.Config{
+		APIKeys: config.APIKeysConfig{SecretPepper: "pepper"},
+	}
+	migrationCfg := &config.Config{
+		APIKeys: config.APIKeysConfig{SecretPepper: "pepper", SecretMigration: true},
+	}
+
+	t.Run("Hashed secret", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		verifier := NewSecretVerifier(cfg, zap.NewNop(), mocks.NewMockAPIKeyManager(ctrl))
+		hash, err := verifier.HashSecret("secret")
+		assert.NoError(t, err)
+
+		key := &dal.APIKey{APIKeyID: "key1", Secret: hash}
+		assert.True(t, verify(verifier, key, "secret"))
+		assert.False(t, verify(verifier, key, "wrong"))
+	})
+
+	t.Run("Plaintext secret without migration", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		verifier := NewSecretVerifier(cfg, zap.NewNop(), mocks.NewMockAPIKeyManager(ctrl))
+		key := &dal.APIKey{APIKeyID: "key1", Secret: "secret"}
+		assert.False(t, verify(verifier, key, "secret"))
+	})
+
+	t.Run("Plaintext secret is rehashed during migration", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeyManager := mocks.NewMockAPIKeyManager(ctrl)
+		verifier := NewSecretVerifier(migrationCfg, zap.NewNop(), mockAPIKeyManager)
+
+		var stored string
+		mockAPIKeyManager.EXPECT().
+			UpdateAPIKeySecret(gomock.Any(), "key1", "secret", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, apiKeyID, plaintext, secret string) (bool, error) {
+				stored = secret
+				return true, nil
+			}).Times(1)
+
+		key := &dal.APIKey{APIKeyID: "key1", Secret: "secret"}
+		assert.True(t, verify(verifier, key, "secret"))
+		assert.True(t, utils.IsSecretHash(stored))
+		assert.True(t, utils.VerifySecretHash("secret", stored, "pepper"))
+		assert.Equal(t, stored, key.Secret)
+	})
+
+	t.Run("Wrong plaintext secret is not rehashed during migration", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		verifier := NewSecretVerifier(migrationCfg, zap.NewNop(), mocks.NewMockAPIKeyManager(ctrl))
+		key := &dal.APIKey{APIKeyID: "key1", Secret: "secret"}
+		assert.False(t, verify(verifier, key, "wrong"))
+	})
+
+	t.Run("Failed rehash still authorizes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeyManager := mocks.NewMockAPIKeyManager(ctrl)
+		verifier := NewSecretVerifier(migrationCfg, zap.NewNop(), mockAPIKeyManager)
+
+		mockAPIKeyManager.EXPECT().
+			UpdateAPIKeySecret(gomock.Any(), "key1", "secret", gomock.Any()).
+			Return(false, fmt.Errorf("database error")).Times(1)
+
+		key := &dal.APIKey{APIKeyID: "key1", Secret: "secret"}
+		assert.True(t, verify(verifier, key, "secret"))
+		assert.Equal(t, "secret", key.Secret)
+	})
+
+	t.Run("Rehash of a rotated or deleted key is skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockAPIKeyManager := mocks.NewMockAPIKeyManager(ctrl)
+		verifier := NewSecretVerifier(migrationCfg, zap.NewNop(), mockAPIKeyManager)
+
+		mockAPIKeyManager.EXPECT().
+			UpdateAPIKeySecret(gomock.Any(), "key1", "secret", gomock.Any()).
+			Return(false, nil).Times(1)
+
+		key := &dal.APIKey{APIKeyID: "key1", Secret: "secret"}
+		assert.True(t, verify(verifier, key, "secret"))
+		assert.Equal(t, "secret", key.Secret)
+	})
+	t.Run("Previous secret during grace period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		verifier := NewSecretVerifier(cfg, zap.NewNop(), mocks.NewMockAPIKeyManager(ctrl))
+		current, _ := verifier.HashSecret("current")
+		previous, _ := verifier.HashSecret("previous")
+
+		key := &dal.APIKey{
+			APIKeyID:             "key1",
+			Secret:               current,
+			PreviousSecret:       previous,
+			PreviousSecretExpiry: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+		}
+
+		version, ok := verifier.Verify(context.Background(), key, "current")
+		assert.True(t, ok)
+		assert.Equal(t, SecretCurrent, version)
+
+		version, ok = verifier.Verify(context.Background(), key, "previous")
+		assert.True(t, ok)
+		assert.Equal(t, SecretPrevious, version)
+
+		assert.False(t, verify(verifier, key, "wrong"))
+	})
+
+	t.Run("Previous secret after grace period", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		verifier := NewSecretVerifier(cfg, zap.NewNop(), mocks.NewMockAPIKeyManager(ctrl))
+		current, _ := verifier.HashSecret("current")
+		previous, _ := verifier.HashSecret("previous")
+
+		key := &dal.APIKey{
+			APIKeyID:             "key1",
+			Secret:               current,
+			PreviousSecret:       previous,
+			PreviousSecretExpiry: time.Now().Add(-time.Second).UTC().Format(time.RFC3339),
+		}
+
+		assert.True(t, verify(verifier, key, "current"))
+		assert.False(t, verify(verifier, key, "previous"))
+	})
+}
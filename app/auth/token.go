@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/payloadops/lanyard/app/config"
+)
+
+// Audience is the aud claim of a token, which is either a single string or an array of strings.
+type Audience []string
+
+// UnmarshalJSON decodes an audience from a string or an array of strings.
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+// MarshalJSON encodes a single audience as a string and several audiences as an array.
+func (a Audience) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// Contains reports whether the audience includes the given value.
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenVerifier verifies the signature and the registered claims of JWTs. HMAC tokens are verified against the
+// current and previous JWT secrets, and asymmetric tokens against the key of the JWKS named by their kid header.
+type TokenVerifier struct {
+	secrets   [][]byte
+	jwks      *JWKS
+	issuers   []string
+	audience  string
+	clockSkew time.Duration
+	now       func() time.Time
+}
+
+// NewTokenVerifier creates a new TokenVerifier. The JWKS may be nil, in which case only HMAC tokens are accepted.
+func NewTokenVerifier(cfg *config.Config, jwks *JWKS) *TokenVerifier {
+	var secrets [][]byte
+	for _, secret := range append([]string{cfg.JWTSecret}, cfg.JWT.PreviousSecrets...) {
+		if secret != "" {
+			secrets = append(secrets, []byte(secret))
+		}
+	}
+
+	return &TokenVerifier{
+		secrets:   secrets,
+		jwks:      jwks,
+		issuers:   cfg.JWT.Issuers,
+		audience:  cfg.JWT.Audience,
+		clockSkew: cfg.JWT.ClockSkew,
+		now:       time.Now,
+	}
+}
+
+// Verify parses a token and returns its claims if its signature and its registered claims are valid.
+func (v *TokenVerifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, parts, err := new(jwt.Parser).ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := v.keys(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	signingString := strings.Join(parts[0:2], ".")
+	verified := false
+	for _, key := range keys {
+		if token.Method.Verify(signingString, parts[2], key) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, jwt.ErrSignatureInvalid
+	}
+
+	err = v.validateClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// keys returns the keys that a token may be signed with, given its algorithm.
+func (v *TokenVerifier) keys(ctx context.Context, token *jwt.Token) ([]interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		keys := make([]interface{}, 0, len(v.secrets))
+		for _, secret := range v.secrets {
+			keys = append(keys, secret)
+		}
+		return keys, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if v.jwks == nil || kid == "" {
+		return nil, fmt.Errorf("unexpected signing method '%s'", token.Method.Alg())
+	}
+
+	key, alg, err := v.jwks.Key(ctx, kid)
+	if err != nil {
+		return nil, err
+	}
+
+	// The algorithm must match the type of the key, so that a public key is never used as an HMAC secret
+	if alg != "" && alg != token.Method.Alg() {
+		return nil, fmt.Errorf("signing method '%s' does not match key '%s'", token.Method.Alg(), kid)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey:
+		_, isRSA := token.Method.(*jwt.SigningMethodRSA)
+		_, isPSS := token.Method.(*jwt.SigningMethodRSAPSS)
+		if isRSA || isPSS {
+			return []interface{}{key}, nil
+		}
+	case *ecdsa.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodECDSA); ok {
+			return []interface{}{key}, nil
+		}
+	case ed25519.PublicKey:
+		if _, ok := token.Method.(*jwt.SigningMethodEd25519); ok {
+			return []interface{}{key}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("signing method '%s' does not match key '%s'", token.Method.Alg(), kid)
+}
+
+// validateClaims checks the time, issuer and audience claims, allowing for the configured clock skew.
+func (v *TokenVerifier) validateClaims(claims *Claims) error {
+	now := v.now()
+	if !claims.VerifyExpiresAt(now.Add(-v.clockSkew).Unix(), false) {
+		return errors.New("token is expired")
+	}
+
+	if !claims.VerifyNotBefore(now.Add(v.clockSkew).Unix(), false) {
+		return errors.New("token is not valid yet")
+	}
+
+	if !claims.VerifyIssuedAt(now.Add(v.clockSkew).Unix(), false) {
+		return errors.New("token used before issued")
+	}
+
+	if len(v.issuers) > 0 {
+		valid := false
+		for _, issuer := range v.issuers {
+			if claims.Issuer == issuer {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unexpected issuer '%s'", claims.Issuer)
+		}
+	}
+
+	if v.audience != "" && !claims.Audience.Contains(v.audience) {
+		return errors.New("token is not intended for this audience")
+	}
+
+	return nil
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt"
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	tokenString, err := token.SignedString(key)
+	require.NoError(t, err)
+	return tokenString
+}
+
+func TestTokenVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublicKey, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	fixture := &jwksFixture{}
+	fixture.setKeys(rsaJWK("rsa", &rsaKey.PublicKey), ecJWK("ec", &ecKey.PublicKey), ed25519JWK("ed", edPublicKey))
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	jwks := NewJWKS(server.URL, zap.NewNop())
+	require.NoError(t, jwks.Refresh(context.Background()))
+
+	cfg := &config.Config{
+		JWTSecret: "secret",
+		JWT: config.JWTConfig{
+			PreviousSecrets: []string{"previous"},
+			Issuers:         []string{"https://issuer.example.com/", "https://other.example.com/"},
+			Audience:        "lanyard",
+			ClockSkew:       time.Minute,
+		},
+	}
+	verifier := NewTokenVerifier(cfg, jwks)
+
+	now := time.Now()
+	claims := func(modify func(*Claims)) *Claims {
+		c := &Claims{
+			OrgID:    "org123",
+			Audience: Audience{"lanyard"},
+			StandardClaims: jwt.StandardClaims{
+				Subject:   "user123",
+				Issuer:    "https://issuer.example.com/",
+				IssuedAt:  now.Unix(),
+				ExpiresAt: now.Add(time.Hour).Unix(),
+			},
+		}
+		if modify != nil {
+			modify(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{
+			name:  "RS256 token",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "ES256 token",
+			token: signToken(t, jwt.SigningMethodES256, "ec", ecKey, claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "EdDSA token",
+			token: signToken(t, jwt.SigningMethodEdDSA, "ed", edKey, claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "HS256 token with current secret",
+			token: signToken(t, jwt.SigningMethodHS256, "", []byte("secret"), claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "HS256 token with previous secret",
+			token: signToken(t, jwt.SigningMethodHS256, "", []byte("previous"), claims(nil)),
+			valid: true,
+		},
+		{
+			name:  "HS256 token with unknown secret",
+			token: signToken(t, jwt.SigningMethodHS256, "", []byte("unknown"), claims(nil)),
+		},
+		{
+			name:  "Unknown key ID",
+			token: signToken(t, jwt.SigningMethodRS256, "missing", rsaKey, claims(nil)),
+		},
+		{
+			name:  "Missing key ID",
+			token: signToken(t, jwt.SigningMethodRS256, "", rsaKey, claims(nil)),
+		},
+		{
+			name:  "Algorithm does not match key",
+			token: signToken(t, jwt.SigningMethodRS512, "rsa", rsaKey, claims(nil)),
+		},
+		{
+			name:  "Key type does not match algorithm",
+			token: signToken(t, jwt.SigningMethodRS256, "ec", rsaKey, claims(nil)),
+		},
+		{
+			name:  "Unsigned token",
+			token: signToken(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, claims(nil)),
+		},
+		{
+			name:  "Other accepted issuer",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.Issuer = "https://other.example.com/" })),
+			valid: true,
+		},
+		{
+			name:  "Unexpected issuer",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.Issuer = "https://evil.example.com/" })),
+		},
+		{
+			name:  "Audience in array",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.Audience = Audience{"other", "lanyard"} })),
+			valid: true,
+		},
+		{
+			name:  "Unexpected audience",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.Audience = Audience{"other"} })),
+		},
+		{
+			name:  "Expired within clock skew",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.ExpiresAt = now.Add(-30 * time.Second).Unix() })),
+			valid: true,
+		},
+		{
+			name:  "Expired beyond clock skew",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.ExpiresAt = now.Add(-2 * time.Minute).Unix() })),
+		},
+		{
+			name:  "Not valid yet within clock skew",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.NotBefore = now.Add(30 * time.Second).Unix() })),
+			valid: true,
+		},
+		{
+			name:  "Not valid yet beyond clock skew",
+			token: signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(func(c *Claims) { c.NotBefore = now.Add(2 * time.Minute).Unix() })),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verified, err := verifier.Verify(context.Background(), tt.token)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "user123", verified.Subject)
+			assert.Equal(t, "org123", verified.OrgID)
+		})
+	}
+}
+
+func TestJWTAuthMiddleware_JWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	fixture := &jwksFixture{}
+	fixture.setKeys(rsaJWK("rsa", &key.PublicKey))
+	server := httptest.NewServer(fixture)
+	defer server.Close()
+
+	cfg := &config.Config{JWTSecret: "secret"}
+	jwks := NewJWKS(server.URL, zap.NewNop())
+
+	r := chi.NewRouter()
+	r.Use(JWTAuthMiddleware(cfg, zap.NewNop(), writeTextError, jwks))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "org123", r.Context().Value("orgID"))
+		assert.Equal(t, "user123", r.Context().Value("userID"))
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// The keys are fetched on the first token with an unknown key ID
+	tokenString := signToken(t, jwt.SigningMethodRS256, "rsa", key, Claims{
+		OrgID: "org123",
+		StandardClaims: jwt.StandardClaims{
+			Subject:   "user123",
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestAudience_JSON(t *testing.T) {
+	var claims Claims
+	require.NoError(t, json.Unmarshal([]byte(`{"aud":"single"}`), &claims))
+	assert.Equal(t, Audience{"single"}, claims.Audience)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"aud":["first","second"]}`), &claims))
+	assert.Equal(t, Audience{"first", "second"}, claims.Audience)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"aud":1}`), &claims))
+
+	data, err := json.Marshal(Claims{Audience: Audience{"single"}})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"aud":"single"`)
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -11,10 +12,15 @@ import (
 
 //go:generate mockgen -package=mocks -destination=mocks/mock_cache_client.go github.com/payloadops/plato/app/cache Cache
 
+// ErrNotSupported is returned by caches that cannot perform an operation, such as the NoopCache evaluating a script.
+var ErrNotSupported = errors.New("operation not supported by cache")
+
 // Cache is an interface defining methods for a caching layer.
 type Cache interface {
 	Set(ctx context.Context, key string, value string, expiration time.Duration) error
+	SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error)
 	Get(ctx context.Context, key string, expiration time.Duration) (string, error)
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
 }
 
 // Ensure RedisCache implements the Cache interface
@@ -38,6 +44,11 @@ func (r *RedisCache) Set(ctx context.Context, key string, value string, expirati
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+// SetNX stores a value in the cache unless the key already exists, and reports whether it was stored.
+func (r *RedisCache) SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 // Get retrieves a value from the cache and resets the expiration atomically.
 func (r *RedisCache) Get(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	script := `
@@ -60,6 +71,11 @@ func (r *RedisCache) Get(ctx context.Context, key string, expiration time.Durati
 	return result.(string), nil
 }
 
+// Eval atomically runs a Lua script against the given keys and returns its result.
+func (r *RedisCache) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return r.client.Eval(ctx, script, keys, args...).Result()
+}
+
 // NoopCache implements the Cache interface as a no-op.
 type NoopCache struct{}
 
@@ -74,8 +90,19 @@ func (n *NoopCache) Set(ctx context.Context, key string, value string, expiratio
 	return nil
 }
 
+// SetNX is a no-op for NoopCache.
+func (n *NoopCache) SetNX(ctx context.Context, key string, value string, expiration time.Duration) (bool, error) {
+	// No operation performed, nothing is stored
+	return false, nil
+}
+
 // Get is a no-op for NoopCache.
 func (n *NoopCache) Get(ctx context.Context, key string, expiration time.Duration) (string, error) {
 	// No operation performed, return an empty string and no error
 	return "", nil
 }
+
+// Eval is not supported by NoopCache, callers are expected to fall back to local state.
+func (n *NoopCache) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, ErrNotSupported
+}
@@ -31,6 +31,33 @@ func TestRedisCache_Set(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestRedisCache_SetNX(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRedisClient := mocks.NewMockCmdable(ctrl)
+	redisCache := cache.NewRedisCache(mockRedisClient)
+
+	ctx := context.Background()
+	key := "test-key"
+	value := "test-value"
+	expiration := 10 * time.Second
+
+	gomock.InOrder(
+		mockRedisClient.EXPECT().SetNX(ctx, key, value, expiration).Return(redis.NewBoolResult(true, nil)),
+		mockRedisClient.EXPECT().SetNX(ctx, key, value, expiration).Return(redis.NewBoolResult(false, nil)),
+	)
+
+	stored, err := redisCache.SetNX(ctx, key, value, expiration)
+	assert.NoError(t, err)
+	assert.True(t, stored)
+
+	// An existing value is kept
+	stored, err = redisCache.SetNX(ctx, key, value, expiration)
+	assert.NoError(t, err)
+	assert.False(t, stored)
+}
+
 func TestRedisCache_Get(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -88,6 +115,25 @@ func TestRedisCache_Get_KeyNotFound(t *testing.T) {
 	assert.Equal(t, "", result)
 }
 
+func TestRedisCache_Eval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRedisClient := mocks.NewMockCmdable(ctrl)
+	redisCache := cache.NewRedisCache(mockRedisClient)
+
+	ctx := context.Background()
+	script := "return redis.call('INCR', KEYS[1])"
+
+	mockRedisClient.EXPECT().
+		Eval(ctx, script, []string{"test-key"}, 1, "two").
+		Return(redis.NewCmdResult(int64(1), nil))
+
+	result, err := redisCache.Eval(ctx, script, []string{"test-key"}, 1, "two")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), result)
+}
+
 func TestNoopCache_Set(t *testing.T) {
 	noopCache := cache.NewNoopCache()
 	ctx := context.Background()
@@ -109,3 +155,11 @@ func TestNoopCache_Get(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "", result)
 }
+
+func TestNoopCache_Eval(t *testing.T) {
+	noopCache := cache.NewNoopCache()
+
+	result, err := noopCache.Eval(context.Background(), "return 1", []string{"test-key"})
+	assert.ErrorIs(t, err, cache.ErrNotSupported)
+	assert.Nil(t, result)
+}
@@ -0,0 +1,122 @@
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/payloadops/lanyard/app/ipblock"
+)
+
+// Resolver resolves the IP address of the client of a request. Clients can set any X-Forwarded-For header they like,
+// so the header is only believed for the hops added by trusted proxies: the client is the right-most address that is
+// not a trusted proxy, starting from the peer of the connection. A nil Resolver trusts no proxy.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver creates a new Resolver that trusts the proxies in the given CIDR ranges or IP addresses.
+func NewResolver(trustedProxies []string) (*Resolver, error) {
+	resolver := &Resolver{}
+	for _, value := range trustedProxies {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		resolver.trusted = append(resolver.trusted, prefix)
+	}
+	return resolver, nil
+}
+
+// parsePrefix parses a CIDR range, or an IP address as the range of that address alone.
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy '%s'", value)
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := ipblock.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy '%s'", value)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isTrusted reports whether an address is one of a trusted proxy.
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	if r == nil {
+		return false
+	}
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client of a request, without a port. It returns the remote address as is
+// when it cannot be parsed.
+func (r *Resolver) ClientIP(req *http.Request) string {
+	peer, err := ipblock.ParseAddr(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	if !r.isTrusted(peer) {
+		return peer.String()
+	}
+
+	// Walk the hops from the right, which were added by the proxies closest to us, until one that no trusted proxy
+	// added. Hops left of it were written by the client and are not believed.
+	client := peer
+	hops := forwardedFor(req.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := ipblock.ParseAddr(hops[i])
+		if err != nil {
+			break
+		}
+		client = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// forwardedFor returns the addresses of every X-Forwarded-For header of a request, in order.
+func forwardedFor(header http.Header) []string {
+	var hops []string
+	for _, value := range header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// Middleware sets the IP address of the client in the request context, where FromRequest finds it.
+func (r *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), "clientIP", r.ClientIP(req))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// FromRequest returns the IP address of the client of a request that was set by Middleware, or the remote address of
+// the request when the middleware did not run.
+func FromRequest(req *http.Request) string {
+	if clientIP, ok := req.Context().Value("clientIP").(string); ok && clientIP != "" {
+		return clientIP
+	}
+	return req.RemoteAddr
+}
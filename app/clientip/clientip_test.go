@@ -0,0 +1,78 @@
+package clientip_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/clientip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResolver(t *testing.T) {
+	_, err := clientip.NewResolver([]string{"10.0.0.0/8", " 192.0.2.1 ", "", "2001:db8::/32"})
+	assert.NoError(t, err)
+
+	_, err = clientip.NewResolver([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = clientip.NewResolver([]string{"proxy.internal"})
+	assert.Error(t, err)
+}
+
+func TestResolver_ClientIP(t *testing.T) {
+	resolver, err := clientip.NewResolver([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		resolver     *clientip.Resolver
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		expected     string
+	}{
+		{name: "Direct client", resolver: resolver, remoteAddr: "203.0.113.7:4321", expected: "203.0.113.7"},
+		{name: "Direct client with a forged header", resolver: resolver, remoteAddr: "203.0.113.7:4321", forwardedFor: []string{"198.51.100.7"}, realIP: "198.51.100.7", expected: "203.0.113.7"},
+		{name: "Client behind a trusted proxy", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"203.0.113.7"}, expected: "203.0.113.7"},
+		{name: "Client behind a chain of trusted proxies", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"203.0.113.7, 10.0.0.2", "10.0.0.3"}, expected: "203.0.113.7"},
+		{name: "Forged hop left of the client", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"198.51.100.7, 203.0.113.7"}, expected: "203.0.113.7"},
+		{name: "X-Real-IP is not believed", resolver: resolver, remoteAddr: "10.0.0.1:4321", realIP: "198.51.100.7", expected: "10.0.0.1"},
+		{name: "Invalid hop", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"203.0.113.7, unknown"}, expected: "10.0.0.1"},
+		{name: "Only trusted hops", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"10.0.0.2"}, expected: "10.0.0.2"},
+		{name: "IPv6 client", resolver: resolver, remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"[2001:db8::1]:4321"}, expected: "2001:db8::1"},
+		{name: "No trusted proxies", remoteAddr: "10.0.0.1:4321", forwardedFor: []string{"203.0.113.7"}, expected: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			assert.Equal(t, tt.expected, tt.resolver.ClientIP(req))
+		})
+	}
+}
+
+func TestResolver_Middleware(t *testing.T) {
+	var clientIP string
+	handler := (*clientip.Resolver)(nil).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP = clientip.FromRequest(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:4321"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "203.0.113.7", clientIP)
+
+	// Requests that did not go through the middleware fall back to the remote address
+	assert.Equal(t, "203.0.113.7:4321", clientip.FromRequest(req))
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -24,6 +26,77 @@ type AWSConfig struct {
 	S3Endpoint       string          `envconfig:"S3_ENDPOINT"`
 }
 
+// APIKeysConfig holds configuration values for API key secrets and expiry.
+type APIKeysConfig struct {
+	// SecretPepper is the server-side key mixed into every API key secret hash.
+	SecretPepper string `envconfig:"API_KEY_SECRET_PEPPER" required:"true"`
+	// SecretMigration accepts legacy plaintext secrets and rehashes them the first time they authenticate.
+	SecretMigration bool `envconfig:"API_KEY_SECRET_MIGRATION" default:"false"`
+	// RotationGracePeriod is how long the previous secret of a rotated API key remains valid by default.
+	RotationGracePeriod time.Duration `envconfig:"API_KEY_ROTATION_GRACE_PERIOD" default:"24h"`
+	// MaxRotationGracePeriod is the longest grace period a rotation may request.
+	MaxRotationGracePeriod time.Duration `envconfig:"API_KEY_MAX_ROTATION_GRACE_PERIOD" default:"168h"`
+	// ExpirySweepInterval is how often API keys whose expiry has passed are transitioned to the expired status.
+	ExpirySweepInterval time.Duration `envconfig:"API_KEY_EXPIRY_SWEEP_INTERVAL" default:"5m"`
+	// TokenPrefix is the prefix of the tokens of API keys of services that do not configure their own.
+	TokenPrefix string `envconfig:"API_KEY_TOKEN_PREFIX" default:"lny"`
+	// TokenEnvironment is the environment of the tokens of API keys, so that tokens of other deployments are rejected.
+	TokenEnvironment string `envconfig:"API_KEY_TOKEN_ENVIRONMENT" default:"live"`
+}
+
+// JWTConfig holds configuration values for verifying JWTs.
+type JWTConfig struct {
+	// PreviousSecrets are HMAC secrets that are still accepted alongside JWT_SECRET while it is being rotated.
+	PreviousSecrets []string `envconfig:"JWT_PREVIOUS_SECRETS"`
+	// JWKSURL is the file path or HTTP(S) URL of a JWKS document with the public keys of asymmetric token issuers.
+	JWKSURL string `envconfig:"JWT_JWKS_URL"`
+	// JWKSRefreshInterval is how often the JWKS document is reloaded.
+	JWKSRefreshInterval time.Duration `envconfig:"JWT_JWKS_REFRESH_INTERVAL" default:"15m"`
+	// Issuers are the accepted values of the iss claim. Any issuer is accepted when empty. The first issuer is used
+	// for the session tokens minted by the API.
+	Issuers []string `envconfig:"JWT_ISSUERS"`
+	// Audience is the required value of the aud claim. Any audience is accepted when empty.
+	Audience string `envconfig:"JWT_AUDIENCE"`
+	// ClockSkew is the tolerance applied to the exp, nbf and iat claims.
+	ClockSkew time.Duration `envconfig:"JWT_CLOCK_SKEW" default:"30s"`
+}
+
+// BlocklistConfig holds configuration values for the IP addresses blocked for each service.
+type BlocklistConfig struct {
+	// RefreshInterval is how often every instance reloads the blocked IPs of every service from storage.
+	RefreshInterval time.Duration `envconfig:"IP_BLOCKLIST_REFRESH_INTERVAL" default:"1m"`
+}
+
+// ProxyConfig holds configuration values for the proxies in front of the API.
+type ProxyConfig struct {
+	// TrustedProxies are the CIDR ranges or IP addresses of the proxies whose X-Forwarded-For hops are believed. The
+	// client is the right-most hop not added by one of them, and the remote address is used when empty.
+	TrustedProxies []string `envconfig:"TRUSTED_PROXIES"`
+}
+
+// LeaksConfig holds configuration values for the reports of leaked API key tokens.
+type LeaksConfig struct {
+	// PartnerSecrets maps the ID of each secret scanning partner to the secret it signs its reports with, given as
+	// "partner1:secret1,partner2:secret2".
+	PartnerSecrets map[string]string `envconfig:"LEAK_REPORT_PARTNER_SECRETS"`
+	// SignatureTolerance is how far the timestamp of a signed report may be from the current time.
+	SignatureTolerance time.Duration `envconfig:"LEAK_REPORT_SIGNATURE_TOLERANCE" default:"5m"`
+}
+
+// EventsConfig holds configuration values for the delivery of events, such as API keys expiring or leaking.
+type EventsConfig struct {
+	// WebhookURL is the URL that events are POSTed to. Events are only written to the log when empty.
+	WebhookURL string `envconfig:"EVENTS_WEBHOOK_URL"`
+	// WebhookSecret is the HMAC secret that event deliveries are signed with. Required with WebhookURL.
+	WebhookSecret string `envconfig:"EVENTS_WEBHOOK_SECRET"`
+}
+
+// PaginationConfig holds configuration values for the cursors of list operations.
+type PaginationConfig struct {
+	// CursorSecret is the HMAC secret that cursors are signed with, so that clients cannot forge them.
+	CursorSecret string `envconfig:"PAGINATION_CURSOR_SECRET" required:"true"`
+}
+
 // OpenTelemetryConfig holds OpenTelemetry-specific configuration values.
 type OpenTelemetryConfig struct {
 	ProviderEndpoint string `envconfig:"OTEL_EXPORTER_OTLP_ENDPOINT"`
@@ -35,6 +108,14 @@ type Config struct {
 	Environment   EnvironmentType `envconfig:"ENVIRONMENT"`
 	BindAddress   string          `envconfig:"BIND_ADDRESS" default:":8080"`
 	JWTSecret     string          `envconfig:"JWT_SECRET" required:"true"`
+	RedisEndpoint string          `envconfig:"REDIS_ENDPOINT"`
+	APIKeys       APIKeysConfig
+	JWT           JWTConfig
+	Blocklist     BlocklistConfig
+	Proxy         ProxyConfig
+	Leaks         LeaksConfig
+	Events        EventsConfig
+	Pagination    PaginationConfig
 	AWS           AWSConfig
 	OpenTelemetry OpenTelemetryConfig
 }
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,7 +33,18 @@ func TestLoadConfig(t *testing.T) {
 	setEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4317")
 	setEnv("OTEL_EXPORTER_OTLP_CA_CERT", "test-ca-cert")
 	setEnv("BIND_ADDRESS", ":8080")
+	setEnv("REDIS_ENDPOINT", "localhost:6379")
 	setEnv("JWT_SECRET", "test-jwt-secret")
+	setEnv("API_KEY_SECRET_PEPPER", "test-pepper")
+	setEnv("API_KEY_SECRET_MIGRATION", "true")
+	setEnv("API_KEY_EXPIRY_SWEEP_INTERVAL", "1m")
+	setEnv("JWT_PREVIOUS_SECRETS", "old-secret-1,old-secret-2")
+	setEnv("JWT_JWKS_URL", "https://auth.example.com/.well-known/jwks.json")
+	setEnv("JWT_ISSUERS", "https://auth.example.com/")
+	setEnv("JWT_AUDIENCE", "lanyard")
+	setEnv("JWT_CLOCK_SKEW", "1m")
+	setEnv("LEAK_REPORT_PARTNER_SECRETS", "scanner:scanner-secret,internal:internal-secret")
+	setEnv("PAGINATION_CURSOR_SECRET", "test-cursor-secret")
 	setEnv("PROMPT_BUCKET", "test-prompt-bucket")
 
 	defer unsetEnv("AWS_DEFAULT_REGION")
@@ -44,7 +56,18 @@ func TestLoadConfig(t *testing.T) {
 	defer unsetEnv("OTEL_EXPORTER_OTLP_ENDPOINT")
 	defer unsetEnv("OTEL_EXPORTER_OTLP_CA_CERT")
 	defer unsetEnv("BIND_ADDRESS")
+	defer unsetEnv("REDIS_ENDPOINT")
 	defer unsetEnv("JWT_SECRET")
+	defer unsetEnv("API_KEY_SECRET_PEPPER")
+	defer unsetEnv("API_KEY_SECRET_MIGRATION")
+	defer unsetEnv("API_KEY_EXPIRY_SWEEP_INTERVAL")
+	defer unsetEnv("JWT_PREVIOUS_SECRETS")
+	defer unsetEnv("JWT_JWKS_URL")
+	defer unsetEnv("JWT_ISSUERS")
+	defer unsetEnv("JWT_AUDIENCE")
+	defer unsetEnv("JWT_CLOCK_SKEW")
+	defer unsetEnv("LEAK_REPORT_PARTNER_SECRETS")
+	defer unsetEnv("PAGINATION_CURSOR_SECRET")
 	defer unsetEnv("PROMPT_BUCKET")
 
 	cfg, err := LoadConfig()
@@ -60,7 +83,18 @@ func TestLoadConfig(t *testing.T) {
 	assert.Equal(t, "http://localhost:4566", cfg.AWS.S3Endpoint)
 	assert.Equal(t, "local", string(cfg.Environment))
 	assert.Equal(t, ":8080", cfg.BindAddress)
+	assert.Equal(t, "localhost:6379", cfg.RedisEndpoint)
 	assert.Equal(t, "test-jwt-secret", cfg.JWTSecret)
+	assert.Equal(t, "test-pepper", cfg.APIKeys.SecretPepper)
+	assert.True(t, cfg.APIKeys.SecretMigration)
+	assert.Equal(t, time.Minute, cfg.APIKeys.ExpirySweepInterval)
+	assert.Equal(t, []string{"old-secret-1", "old-secret-2"}, cfg.JWT.PreviousSecrets)
+	assert.Equal(t, "https://auth.example.com/.well-known/jwks.json", cfg.JWT.JWKSURL)
+	assert.Equal(t, []string{"https://auth.example.com/"}, cfg.JWT.Issuers)
+	assert.Equal(t, "lanyard", cfg.JWT.Audience)
+	assert.Equal(t, time.Minute, cfg.JWT.ClockSkew)
+	assert.Equal(t, map[string]string{"scanner": "scanner-secret", "internal": "internal-secret"}, cfg.Leaks.PartnerSecrets)
+	assert.Equal(t, "test-cursor-secret", cfg.Pagination.CursorSecret)
 	assert.Equal(t, "http://localhost:4317", cfg.OpenTelemetry.ProviderEndpoint)
 	assert.Equal(t, "test-ca-cert", cfg.OpenTelemetry.CACert)
 }
@@ -77,6 +111,8 @@ func TestLoadConfigMissingEnvVars(t *testing.T) {
 	unsetEnv("OTEL_EXPORTER_OTLP_CA_CERT")
 	unsetEnv("BIND_ADDRESS")
 	unsetEnv("JWT_SECRET")
+	unsetEnv("API_KEY_SECRET_PEPPER")
+	unsetEnv("PAGINATION_CURSOR_SECRET")
 	unsetEnv("PROMPT_BUCKET")
 
 	cfg, err := LoadConfig()
@@ -91,11 +127,15 @@ func TestLoadConfigDefaultValues(t *testing.T) {
 	setEnv("AWS_ACCESS_KEY_ID", "test-access-key-id")
 	setEnv("AWS_SECRET_ACCESS_KEY", "test-secret-access-key")
 	setEnv("JWT_SECRET", "test-jwt-secret")
+	setEnv("API_KEY_SECRET_PEPPER", "test-pepper")
+	setEnv("PAGINATION_CURSOR_SECRET", "test-cursor-secret")
 	setEnv("PROMPT_BUCKET", "test-prompt-bucket")
 
 	defer unsetEnv("AWS_DEFAULT_REGION")
 	defer unsetEnv("AWS_ACCESS_KEY_ID")
 	defer unsetEnv("AWS_SECRET_ACCESS_KEY")
+	defer unsetEnv("API_KEY_SECRET_PEPPER")
+	defer unsetEnv("PAGINATION_CURSOR_SECRET")
 
 	cfg, err := LoadConfig()
 
@@ -108,4 +148,15 @@ func TestLoadConfigDefaultValues(t *testing.T) {
 	assert.Equal(t, ":8080", cfg.BindAddress)               // default value
 	assert.Equal(t, "", cfg.OpenTelemetry.ProviderEndpoint) // default value when not set
 	assert.Equal(t, "", cfg.OpenTelemetry.CACert)
+	assert.False(t, cfg.APIKeys.SecretMigration) // default value
+	assert.Equal(t, 15*time.Minute, cfg.JWT.JWKSRefreshInterval)
+	assert.Equal(t, 5*time.Minute, cfg.APIKeys.ExpirySweepInterval)
+	assert.Equal(t, 24*time.Hour, cfg.APIKeys.RotationGracePeriod)
+	assert.Equal(t, 168*time.Hour, cfg.APIKeys.MaxRotationGracePeriod)
+	assert.Equal(t, "lny", cfg.APIKeys.TokenPrefix)
+	assert.Equal(t, "live", cfg.APIKeys.TokenEnvironment)
+	assert.Equal(t, time.Minute, cfg.Blocklist.RefreshInterval)
+	assert.Equal(t, 30*time.Second, cfg.JWT.ClockSkew)
+	assert.Empty(t, cfg.Leaks.PartnerSecrets)
+	assert.Equal(t, 5*time.Minute, cfg.Leaks.SignatureTolerance)
 }
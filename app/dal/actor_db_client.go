@@ -19,9 +19,12 @@ import (
 type ActorManager interface {
 	CreateActor(ctx context.Context, orgID, serviceID string, actor *Actor) error
 	GetActor(ctx context.Context, orgID, serviceID string, externalID string) (*Actor, error)
+	GetActorIncludingDeleted(ctx context.Context, orgID, serviceID string, externalID string) (*Actor, error)
 	UpdateActor(ctx context.Context, orgID, serviceID string, actor *Actor) error
-	DeleteActor(ctx context.Context, orgID, serviceID string, externalID string) error
-	ListActors(ctx context.Context, orgID, serviceID string) ([]Actor, error)
+	DeleteActor(ctx context.Context, orgID, serviceID string, externalID string, version int64) error
+	StageDeleteActor(ctx context.Context, unit *UnitOfWork, orgID, serviceID string, externalID string, version int64) error
+	ListActors(ctx context.Context, orgID, serviceID string, page Page) ([]Actor, string, error)
+	ListActorsByTier(ctx context.Context, orgID, serviceID, tierID string, page Page) ([]Actor, string, error)
 }
 
 // Ensure ActorDBClient implements the ActorManager interface
@@ -29,12 +32,12 @@ var _ ActorManager = &ActorDBClient{}
 
 // BillingInfo represents an actor's basic billing info in the system.
 type BillingInfo struct {
-	tier             string `json:"tier"`
-	tierId           string `json:"tierId"`
-	trialExpiry      string `json:"trialExpiry"`
-	isTrialActive    bool   `json:"isTrialActive"`
-	isTrialElgible   bool   `json:"isTrialActive"`
-	stripeCustomerId string `json:"stripeCustomerId"`
+	TierID           string `json:"tierId"`
+	TrialExpiry      string `json:"trialExpiry"`
+	IsTrialActive    bool   `json:"isTrialActive"`
+	IsTrialEligible  bool   `json:"isTrialEligible"`
+	StripeCustomerID string `json:"stripeCustomerId"`
+	PaymentMethodID  string `json:"paymentMethodId"`
 }
 
 // Actor represents a actor in the system.
@@ -44,17 +47,20 @@ type Actor struct {
 	MonthlyRequestLimit int         `json:"monthlyRequestLimit"`
 	Deleted             bool        `json:"deleted"`
 	BillingInfo         BillingInfo `json:"billingInfo"`
+	Version             int64       `json:"version"`
 }
 
 // ActorDBClient is a client for interacting with DynamoDB for actor-related operations.
 type ActorDBClient struct {
-	actor DynamoDBAPI
+	actor   DynamoDBAPI
+	cursors *CursorCodec
 }
 
-// NewActorDBClient creates a new ActorDBClient.
-func NewActorDBClient(actor DynamoDBAPI) *ActorDBClient {
+// NewActorDBClient creates a new ActorDBClient. The cursors of its list operations are signed by the codec.
+func NewActorDBClient(actor DynamoDBAPI, cursors *CursorCodec) *ActorDBClient {
 	return &ActorDBClient{
-		actor: actor,
+		actor:   withClassifiedErrors(actor),
+		cursors: cursors,
 	}
 }
 
@@ -68,20 +74,29 @@ func createActorGSI1(orgID, serviceID, actorID string) string {
 	return "Org#" + orgID + "Service#" + serviceID + "Actor#" + actorID
 }
 
-// CreateActor creates a new actor in the DynamoDB table.
+// createActorTierGSI generates the partition key of an actor in the Tier-Index, a sparse index of the actors that are
+// assigned to a pricing tier.
+func createActorTierGSI(orgID, serviceID, tierID string) string {
+	return "Org#" + orgID + "Service#" + serviceID + "Tier#" + tierID
+}
+
+// CreateActor creates a new actor in the DynamoDB table. The transaction is canceled if its service was deleted or
+// started being deleted, and a *ServiceUnavailableError is returned, or if the actor is assigned to a tier that was
+// deleted or started retiring since it was read, and a *TierUnavailableError is returned.
 func (d *ActorDBClient) CreateActor(ctx context.Context, orgID, serviceID string, actor *Actor) error {
 	ksuid, err := utils.GenerateKSUID()
 	if err != nil {
-		return fmt.Errorf("failed to create ksuid: %v", err)
+		return fmt.Errorf("failed to create ksuid: %w", err)
 	}
 
 	actor.ActorID = ksuid
+	actor.Version = 1
 	pk, sk := createActorCompositeKeys(orgID, serviceID, actor.ExternalID)
 	gsi1PK := createActorGSI1(orgID, serviceID, actor.ExternalID)
 
 	av, err := attributevalue.MarshalMap(actor)
 	if err != nil {
-		return fmt.Errorf("failed to marshal actor: %v", err)
+		return fmt.Errorf("failed to marshal actor: %w", err)
 	}
 
 	item := map[string]types.AttributeValue{
@@ -89,25 +104,61 @@ func (d *ActorDBClient) CreateActor(ctx context.Context, orgID, serviceID string
 		"sk":     &types.AttributeValueMemberS{Value: sk},
 		"GSI1PK": &types.AttributeValueMemberS{Value: gsi1PK},
 	}
+	// Only actors assigned to a tier are indexed by tier
+	if actor.BillingInfo.TierID != "" {
+		item["TierPK"] = &types.AttributeValueMemberS{Value: createActorTierGSI(orgID, serviceID, actor.BillingInfo.TierID)}
+	}
 	for k, v := range av {
 		item[k] = v
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String("Services"),
-		Item:      item,
+	audit, err := auditPut(ctx, orgID, "actor.created", AuditTargetActor, actor.ExternalID, nil, actor)
+	if err != nil {
+		return err
 	}
 
-	_, err = d.actor.PutItem(ctx, input)
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName: aws.String("Services"),
+				Item:      item,
+			},
+		},
+		audit,
+		serviceAvailableCheck(orgID, serviceID),
+	}
+	if actor.BillingInfo.TierID != "" {
+		items = append(items, tierAssignableCheck(orgID, serviceID, actor.BillingInfo.TierID))
+	}
+
+	_, err = d.actor.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to put item in DynamoDB: %v", err)
+		if isConditionFailed(err, 2) {
+			return &ServiceUnavailableError{ID: serviceID}
+		}
+		if isConditionFailed(err, 3) {
+			return &TierUnavailableError{ID: actor.BillingInfo.TierID}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// GetActor retrieves a actor by organization ID and actor ID from the DynamoDB table.
+// GetActor retrieves a actor by organization ID and actor ID from the DynamoDB table, or nil when it does not exist
+// or was deleted.
 func (d *ActorDBClient) GetActor(ctx context.Context, orgID, serviceID, externalID string) (*Actor, error) {
+	actor, err := d.GetActorIncludingDeleted(ctx, orgID, serviceID, externalID)
+	if err != nil || actor == nil || actor.Deleted {
+		return nil, err
+	}
+
+	return actor, nil
+}
+
+// GetActorIncludingDeleted retrieves a actor from the DynamoDB table, or nil when it does not exist. Deleted actors
+// are kept as tombstones and are returned, for callers such as usage reports that cover their billing history.
+func (d *ActorDBClient) GetActorIncludingDeleted(ctx context.Context, orgID, serviceID, externalID string) (*Actor, error) {
 	pk, sk := createActorCompositeKeys(orgID, serviceID, externalID)
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String("Services"),
@@ -119,7 +170,7 @@ func (d *ActorDBClient) GetActor(ctx context.Context, orgID, serviceID, external
 
 	result, err := d.actor.GetItem(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
@@ -129,11 +180,7 @@ func (d *ActorDBClient) GetActor(ctx context.Context, orgID, serviceID, external
 	var actor Actor
 	err = attributevalue.UnmarshalMap(result.Item, &actor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %v", err)
-	}
-
-	if actor.Deleted {
-		return nil, nil
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
 	}
 
 	return &actor, nil
@@ -159,7 +206,7 @@ func (d *ActorDBClient) GetActorByGSI(ctx context.Context, orgID, serviceID, act
 	// Perform the query
 	result, err := d.actor.Query(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query GSI in DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to query GSI in DynamoDB: %w", err)
 	}
 
 	// Check if there are any results
@@ -171,7 +218,7 @@ func (d *ActorDBClient) GetActorByGSI(ctx context.Context, orgID, serviceID, act
 	var actor Actor
 	err = attributevalue.UnmarshalMap(result.Items[0], &actor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal actor from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal actor from DynamoDB: %w", err)
 	}
 
 	// Check if the actor is marked as deleted
@@ -182,68 +229,146 @@ func (d *ActorDBClient) GetActorByGSI(ctx context.Context, orgID, serviceID, act
 	return &actor, nil
 }
 
-// UpdateActor updates the name, description, and updatedAt fields of an existing actor in the DynamoDB table.
+// UpdateActor updates the external ID, monthly request limit, and billing info of an existing actor in the DynamoDB table.
+// The actor must still be at the version of actor, which is advanced to the next version. A *ConflictError is returned
+// otherwise, and a *TierUnavailableError if the actor is moved to a tier that was deleted or started retiring since it
+// was read.
 func (d *ActorDBClient) UpdateActor(ctx context.Context, orgID, serviceID string, actor *Actor) error {
+	current, err := d.GetActor(ctx, orgID, serviceID, actor.ExternalID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "actor", ID: actor.ExternalID}
+	}
+	if current.Version != actor.Version {
+		return &ConflictError{Entity: "actor", ID: actor.ExternalID, Version: actor.Version}
+	}
+
 	pk, sk := createActorCompositeKeys(orgID, serviceID, actor.ExternalID)
 
-	updateExpr := "SET #externalId = :externalId, #monthlyRequestLimit = :monthlyRequestLimit"
+	billingInfo, err := attributevalue.Marshal(actor.BillingInfo)
+	if err != nil {
+		return fmt.Errorf("failed to marshal billing info: %w", err)
+	}
+
+	updateExpr := "SET #externalId = :externalId, #monthlyRequestLimit = :monthlyRequestLimit, #billingInfo = :billingInfo"
 	exprAttrNames := map[string]string{
-		"#externalId":          "ExternalId",
+		"#externalId":          "ExternalID",
 		"#monthlyRequestLimit": "MonthlyRequestLimit",
+		"#billingInfo":         "BillingInfo",
 	}
 
 	exprAttrValues := map[string]types.AttributeValue{
 		":externalId":          &types.AttributeValueMemberS{Value: actor.ExternalID},
 		":monthlyRequestLimit": &types.AttributeValueMemberN{Value: strconv.Itoa(actor.MonthlyRequestLimit)},
+		":billingInfo":         billingInfo,
+	}
+
+	// The actor moves to the partition of its new tier in the Tier-Index, or leaves the index
+	exprAttrNames["#tierPK"] = "TierPK"
+	tierID := actor.BillingInfo.TierID
+	if tierID != "" {
+		updateExpr += ", #tierPK = :tierPK"
+		exprAttrValues[":tierPK"] = &types.AttributeValueMemberS{Value: createActorTierGSI(orgID, serviceID, tierID)}
+	}
+
+	updated := *current
+	updated.MonthlyRequestLimit = actor.MonthlyRequestLimit
+	updated.BillingInfo = actor.BillingInfo
+	updated.Version = actor.Version + 1
+
+	audit, err := auditPut(ctx, orgID, "actor.updated", AuditTargetActor, actor.ExternalID, current, &updated)
+	if err != nil {
+		return err
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	update := &types.Update{
 		TableName:                 aws.String("Services"),
 		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 	}
+	withVersion(update, actor.Version)
+	if tierID == "" {
+		update.UpdateExpression = aws.String(aws.ToString(update.UpdateExpression) + " REMOVE #tierPK")
+	}
+
+	items := []types.TransactWriteItem{{Update: update}, audit}
+	// Actors that stay on their tier may keep it while it retires, until they are moved off it
+	if tierID != "" && tierID != current.BillingInfo.TierID {
+		items = append(items, tierAssignableCheck(orgID, serviceID, tierID))
+	}
 
-	_, err := d.actor.UpdateItem(ctx, input)
+	_, err = d.actor.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to update item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "actor", ID: actor.ExternalID, Version: actor.Version}
+		}
+		if isConditionFailed(err, 2) {
+			return &TierUnavailableError{ID: tierID}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
+	actor.Version = updated.Version
 	return nil
 }
 
-// DeleteActor marks a actor as deleted by organization ID and actor ID in the DynamoDB table.
-func (d *ActorDBClient) DeleteActor(ctx context.Context, orgID, serviceID, externalID string) error {
+// DeleteActor marks a actor as deleted by organization ID and actor ID in the DynamoDB table. The actor must still be
+// at the given version, and a *ConflictError is returned otherwise.
+func (d *ActorDBClient) DeleteActor(ctx context.Context, orgID, serviceID, externalID string, version int64) error {
+	unit := NewUnitOfWork()
+	if err := d.StageDeleteActor(ctx, unit, orgID, serviceID, externalID, version); err != nil {
+		return err
+	}
+	return commitUnit(ctx, d.actor, unit)
+}
+
+// StageDeleteActor adds the deletion of an actor to a unit of work, along with its audit record. The actor must still
+// be at the given version when the unit of work is committed, and a *ConflictError is returned otherwise.
+func (d *ActorDBClient) StageDeleteActor(ctx context.Context, unit *UnitOfWork, orgID, serviceID, externalID string, version int64) error {
+	current, err := d.GetActor(ctx, orgID, serviceID, externalID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "actor", ID: externalID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "actor", ID: externalID, Version: version}
+	}
+
 	pk, sk := createActorCompositeKeys(orgID, serviceID, externalID)
 
-	update := map[string]types.AttributeValueUpdate{
-		"Deleted": {
-			Value:  &types.AttributeValueMemberBOOL{Value: true},
-			Action: types.AttributeActionPut,
-		},
+	audit, err := auditPut(ctx, orgID, "actor.deleted", AuditTargetActor, externalID, current, nil)
+	if err != nil {
+		return err
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	update := &types.Update{
 		TableName: aws.String("Services"),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: pk},
 			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
-		AttributeUpdates:    update,
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
-	}
-
-	_, err := d.actor.UpdateItem(ctx, input)
-	if err != nil {
-		return fmt.Errorf("failed to delete item in DynamoDB: %v", err)
+		UpdateExpression:         aws.String("SET #deleted = :true"),
+		ConditionExpression:      aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "Deleted"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true": &types.AttributeValueMemberBOOL{Value: true},
+		},
 	}
+	withVersion(update, version)
 
-	return nil
+	conflict := &ConflictError{Entity: "actor", ID: externalID, Version: version}
+	return unit.write(conflict, []types.TransactWriteItem{{Update: update}, audit})
 }
 
-// ListActorsByOrganization retrieves all actors for a specific organization from the DynamoDB table.
-func (d *ActorDBClient) ListActors(ctx context.Context, orgID, serviceID string) ([]Actor, error) {
+// ListActors retrieves a page of the actors of a service from the DynamoDB table, along with the cursor of the next
+// page.
+func (d *ActorDBClient) ListActors(ctx context.Context, orgID, serviceID string, page Page) ([]Actor, string, error) {
 	pk, _ := createActorCompositeKeys(orgID, serviceID, "")
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String("Services"),
@@ -255,24 +380,28 @@ func (d *ActorDBClient) ListActors(ctx context.Context, orgID, serviceID string)
 		},
 	}
 
-	result, err := d.actor.Query(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
-	}
-
-	var actors []Actor
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &actors)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
-	}
+	return queryPage(ctx, d.actor, d.cursors, input, pk, page, func(actor *Actor) bool {
+		return !actor.Deleted
+	})
+}
 
-	results := []Actor{}
-	for _, actor := range actors {
-		if actor.Deleted {
-			continue
-		}
-		results = append(results, actor)
+// ListActorsByTier retrieves a page of the actors assigned to a pricing tier from the Tier-Index, along with the
+// cursor of the next page. The index is eventually consistent, so actors assigned or moved in the last moments may be
+// missing or still listed.
+func (d *ActorDBClient) ListActorsByTier(ctx context.Context, orgID, serviceID, tierID string, page Page) ([]Actor, string, error) {
+	tierPK := createActorTierGSI(orgID, serviceID, tierID)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		IndexName:              aws.String("Tier-Index"),
+		KeyConditionExpression: aws.String("TierPK = :tierPK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":tierPK": &types.AttributeValueMemberS{
+				Value: tierPK,
+			},
+		},
 	}
 
-	return actors, nil
+	return queryPage(ctx, d.actor, d.cursors, input, tierPK, page, func(actor *Actor) bool {
+		return !actor.Deleted && actor.BillingInfo.TierID == tierID
+	})
 }
@@ -2,10 +2,12 @@ package dal_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/payloadops/lanyard/app/dal"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -19,7 +21,7 @@ func TestCreateActor(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewActorDBClient(mockSvc)
+	client := dal.NewActorDBClient(mockSvc, cursors)
 
 	actor := &dal.Actor{
 		ExternalID:          "12342341234",
@@ -27,11 +29,37 @@ func TestCreateActor(t *testing.T) {
 	}
 
 	mockSvc.EXPECT().
-		PutItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.PutItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, "Services", *input.TransactItems[0].Put.TableName)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "actor.created", event.Action)
+			assert.Equal(t, dal.AuditTargetActor, event.TargetType)
+			assert.Equal(t, "12342341234", event.TargetID)
+
+			// The service must not be deleted or being deleted
+			check := input.TransactItems[2].ConditionCheck
+			assert.Equal(t, "Service#serv1", check.Key["sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
 	err := client.CreateActor(context.Background(), "org1", "serv1", actor)
 	assert.NoError(t, err)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		})
+
+	err = client.CreateActor(context.Background(), "org1", "serv1", actor)
+	var unavailable *dal.ServiceUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
 }
 
 func TestGetActor(t *testing.T) {
@@ -39,7 +67,7 @@ func TestGetActor(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewActorDBClient(mockSvc)
+	client := dal.NewActorDBClient(mockSvc, cursors)
 
 	actor := dal.Actor{
 		ActorID:             "actor1",
@@ -59,49 +87,179 @@ func TestGetActor(t *testing.T) {
 	assert.Equal(t, "actor1", result.ActorID)
 }
 
+func TestGetActor_Deleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewActorDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Actor{ActorID: "actor1", ExternalID: "actor1", Deleted: true})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	// Deleted actors are only returned to callers that ask for them
+	result, err := client.GetActor(context.Background(), "org1", "serv1", "actor1")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	result, err = client.GetActorIncludingDeleted(context.Background(), "org1", "serv1", "actor1")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.True(t, result.Deleted)
+}
+
 func TestUpdateActor(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewActorDBClient(mockSvc)
+	client := dal.NewActorDBClient(mockSvc, cursors)
 
 	actor := &dal.Actor{
 		ActorID:             "actor1",
 		ExternalID:          "12342341234",
 		MonthlyRequestLimit: 1000000,
 		Deleted:             false,
+		BillingInfo:         dal.BillingInfo{TierID: "tier1"},
 	}
 
+	item, _ := attributevalue.MarshalMap(dal.Actor{ActorID: "actor1", ExternalID: "12342341234", MonthlyRequestLimit: 1000})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
-			assert.Equal(t, "Service#serv1", input.Key["pk"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "Actor#actor1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "12342341234", input.ExpressionAttributeValues[":externalId"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "1000000", input.ExpressionAttributeValues[":monthlyRequestLimit"].(*types.AttributeValueMemberN).Value)
-			assert.Equal(t, "SET #externalId = :externalId, #monthlyRequestLimit = :monthlyRequestLimit", *input.UpdateExpression)
-			assert.Equal(t, "ExternalId", input.ExpressionAttributeNames["#externalId"])
-			assert.Equal(t, "MonthlyRequestLimit", input.ExpressionAttributeNames["#monthlyRequestLimit"])
-			return &dynamodb.UpdateItemOutput{}, nil
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "Org#org1Service#serv1Actor", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Actor#12342341234", update.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "12342341234", update.ExpressionAttributeValues[":externalId"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "1000000", update.ExpressionAttributeValues[":monthlyRequestLimit"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "SET #externalId = :externalId, #monthlyRequestLimit = :monthlyRequestLimit, #billingInfo = :billingInfo, #tierPK = :tierPK, #version = :nextVersion", *update.UpdateExpression)
+			assert.Equal(t, "Org#org1Service#serv1Tier#tier1", update.ExpressionAttributeValues[":tierPK"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "ExternalID", update.ExpressionAttributeNames["#externalId"])
+			assert.Equal(t, "MonthlyRequestLimit", update.ExpressionAttributeNames["#monthlyRequestLimit"])
+			assert.Equal(t, "BillingInfo", update.ExpressionAttributeNames["#billingInfo"])
+
+			var billingInfo dal.BillingInfo
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":billingInfo"], &billingInfo))
+			assert.Equal(t, actor.BillingInfo, billingInfo)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "actor.updated", event.Action)
+			assert.Contains(t, event.Before, `"monthlyRequestLimit":1000`)
+			assert.Contains(t, event.After, `"monthlyRequestLimit":1000000`)
+			assert.NotContains(t, event.After, "externalId")
+
+			// The actor moves to a new tier, which must still be active
+			check := input.TransactItems[2].ConditionCheck
+			assert.Equal(t, "Tier#tier1", check.Key["sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
 		})
 
 	err := client.UpdateActor(context.Background(), "org1", "serv1", actor)
 	assert.NoError(t, err)
 }
 
+func TestUpdateActor_TierUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewActorDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Actor{ActorID: "actor1", ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	// The new tier started retiring since it was read
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		})
+
+	err := client.UpdateActor(context.Background(), "org1", "serv1", &dal.Actor{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier2"}})
+	var unavailable *dal.TierUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, "tier2", unavailable.ID)
+
+	// Actors leaving their tier leave the Tier-Index, and need no check
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 2, len(input.TransactItems))
+			assert.True(t, strings.HasSuffix(*input.TransactItems[0].Update.UpdateExpression, " REMOVE #tierPK"))
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err = client.UpdateActor(context.Background(), "org1", "serv1", &dal.Actor{ExternalID: "actor1"})
+	assert.NoError(t, err)
+}
+
+func TestListActorsByTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewActorDBClient(mockSvc, cursors)
+
+	assigned, _ := attributevalue.MarshalMap(dal.Actor{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}})
+	deleted, _ := attributevalue.MarshalMap(dal.Actor{ExternalID: "actor2", BillingInfo: dal.BillingInfo{TierID: "tier1"}, Deleted: true})
+	moved, _ := attributevalue.MarshalMap(dal.Actor{ExternalID: "actor3", BillingInfo: dal.BillingInfo{TierID: "tier2"}})
+
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "Services", *input.TableName)
+			assert.Equal(t, "Tier-Index", *input.IndexName)
+			assert.Equal(t, "Org#org1Service#serv1Tier#tier1", input.ExpressionAttributeValues[":tierPK"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{assigned, deleted, moved}}, nil
+		})
+
+	// Deleted actors, and actors whose move has not reached the index yet, are left out
+	actors, cursor, err := client.ListActorsByTier(context.Background(), "org1", "serv1", "tier1", dal.Page{})
+	assert.NoError(t, err)
+	assert.Empty(t, cursor)
+	assert.Equal(t, 1, len(actors))
+	assert.Equal(t, "actor1", actors[0].ExternalID)
+}
+
 func TestDeleteActor(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewActorDBClient(mockSvc)
+	client := dal.NewActorDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Actor{ActorID: "actor1", ExternalID: "actor1"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
 
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.UpdateItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "SET #deleted = :true, #version = :nextVersion", *update.UpdateExpression)
+			// Actors stored before entities were versioned are at version 0
+			assert.Equal(t, "attribute_exists(pk) AND attribute_exists(sk) AND attribute_not_exists(#version)", *update.ConditionExpression)
+			assert.Equal(t, "1", update.ExpressionAttributeValues[":nextVersion"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "actor.deleted", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
-	err := client.DeleteActor(context.Background(), "org1", "serv1", "actor1")
+	err := client.DeleteActor(context.Background(), "org1", "serv1", "actor1", 0)
 	assert.NoError(t, err)
 }
 
@@ -110,7 +268,7 @@ func TestListActorsByServiceanization(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewActorDBClient(mockSvc)
+	client := dal.NewActorDBClient(mockSvc, cursors)
 
 	actor := dal.Actor{
 		ActorID:             "actor1",
@@ -124,8 +282,9 @@ func TestListActorsByServiceanization(t *testing.T) {
 		Query(gomock.Any(), gomock.Any()).
 		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil)
 
-	result, err := client.ListActors(context.Background(), "org1", "serv1")
+	result, cursor, err := client.ListActors(context.Background(), "org1", "serv1", dal.Page{})
 	assert.NoError(t, err)
+	assert.Empty(t, cursor)
 	assert.NotNil(t, result)
 	assert.Equal(t, 1, len(result))
 	assert.Equal(t, "actor1", result[0].ActorID)
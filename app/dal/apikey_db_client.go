@@ -2,6 +2,7 @@ package dal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,15 +16,34 @@ import (
 // SecretLength represents the length of the secret to generate for API keys.
 const SecretLength = 32
 
+const (
+	// APIKeyStatusActive is the status of API keys that may be used. Keys stored without a status are active.
+	APIKeyStatusActive = "active"
+	// APIKeyStatusExpired is the terminal status of API keys whose expiry has passed.
+	APIKeyStatusExpired = "expired"
+	// APIKeyStatusQuarantined is the status of API keys that were reported leaked and may no longer be used, but are
+	// kept for their owners to investigate.
+	APIKeyStatusQuarantined = "quarantined"
+)
+
 //go:generate mockgen -package=mocks -destination=mocks/mock_apikey_db_client.go "github.com/payloadops/lanyard/app/dal" APIKeyManager
 
 // APIKeyManager defines the operations available for managing API keys.
 type APIKeyManager interface {
 	CreateAPIKey(ctx context.Context, apiKey *APIKey) error
 	GetAPIKey(ctx context.Context, apiKeyID string) (*APIKey, error)
+	GetAPIKeyIncludingDeleted(ctx context.Context, apiKeyID string) (*APIKey, error)
 	UpdateAPIKey(ctx context.Context, apiKey *APIKey) error
-	DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string) error
-	ListAPIKeysByService(ctx context.Context, orgID, serviceID string) ([]APIKey, error)
+	UpdateAPIKeySecret(ctx context.Context, apiKeyID, plaintext, secret string) (bool, error)
+	RotateAPIKeySecret(ctx context.Context, apiKeyID, currentSecret, newSecret, previousSecretExpiry string) (bool, error)
+	ExpireAPIKey(ctx context.Context, apiKeyID string) (bool, error)
+	QuarantineAPIKey(ctx context.Context, apiKeyID string) (bool, error)
+	StageQuarantineAPIKey(ctx context.Context, unit *UnitOfWork, apiKeyID string) error
+	DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string, version int64) error
+	StageDeleteAPIKey(ctx context.Context, unit *UnitOfWork, orgID, serviceID, apiKeyID string, version int64) error
+	ListAPIKeysByService(ctx context.Context, orgID, serviceID string, page Page) ([]APIKey, string, error)
+	ListAPIKeysByActor(ctx context.Context, orgID, serviceID, actorID string, page Page) ([]APIKey, string, error)
+	ListExpiredAPIKeys(ctx context.Context, now time.Time, page Page) ([]APIKey, string, error)
 }
 
 // Ensure APIKeyDBClient implements the APIKeyManager interface
@@ -31,90 +51,195 @@ var _ APIKeyManager = &APIKeyDBClient{}
 
 // APIKey represents an API key associated with a service.
 type APIKey struct {
-	OrgID     string   `json:"orgId"`
-	ServiceID string   `json:"serviceId"`
-	ActorID   string   `json:"actorId"`
-	APIKeyID  string   `json:"apiKeyId"`
-	Secret    string   `json:"secret"`
-	Scopes    []string `json:"scopes"`
-	Roles     []string `json:"roles"`
-	Expiry    string   `json:"expiry"`
-	Deleted   bool     `json:"deleted"`
-	CreatedAt string   `json:"createdAt"`
-	UpdatedAt string   `json:"updatedAt"`
+	OrgID                string      `json:"orgId"`
+	ServiceID            string      `json:"serviceId"`
+	ActorID              string      `json:"actorId"`
+	APIKeyID             string      `json:"apiKeyId"`
+	Secret               string      `json:"secret"`
+	PreviousSecret       string      `json:"previousSecret"`
+	PreviousSecretExpiry string      `json:"previousSecretExpiry"`
+	Scopes               []string    `json:"scopes"`
+	Roles                []string    `json:"roles"`
+	RateLimits           []RateLimit `json:"rateLimits"`
+	AllowedCIDRs         []string    `json:"allowedCidrs"`
+	AllowedOrigins       []string    `json:"allowedOrigins"`
+	Expiry               string      `json:"expiry"`
+	Status               string      `json:"status"`
+	Deleted              bool        `json:"deleted"`
+	CreatedAt            string      `json:"createdAt"`
+	UpdatedAt            string      `json:"updatedAt"`
+	Version              int64       `json:"version"`
+}
+
+// Expired reports whether the API key is expired at the given time, either because its expiry has passed or because
+// it has already transitioned to the expired status.
+func (k *APIKey) Expired(now time.Time) (bool, error) {
+	if k.Status == APIKeyStatusExpired {
+		return true, nil
+	}
+	if k.Expiry == "" {
+		return false, nil
+	}
+
+	expiry, err := utils.ParseTimestamp(k.Expiry)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse expiry: %w", err)
+	}
+
+	return !now.Before(expiry), nil
+}
+
+// RateLimit represents a rate limit enforced when authorizing requests made with an API key.
+type RateLimit struct {
+	Name      string `json:"name"`
+	Algorithm string `json:"algorithm"`
+	Scope     string `json:"scope"`
+	Limit     int64  `json:"limit"`
+	Window    string `json:"window"`
 }
 
 // APIKeyDBClient is a client for interacting with DynamoDB for API key-related operations.
 type APIKeyDBClient struct {
 	service DynamoDBAPI
+	cursors *CursorCodec
 }
 
-// NewAPIKeyDBClient creates a new APIKeyDBClient.
-func NewAPIKeyDBClient(service DynamoDBAPI) *APIKeyDBClient {
+// NewAPIKeyDBClient creates a new APIKeyDBClient. The cursors of its list operations are signed by the codec.
+func NewAPIKeyDBClient(service DynamoDBAPI, cursors *CursorCodec) *APIKeyDBClient {
 	return &APIKeyDBClient{
-		service: service,
+		service: withClassifiedErrors(service),
+		cursors: cursors,
 	}
 }
 
-// createAPIKeyGSICompositeKeys generates the partition key (pk) and sort key (sk) for an API key.
+// createAPIKeyGSI1 generates the partition key of an API key in the Org-Service-Index.
 func createAPIKeyGSI1(orgID, serviceID string) string {
 	return "Org#" + orgID + "Service#" + serviceID
 }
 
-// createAPIKeyGSICompositeKeys generates the partition key (pk) and sort key (sk) for an API key.
+// createAPIKeyGSI2 generates the partition key of an API key in the Org-Service-Actor-Index.
 func createAPIKeyGSI2(orgID, serviceID, actorID string) string {
 	return "Org#" + orgID + "Service#" + serviceID + "Actor#" + actorID
 }
 
+// apiKeyExpiryIndex is a sparse index of the API keys that are due to expire, sorted by expiry, so that the expiry
+// sweeper reads the keys whose expiry has passed without scanning the table. Keys leave the index when they expire
+// or are deleted, and keys that never expire are never in it.
+const apiKeyExpiryIndex = "Expiry-Index"
+
+// apiKeyExpiryPK is the partition key of every API key in the Expiry-Index. Keys are sorted by ExpirySK, a copy of
+// their expiry, as keys that never expire have an empty expiry, which cannot be an index key.
+const apiKeyExpiryPK = "Expiring"
+
+// withAPIKeyOwner makes an update of an API key conditional on the key belonging to the given organization and
+// service, as keys are addressed by their ID alone.
+func withAPIKeyOwner(update *types.Update, orgID, serviceID string) {
+	condition := "#orgId = :orgId AND #serviceId = :serviceId"
+	if update.ConditionExpression != nil {
+		condition = aws.ToString(update.ConditionExpression) + " AND " + condition
+	}
+	update.ConditionExpression = aws.String(condition)
+
+	if update.ExpressionAttributeNames == nil {
+		update.ExpressionAttributeNames = map[string]string{}
+	}
+	update.ExpressionAttributeNames["#orgId"] = "OrgID"
+	update.ExpressionAttributeNames["#serviceId"] = "ServiceID"
+
+	if update.ExpressionAttributeValues == nil {
+		update.ExpressionAttributeValues = map[string]types.AttributeValue{}
+	}
+	update.ExpressionAttributeValues[":orgId"] = &types.AttributeValueMemberS{Value: orgID}
+	update.ExpressionAttributeValues[":serviceId"] = &types.AttributeValueMemberS{Value: serviceID}
+}
+
 // createAPIKeyCompositeKey generates the partition key (pk) for an API key.
 func createAPIKeyCompositeKey(apiKeyID string) string {
 	return "APIKey#" + apiKeyID
 }
 
-// CreateAPIKey creates a new API key in the DynamoDB table.
+// CreateAPIKey creates a new API key in the DynamoDB table. A *ServiceUnavailableError is returned when its service
+// was deleted or started being deleted.
 func (d *APIKeyDBClient) CreateAPIKey(ctx context.Context, apiKey *APIKey) error {
 	ksuid, err := utils.GenerateKSUID()
 	if err != nil {
-		return fmt.Errorf("failed to create ksuid: %v", err)
+		return fmt.Errorf("failed to create ksuid: %w", err)
 	}
 
 	apiKey.APIKeyID = ksuid
 	pk := createAPIKeyCompositeKey(apiKey.APIKeyID)
 	gsi1PK := createAPIKeyGSI1(apiKey.OrgID, apiKey.ServiceID)
-	gsi2PK := createAPIKeyGSI2(apiKey.OrgID, apiKey.ServiceID, apiKey.ActorID)
 
 	now := time.Now().UTC().Format(time.RFC3339)
 	apiKey.CreatedAt = now
 	apiKey.UpdatedAt = now
+	apiKey.Version = 1
+	if apiKey.Status == "" {
+		apiKey.Status = APIKeyStatusActive
+	}
 
 	av, err := attributevalue.MarshalMap(apiKey)
 	if err != nil {
-		return fmt.Errorf("failed to marshal API key: %v", err)
+		return fmt.Errorf("failed to marshal API key: %w", err)
 	}
 
 	item := map[string]types.AttributeValue{
 		"pk":     &types.AttributeValueMemberS{Value: pk},
 		"GSI1PK": &types.AttributeValueMemberS{Value: gsi1PK},
-		"GSI2PK": &types.AttributeValueMemberS{Value: gsi2PK},
+	}
+	// Only keys bound to an actor are indexed by actor
+	if apiKey.ActorID != "" {
+		item["GSI2PK"] = &types.AttributeValueMemberS{Value: createAPIKeyGSI2(apiKey.OrgID, apiKey.ServiceID, apiKey.ActorID)}
+	}
+	// Only keys that expire are indexed by expiry
+	if apiKey.Expiry != "" {
+		item["ExpiryPK"] = &types.AttributeValueMemberS{Value: apiKeyExpiryPK}
+		item["ExpirySK"] = &types.AttributeValueMemberS{Value: apiKey.Expiry}
 	}
 	for k, v := range av {
 		item[k] = v
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String("APIKeys"),
-		Item:      item,
+	audit, err := auditPut(ctx, apiKey.OrgID, "api_key.created", AuditTargetAPIKey, apiKey.APIKeyID, nil, apiKey)
+	if err != nil {
+		return err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName: aws.String("APIKeys"),
+				Item:      item,
+			},
+		},
+		audit,
+		serviceAvailableCheck(apiKey.OrgID, apiKey.ServiceID),
 	}
 
-	_, err = d.service.PutItem(ctx, input)
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to put item in DynamoDB: %v", err)
+		if isConditionFailed(err, 2) {
+			return &ServiceUnavailableError{ID: apiKey.ServiceID}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
+// GetAPIKey retrieves an API key by its ID from the DynamoDB table, or nil when it does not exist or was deleted.
 func (d *APIKeyDBClient) GetAPIKey(ctx context.Context, apiKeyID string) (*APIKey, error) {
+	apiKey, err := d.GetAPIKeyIncludingDeleted(ctx, apiKeyID)
+	if err != nil || apiKey == nil || apiKey.Deleted {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// GetAPIKeyIncludingDeleted retrieves an API key by its ID from the DynamoDB table, or nil when it does not exist.
+// Deleted keys are kept as tombstones and are returned, for callers such as usage reports that cover their history.
+func (d *APIKeyDBClient) GetAPIKeyIncludingDeleted(ctx context.Context, apiKeyID string) (*APIKey, error) {
 	pk := createAPIKeyCompositeKey(apiKeyID)
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String("APIKeys"),
@@ -125,7 +250,7 @@ func (d *APIKeyDBClient) GetAPIKey(ctx context.Context, apiKeyID string) (*APIKe
 
 	result, err := d.service.GetItem(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
@@ -135,147 +260,468 @@ func (d *APIKeyDBClient) GetAPIKey(ctx context.Context, apiKeyID string) (*APIKe
 	var apiKey APIKey
 	err = attributevalue.UnmarshalMap(result.Item, &apiKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %v", err)
-	}
-
-	if apiKey.Deleted {
-		return nil, nil
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
 	}
 
 	return &apiKey, nil
 }
 
-// UpdateAPIKey updates the scopes and updatedAt fields of an existing API key in the DynamoDB table.
+// UpdateAPIKey updates the scopes, roles, rateLimits, allowedCidrs, allowedOrigins, expiry and updatedAt fields of an existing API key in the DynamoDB table.
+// The key must still be at the version of apiKey, which is advanced to the next version. A *ConflictError is returned
+// otherwise.
 func (d *APIKeyDBClient) UpdateAPIKey(ctx context.Context, apiKey *APIKey) error {
+	current, err := d.GetAPIKey(ctx, apiKey.APIKeyID)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.OrgID != apiKey.OrgID || current.ServiceID != apiKey.ServiceID {
+		return &NotFoundError{Entity: "API key", ID: apiKey.APIKeyID}
+	}
+	if current.Version != apiKey.Version {
+		return &ConflictError{Entity: "API key", ID: apiKey.APIKeyID, Version: apiKey.Version}
+	}
+
 	pk := createAPIKeyCompositeKey(apiKey.APIKeyID)
 	apiKey.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
 
-	updateExpr := "SET #scopes = :scopes, #updatedAt = :updatedAt"
+	scopes, err := attributevalue.Marshal(apiKey.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal scopes: %w", err)
+	}
+
+	roles, err := attributevalue.Marshal(apiKey.Roles)
+	if err != nil {
+		return fmt.Errorf("failed to marshal roles: %w", err)
+	}
+
+	rateLimits, err := attributevalue.Marshal(apiKey.RateLimits)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rate limits: %w", err)
+	}
+
+	allowedCIDRs, err := attributevalue.Marshal(apiKey.AllowedCIDRs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed CIDRs: %w", err)
+	}
+
+	allowedOrigins, err := attributevalue.Marshal(apiKey.AllowedOrigins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed origins: %w", err)
+	}
+
+	updateExpr := "SET #scopes = :scopes, #roles = :roles, #rateLimits = :rateLimits, #allowedCidrs = :allowedCidrs, #allowedOrigins = :allowedOrigins, #expiry = :expiry, #updatedAt = :updatedAt"
 	exprAttrNames := map[string]string{
-		"#scopes":    "Scopes",
-		"#updatedAt": "UpdatedAt",
+		"#scopes":         "Scopes",
+		"#roles":          "Roles",
+		"#rateLimits":     "RateLimits",
+		"#allowedCidrs":   "AllowedCIDRs",
+		"#allowedOrigins": "AllowedOrigins",
+		"#expiry":         "Expiry",
+		"#updatedAt":      "UpdatedAt",
 	}
 
 	exprAttrValues := map[string]types.AttributeValue{
-		":scopes":    &types.AttributeValueMemberSS{Value: apiKey.Scopes},
-		":updatedAt": &types.AttributeValueMemberS{Value: apiKey.UpdatedAt},
+		":scopes":         scopes,
+		":roles":          roles,
+		":rateLimits":     rateLimits,
+		":allowedCidrs":   allowedCIDRs,
+		":allowedOrigins": allowedOrigins,
+		":expiry":         &types.AttributeValueMemberS{Value: apiKey.Expiry},
+		":updatedAt":      &types.AttributeValueMemberS{Value: apiKey.UpdatedAt},
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	// The key enters the Expiry-Index when it is given an expiry, and leaves it when its expiry is removed
+	indexed := apiKey.Expiry != "" && current.Status != APIKeyStatusExpired
+	exprAttrNames["#expiryPK"] = "ExpiryPK"
+	exprAttrNames["#expirySK"] = "ExpirySK"
+	if indexed {
+		updateExpr += ", #expiryPK = :expiryPK, #expirySK = :expiry"
+		exprAttrValues[":expiryPK"] = &types.AttributeValueMemberS{Value: apiKeyExpiryPK}
+	}
+
+	updated := *current
+	updated.Scopes = apiKey.Scopes
+	updated.Roles = apiKey.Roles
+	updated.RateLimits = apiKey.RateLimits
+	updated.AllowedCIDRs = apiKey.AllowedCIDRs
+	updated.AllowedOrigins = apiKey.AllowedOrigins
+	updated.Expiry = apiKey.Expiry
+	updated.UpdatedAt = apiKey.UpdatedAt
+	updated.Version = apiKey.Version + 1
+
+	audit, err := auditPut(ctx, current.OrgID, "api_key.updated", AuditTargetAPIKey, current.APIKeyID, current, &updated)
+	if err != nil {
+		return err
+	}
+
+	update := &types.Update{
 		TableName:                 aws.String("APIKeys"),
 		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 	}
+	withAPIKeyOwner(update, apiKey.OrgID, apiKey.ServiceID)
+	withVersion(update, apiKey.Version)
+	if !indexed {
+		update.UpdateExpression = aws.String(aws.ToString(update.UpdateExpression) + " REMOVE #expiryPK, #expirySK")
+	}
 
-	_, err := d.service.UpdateItem(ctx, input)
+	items := []types.TransactWriteItem{{Update: update}, audit}
+
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to update item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "API key", ID: apiKey.APIKeyID, Version: apiKey.Version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
+	apiKey.Version = updated.Version
 	return nil
 }
 
-// DeleteAPIKey marks an API key as deleted by org ID, service ID, and API key ID in the DynamoDB table.
-func (d *APIKeyDBClient) DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string) error {
+// UpdateAPIKeySecret replaces the plaintext secret of an existing API key with its hash in the DynamoDB table. It is
+// not audited, as it only rehashes a secret that remains valid. It reports false when the key was deleted or its
+// secret changed since it was read, so that a rehash cannot undo a concurrent rotation or revive a deleted key.
+func (d *APIKeyDBClient) UpdateAPIKeySecret(ctx context.Context, apiKeyID, plaintext, secret string) (bool, error) {
 	pk := createAPIKeyCompositeKey(apiKeyID)
-	update := map[string]types.AttributeValueUpdate{
-		"Deleted": {
-			Value:  &types.AttributeValueMemberBOOL{Value: true},
-			Action: types.AttributeActionPut,
-		},
-		"UpdatedAt": {
-			Value:  &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
-			Action: types.AttributeActionPut,
-		},
+
+	updateExpr := "SET #secret = :secret, #updatedAt = :updatedAt"
+	exprAttrNames := map[string]string{
+		"#secret":    "Secret",
+		"#deleted":   "Deleted",
+		"#updatedAt": "UpdatedAt",
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":secret":    &types.AttributeValueMemberS{Value: secret},
+		":plaintext": &types.AttributeValueMemberS{Value: plaintext},
+		":false":     &types.AttributeValueMemberBOOL{Value: false},
+		":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
 	}
 
 	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String("APIKeys"),
-		Key: map[string]types.AttributeValue{
-			"pk": &types.AttributeValueMemberS{Value: pk},
-		},
-		AttributeUpdates:    update,
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		TableName:                 aws.String("APIKeys"),
+		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}},
+		UpdateExpression:          aws.String(updateExpr),
+		ConditionExpression:       aws.String("#secret = :plaintext AND #deleted = :false"),
+		ExpressionAttributeNames:  exprAttrNames,
+		ExpressionAttributeValues: exprAttrValues,
 	}
 
 	_, err := d.service.UpdateItem(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to delete item in DynamoDB: %v", err)
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to update item in DynamoDB: %w", err)
 	}
 
-	return nil
+	return true, nil
 }
 
-// ListAPIKeysByService retrieves all API keys for a specific service from the DynamoDB table.
-func (d *APIKeyDBClient) ListAPIKeysByService(ctx context.Context, orgID, serviceID string) ([]APIKey, error) {
-	gsi1PK := createAPIKeyGSI1(orgID, serviceID)
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String("APIKeys"),
-		IndexName:              aws.String("Org-Service-Index"),
-		KeyConditionExpression: aws.String("GSI1PK = :gsi1PK"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":gsi1PK": &types.AttributeValueMemberS{
-				Value: gsi1PK,
+// RotateAPIKeySecret replaces the secret of an existing API key in the DynamoDB table, keeping the current secret as
+// the previous secret until previousSecretExpiry. It reports false when the key was deleted or its secret changed since
+// it was read, so that concurrent rotations cannot silently discard each other's secrets.
+func (d *APIKeyDBClient) RotateAPIKeySecret(ctx context.Context, apiKeyID, currentSecret, newSecret, previousSecretExpiry string) (bool, error) {
+	current, err := d.GetAPIKey(ctx, apiKeyID)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, nil
+	}
+
+	pk := createAPIKeyCompositeKey(apiKeyID)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	updateExpr := "SET #secret = :newSecret, #previousSecret = :currentSecret, #previousSecretExpiry = :previousSecretExpiry, #updatedAt = :updatedAt ADD #version :one"
+	exprAttrNames := map[string]string{
+		"#secret":               "Secret",
+		"#previousSecret":       "PreviousSecret",
+		"#previousSecretExpiry": "PreviousSecretExpiry",
+		"#deleted":              "Deleted",
+		"#updatedAt":            "UpdatedAt",
+		"#version":              "Version",
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":newSecret":            &types.AttributeValueMemberS{Value: newSecret},
+		":currentSecret":        &types.AttributeValueMemberS{Value: currentSecret},
+		":previousSecretExpiry": &types.AttributeValueMemberS{Value: previousSecretExpiry},
+		":false":                &types.AttributeValueMemberBOOL{Value: false},
+		":updatedAt":            &types.AttributeValueMemberS{Value: now},
+		":one":                  &types.AttributeValueMemberN{Value: "1"},
+	}
+
+	updated := *current
+	updated.Secret = newSecret
+	updated.PreviousSecret = currentSecret
+	updated.PreviousSecretExpiry = previousSecretExpiry
+	updated.UpdatedAt = now
+	updated.Version = current.Version + 1
+
+	audit, err := auditPut(ctx, current.OrgID, "api_key.rotated", AuditTargetAPIKey, apiKeyID, current, &updated)
+	if err != nil {
+		return false, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName:                 aws.String("APIKeys"),
+				Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}},
+				UpdateExpression:          aws.String(updateExpr),
+				ConditionExpression:       aws.String("attribute_exists(pk) AND #deleted = :false AND #secret = :currentSecret"),
+				ExpressionAttributeNames:  exprAttrNames,
+				ExpressionAttributeValues: exprAttrValues,
 			},
 		},
+		audit,
 	}
 
-	result, err := d.service.Query(ctx, input)
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
-	var apiKeys []APIKey
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &apiKeys)
+	return true, nil
+}
+
+// ExpireAPIKey transitions an existing API key to the expired status in the DynamoDB table. It reports false when the
+// key was deleted or already expired, so that concurrent callers transition each key exactly once.
+func (d *APIKeyDBClient) ExpireAPIKey(ctx context.Context, apiKeyID string) (bool, error) {
+	return d.transitionAPIKey(ctx, apiKeyID, APIKeyStatusExpired, "api_key.expired",
+		"attribute_exists(pk) AND #deleted = :false AND (attribute_not_exists(#status) OR #status <> :status)", nil)
+}
+
+// quarantineCondition is the condition under which an API key can be quarantined.
+const quarantineCondition = "attribute_exists(pk) AND #deleted = :false AND (attribute_not_exists(#status) OR #status = :active)"
+
+// QuarantineAPIKey transitions an active API key to the quarantined status in the DynamoDB table. It reports false
+// when the key was deleted, expired or already quarantined.
+func (d *APIKeyDBClient) QuarantineAPIKey(ctx context.Context, apiKeyID string) (bool, error) {
+	return d.transitionAPIKey(ctx, apiKeyID, APIKeyStatusQuarantined, "api_key.quarantined", quarantineCondition,
+		map[string]types.AttributeValue{":active": &types.AttributeValueMemberS{Value: APIKeyStatusActive}})
+}
+
+// StageQuarantineAPIKey adds the quarantine of an active API key to a unit of work, along with its audit record. A
+// *NotFoundError is returned when the key does not exist, and a *ConflictError is returned when the unit of work is
+// committed after the key was deleted, expired or quarantined.
+func (d *APIKeyDBClient) StageQuarantineAPIKey(ctx context.Context, unit *UnitOfWork, apiKeyID string) error {
+	current, items, err := d.transitionItems(ctx, apiKeyID, APIKeyStatusQuarantined, "api_key.quarantined", quarantineCondition,
+		map[string]types.AttributeValue{":active": &types.AttributeValueMemberS{Value: APIKeyStatusActive}})
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "API key", ID: apiKeyID}
+	}
+
+	conflict := &ConflictError{Entity: "API key", ID: apiKeyID, Version: current.Version}
+	return unit.write(conflict, items)
+}
+
+// transitionAPIKey sets the status of an existing API key when a condition holds, and records the transition in the
+// audit log. The condition may refer to the new status as :status, and to the given values. It reports false when the
+// key was deleted or the condition does not hold.
+func (d *APIKeyDBClient) transitionAPIKey(ctx context.Context, apiKeyID, status, action, conditionExpr string, conditionValues map[string]types.AttributeValue) (bool, error) {
+	current, items, err := d.transitionItems(ctx, apiKeyID, status, action, conditionExpr, conditionValues)
+	if err != nil || current == nil {
+		return false, err
 	}
 
-	results := []APIKey{}
-	for _, apiKey := range apiKeys {
-		if apiKey.Deleted {
-			continue
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return false, nil
 		}
-		results = append(results, apiKey)
+		return false, fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
-	return results, nil
+	return true, nil
 }
 
-// ListAPIKeysByActor retrieves all API keys for a specific actor from the DynamoDB table.
-func (d *APIKeyDBClient) ListAPIKeysByActor(ctx context.Context, orgID, serviceID, actorID string) ([]APIKey, error) {
-	gsi2PK := createAPIKeyGSI2(orgID, serviceID, actorID)
+// transitionItems returns the items that set the status of an existing API key when a condition holds, along with
+// the key as it was read. The key is nil when it was deleted, in which case there are no items.
+func (d *APIKeyDBClient) transitionItems(ctx context.Context, apiKeyID, status, action, conditionExpr string, conditionValues map[string]types.AttributeValue) (*APIKey, []types.TransactWriteItem, error) {
+	current, err := d.GetAPIKey(ctx, apiKeyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		return nil, nil, nil
+	}
+
+	pk := createAPIKeyCompositeKey(apiKeyID)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	updateExpr := "SET #status = :status, #updatedAt = :updatedAt ADD #version :one"
+	exprAttrNames := map[string]string{
+		"#status":    "Status",
+		"#deleted":   "Deleted",
+		"#updatedAt": "UpdatedAt",
+		"#version":   "Version",
+	}
+	// Expired keys leave the Expiry-Index
+	if status == APIKeyStatusExpired {
+		updateExpr += " REMOVE #expiryPK, #expirySK"
+		exprAttrNames["#expiryPK"] = "ExpiryPK"
+		exprAttrNames["#expirySK"] = "ExpirySK"
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":status":    &types.AttributeValueMemberS{Value: status},
+		":false":     &types.AttributeValueMemberBOOL{Value: false},
+		":updatedAt": &types.AttributeValueMemberS{Value: now},
+		":one":       &types.AttributeValueMemberN{Value: "1"},
+	}
+	for k, v := range conditionValues {
+		exprAttrValues[k] = v
+	}
+
+	updated := *current
+	updated.Status = status
+	updated.UpdatedAt = now
+	updated.Version = current.Version + 1
+
+	audit, err := auditPut(ctx, current.OrgID, action, AuditTargetAPIKey, apiKeyID, current, &updated)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName:                 aws.String("APIKeys"),
+				Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}},
+				UpdateExpression:          aws.String(updateExpr),
+				ConditionExpression:       aws.String(conditionExpr),
+				ExpressionAttributeNames:  exprAttrNames,
+				ExpressionAttributeValues: exprAttrValues,
+			},
+		},
+		audit,
+	}
+
+	return current, items, nil
+}
+
+// DeleteAPIKey marks an API key as deleted by org ID, service ID, and API key ID in the DynamoDB table. The key must
+// still be at the given version, and a *ConflictError is returned otherwise.
+func (d *APIKeyDBClient) DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string, version int64) error {
+	unit := NewUnitOfWork()
+	if err := d.StageDeleteAPIKey(ctx, unit, orgID, serviceID, apiKeyID, version); err != nil {
+		return err
+	}
+	return commitUnit(ctx, d.service, unit)
+}
+
+// StageDeleteAPIKey adds the deletion of an API key to a unit of work, along with its audit record. The key must still
+// be at the given version when the unit of work is committed, and a *ConflictError is returned otherwise.
+func (d *APIKeyDBClient) StageDeleteAPIKey(ctx context.Context, unit *UnitOfWork, orgID, serviceID, apiKeyID string, version int64) error {
+	current, err := d.GetAPIKey(ctx, apiKeyID)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.OrgID != orgID || current.ServiceID != serviceID {
+		return &NotFoundError{Entity: "API key", ID: apiKeyID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "API key", ID: apiKeyID, Version: version}
+	}
+
+	pk := createAPIKeyCompositeKey(apiKeyID)
+
+	audit, err := auditPut(ctx, orgID, "api_key.deleted", AuditTargetAPIKey, apiKeyID, current, nil)
+	if err != nil {
+		return err
+	}
+
+	update := &types.Update{
+		TableName: aws.String("APIKeys"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+		},
+		UpdateExpression:         aws.String("SET #deleted = :true, #updatedAt = :updatedAt"),
+		ConditionExpression:      aws.String("attribute_exists(pk)"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "Deleted", "#updatedAt": "UpdatedAt"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true":      &types.AttributeValueMemberBOOL{Value: true},
+			":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	}
+	withAPIKeyOwner(update, orgID, serviceID)
+	withVersion(update, version)
+	// Deleted keys leave the Expiry-Index
+	update.UpdateExpression = aws.String(aws.ToString(update.UpdateExpression) + " REMOVE #expiryPK, #expirySK")
+	update.ExpressionAttributeNames["#expiryPK"] = "ExpiryPK"
+	update.ExpressionAttributeNames["#expirySK"] = "ExpirySK"
+
+	conflict := &ConflictError{Entity: "API key", ID: apiKeyID, Version: version}
+	return unit.write(conflict, []types.TransactWriteItem{{Update: update}, audit})
+}
+
+// ListAPIKeysByService retrieves a page of the API keys of a service from the DynamoDB table, along with the cursor of
+// the next page.
+func (d *APIKeyDBClient) ListAPIKeysByService(ctx context.Context, orgID, serviceID string, page Page) ([]APIKey, string, error) {
+	gsi1PK := createAPIKeyGSI1(orgID, serviceID)
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String("APIKeys"),
 		IndexName:              aws.String("Org-Service-Index"),
 		KeyConditionExpression: aws.String("GSI1PK = :gsi1PK"),
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":gsi2PK": &types.AttributeValueMemberS{
-				Value: gsi2PK,
+			":gsi1PK": &types.AttributeValueMemberS{
+				Value: gsi1PK,
 			},
 		},
 	}
 
-	result, err := d.service.Query(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
-	}
+	return queryPage(ctx, d.service, d.cursors, input, gsi1PK, page, func(apiKey *APIKey) bool {
+		return !apiKey.Deleted
+	})
+}
 
-	var apiKeys []APIKey
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &apiKeys)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
+// ListExpiredAPIKeys retrieves a page of the API keys whose expiry has passed at the given time but that have not
+// transitioned to the expired status yet from the Expiry-Index, along with the cursor of the next page. Expiry
+// timestamps are stored in UTC RFC 3339 format, so they compare lexically.
+func (d *APIKeyDBClient) ListExpiredAPIKeys(ctx context.Context, now time.Time, page Page) ([]APIKey, string, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("APIKeys"),
+		IndexName:              aws.String(apiKeyExpiryIndex),
+		KeyConditionExpression: aws.String("ExpiryPK = :expiryPK AND ExpirySK <= :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":expiryPK": &types.AttributeValueMemberS{Value: apiKeyExpiryPK},
+			":now":      &types.AttributeValueMemberS{Value: now.UTC().Format(time.RFC3339)},
+		},
 	}
 
-	results := []APIKey{}
-	for _, apiKey := range apiKeys {
-		if apiKey.Deleted {
-			continue
-		}
-		results = append(results, apiKey)
+	return queryPage(ctx, d.service, d.cursors, input, apiKeyExpiryPK, page, func(apiKey *APIKey) bool {
+		return !apiKey.Deleted && apiKey.Status != APIKeyStatusExpired
+	})
+}
+
+// ListAPIKeysByActor retrieves a page of the API keys bound to a specific actor from the DynamoDB table, along with
+// the cursor of the next page.
+func (d *APIKeyDBClient) ListAPIKeysByActor(ctx context.Context, orgID, serviceID, actorID string, page Page) ([]APIKey, string, error) {
+	gsi2PK := createAPIKeyGSI2(orgID, serviceID, actorID)
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("APIKeys"),
+		IndexName:              aws.String("Org-Service-Actor-Index"),
+		KeyConditionExpression: aws.String("GSI2PK = :gsi2PK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":gsi2PK": &types.AttributeValueMemberS{
+				Value: gsi2PK,
+			},
+		},
 	}
 
-	return results, nil
+	return queryPage(ctx, d.service, d.cursors, input, gsi2PK, page, func(apiKey *APIKey) bool {
+		return !apiKey.Deleted
+	})
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -19,7 +20,7 @@ func TestCreateAPIKey(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewAPIKeyDBClient(mockSvc)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
 
 	apiKey := &dal.APIKey{
 		ServiceID: "serv1",
@@ -29,19 +30,67 @@ func TestCreateAPIKey(t *testing.T) {
 	}
 
 	mockSvc.EXPECT().
-		PutItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.PutItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "APIKeys", *put.TableName)
+			assert.Equal(t, "Org#org1Service#serv1", put.Item["GSI1PK"].(*types.AttributeValueMemberS).Value)
+			assert.NotContains(t, put.Item, "GSI2PK")
+			assert.NotContains(t, put.Item, "ExpiryPK")
+			assert.NotContains(t, put.Item, "ExpirySK")
+
+			// Secrets never reach the audit log
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "api_key.created", event.Action)
+			assert.Equal(t, "org1", event.OrgID)
+			assert.Equal(t, apiKey.APIKeyID, event.TargetID)
+			assert.Contains(t, event.After, `"secret":"[redacted]"`)
+			assert.NotContains(t, event.After, "key1")
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
 	err := client.CreateAPIKey(context.Background(), apiKey)
 	assert.NoError(t, err)
 }
 
+func TestCreateAPIKey_ServiceUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	// The service is checked in the same transaction, so that no key is created in a service that is being deleted
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			check := input.TransactItems[2].ConditionCheck
+			assert.Equal(t, "Services", *check.TableName)
+			assert.Equal(t, "Org#org1", check.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Service#serv1", check.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, dal.ServiceStatusDeleting, check.ExpressionAttributeValues[":deleting"].(*types.AttributeValueMemberS).Value)
+			return nil, &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String("None")},
+					{Code: aws.String("ConditionalCheckFailed")},
+				},
+			}
+		})
+
+	err := client.CreateAPIKey(context.Background(), &dal.APIKey{OrgID: "org1", ServiceID: "serv1"})
+	var unavailable *dal.ServiceUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, "serv1", unavailable.ID)
+	assert.ErrorIs(t, err, dal.ErrConflict)
+}
+
 func TestGetAPIKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewAPIKeyDBClient(mockSvc)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
 
 	apiKey := dal.APIKey{
 		ServiceID: "serv1",
@@ -64,32 +113,183 @@ func TestGetAPIKey(t *testing.T) {
 	assert.Equal(t, "key1", result.Secret)
 }
 
+func TestGetAPIKey_Deleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key1", Deleted: true})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	// Deleted keys are only returned to callers that ask for them
+	result, err := client.GetAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	result, err = client.GetAPIKeyIncludingDeleted(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.True(t, result.Deleted)
+}
+
 func TestUpdateAPIKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewAPIKeyDBClient(mockSvc)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
 
 	apiKey := &dal.APIKey{
-		APIKeyID: "key1",
-		Scopes:   []string{"scope1", "scope2"},
+		OrgID:          "org1",
+		ServiceID:      "serv1",
+		APIKeyID:       "key1",
+		Scopes:         []string{"scope1", "scope2"},
+		Roles:          []string{"admin"},
+		RateLimits:     []dal.RateLimit{{Name: "minute", Algorithm: "fixed_window", Scope: "key", Limit: 10, Window: "1m"}},
+		AllowedCIDRs:   []string{"203.0.113.0/24"},
+		AllowedOrigins: []string{"https://app.example.com"},
 	}
 
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1", Scopes: []string{"scope1"}})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "APIKey#key1", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			var scopes []string
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":scopes"], &scopes))
+			assert.Equal(t, []string{"scope1", "scope2"}, scopes)
+			assert.NotEmpty(t, update.ExpressionAttributeValues[":updatedAt"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #scopes = :scopes, #roles = :roles, #rateLimits = :rateLimits, #allowedCidrs = :allowedCidrs, #allowedOrigins = :allowedOrigins, #expiry = :expiry, #updatedAt = :updatedAt, #version = :nextVersion REMOVE #expiryPK, #expirySK", *update.UpdateExpression)
+			assert.Equal(t, "#orgId = :orgId AND #serviceId = :serviceId AND attribute_not_exists(#version)", *update.ConditionExpression)
+			assert.Equal(t, "org1", update.ExpressionAttributeValues[":orgId"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Scopes", update.ExpressionAttributeNames["#scopes"])
+			assert.Equal(t, "RateLimits", update.ExpressionAttributeNames["#rateLimits"])
+
+			var rateLimits []dal.RateLimit
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":rateLimits"], &rateLimits))
+			assert.Equal(t, apiKey.RateLimits, rateLimits)
+
+			var roles []string
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":roles"], &roles))
+			assert.Equal(t, apiKey.Roles, roles)
+			assert.Equal(t, "Roles", update.ExpressionAttributeNames["#roles"])
+
+			var allowedCIDRs, allowedOrigins []string
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":allowedCidrs"], &allowedCIDRs))
+			assert.NoError(t, attributevalue.Unmarshal(update.ExpressionAttributeValues[":allowedOrigins"], &allowedOrigins))
+			assert.Equal(t, apiKey.AllowedCIDRs, allowedCIDRs)
+			assert.Equal(t, apiKey.AllowedOrigins, allowedOrigins)
+			assert.Equal(t, "AllowedCIDRs", update.ExpressionAttributeNames["#allowedCidrs"])
+			assert.Equal(t, "UpdatedAt", update.ExpressionAttributeNames["#updatedAt"])
+
+			// Only the fields that changed are recorded
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "api_key.updated", event.Action)
+			assert.Equal(t, "org1", event.OrgID)
+			assert.Contains(t, event.Before, `"scopes":["scope1"]`)
+			assert.Contains(t, event.After, `"scopes":["scope1","scope2"]`)
+			assert.NotContains(t, event.After, "orgId")
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.UpdateAPIKey(context.Background(), apiKey)
+	assert.NoError(t, err)
+}
+
+func TestUpdateAPIKeySecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
 	mockSvc.EXPECT().
 		UpdateItem(gomock.Any(), gomock.Any()).
 		DoAndReturn(func(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
 			assert.Equal(t, "APIKey#key1", input.Key["pk"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, []string{"scope1", "scope2"}, input.ExpressionAttributeValues[":scopes"].(*types.AttributeValueMemberSS).Value)
+			assert.Equal(t, "v1$salt$mac", input.ExpressionAttributeValues[":secret"].(*types.AttributeValueMemberS).Value)
 			assert.NotEmpty(t, input.ExpressionAttributeValues[":updatedAt"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "SET #scopes = :scopes, #updatedAt = :updatedAt", *input.UpdateExpression)
-			assert.Equal(t, "Scopes", input.ExpressionAttributeNames["#scopes"])
-			assert.Equal(t, "UpdatedAt", input.ExpressionAttributeNames["#updatedAt"])
+			assert.Equal(t, "SET #secret = :secret, #updatedAt = :updatedAt", *input.UpdateExpression)
+			assert.Equal(t, "#secret = :plaintext AND #deleted = :false", *input.ConditionExpression)
+			assert.Equal(t, "secret", input.ExpressionAttributeValues[":plaintext"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Secret", input.ExpressionAttributeNames["#secret"])
+			assert.Equal(t, "Deleted", input.ExpressionAttributeNames["#deleted"])
 			return &dynamodb.UpdateItemOutput{}, nil
 		})
 
-	err := client.UpdateAPIKey(context.Background(), apiKey)
+	updated, err := client.UpdateAPIKeySecret(context.Background(), "key1", "secret", "v1$salt$mac")
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	// The key was rotated or deleted since it was read
+	mockSvc.EXPECT().
+		UpdateItem(gomock.Any(), gomock.Any()).
+		Return(nil, &types.ConditionalCheckFailedException{})
+
+	updated, err = client.UpdateAPIKeySecret(context.Background(), "key1", "secret", "v1$salt$mac")
 	assert.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestRotateAPIKeySecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", APIKeyID: "key1", Secret: "v1$old$mac"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "APIKey#key1", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #secret = :newSecret, #previousSecret = :currentSecret, #previousSecretExpiry = :previousSecretExpiry, #updatedAt = :updatedAt ADD #version :one", *update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #deleted = :false AND #secret = :currentSecret", *update.ConditionExpression)
+			assert.Equal(t, "v1$new$mac", update.ExpressionAttributeValues[":newSecret"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "v1$old$mac", update.ExpressionAttributeValues[":currentSecret"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "2024-06-02T12:00:00Z", update.ExpressionAttributeValues[":previousSecretExpiry"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "PreviousSecret", update.ExpressionAttributeNames["#previousSecret"])
+			assert.Equal(t, "PreviousSecretExpiry", update.ExpressionAttributeNames["#previousSecretExpiry"])
+
+			// The change of secret is recorded without the secrets themselves
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "api_key.rotated", event.Action)
+			assert.Contains(t, event.Before, `"secret":"[redacted]"`)
+			assert.Contains(t, event.After, `"secret":"[redacted]"`)
+			assert.Contains(t, event.After, `"previousSecret":"[redacted]"`)
+			assert.Contains(t, event.After, `"previousSecretExpiry":"2024-06-02T12:00:00Z"`)
+			assert.NotContains(t, event.Before+event.After, "mac")
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	rotated, err := client.RotateAPIKeySecret(context.Background(), "key1", "v1$old$mac", "v1$new$mac", "2024-06-02T12:00:00Z")
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+
+	// The secret changed since it was read
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	rotated, err = client.RotateAPIKeySecret(context.Background(), "key1", "v1$old$mac", "v1$new$mac", "2024-06-02T12:00:00Z")
+	assert.NoError(t, err)
+	assert.False(t, rotated)
 }
 
 func TestDeleteAPIKey(t *testing.T) {
@@ -97,14 +297,193 @@ func TestDeleteAPIKey(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewAPIKeyDBClient(mockSvc)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
 
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1", Version: 2})
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.UpdateItemOutput{}, nil)
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "APIKey#key1", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #deleted = :true, #updatedAt = :updatedAt, #version = :nextVersion REMOVE #expiryPK, #expirySK", *update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #orgId = :orgId AND #serviceId = :serviceId AND #version = :version", *update.ConditionExpression)
+			assert.Equal(t, "serv1", update.ExpressionAttributeValues[":serviceId"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "2", update.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "3", update.ExpressionAttributeValues[":nextVersion"].(*types.AttributeValueMemberN).Value)
 
-	err := client.DeleteAPIKey(context.Background(), "org1", "serv1", "key1")
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "api_key.deleted", event.Action)
+			assert.Contains(t, event.Before, `"apiKeyId":"key1"`)
+			assert.Empty(t, event.After)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.DeleteAPIKey(context.Background(), "org1", "serv1", "key1", 2)
 	assert.NoError(t, err)
+
+	// Keys that do not exist are not deleted
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	err = client.DeleteAPIKey(context.Background(), "org1", "serv1", "key2", 1)
+	assert.Error(t, err)
+}
+
+func TestAPIKey_OtherOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(3)
+
+	// Keys of other organizations and services are not found, and nothing is written
+	err := client.UpdateAPIKey(context.Background(), &dal.APIKey{OrgID: "org2", ServiceID: "serv1", APIKeyID: "key1"})
+	assert.ErrorIs(t, err, dal.ErrNotFound)
+
+	err = client.DeleteAPIKey(context.Background(), "org2", "serv1", "key1", 0)
+	assert.ErrorIs(t, err, dal.ErrNotFound)
+
+	err = client.DeleteAPIKey(context.Background(), "org1", "serv2", "key1", 0)
+	assert.ErrorIs(t, err, dal.ErrNotFound)
+}
+
+func TestDeleteAPIKey_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1", Version: 2})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	// The key was changed since version 1 was read
+	err := client.DeleteAPIKey(context.Background(), "org1", "serv1", "key1", 1)
+	var conflict *dal.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "key1", conflict.ID)
+	assert.Equal(t, int64(1), conflict.Version)
+
+	// The key was changed between the read and the write
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	err = client.DeleteAPIKey(context.Background(), "org1", "serv1", "key1", 2)
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, int64(2), conflict.Version)
+}
+
+func TestAPIKey_ExpiryIndex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	// Keys that expire are created in the expiry index
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "Expiring", put.Item["ExpiryPK"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "2024-06-01T12:00:00Z", put.Item["ExpirySK"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.CreateAPIKey(context.Background(), &dal.APIKey{OrgID: "org1", ServiceID: "serv1", Expiry: "2024-06-01T12:00:00Z"})
+	assert.NoError(t, err)
+
+	// Keys that are given an expiry enter the index
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "SET #scopes = :scopes, #roles = :roles, #rateLimits = :rateLimits, #allowedCidrs = :allowedCidrs, #allowedOrigins = :allowedOrigins, #expiry = :expiry, #updatedAt = :updatedAt, #expiryPK = :expiryPK, #expirySK = :expiry, #version = :nextVersion", *update.UpdateExpression)
+			assert.Equal(t, "Expiring", update.ExpressionAttributeValues[":expiryPK"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "ExpiryPK", update.ExpressionAttributeNames["#expiryPK"])
+			// Keys without scopes must not be written as an empty string set, which DynamoDB rejects
+			_, isSet := update.ExpressionAttributeValues[":scopes"].(*types.AttributeValueMemberSS)
+			assert.False(t, isSet)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err = client.UpdateAPIKey(context.Background(), &dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1", Expiry: "2024-06-01T12:00:00Z"})
+	assert.NoError(t, err)
+}
+
+func TestCreateAPIKey_Actor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "Org#org1Service#serv1Actor#actor1", put.Item["GSI2PK"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.CreateAPIKey(context.Background(), &dal.APIKey{OrgID: "org1", ServiceID: "serv1", ActorID: "actor1"})
+	assert.NoError(t, err)
+}
+
+func TestListAPIKeysByActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	key1, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key1", ActorID: "actor1"})
+	deleted, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key2", ActorID: "actor1", Deleted: true})
+	lastKey := map[string]types.AttributeValue{
+		"pk":     &types.AttributeValueMemberS{Value: "APIKey#key2"},
+		"GSI2PK": &types.AttributeValueMemberS{Value: "Org#org1Service#serv1Actor#actor1"},
+	}
+
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "APIKeys", *input.TableName)
+			assert.Equal(t, "Org-Service-Actor-Index", *input.IndexName)
+			assert.Equal(t, "GSI2PK = :gsi2PK", *input.KeyConditionExpression)
+			assert.Equal(t, "Org#org1Service#serv1Actor#actor1", input.ExpressionAttributeValues[":gsi2PK"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{key1, deleted}, LastEvaluatedKey: lastKey}, nil
+		})
+
+	result, cursor, err := client.ListAPIKeysByActor(context.Background(), "org1", "serv1", "actor1", dal.Page{Limit: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, result, 1) {
+		assert.Equal(t, "key1", result[0].APIKeyID)
+	}
+	assert.NotEmpty(t, cursor)
+
+	// Cursors of one actor cannot be used to list the keys of another
+	_, _, err = client.ListAPIKeysByActor(context.Background(), "org1", "serv1", "actor2", dal.Page{Cursor: cursor})
+	assert.ErrorIs(t, err, dal.ErrInvalidCursor)
 }
 
 func TestListAPIKeysByService(t *testing.T) {
@@ -112,7 +491,7 @@ func TestListAPIKeysByService(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewAPIKeyDBClient(mockSvc)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
 
 	apiKey := dal.APIKey{
 		ServiceID: "serv1",
@@ -129,9 +508,216 @@ func TestListAPIKeysByService(t *testing.T) {
 		Query(gomock.Any(), gomock.Any()).
 		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil)
 
-	result, err := client.ListAPIKeysByService(context.Background(), "org1", "serv1")
+	result, cursor, err := client.ListAPIKeysByService(context.Background(), "org1", "serv1", dal.Page{})
 	assert.NoError(t, err)
+	assert.Empty(t, cursor)
 	assert.NotNil(t, result)
 	assert.Equal(t, 1, len(result))
 	assert.Equal(t, "key1", result[0].Secret)
 }
+
+func TestExpireAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", APIKeyID: "key1", Status: dal.APIKeyStatusActive})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "APIKey#key1", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #status = :status, #updatedAt = :updatedAt ADD #version :one REMOVE #expiryPK, #expirySK", *update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #deleted = :false AND (attribute_not_exists(#status) OR #status <> :status)", *update.ConditionExpression)
+			assert.Equal(t, dal.APIKeyStatusExpired, update.ExpressionAttributeValues[":status"].(*types.AttributeValueMemberS).Value)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "api_key.expired", event.Action)
+			assert.Contains(t, event.Before, `"status":"active"`)
+			assert.Contains(t, event.After, `"status":"expired"`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	transitioned, err := client.ExpireAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.True(t, transitioned)
+
+	// Keys that were already expired or deleted are left alone
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	transitioned, err = client.ExpireAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.False(t, transitioned)
+
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	transitioned, err = client.ExpireAPIKey(context.Background(), "key2")
+	assert.NoError(t, err)
+	assert.False(t, transitioned)
+}
+
+func TestQuarantineAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", APIKeyID: "key1", Status: dal.APIKeyStatusActive})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "APIKey#key1", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #status = :status, #updatedAt = :updatedAt ADD #version :one", *update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #deleted = :false AND (attribute_not_exists(#status) OR #status = :active)", *update.ConditionExpression)
+			assert.Equal(t, dal.APIKeyStatusQuarantined, update.ExpressionAttributeValues[":status"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, dal.APIKeyStatusActive, update.ExpressionAttributeValues[":active"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "api_key.quarantined", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	transitioned, err := client.QuarantineAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.True(t, transitioned)
+
+	// Keys that were deleted, expired or already quarantined are left alone
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	transitioned, err = client.QuarantineAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+	assert.False(t, transitioned)
+}
+
+func TestStageQuarantineAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+	transactionClient := dal.NewTransactionDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", APIKeyID: "key1", Status: dal.APIKeyStatusActive, Version: 3})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	unit := dal.NewUnitOfWork()
+	assert.NoError(t, client.StageQuarantineAPIKey(context.Background(), unit, "key1"))
+	assert.Equal(t, 2, unit.Len())
+
+	// The key was deleted, expired or quarantined before the unit of work was committed
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "attribute_exists(pk) AND #deleted = :false AND (attribute_not_exists(#status) OR #status = :active)", *update.ConditionExpression)
+			assert.Equal(t, "api_key.quarantined", auditEvent(t, input.TransactItems[1]).Action)
+			return nil, conditionFailed()
+		})
+
+	err := transactionClient.Commit(context.Background(), unit)
+	var conflict *dal.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, int64(3), conflict.Version)
+
+	// Keys that do not exist cannot be quarantined
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	err = client.StageQuarantineAPIKey(context.Background(), dal.NewUnitOfWork(), "key2")
+	assert.ErrorIs(t, err, dal.ErrNotFound)
+}
+
+func TestListExpiredAPIKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	first, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key1", Expiry: "2024-06-01T11:00:00Z"})
+	second, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key2", Expiry: "2024-06-01T12:00:00Z"})
+	swept, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key3", Expiry: "2024-06-01T12:00:00Z", Status: dal.APIKeyStatusExpired})
+	lastKey := map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "APIKey#key1"}}
+
+	// The expiry index is queried a page at a time, without scanning the table
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "APIKeys", *input.TableName)
+			assert.Equal(t, "Expiry-Index", *input.IndexName)
+			assert.Equal(t, "ExpiryPK = :expiryPK AND ExpirySK <= :now", *input.KeyConditionExpression)
+			assert.Equal(t, "Expiring", input.ExpressionAttributeValues[":expiryPK"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "2024-06-01T12:00:00Z", input.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, int32(1), *input.Limit)
+			assert.Nil(t, input.ExclusiveStartKey)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{first}, LastEvaluatedKey: lastKey}, nil
+		})
+
+	result, cursor, err := client.ListExpiredAPIKeys(context.Background(), now, dal.Page{Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "key1", result[0].APIKeyID)
+	assert.NotEmpty(t, cursor)
+
+	// The next page starts after the last key read, and keys that were already swept are left out
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, lastKey, input.ExclusiveStartKey)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second, swept}}, nil
+		})
+
+	result, cursor, err = client.ListExpiredAPIKeys(context.Background(), now, dal.Page{Cursor: cursor, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "key2", result[0].APIKeyID)
+	assert.Empty(t, cursor)
+}
+
+func TestAPIKey_Expired(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		apiKey  dal.APIKey
+		expired bool
+	}{
+		{"No expiry", dal.APIKey{}, false},
+		{"Expiry in the future", dal.APIKey{Expiry: "2024-06-01T12:00:01Z"}, false},
+		{"Expiry reached", dal.APIKey{Expiry: "2024-06-01T12:00:00Z"}, true},
+		{"Expired status", dal.APIKey{Status: dal.APIKeyStatusExpired}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expired, err := tt.apiKey.Expired(now)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expired, expired)
+		})
+	}
+
+	_, err := (&dal.APIKey{Expiry: "tomorrow"}).Expired(now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,324 @@
+package dal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/utils"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// AuditTargetOrganization is the target type of audit events about organizations.
+	AuditTargetOrganization = "organization"
+	// AuditTargetService is the target type of audit events about services.
+	AuditTargetService = "service"
+	// AuditTargetAPIKey is the target type of audit events about API keys.
+	AuditTargetAPIKey = "api_key"
+	// AuditTargetActor is the target type of audit events about actors.
+	AuditTargetActor = "actor"
+	// AuditTargetPricingTier is the target type of audit events about pricing tiers.
+	AuditTargetPricingTier = "pricing_tier"
+	// AuditTargetBlockedIP is the target type of audit events about blocked IP addresses.
+	AuditTargetBlockedIP = "blocked_ip"
+)
+
+const (
+	// AuditActorUser is the actor type of mutations made by users authenticated with a JWT.
+	AuditActorUser = "user"
+	// AuditActorPartner is the actor type of mutations made by partners, such as leak reports.
+	AuditActorPartner = "partner"
+	// AuditActorSystem is the actor type of mutations made by the server itself, such as the expiry sweeper.
+	AuditActorSystem = "system"
+)
+
+// auditTimestampFormat is the format of the timestamps of audit events. Unlike RFC 3339, its fractional seconds have a
+// fixed width, so that events sort lexically in the order they occurred.
+const auditTimestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// auditRedacted replaces the values of redacted fields in the before and after states of audit events.
+const auditRedacted = "[redacted]"
+
+// auditRedactedFields are the fields whose values are never recorded in audit events. Changes to them are still
+// recorded, with their values redacted.
+var auditRedactedFields = map[string]bool{
+	"secret":         true,
+	"previousSecret": true,
+}
+
+//go:generate mockgen -package=mocks -destination=mocks/mock_audit_db_client.go "github.com/payloadops/lanyard/app/dal" AuditEventManager
+
+// AuditEventManager defines the operations available for reading the audit log. Audit events are written by the other
+// managers, in the same transaction as the mutation they record.
+type AuditEventManager interface {
+	ListAuditEvents(ctx context.Context, orgID string, filter AuditEventFilter, page Page) ([]AuditEvent, string, error)
+}
+
+// Ensure AuditEventDBClient implements the AuditEventManager interface
+var _ AuditEventManager = &AuditEventDBClient{}
+
+// AuditEvent records a mutation of a resource of an organization. Before and After hold the JSON encoded fields of the
+// target that the mutation changed, and are empty when the target was created or deleted respectively.
+type AuditEvent struct {
+	EventID    string `json:"eventId"`
+	OrgID      string `json:"orgId"`
+	ActorType  string `json:"actorType"`
+	ActorID    string `json:"actorId"`
+	Action     string `json:"action"`
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	RequestID  string `json:"requestId"`
+	IPAddress  string `json:"ipAddress"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// AuditEventFilter narrows the audit events returned by ListAuditEvents. Empty fields match every event, Since is
+// inclusive and Until is exclusive.
+type AuditEventFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Since      time.Time
+	Until      time.Time
+}
+
+// AuditEventDBClient is a client for interacting with DynamoDB for audit event related operations. Audit events are
+// stored in the Services table, partitioned by organization.
+type AuditEventDBClient struct {
+	service DynamoDBAPI
+	cursors *CursorCodec
+}
+
+// NewAuditEventDBClient creates a new AuditEventDBClient. The cursors of its list operations are signed by the codec.
+func NewAuditEventDBClient(service DynamoDBAPI, cursors *CursorCodec) *AuditEventDBClient {
+	return &AuditEventDBClient{
+		service: withClassifiedErrors(service),
+		cursors: cursors,
+	}
+}
+
+// createAuditEventCompositeKeys generates the partition key (pk) and sort key (sk) for an audit event.
+func createAuditEventCompositeKeys(orgID, createdAt, eventID string) (string, string) {
+	return "Org#" + orgID, "AuditEvent#" + createdAt + "#" + eventID
+}
+
+// auditPut returns a transaction item that appends an audit event for a mutation of a target to the audit log of an
+// organization. The actor, request ID and IP address of the event are taken from the request context, and only the
+// fields that differ between before and after are recorded.
+func auditPut(ctx context.Context, orgID, action, targetType, targetID string, before, after interface{}) (types.TransactWriteItem, error) {
+	ksuid, err := utils.GenerateKSUID()
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to create ksuid: %w", err)
+	}
+
+	beforeJSON, afterJSON, err := auditDiff(before, after)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to diff audit states: %w", err)
+	}
+
+	actorType, actorID := auditActor(ctx)
+	clientIP, _ := ctx.Value("clientIP").(string)
+	event := AuditEvent{
+		EventID:    ksuid,
+		OrgID:      orgID,
+		ActorType:  actorType,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		RequestID:  middleware.GetReqID(ctx),
+		IPAddress:  auditIPAddress(clientIP),
+		CreatedAt:  time.Now().UTC().Format(auditTimestampFormat),
+	}
+
+	av, err := attributevalue.MarshalMap(event)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	pk, sk := createAuditEventCompositeKeys(orgID, event.CreatedAt, event.EventID)
+	item := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: sk},
+	}
+	for k, v := range av {
+		item[k] = v
+	}
+
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName:           aws.String("Services"),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		},
+	}, nil
+}
+
+// auditActor returns the type and ID of the actor of a mutation, from the identity authenticated for its request.
+// Mutations outside of requests are made by the system.
+func auditActor(ctx context.Context) (string, string) {
+	if userID, ok := ctx.Value("userID").(string); ok && userID != "" {
+		return AuditActorUser, userID
+	}
+	if partnerID, ok := ctx.Value("partnerID").(string); ok && partnerID != "" {
+		return AuditActorPartner, partnerID
+	}
+	return AuditActorSystem, ""
+}
+
+// auditOrgID returns the organization of the caller of a request, for mutations of resources that are not stored with
+// their organization.
+func auditOrgID(ctx context.Context) string {
+	orgID, _ := ctx.Value("orgID").(string)
+	return orgID
+}
+
+// auditIPAddress strips the port from the remote address of a request, leaving other values as they are.
+func auditIPAddress(value string) string {
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap().String()
+	}
+	return value
+}
+
+// auditDiff returns the JSON encoded fields that differ between the before and after states of a target, either of
+// which may be nil. The values of redacted fields are replaced, so that secrets never reach the audit log.
+func auditDiff(before, after interface{}) (string, string, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return "", "", err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return "", "", err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for field, value := range beforeFields {
+			if other, ok := afterFields[field]; ok && reflect.DeepEqual(value, other) {
+				delete(beforeFields, field)
+				delete(afterFields, field)
+			}
+		}
+	}
+
+	beforeJSON, err := encodeAuditFields(beforeFields)
+	if err != nil {
+		return "", "", err
+	}
+	afterJSON, err := encodeAuditFields(afterFields)
+	if err != nil {
+		return "", "", err
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+// auditFields decodes the JSON representation of a state into its fields, or returns nil for a nil state.
+func auditFields(state interface{}) (map[string]interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	if value := reflect.ValueOf(state); value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	err = json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// encodeAuditFields encodes the fields of a state with the values of redacted fields replaced, or returns an empty
+// string for a nil state.
+func encodeAuditFields(fields map[string]interface{}) (string, error) {
+	if fields == nil {
+		return "", nil
+	}
+
+	for field, value := range fields {
+		if auditRedactedFields[field] && value != "" {
+			fields[field] = auditRedacted
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// ListAuditEvents retrieves the audit events of an organization matching a filter from the DynamoDB table, newest
+// first, along with the cursor of the next page.
+func (d *AuditEventDBClient) ListAuditEvents(ctx context.Context, orgID string, filter AuditEventFilter, page Page) ([]AuditEvent, string, error) {
+	pk, prefix := createAuditEventCompositeKeys(orgID, "", "")
+	prefix = strings.TrimRight(prefix, "#")
+
+	// Events are sorted by timestamp, so the time range is part of the key condition. The end of the range is the
+	// timestamp itself, which sorts before every event that occurred at that time.
+	from, to := prefix+"#", prefix+"$"
+	if !filter.Since.IsZero() {
+		from = prefix + "#" + filter.Since.UTC().Format(auditTimestampFormat)
+	}
+	if !filter.Until.IsZero() {
+		to = prefix + "#" + filter.Until.UTC().Format(auditTimestampFormat)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		KeyConditionExpression: aws.String("pk = :pk AND sk BETWEEN :from AND :to"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk":   &types.AttributeValueMemberS{Value: pk},
+			":from": &types.AttributeValueMemberS{Value: from},
+			":to":   &types.AttributeValueMemberS{Value: to},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	var conditions []string
+	exprAttrNames := map[string]string{}
+	for _, field := range []struct{ name, placeholder, value string }{
+		{"ActorID", "actorId", filter.ActorID},
+		{"Action", "action", filter.Action},
+		{"TargetType", "targetType", filter.TargetType},
+		{"TargetID", "targetId", filter.TargetID},
+	} {
+		if field.value == "" {
+			continue
+		}
+		conditions = append(conditions, "#"+field.placeholder+" = :"+field.placeholder)
+		exprAttrNames["#"+field.placeholder] = field.name
+		input.ExpressionAttributeValues[":"+field.placeholder] = &types.AttributeValueMemberS{Value: field.value}
+	}
+	if len(conditions) > 0 {
+		input.FilterExpression = aws.String(strings.Join(conditions, " AND "))
+		input.ExpressionAttributeNames = exprAttrNames
+	}
+
+	return queryPage[AuditEvent](ctx, d.service, d.cursors, input, pk, page, nil)
+}
@@ -0,0 +1,237 @@
+package dal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// auditEvent unmarshals the audit event put by a transaction item.
+func auditEvent(t *testing.T, item types.TransactWriteItem) dal.AuditEvent {
+	t.Helper()
+
+	var event dal.AuditEvent
+	if assert.NotNil(t, item.Put) {
+		assert.Equal(t, "Services", *item.Put.TableName)
+		assert.NoError(t, attributevalue.UnmarshalMap(item.Put.Item, &event))
+	}
+	return event
+}
+
+// conditionFailed returns the error of a transaction canceled because the condition of its first item failed.
+func conditionFailed() error {
+	return &types.TransactionCanceledException{
+		CancellationReasons: []types.CancellationReason{
+			{Code: aws.String("ConditionalCheckFailed")},
+			{Code: aws.String("None")},
+		},
+	}
+}
+
+func TestAuditEvent_RequestContext(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "req-1")
+	ctx = context.WithValue(ctx, "userID", "user1")
+	ctx = context.WithValue(ctx, "clientIP", "203.0.113.7:54321")
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Len(t, input.TransactItems, 2)
+			put := input.TransactItems[1].Put
+			assert.Equal(t, "Org#org1", put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Regexp(t, `^AuditEvent#\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{9}Z#\w+$`, put.Item["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "attribute_not_exists(pk)", *put.ConditionExpression)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.NotEmpty(t, event.EventID)
+			assert.Equal(t, "org1", event.OrgID)
+			assert.Equal(t, dal.AuditActorUser, event.ActorType)
+			assert.Equal(t, "user1", event.ActorID)
+			assert.Equal(t, "service.created", event.Action)
+			assert.Equal(t, dal.AuditTargetService, event.TargetType)
+			assert.NotEmpty(t, event.TargetID)
+			assert.Equal(t, "req-1", event.RequestID)
+			assert.Equal(t, "203.0.113.7", event.IPAddress)
+			assert.Empty(t, event.Before)
+			assert.Contains(t, event.After, `"name":"Payments"`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.CreateService(ctx, "org1", &dal.Service{Name: "Payments"})
+	assert.NoError(t, err)
+}
+
+func TestAuditEvent_Actor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.APIKey{OrgID: "org1", ServiceID: "serv1", APIKeyID: "key1"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	var events []dal.AuditEvent
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			events = append(events, auditEvent(t, input.TransactItems[1]))
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}).
+		Times(2)
+
+	// Leak reports are made by partners, and the expiry sweeper runs outside of any request
+	_, err := client.QuarantineAPIKey(context.WithValue(context.Background(), "partnerID", "scanner"), "key1")
+	assert.NoError(t, err)
+	_, err = client.ExpireAPIKey(context.Background(), "key1")
+	assert.NoError(t, err)
+
+	assert.Equal(t, dal.AuditActorPartner, events[0].ActorType)
+	assert.Equal(t, "scanner", events[0].ActorID)
+	assert.Equal(t, dal.AuditActorSystem, events[1].ActorType)
+	assert.Empty(t, events[1].ActorID)
+	assert.Equal(t, "org1", events[1].OrgID)
+}
+
+func TestListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAuditEventDBClient(mockSvc, cursors)
+
+	first, _ := attributevalue.MarshalMap(dal.AuditEvent{EventID: "event2", Action: "api_key.deleted"})
+	second, _ := attributevalue.MarshalMap(dal.AuditEvent{EventID: "event1", Action: "api_key.deleted"})
+	firstKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "Org#org1"},
+		"sk": &types.AttributeValueMemberS{Value: "AuditEvent#2024-06-01T12:00:02.000000000Z#event2"},
+	}
+	secondKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "Org#org1"},
+		"sk": &types.AttributeValueMemberS{Value: "AuditEvent#2024-06-01T12:00:01.000000000Z#event1"},
+	}
+
+	filter := dal.AuditEventFilter{
+		Action:   "api_key.deleted",
+		TargetID: "key1",
+		Since:    time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	// Pages are read until the limit is reached, since filtered events count against the limit of a query
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, "Services", *input.TableName)
+				assert.Equal(t, "pk = :pk AND sk BETWEEN :from AND :to", *input.KeyConditionExpression)
+				assert.Equal(t, "Org#org1", input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "AuditEvent#2024-06-01T00:00:00.000000000Z", input.ExpressionAttributeValues[":from"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "AuditEvent#2024-06-02T00:00:00.000000000Z", input.ExpressionAttributeValues[":to"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "#action = :action AND #targetId = :targetId", *input.FilterExpression)
+				assert.Equal(t, "TargetID", input.ExpressionAttributeNames["#targetId"])
+				assert.Equal(t, "key1", input.ExpressionAttributeValues[":targetId"].(*types.AttributeValueMemberS).Value)
+				assert.False(t, *input.ScanIndexForward)
+				assert.Equal(t, int32(2), *input.Limit)
+				assert.Nil(t, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{first}, LastEvaluatedKey: firstKey}, nil
+			}),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, int32(1), *input.Limit)
+				assert.Equal(t, firstKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second}, LastEvaluatedKey: secondKey}, nil
+			}),
+	)
+
+	events, cursor, err := client.ListAuditEvents(context.Background(), "org1", filter, dal.Page{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "event2", events[0].EventID)
+	assert.Equal(t, "event1", events[1].EventID)
+	assert.NotEmpty(t, cursor)
+
+	// The cursor resumes after the last event of the page
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, secondKey, input.ExclusiveStartKey)
+			return &dynamodb.QueryOutput{}, nil
+		})
+
+	events, cursor, err = client.ListAuditEvents(context.Background(), "org1", filter, dal.Page{Cursor: cursor, Limit: 2})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Empty(t, cursor)
+}
+
+func TestListAuditEvents_Unfiltered(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAuditEventDBClient(mockSvc, cursors)
+
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "AuditEvent#", input.ExpressionAttributeValues[":from"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "AuditEvent$", input.ExpressionAttributeValues[":to"].(*types.AttributeValueMemberS).Value)
+			assert.Nil(t, input.FilterExpression)
+			assert.Equal(t, int32(dal.MaxPageLimit), *input.Limit)
+			return &dynamodb.QueryOutput{}, nil
+		})
+
+	events, cursor, err := client.ListAuditEvents(context.Background(), "org1", dal.AuditEventFilter{}, dal.Page{})
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Empty(t, cursor)
+}
+
+func TestListAuditEvents_InvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAuditEventDBClient(mockSvc, cursors)
+
+	// A cursor of another organization is rejected before any query
+	event, _ := attributevalue.MarshalMap(dal.AuditEvent{EventID: "event1"})
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{event},
+			LastEvaluatedKey: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: "Org#org2"},
+				"sk": &types.AttributeValueMemberS{Value: "AuditEvent#2024-06-01T12:00:01.000000000Z#event1"},
+			},
+		}, nil)
+	_, otherCursor, err := client.ListAuditEvents(context.Background(), "org2", dal.AuditEventFilter{}, dal.Page{Limit: 1})
+	assert.NoError(t, err)
+
+	for _, cursor := range []string{"not a cursor", otherCursor} {
+		_, _, err := client.ListAuditEvents(context.Background(), "org1", dal.AuditEventFilter{}, dal.Page{Cursor: cursor})
+		assert.ErrorIs(t, err, dal.ErrInvalidCursor)
+	}
+}
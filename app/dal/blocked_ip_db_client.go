@@ -0,0 +1,329 @@
+package dal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/mock_blocked_ip_db_client.go "github.com/payloadops/lanyard/app/dal" BlockedIPManager
+
+// BlockedIPManager defines the operations available for managing the IP addresses blocked from using the API keys of
+// a service.
+type BlockedIPManager interface {
+	CreateBlockedIP(ctx context.Context, blockedIP *BlockedIP) (bool, error)
+	GetBlockedIP(ctx context.Context, serviceID, ipAddress string) (*BlockedIP, error)
+	UpdateBlockedIP(ctx context.Context, blockedIP *BlockedIP) (bool, error)
+	DeleteBlockedIP(ctx context.Context, serviceID, ipAddress string) (bool, error)
+	ListBlockedIPsByService(ctx context.Context, serviceID string, page Page) ([]BlockedIP, string, error)
+	ListBlockedIPs(ctx context.Context) ([]BlockedIP, error)
+}
+
+// Ensure BlockedIPDBClient implements the BlockedIPManager interface
+var _ BlockedIPManager = &BlockedIPDBClient{}
+
+// BlockedIP represents an IP address or CIDR range blocked from using the API keys of a service. The IP address is
+// stored in its canonical form, so that each address or range is blocked at most once per service.
+type BlockedIP struct {
+	ServiceID string `json:"serviceId"`
+	IPAddress string `json:"ipAddress"`
+	Reason    string `json:"reason"`
+	Expiry    string `json:"expiry"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// Expired reports whether the block has an expiry that has passed at the given time.
+func (b *BlockedIP) Expired(now time.Time) (bool, error) {
+	if b.Expiry == "" {
+		return false, nil
+	}
+
+	expiry, err := time.Parse(time.RFC3339, b.Expiry)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse expiry: %w", err)
+	}
+
+	return !now.Before(expiry), nil
+}
+
+// BlockedIPDBClient is a client for interacting with DynamoDB for blocked IP related operations. Blocked IPs are
+// stored in the Services table, partitioned by service.
+type BlockedIPDBClient struct {
+	service DynamoDBAPI
+	cursors *CursorCodec
+}
+
+// NewBlockedIPDBClient creates a new BlockedIPDBClient.
+func NewBlockedIPDBClient(service DynamoDBAPI, cursors *CursorCodec) *BlockedIPDBClient {
+	return &BlockedIPDBClient{
+		service: withClassifiedErrors(service),
+		cursors: cursors,
+	}
+}
+
+// blockedIPIndex is a sparse index of the Services table holding only the blocked IPs, so that the blocked IPs of
+// every service are queried without reading the other items of the table.
+const blockedIPIndex = "Blocked-IP-Index"
+
+// blockedIPIndexPK is the partition key of every blocked IP in the Blocked-IP-Index. Items of the Services table
+// that are not blocked IPs do not have the attribute, and are left out of the index.
+const blockedIPIndexPK = "BlockedIp"
+
+// createBlockedIPCompositeKeys generates the partition key (pk) and sort key (sk) for a blocked IP.
+func createBlockedIPCompositeKeys(serviceID, ipAddress string) (string, string) {
+	return "Service#" + serviceID, "BlockedIp#" + ipAddress
+}
+
+// CreateBlockedIP creates a new blocked IP in the DynamoDB table. It reports false when the IP address is already
+// blocked for the service, unless the existing block has expired, in which case it is replaced.
+func (d *BlockedIPDBClient) CreateBlockedIP(ctx context.Context, blockedIP *BlockedIP) (bool, error) {
+	pk, sk := createBlockedIPCompositeKeys(blockedIP.ServiceID, blockedIP.IPAddress)
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	blockedIP.CreatedAt = now
+	blockedIP.UpdatedAt = now
+
+	av, err := attributevalue.MarshalMap(blockedIP)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal blocked IP: %w", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+		"sk": &types.AttributeValueMemberS{Value: sk},
+		// Index the block in the Blocked-IP-Index
+		"BlocklistPK": &types.AttributeValueMemberS{Value: blockedIPIndexPK},
+	}
+	for k, v := range av {
+		item[k] = v
+	}
+
+	audit, err := auditPut(ctx, auditOrgID(ctx), "blocked_ip.created", AuditTargetBlockedIP, blockedIP.IPAddress, nil, blockedIP)
+	if err != nil {
+		return false, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:           aws.String("Services"),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(pk) OR (#expiry <> :empty AND #expiry <= :now)"),
+				ExpressionAttributeNames: map[string]string{
+					"#expiry": "Expiry",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":empty": &types.AttributeValueMemberS{Value: ""},
+					":now":   &types.AttributeValueMemberS{Value: now},
+				},
+			},
+		},
+		audit,
+	}
+
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// GetBlockedIP retrieves a blocked IP by service ID and canonical IP address from the DynamoDB table.
+func (d *BlockedIPDBClient) GetBlockedIP(ctx context.Context, serviceID, ipAddress string) (*BlockedIP, error) {
+	pk, sk := createBlockedIPCompositeKeys(serviceID, ipAddress)
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("Services"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+	}
+
+	result, err := d.service.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var blockedIP BlockedIP
+	err = attributevalue.UnmarshalMap(result.Item, &blockedIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
+	}
+
+	return &blockedIP, nil
+}
+
+// UpdateBlockedIP updates the reason, expiry, and updatedAt fields of an existing blocked IP in the DynamoDB table.
+// It reports false when the IP address is not blocked for the service.
+func (d *BlockedIPDBClient) UpdateBlockedIP(ctx context.Context, blockedIP *BlockedIP) (bool, error) {
+	current, err := d.GetBlockedIP(ctx, blockedIP.ServiceID, blockedIP.IPAddress)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, nil
+	}
+
+	pk, sk := createBlockedIPCompositeKeys(blockedIP.ServiceID, blockedIP.IPAddress)
+	blockedIP.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	// Blocks created before the Blocked-IP-Index are indexed when they are updated
+	updateExpr := "SET #reason = :reason, #expiry = :expiry, #updatedAt = :updatedAt, #blocklistPK = :blocklistPK"
+	exprAttrNames := map[string]string{
+		"#reason":      "Reason",
+		"#expiry":      "Expiry",
+		"#updatedAt":   "UpdatedAt",
+		"#blocklistPK": "BlocklistPK",
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":reason":      &types.AttributeValueMemberS{Value: blockedIP.Reason},
+		":expiry":      &types.AttributeValueMemberS{Value: blockedIP.Expiry},
+		":updatedAt":   &types.AttributeValueMemberS{Value: blockedIP.UpdatedAt},
+		":blocklistPK": &types.AttributeValueMemberS{Value: blockedIPIndexPK},
+	}
+
+	updated := *current
+	updated.Reason = blockedIP.Reason
+	updated.Expiry = blockedIP.Expiry
+	updated.UpdatedAt = blockedIP.UpdatedAt
+
+	audit, err := auditPut(ctx, auditOrgID(ctx), "blocked_ip.updated", AuditTargetBlockedIP, blockedIP.IPAddress, current, &updated)
+	if err != nil {
+		return false, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Update: &types.Update{
+				TableName:                 aws.String("Services"),
+				Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
+				UpdateExpression:          aws.String(updateExpr),
+				ConditionExpression:       aws.String("attribute_exists(pk)"),
+				ExpressionAttributeNames:  exprAttrNames,
+				ExpressionAttributeValues: exprAttrValues,
+			},
+		},
+		audit,
+	}
+
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// DeleteBlockedIP removes a blocked IP by service ID and canonical IP address from the DynamoDB table. It reports
+// false when the IP address is not blocked for the service.
+func (d *BlockedIPDBClient) DeleteBlockedIP(ctx context.Context, serviceID, ipAddress string) (bool, error) {
+	current, err := d.GetBlockedIP(ctx, serviceID, ipAddress)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		return false, nil
+	}
+
+	pk, sk := createBlockedIPCompositeKeys(serviceID, ipAddress)
+
+	audit, err := auditPut(ctx, auditOrgID(ctx), "blocked_ip.deleted", AuditTargetBlockedIP, ipAddress, current, nil)
+	if err != nil {
+		return false, err
+	}
+
+	items := []types.TransactWriteItem{
+		{
+			Delete: &types.Delete{
+				TableName: aws.String("Services"),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: pk},
+					"sk": &types.AttributeValueMemberS{Value: sk},
+				},
+				ConditionExpression: aws.String("attribute_exists(pk)"),
+			},
+		},
+		audit,
+	}
+
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	return true, nil
+}
+
+// ListBlockedIPsByService retrieves a page of the blocked IPs of a specific service from the DynamoDB table,
+// including those whose expiry has passed.
+func (d *BlockedIPDBClient) ListBlockedIPsByService(ctx context.Context, serviceID string, page Page) ([]BlockedIP, string, error) {
+	pk, sk := createBlockedIPCompositeKeys(serviceID, "")
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{
+				Value: pk,
+			},
+			":sk": &types.AttributeValueMemberS{
+				Value: sk,
+			},
+		},
+	}
+
+	return queryPage[BlockedIP](ctx, d.service, d.cursors, input, pk, page, nil)
+}
+
+// ListBlockedIPs retrieves the blocked IPs of every service from the Blocked-IP-Index, including those whose expiry
+// has passed.
+func (d *BlockedIPDBClient) ListBlockedIPs(ctx context.Context) ([]BlockedIP, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		IndexName:              aws.String(blockedIPIndex),
+		KeyConditionExpression: aws.String("BlocklistPK = :blocklistPK"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":blocklistPK": &types.AttributeValueMemberS{Value: blockedIPIndexPK},
+		},
+	}
+
+	results := []BlockedIP{}
+	for {
+		result, err := d.service.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query items in DynamoDB: %w", err)
+		}
+
+		var blockedIPs []BlockedIP
+		err = attributevalue.UnmarshalListOfMaps(result.Items, &blockedIPs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %w", err)
+		}
+		results = append(results, blockedIPs...)
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return results, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,228 @@
+package dal_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateBlockedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewBlockedIPDBClient(mockSvc, cursors)
+
+	blockedIP := &dal.BlockedIP{
+		ServiceID: "serv1",
+		IPAddress: "203.0.113.0/24",
+		Reason:    "Credential stuffing",
+	}
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "Services", *put.TableName)
+			assert.Equal(t, "Service#serv1", put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "BlockedIp#203.0.113.0/24", put.Item["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "attribute_not_exists(pk) OR (#expiry <> :empty AND #expiry <= :now)", *put.ConditionExpression)
+			assert.Equal(t, "BlockedIp", put.Item["BlocklistPK"].(*types.AttributeValueMemberS).Value)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "blocked_ip.created", event.Action)
+			assert.Equal(t, "org1", event.OrgID)
+			assert.Equal(t, "203.0.113.0/24", event.TargetID)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	created, err := client.CreateBlockedIP(ctx, blockedIP)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEmpty(t, blockedIP.CreatedAt)
+
+	// IP addresses that are already blocked are left alone
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	created, err = client.CreateBlockedIP(ctx, blockedIP)
+	assert.NoError(t, err)
+	assert.False(t, created)
+}
+
+func TestGetBlockedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewBlockedIPDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7", Reason: "Abuse"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	result, err := client.GetBlockedIP(context.Background(), "serv1", "203.0.113.7")
+	assert.NoError(t, err)
+	assert.Equal(t, "Abuse", result.Reason)
+
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	result, err = client.GetBlockedIP(context.Background(), "serv1", "203.0.113.8")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestUpdateBlockedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewBlockedIPDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7", Reason: "Spam"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "SET #reason = :reason, #expiry = :expiry, #updatedAt = :updatedAt, #blocklistPK = :blocklistPK", *update.UpdateExpression)
+			assert.Equal(t, "BlockedIp", update.ExpressionAttributeValues[":blocklistPK"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Abuse", update.ExpressionAttributeValues[":reason"].(*types.AttributeValueMemberS).Value)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "blocked_ip.updated", event.Action)
+			assert.Contains(t, event.Before, `"reason":"Spam"`)
+			assert.Contains(t, event.After, `"reason":"Abuse"`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	updated, err := client.UpdateBlockedIP(context.Background(), &dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7", Reason: "Abuse"})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	// IP addresses that are not blocked are not updated
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	updated, err = client.UpdateBlockedIP(context.Background(), &dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.8"})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+}
+
+func TestDeleteBlockedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewBlockedIPDBClient(mockSvc, cursors)
+
+	first, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7"})
+	second, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv1", IPAddress: "2001:db8::/32"})
+	lastKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "Service#serv1"},
+		"sk": &types.AttributeValueMemberS{Value: "BlockedIp#203.0.113.7"},
+	}
+
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, "Service#serv1", input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "BlockedIp#", input.ExpressionAttributeValues[":sk"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, int32(1), *input.Limit)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{first}, LastEvaluatedKey: lastKey}, nil
+			}),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second}}, nil
+			}),
+	)
+
+	result, cursor, err := client.ListBlockedIPsByService(context.Background(), "serv1", dal.Page{Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "203.0.113.7", result[0].IPAddress)
+	assert.NotEmpty(t, cursor)
+
+	// The cursor continues the listing after the last blocked IP of the page
+	result, cursor, err = client.ListBlockedIPsByService(context.Background(), "serv1", dal.Page{Cursor: cursor, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "2001:db8::/32", result[0].IPAddress)
+	assert.Empty(t, cursor)
+}
+
+func TestListBlockedIPs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewBlockedIPDBClient(mockSvc, cursors)
+
+	first, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7"})
+	second, _ := attributevalue.MarshalMap(dal.BlockedIP{ServiceID: "serv2", IPAddress: "2001:db8::/32"})
+	lastKey := map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: "Service#serv1"}}
+
+	// Every page of the sparse index is read, without scanning the table
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, "Services", *input.TableName)
+				assert.Equal(t, "Blocked-IP-Index", *input.IndexName)
+				assert.Equal(t, "BlocklistPK = :blocklistPK", *input.KeyConditionExpression)
+				assert.Equal(t, "BlockedIp", input.ExpressionAttributeValues[":blocklistPK"].(*types.AttributeValueMemberS).Value)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{first}, LastEvaluatedKey: lastKey}, nil
+			}),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{second}}, nil
+			}),
+	)
+
+	result, err := client.ListBlockedIPs(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, "serv2", result[1].ServiceID)
+}
+
+func TestBlockedIP_Expired(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	expired, err := (&dal.BlockedIP{}).Expired(now)
+	assert.NoError(t, err)
+	assert.False(t, expired)
+
+	expired, err = (&dal.BlockedIP{Expiry: "2024-06-01T12:00:01Z"}).Expired(now)
+	assert.NoError(t, err)
+	assert.False(t, expired)
+
+	expired, err = (&dal.BlockedIP{Expiry: "2024-06-01T12:00:00Z"}).Expired(now)
+	assert.NoError(t, err)
+	assert.True(t, expired)
+
+	_, err = (&dal.BlockedIP{Expiry: "tomorrow"}).Expired(now)
+	assert.Error(t, err)
+}
@@ -18,4 +18,64 @@ type DynamoDBAPI interface {
 	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
 	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// classifyingClient is a DynamoDBAPI whose errors match the sentinel error classifying them.
+type classifyingClient struct {
+	client DynamoDBAPI
+}
+
+// withClassifiedErrors wraps a DynamoDB client so that its errors match the sentinel error classifying them, such as
+// ErrThrottled when a request is throttled.
+func withClassifiedErrors(client DynamoDBAPI) DynamoDBAPI {
+	return classifyingClient{client: client}
+}
+
+func (c classifyingClient) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	output, err := c.client.PutItem(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	output, err := c.client.GetItem(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	output, err := c.client.UpdateItem(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	output, err := c.client.DeleteItem(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	output, err := c.client.Query(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	output, err := c.client.Scan(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	output, err := c.client.TransactWriteItems(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	output, err := c.client.BatchGetItem(ctx, params, optFns...)
+	return output, classifyError(err)
+}
+
+func (c classifyingClient) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	output, err := c.client.BatchWriteItem(ctx, params, optFns...)
+	return output, classifyError(err)
 }
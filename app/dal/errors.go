@@ -0,0 +1,102 @@
+package dal
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+var (
+	// ErrNotFound is matched by errors returned when an entity that is updated or deleted does not exist. Lookups
+	// return nil instead.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is matched by errors returned when a write conflicts with another write, such as a *ConflictError
+	// or a transaction that DynamoDB canceled because another transaction was writing the same item.
+	ErrConflict = errors.New("conflict")
+	// ErrPrecondition is matched by errors returned when the condition of a write does not hold in DynamoDB.
+	ErrPrecondition = errors.New("precondition failed")
+	// ErrThrottled is matched by errors returned when DynamoDB throttles a request, which can be retried later.
+	ErrThrottled = errors.New("throttled")
+)
+
+// NotFoundError is returned when an entity that is updated or deleted does not exist. It matches ErrNotFound.
+type NotFoundError struct {
+	Entity string
+	ID     string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s '%s' not found", e.Entity, e.ID)
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return ErrNotFound
+}
+
+// classifiedError is an error returned by DynamoDB that matches the sentinel error classifying it.
+type classifiedError struct {
+	err  error
+	kind error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
+
+// classifyError wraps an error returned by DynamoDB so that it matches the sentinel error classifying it. Errors that
+// are not classified are returned as is.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var (
+		conditionFailed *types.ConditionalCheckFailedException
+		conflict        *types.TransactionConflictException
+		canceled        *types.TransactionCanceledException
+		throughput      *types.ProvisionedThroughputExceededException
+		requestLimit    *types.RequestLimitExceeded
+	)
+
+	var kind error
+	switch {
+	case errors.As(err, &conditionFailed):
+		kind = ErrPrecondition
+	case errors.As(err, &conflict):
+		kind = ErrConflict
+	case errors.As(err, &throughput), errors.As(err, &requestLimit):
+		kind = ErrThrottled
+	case errors.As(err, &canceled):
+		kind = cancellationKind(canceled.CancellationReasons)
+	}
+
+	if kind == nil {
+		return err
+	}
+	return &classifiedError{err: err, kind: kind}
+}
+
+// cancellationKind returns the sentinel error classifying a canceled transaction by the reasons of its items. A failed
+// condition is reported over a conflicting transaction, which is reported over throttling.
+func cancellationKind(reasons []types.CancellationReason) error {
+	var kind error
+	for _, reason := range reasons {
+		switch aws.ToString(reason.Code) {
+		case "ConditionalCheckFailed":
+			return ErrPrecondition
+		case "TransactionConflict":
+			kind = ErrConflict
+		case "ThrottlingError", "ProvisionedThroughputExceeded", "RequestLimitExceeded":
+			if kind == nil {
+				kind = ErrThrottled
+			}
+		}
+	}
+	return kind
+}
@@ -0,0 +1,94 @@
+package dal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestClassifiedErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{name: "Condition failed", err: &types.ConditionalCheckFailedException{}, kind: dal.ErrPrecondition},
+		{name: "Transaction conflict", err: &types.TransactionConflictException{}, kind: dal.ErrConflict},
+		{name: "Throughput exceeded", err: &types.ProvisionedThroughputExceededException{}, kind: dal.ErrThrottled},
+		{name: "Request limit exceeded", err: &types.RequestLimitExceeded{}, kind: dal.ErrThrottled},
+		{
+			name: "Transaction canceled by a condition",
+			err: &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			}},
+			kind: dal.ErrPrecondition,
+		},
+		{
+			name: "Transaction canceled by a conflict",
+			err: &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ThrottlingError")},
+				{Code: aws.String("TransactionConflict")},
+			}},
+			kind: dal.ErrConflict,
+		},
+		{
+			name: "Transaction canceled by throttling",
+			err: &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("ThrottlingError")},
+			}},
+			kind: dal.ErrThrottled,
+		},
+		{name: "Other error", err: errors.New("dynamodb error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+			client := dal.NewServiceDBClient(mockSvc, cursors)
+
+			mockSvc.EXPECT().
+				TransactWriteItems(gomock.Any(), gomock.Any()).
+				Return(nil, tt.err)
+
+			err := client.CreateService(context.Background(), "org1", &dal.Service{Name: "Service1"})
+			assert.Error(t, err)
+			assert.ErrorIs(t, err, tt.err)
+			for _, kind := range []error{dal.ErrNotFound, dal.ErrConflict, dal.ErrPrecondition, dal.ErrThrottled} {
+				assert.Equal(t, kind == tt.kind, errors.Is(err, kind), "matches %v", kind)
+			}
+		})
+	}
+}
+
+func TestNotFoundAndConflictErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: nil}, nil)
+
+	// Entities that do not exist cannot be deleted
+	err := client.DeleteService(context.Background(), "org1", "serv1", 1)
+	assert.ErrorIs(t, err, dal.ErrNotFound)
+	assert.EqualError(t, err, "service 'serv1' not found")
+
+	// Version conflicts are conflicts
+	assert.ErrorIs(t, &dal.ConflictError{Entity: "service", ID: "serv1", Version: 1}, dal.ErrConflict)
+}
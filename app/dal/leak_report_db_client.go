@@ -0,0 +1,120 @@
+package dal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// LeakActionRevoked is the action taken on leaked API keys of services that revoke them.
+	LeakActionRevoked = "revoked"
+	// LeakActionQuarantined is the action taken on leaked API keys of services that quarantine them.
+	LeakActionQuarantined = "quarantined"
+)
+
+// ErrLeakReported is returned when a unit of work that creates a leak report is committed after the same token was
+// already reported.
+var ErrLeakReported = errors.New("token was already reported")
+
+//go:generate mockgen -package=mocks -destination=mocks/mock_leak_report_db_client.go "github.com/payloadops/lanyard/app/dal" LeakReportManager
+
+// LeakReportManager defines the operations available for recording reports of leaked API key tokens.
+type LeakReportManager interface {
+	StageCreateLeakReport(ctx context.Context, unit *UnitOfWork, report *LeakReport) error
+	GetLeakReport(ctx context.Context, fingerprint string) (*LeakReport, error)
+}
+
+// Ensure LeakReportDBClient implements the LeakReportManager interface
+var _ LeakReportManager = &LeakReportDBClient{}
+
+// LeakReport records the first report of a leaked API key token and the action taken on its key. Reports are
+// identified by the fingerprint of the token, so that the token itself is never stored.
+type LeakReport struct {
+	Fingerprint string `json:"fingerprint"`
+	OrgID       string `json:"orgId"`
+	ServiceID   string `json:"serviceId"`
+	APIKeyID    string `json:"apiKeyId"`
+	PartnerID   string `json:"partnerId"`
+	URL         string `json:"url"`
+	Action      string `json:"action"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// LeakReportDBClient is a client for interacting with DynamoDB for leak report related operations. Leak reports are
+// stored in the APIKeys table, next to the keys they revoke.
+type LeakReportDBClient struct {
+	service DynamoDBAPI
+}
+
+// NewLeakReportDBClient creates a new LeakReportDBClient.
+func NewLeakReportDBClient(service DynamoDBAPI) *LeakReportDBClient {
+	return &LeakReportDBClient{
+		service: withClassifiedErrors(service),
+	}
+}
+
+// createLeakReportCompositeKey generates the partition key (pk) for a leak report.
+func createLeakReportCompositeKey(fingerprint string) string {
+	return "LeakReport#" + fingerprint
+}
+
+// StageCreateLeakReport adds the creation of a new leak report to a unit of work, so that it is recorded along with the
+// action taken on the leaked key. ErrLeakReported is returned when the unit of work is committed after the token was
+// already reported, in which case the existing report is left alone.
+func (d *LeakReportDBClient) StageCreateLeakReport(ctx context.Context, unit *UnitOfWork, report *LeakReport) error {
+	pk := createLeakReportCompositeKey(report.Fingerprint)
+	report.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	av, err := attributevalue.MarshalMap(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal leak report: %w", err)
+	}
+
+	item := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: pk},
+	}
+	for k, v := range av {
+		item[k] = v
+	}
+
+	put := &types.Put{
+		TableName:           aws.String("APIKeys"),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(pk)"),
+	}
+	return unit.write(ErrLeakReported, []types.TransactWriteItem{{Put: put}})
+}
+
+// GetLeakReport retrieves the report of a leaked token by its fingerprint from the DynamoDB table.
+func (d *LeakReportDBClient) GetLeakReport(ctx context.Context, fingerprint string) (*LeakReport, error) {
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("APIKeys"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: createLeakReportCompositeKey(fingerprint)},
+		},
+	}
+
+	result, err := d.service.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var report LeakReport
+	err = attributevalue.UnmarshalMap(result.Item, &report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
+	}
+
+	return &report, nil
+}
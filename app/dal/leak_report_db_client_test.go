@@ -0,0 +1,88 @@
+package dal_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStageCreateLeakReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewLeakReportDBClient(mockSvc)
+	transactionClient := dal.NewTransactionDBClient(mockSvc)
+
+	report := &dal.LeakReport{
+		Fingerprint: "abc123",
+		OrgID:       "org1",
+		ServiceID:   "serv1",
+		APIKeyID:    "key1",
+		PartnerID:   "scanner",
+		Action:      dal.LeakActionRevoked,
+	}
+
+	unit := dal.NewUnitOfWork()
+	assert.NoError(t, client.StageCreateLeakReport(context.Background(), unit, report))
+	assert.Equal(t, 1, unit.Len())
+	assert.NotEmpty(t, report.CreatedAt)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "APIKeys", *put.TableName)
+			assert.Equal(t, "LeakReport#abc123", put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "key1", put.Item["APIKeyID"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "attribute_not_exists(pk)", *put.ConditionExpression)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := transactionClient.Commit(context.Background(), unit)
+	assert.NoError(t, err)
+
+	// Tokens that were already reported keep their first report
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	err = transactionClient.Commit(context.Background(), unit)
+	assert.ErrorIs(t, err, dal.ErrLeakReported)
+}
+
+func TestGetLeakReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewLeakReportDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.LeakReport{Fingerprint: "abc123", APIKeyID: "key1", Action: dal.LeakActionQuarantined})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, "LeakReport#abc123", input.Key["pk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.GetItemOutput{Item: item}, nil
+		})
+
+	result, err := client.GetLeakReport(context.Background(), "abc123")
+	assert.NoError(t, err)
+	assert.Equal(t, dal.LeakActionQuarantined, result.Action)
+
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	result, err = client.GetLeakReport(context.Background(), "def456")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
@@ -55,17 +55,17 @@ func (mr *MockActorManagerMockRecorder) CreateActor(ctx, orgID, serviceID, actor
 }
 
 // DeleteActor mocks base method.
-func (m *MockActorManager) DeleteActor(ctx context.Context, orgID, serviceID, externalID string) error {
+func (m *MockActorManager) DeleteActor(ctx context.Context, orgID, serviceID, externalID string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteActor", ctx, orgID, serviceID, externalID)
+	ret := m.ctrl.Call(m, "DeleteActor", ctx, orgID, serviceID, externalID, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteActor indicates an expected call of DeleteActor.
-func (mr *MockActorManagerMockRecorder) DeleteActor(ctx, orgID, serviceID, externalID any) *gomock.Call {
+func (mr *MockActorManagerMockRecorder) DeleteActor(ctx, orgID, serviceID, externalID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteActor", reflect.TypeOf((*MockActorManager)(nil).DeleteActor), ctx, orgID, serviceID, externalID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteActor", reflect.TypeOf((*MockActorManager)(nil).DeleteActor), ctx, orgID, serviceID, externalID, version)
 }
 
 // GetActor mocks base method.
@@ -83,19 +83,67 @@ func (mr *MockActorManagerMockRecorder) GetActor(ctx, orgID, serviceID, external
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActor", reflect.TypeOf((*MockActorManager)(nil).GetActor), ctx, orgID, serviceID, externalID)
 }
 
-// ListActors mocks base method.
-func (m *MockActorManager) ListActors(ctx context.Context, orgID, serviceID string) ([]dal.Actor, error) {
+// GetActorIncludingDeleted mocks base method.
+func (m *MockActorManager) GetActorIncludingDeleted(ctx context.Context, orgID, serviceID, externalID string) (*dal.Actor, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListActors", ctx, orgID, serviceID)
-	ret0, _ := ret[0].([]dal.Actor)
+	ret := m.ctrl.Call(m, "GetActorIncludingDeleted", ctx, orgID, serviceID, externalID)
+	ret0, _ := ret[0].(*dal.Actor)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// GetActorIncludingDeleted indicates an expected call of GetActorIncludingDeleted.
+func (mr *MockActorManagerMockRecorder) GetActorIncludingDeleted(ctx, orgID, serviceID, externalID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActorIncludingDeleted", reflect.TypeOf((*MockActorManager)(nil).GetActorIncludingDeleted), ctx, orgID, serviceID, externalID)
+}
+
+// ListActors mocks base method.
+func (m *MockActorManager) ListActors(ctx context.Context, orgID, serviceID string, page dal.
+	Page) ([]dal.Actor, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActors", ctx, orgID, serviceID, page)
+	ret0, _ := ret[0].([]dal.Actor)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // ListActors indicates an expected call of ListActors.
-func (mr *MockActorManagerMockRecorder) ListActors(ctx, orgID, serviceID any) *gomock.Call {
+func (mr *MockActorManagerMockRecorder) ListActors(ctx, orgID, serviceID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActors", reflect.TypeOf((*MockActorManager)(nil).ListActors), ctx, orgID, serviceID, page)
+}
+
+// ListActorsByTier mocks base method.
+func (m *MockActorManager) ListActorsByTier(ctx context.Context, orgID, serviceID, tierID string, page dal.
+	Page) ([]dal.Actor, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListActorsByTier", ctx, orgID, serviceID, tierID, page)
+	ret0, _ := ret[0].([]dal.Actor)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListActorsByTier indicates an expected call of ListActorsByTier.
+func (mr *MockActorManagerMockRecorder) ListActorsByTier(ctx, orgID, serviceID, tierID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActorsByTier", reflect.TypeOf((*MockActorManager)(nil).ListActorsByTier), ctx, orgID, serviceID, tierID, page)
+}
+
+// StageDeleteActor mocks base method.
+func (m *MockActorManager) StageDeleteActor(ctx context.Context, unit *dal.UnitOfWork, orgID, serviceID, externalID string, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageDeleteActor", ctx, unit, orgID, serviceID, externalID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageDeleteActor indicates an expected call of StageDeleteActor.
+func (mr *MockActorManagerMockRecorder) StageDeleteActor(ctx, unit, orgID, serviceID, externalID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListActors", reflect.TypeOf((*MockActorManager)(nil).ListActors), ctx, orgID, serviceID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageDeleteActor", reflect.TypeOf((*MockActorManager)(nil).StageDeleteActor), ctx, unit, orgID, serviceID, externalID, version)
 }
 
 // UpdateActor mocks base method.
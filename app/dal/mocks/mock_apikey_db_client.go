@@ -12,6 +12,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	dal "github.com/payloadops/lanyard/app/dal"
 	gomock "go.uber.org/mock/gomock"
@@ -55,17 +56,32 @@ func (mr *MockAPIKeyManagerMockRecorder) CreateAPIKey(ctx, apiKey any) *gomock.C
 }
 
 // DeleteAPIKey mocks base method.
-func (m *MockAPIKeyManager) DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string) error {
+func (m *MockAPIKeyManager) DeleteAPIKey(ctx context.Context, orgID, serviceID, apiKeyID string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, orgID, serviceID, apiKeyID)
+	ret := m.ctrl.Call(m, "DeleteAPIKey", ctx, orgID, serviceID, apiKeyID, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteAPIKey indicates an expected call of DeleteAPIKey.
-func (mr *MockAPIKeyManagerMockRecorder) DeleteAPIKey(ctx, orgID, serviceID, apiKeyID any) *gomock.Call {
+func (mr *MockAPIKeyManagerMockRecorder) DeleteAPIKey(ctx, orgID, serviceID, apiKeyID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).DeleteAPIKey), ctx, orgID, serviceID, apiKeyID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).DeleteAPIKey), ctx, orgID, serviceID, apiKeyID, version)
+}
+
+// ExpireAPIKey mocks base method.
+func (m *MockAPIKeyManager) ExpireAPIKey(ctx context.Context, apiKeyID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireAPIKey", ctx, apiKeyID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireAPIKey indicates an expected call of ExpireAPIKey.
+func (mr *MockAPIKeyManagerMockRecorder) ExpireAPIKey(ctx, apiKeyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).ExpireAPIKey), ctx, apiKeyID)
 }
 
 // GetAPIKey mocks base method.
@@ -83,19 +99,128 @@ func (mr *MockAPIKeyManagerMockRecorder) GetAPIKey(ctx, apiKeyID any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).GetAPIKey), ctx, apiKeyID)
 }
 
+// GetAPIKeyIncludingDeleted mocks base method.
+func (m *MockAPIKeyManager) GetAPIKeyIncludingDeleted(ctx context.Context, apiKeyID string) (*dal.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyIncludingDeleted", ctx, apiKeyID)
+	ret0, _ := ret[0].(*dal.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyIncludingDeleted indicates an expected call of GetAPIKeyIncludingDeleted.
+func (mr *MockAPIKeyManagerMockRecorder) GetAPIKeyIncludingDeleted(ctx, apiKeyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyIncludingDeleted", reflect.TypeOf((*MockAPIKeyManager)(nil).GetAPIKeyIncludingDeleted), ctx, apiKeyID)
+}
+
+// ListAPIKeysByActor mocks base method.
+func (m *MockAPIKeyManager) ListAPIKeysByActor(ctx context.Context, orgID, serviceID, actorID string, page dal.
+	Page) ([]dal.APIKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeysByActor", ctx, orgID, serviceID, actorID, page)
+	ret0, _ := ret[0].([]dal.APIKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAPIKeysByActor indicates an expected call of ListAPIKeysByActor.
+func (mr *MockAPIKeyManagerMockRecorder) ListAPIKeysByActor(ctx, orgID, serviceID, actorID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeysByActor", reflect.TypeOf((*MockAPIKeyManager)(nil).ListAPIKeysByActor), ctx, orgID, serviceID, actorID, page)
+}
+
 // ListAPIKeysByService mocks base method.
-func (m *MockAPIKeyManager) ListAPIKeysByService(ctx context.Context, orgID, serviceID string) ([]dal.APIKey, error) {
+func (m *MockAPIKeyManager) ListAPIKeysByService(ctx context.Context, orgID, serviceID string, page dal.
+	Page) ([]dal.APIKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeysByService", ctx, orgID, serviceID, page)
+	ret0, _ := ret[0].([]dal.APIKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListAPIKeysByService indicates an expected call of ListAPIKeysByService.
+func (mr *MockAPIKeyManagerMockRecorder) ListAPIKeysByService(ctx, orgID, serviceID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeysByService", reflect.TypeOf((*MockAPIKeyManager)(nil).ListAPIKeysByService), ctx, orgID, serviceID, page)
+}
+
+// ListExpiredAPIKeys mocks base method.
+func (m *MockAPIKeyManager) ListExpiredAPIKeys(ctx context.Context, now time.Time, page dal.
+	Page) ([]dal.APIKey, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAPIKeysByService", ctx, orgID, serviceID)
+	ret := m.ctrl.Call(m, "ListExpiredAPIKeys", ctx, now, page)
 	ret0, _ := ret[0].([]dal.APIKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListExpiredAPIKeys indicates an expected call of ListExpiredAPIKeys.
+func (mr *MockAPIKeyManagerMockRecorder) ListExpiredAPIKeys(ctx, now, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListExpiredAPIKeys", reflect.TypeOf((*MockAPIKeyManager)(nil).ListExpiredAPIKeys), ctx, now, page)
+}
+
+// QuarantineAPIKey mocks base method.
+func (m *MockAPIKeyManager) QuarantineAPIKey(ctx context.Context, apiKeyID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "QuarantineAPIKey", ctx, apiKeyID)
+	ret0, _ := ret[0].(bool)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListAPIKeysByService indicates an expected call of ListAPIKeysByService.
-func (mr *MockAPIKeyManagerMockRecorder) ListAPIKeysByService(ctx, orgID, serviceID any) *gomock.Call {
+// QuarantineAPIKey indicates an expected call of QuarantineAPIKey.
+func (mr *MockAPIKeyManagerMockRecorder) QuarantineAPIKey(ctx, apiKeyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QuarantineAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).QuarantineAPIKey), ctx, apiKeyID)
+}
+
+// RotateAPIKeySecret mocks base method.
+func (m *MockAPIKeyManager) RotateAPIKeySecret(ctx context.Context, apiKeyID, currentSecret, newSecret, previousSecretExpiry string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateAPIKeySecret", ctx, apiKeyID, currentSecret, newSecret, previousSecretExpiry)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateAPIKeySecret indicates an expected call of RotateAPIKeySecret.
+func (mr *MockAPIKeyManagerMockRecorder) RotateAPIKeySecret(ctx, apiKeyID, currentSecret, newSecret, previousSecretExpiry any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeysByService", reflect.TypeOf((*MockAPIKeyManager)(nil).ListAPIKeysByService), ctx, orgID, serviceID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateAPIKeySecret", reflect.TypeOf((*MockAPIKeyManager)(nil).RotateAPIKeySecret), ctx, apiKeyID, currentSecret, newSecret, previousSecretExpiry)
+}
+
+// StageDeleteAPIKey mocks base method.
+func (m *MockAPIKeyManager) StageDeleteAPIKey(ctx context.Context, unit *dal.UnitOfWork, orgID, serviceID, apiKeyID string, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageDeleteAPIKey", ctx, unit, orgID, serviceID, apiKeyID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageDeleteAPIKey indicates an expected call of StageDeleteAPIKey.
+func (mr *MockAPIKeyManagerMockRecorder) StageDeleteAPIKey(ctx, unit, orgID, serviceID, apiKeyID, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageDeleteAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).StageDeleteAPIKey), ctx, unit, orgID, serviceID, apiKeyID, version)
+}
+
+// StageQuarantineAPIKey mocks base method.
+func (m *MockAPIKeyManager) StageQuarantineAPIKey(ctx context.Context, unit *dal.UnitOfWork, apiKeyID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageQuarantineAPIKey", ctx, unit, apiKeyID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageQuarantineAPIKey indicates an expected call of StageQuarantineAPIKey.
+func (mr *MockAPIKeyManagerMockRecorder) StageQuarantineAPIKey(ctx, unit, apiKeyID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageQuarantineAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).StageQuarantineAPIKey), ctx, unit, apiKeyID)
 }
 
 // UpdateAPIKey mocks base method.
@@ -111,3 +236,18 @@ func (mr *MockAPIKeyManagerMockRecorder) UpdateAPIKey(ctx, apiKey any) *gomock.C
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAPIKey", reflect.TypeOf((*MockAPIKeyManager)(nil).UpdateAPIKey), ctx, apiKey)
 }
+
+// UpdateAPIKeySecret mocks base method.
+func (m *MockAPIKeyManager) UpdateAPIKeySecret(ctx context.Context, apiKeyID, plaintext, secret string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAPIKeySecret", ctx, apiKeyID, plaintext, secret)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAPIKeySecret indicates an expected call of UpdateAPIKeySecret.
+func (mr *MockAPIKeyManagerMockRecorder) UpdateAPIKeySecret(ctx, apiKeyID, plaintext, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAPIKeySecret", reflect.TypeOf((*MockAPIKeyManager)(nil).UpdateAPIKeySecret), ctx, apiKeyID, plaintext, secret)
+}
@@ -41,58 +41,58 @@ func (m *MockOrgManager) EXPECT() *MockOrgManagerMockRecorder {
 }
 
 // CreateOrg mocks base method.
-func (m *MockOrgManager) CreateOrg(ctx context.Context, orgID, serviceID string, Org *dal.Org) error {
+func (m *MockOrgManager) CreateOrg(ctx context.Context, Org *dal.Org) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrg", ctx, orgID, serviceID, Org)
+	ret := m.ctrl.Call(m, "CreateOrg", ctx, Org)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CreateOrg indicates an expected call of CreateOrg.
-func (mr *MockOrgManagerMockRecorder) CreateOrg(ctx, orgID, serviceID, Org any) *gomock.Call {
+func (mr *MockOrgManagerMockRecorder) CreateOrg(ctx, Org any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrg", reflect.TypeOf((*MockOrgManager)(nil).CreateOrg), ctx, orgID, serviceID, Org)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrg", reflect.TypeOf((*MockOrgManager)(nil).CreateOrg), ctx, Org)
 }
 
 // DeleteOrg mocks base method.
-func (m *MockOrgManager) DeleteOrg(ctx context.Context, orgID, serviceID, name string) error {
+func (m *MockOrgManager) DeleteOrg(ctx context.Context, orgID string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteOrg", ctx, orgID, serviceID, name)
+	ret := m.ctrl.Call(m, "DeleteOrg", ctx, orgID, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteOrg indicates an expected call of DeleteOrg.
-func (mr *MockOrgManagerMockRecorder) DeleteOrg(ctx, orgID, serviceID, name any) *gomock.Call {
+func (mr *MockOrgManagerMockRecorder) DeleteOrg(ctx, orgID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrg", reflect.TypeOf((*MockOrgManager)(nil).DeleteOrg), ctx, orgID, serviceID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrg", reflect.TypeOf((*MockOrgManager)(nil).DeleteOrg), ctx, orgID, version)
 }
 
 // GetOrg mocks base method.
-func (m *MockOrgManager) GetOrg(ctx context.Context, orgID, serviceID, name string) (*dal.Org, error) {
+func (m *MockOrgManager) GetOrg(ctx context.Context, orgID string) (*dal.Org, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrg", ctx, orgID, serviceID, name)
+	ret := m.ctrl.Call(m, "GetOrg", ctx, orgID)
 	ret0, _ := ret[0].(*dal.Org)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrg indicates an expected call of GetOrg.
-func (mr *MockOrgManagerMockRecorder) GetOrg(ctx, orgID, serviceID, name any) *gomock.Call {
+func (mr *MockOrgManagerMockRecorder) GetOrg(ctx, orgID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrg", reflect.TypeOf((*MockOrgManager)(nil).GetOrg), ctx, orgID, serviceID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrg", reflect.TypeOf((*MockOrgManager)(nil).GetOrg), ctx, orgID)
 }
 
 // UpdateOrg mocks base method.
-func (m *MockOrgManager) UpdateOrg(ctx context.Context, orgID, serviceID string, Org *dal.Org) error {
+func (m *MockOrgManager) UpdateOrg(ctx context.Context, Org *dal.Org) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateOrg", ctx, orgID, serviceID, Org)
+	ret := m.ctrl.Call(m, "UpdateOrg", ctx, Org)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // UpdateOrg indicates an expected call of UpdateOrg.
-func (mr *MockOrgManagerMockRecorder) UpdateOrg(ctx, orgID, serviceID, Org any) *gomock.Call {
+func (mr *MockOrgManagerMockRecorder) UpdateOrg(ctx, Org any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrg", reflect.TypeOf((*MockOrgManager)(nil).UpdateOrg), ctx, orgID, serviceID, Org)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOrg", reflect.TypeOf((*MockOrgManager)(nil).UpdateOrg), ctx, Org)
 }
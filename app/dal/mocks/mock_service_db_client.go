@@ -55,17 +55,17 @@ func (mr *MockServiceManagerMockRecorder) CreateService(ctx, orgID, service any)
 }
 
 // DeleteService mocks base method.
-func (m *MockServiceManager) DeleteService(ctx context.Context, orgID, serviceID string) error {
+func (m *MockServiceManager) DeleteService(ctx context.Context, orgID, serviceID string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteService", ctx, orgID, serviceID)
+	ret := m.ctrl.Call(m, "DeleteService", ctx, orgID, serviceID, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteService indicates an expected call of DeleteService.
-func (mr *MockServiceManagerMockRecorder) DeleteService(ctx, orgID, serviceID any) *gomock.Call {
+func (mr *MockServiceManagerMockRecorder) DeleteService(ctx, orgID, serviceID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteService", reflect.TypeOf((*MockServiceManager)(nil).DeleteService), ctx, orgID, serviceID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteService", reflect.TypeOf((*MockServiceManager)(nil).DeleteService), ctx, orgID, serviceID, version)
 }
 
 // GetService mocks base method.
@@ -84,18 +84,48 @@ func (mr *MockServiceManagerMockRecorder) GetService(ctx, orgID, serviceID any)
 }
 
 // ListServicesByOrganization mocks base method.
-func (m *MockServiceManager) ListServicesByOrganization(ctx context.Context, orgID string) ([]dal.Service, error) {
+func (m *MockServiceManager) ListServicesByOrganization(ctx context.Context, orgID string, page dal.
+	Page) ([]dal.Service, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListServicesByOrganization", ctx, orgID)
+	ret := m.ctrl.Call(m, "ListServicesByOrganization", ctx, orgID, page)
 	ret0, _ := ret[0].([]dal.Service)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // ListServicesByOrganization indicates an expected call of ListServicesByOrganization.
-func (mr *MockServiceManagerMockRecorder) ListServicesByOrganization(ctx, orgID any) *gomock.Call {
+func (mr *MockServiceManagerMockRecorder) ListServicesByOrganization(ctx, orgID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServicesByOrganization", reflect.TypeOf((*MockServiceManager)(nil).ListServicesByOrganization), ctx, orgID, page)
+}
+
+// MarkServiceDeleting mocks base method.
+func (m *MockServiceManager) MarkServiceDeleting(ctx context.Context, orgID, serviceID string, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkServiceDeleting", ctx, orgID, serviceID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkServiceDeleting indicates an expected call of MarkServiceDeleting.
+func (mr *MockServiceManagerMockRecorder) MarkServiceDeleting(ctx, orgID, serviceID, version any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkServiceDeleting", reflect.TypeOf((*MockServiceManager)(nil).MarkServiceDeleting), ctx, orgID, serviceID, version)
+}
+
+// StageDeleteService mocks base method.
+func (m *MockServiceManager) StageDeleteService(ctx context.Context, unit *dal.UnitOfWork, orgID, serviceID string, version int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StageDeleteService", ctx, unit, orgID, serviceID, version)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StageDeleteService indicates an expected call of StageDeleteService.
+func (mr *MockServiceManagerMockRecorder) StageDeleteService(ctx, unit, orgID, serviceID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServicesByOrganization", reflect.TypeOf((*MockServiceManager)(nil).ListServicesByOrganization), ctx, orgID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StageDeleteService", reflect.TypeOf((*MockServiceManager)(nil).StageDeleteService), ctx, unit, orgID, serviceID, version)
 }
 
 // UpdateService mocks base method.
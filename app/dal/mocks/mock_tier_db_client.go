@@ -55,47 +55,77 @@ func (mr *MockTierManagerMockRecorder) CreateTier(ctx, orgID, serviceID, Tier an
 }
 
 // DeleteTier mocks base method.
-func (m *MockTierManager) DeleteTier(ctx context.Context, orgID, serviceID, name string) error {
+func (m *MockTierManager) DeleteTier(ctx context.Context, orgID, serviceID, tierID string, version int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteTier", ctx, orgID, serviceID, name)
+	ret := m.ctrl.Call(m, "DeleteTier", ctx, orgID, serviceID, tierID, version)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // DeleteTier indicates an expected call of DeleteTier.
-func (mr *MockTierManagerMockRecorder) DeleteTier(ctx, orgID, serviceID, name any) *gomock.Call {
+func (mr *MockTierManagerMockRecorder) DeleteTier(ctx, orgID, serviceID, tierID, version any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTier", reflect.TypeOf((*MockTierManager)(nil).DeleteTier), ctx, orgID, serviceID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTier", reflect.TypeOf((*MockTierManager)(nil).DeleteTier), ctx, orgID, serviceID, tierID, version)
 }
 
 // GetTier mocks base method.
-func (m *MockTierManager) GetTier(ctx context.Context, orgID, serviceID, name string) (*dal.Tier, error) {
+func (m *MockTierManager) GetTier(ctx context.Context, orgID, serviceID, tierID string) (*dal.Tier, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTier", ctx, orgID, serviceID, name)
+	ret := m.ctrl.Call(m, "GetTier", ctx, orgID, serviceID, tierID)
 	ret0, _ := ret[0].(*dal.Tier)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetTier indicates an expected call of GetTier.
-func (mr *MockTierManagerMockRecorder) GetTier(ctx, orgID, serviceID, name any) *gomock.Call {
+func (mr *MockTierManagerMockRecorder) GetTier(ctx, orgID, serviceID, tierID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTier", reflect.TypeOf((*MockTierManager)(nil).GetTier), ctx, orgID, serviceID, name)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTier", reflect.TypeOf((*MockTierManager)(nil).GetTier), ctx, orgID, serviceID, tierID)
 }
 
 // ListTiers mocks base method.
-func (m *MockTierManager) ListTiers(ctx context.Context, orgID, serviceID string) ([]dal.Tier, error) {
+func (m *MockTierManager) ListTiers(ctx context.Context, orgID, serviceID string, page dal.
+	Page) ([]dal.Tier, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListTiers", ctx, orgID, serviceID)
+	ret := m.ctrl.Call(m, "ListTiers", ctx, orgID, serviceID, page)
 	ret0, _ := ret[0].([]dal.Tier)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // ListTiers indicates an expected call of ListTiers.
-func (mr *MockTierManagerMockRecorder) ListTiers(ctx, orgID, serviceID any) *gomock.Call {
+func (mr *MockTierManagerMockRecorder) ListTiers(ctx, orgID, serviceID, page any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTiers", reflect.TypeOf((*MockTierManager)(nil).ListTiers), ctx, orgID, serviceID, page)
+}
+
+// ReassignTierActors mocks base method.
+func (m *MockTierManager) ReassignTierActors(ctx context.Context, orgID, serviceID, tierID, reassignTo string, actorExternalIDs []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReassignTierActors", ctx, orgID, serviceID, tierID, reassignTo, actorExternalIDs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReassignTierActors indicates an expected call of ReassignTierActors.
+func (mr *MockTierManagerMockRecorder) ReassignTierActors(ctx, orgID, serviceID, tierID, reassignTo, actorExternalIDs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReassignTierActors", reflect.TypeOf((*MockTierManager)(nil).ReassignTierActors), ctx, orgID, serviceID, tierID, reassignTo, actorExternalIDs)
+}
+
+// RetireTier mocks base method.
+func (m *MockTierManager) RetireTier(ctx context.Context, orgID, serviceID, tierID string, version int64, reassignTo string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetireTier", ctx, orgID, serviceID, tierID, version, reassignTo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RetireTier indicates an expected call of RetireTier.
+func (mr *MockTierManagerMockRecorder) RetireTier(ctx, orgID, serviceID, tierID, version, reassignTo any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTiers", reflect.TypeOf((*MockTierManager)(nil).ListTiers), ctx, orgID, serviceID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetireTier", reflect.TypeOf((*MockTierManager)(nil).RetireTier), ctx, orgID, serviceID, tierID, version, reassignTo)
 }
 
 // UpdateTier mocks base method.
@@ -2,7 +2,9 @@ package dal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/payloadops/lanyard/app/utils"
 
@@ -16,20 +18,27 @@ import (
 
 // OrgManager defines the operations available for managing Orgs.
 type OrgManager interface {
-	CreateOrg(ctx context.Context, orgID, serviceID string, Org *Org) error
-	GetOrg(ctx context.Context, orgID, serviceID string, name string) (*Org, error)
-	UpdateOrg(ctx context.Context, orgID, serviceID string, Org *Org) error
-	DeleteOrg(ctx context.Context, orgID, serviceID string, name string) error
+	CreateOrg(ctx context.Context, Org *Org) error
+	GetOrg(ctx context.Context, orgID string) (*Org, error)
+	UpdateOrg(ctx context.Context, Org *Org) error
+	DeleteOrg(ctx context.Context, orgID string, version int64) error
 }
 
 // Ensure OrgDBClient implements the OrgManager interface
 var _ OrgManager = &OrgDBClient{}
 
+// ErrDomainTaken is returned when an Org is created or updated with a domain that another Org already uses.
+var ErrDomainTaken = errors.New("domain is already in use")
+
+// Org represents an organization in the system.
 type Org struct {
 	OrgID           string `json:"orgId"`
 	Name            string `json:"name"`
 	StripeAccountId string `json:"stripeAccountId"`
 	Domain          string `json:"domain"`
+	OwnerID         string `json:"ownerId"`
+	Deleted         bool   `json:"deleted"`
+	Version         int64  `json:"version"`
 }
 
 // OrgDBClient is a client for interacting with DynamoDB for Org-related operations.
@@ -40,7 +49,7 @@ type OrgDBClient struct {
 // NewOrgDBClient creates a new OrgDBClient.
 func NewOrgDBClient(Org DynamoDBAPI) *OrgDBClient {
 	return &OrgDBClient{
-		Org: Org,
+		Org: withClassifiedErrors(Org),
 	}
 }
 
@@ -49,19 +58,66 @@ func createOrgCompositeKeys(orgID string) (string, string) {
 	return "Org#" + orgID, "Org#" + orgID
 }
 
-// CreateOrg creates a new Org in the DynamoDB table.
-func (d *OrgDBClient) CreateOrg(ctx context.Context, orgID, serviceID string, Org *Org) error {
+// createDomainCompositeKeys generates the partition key (pk) and sort key (sk) for the item that claims a domain for
+// an Org. Domains are compared case-insensitively.
+func createDomainCompositeKeys(domain string) (string, string) {
+	domain = strings.ToLower(domain)
+	return "Domain#" + domain, "Domain#" + domain
+}
+
+// putDomainClaim returns a transaction item that claims a domain for an Org, failing if the domain is already claimed.
+func putDomainClaim(orgID, domain string) types.TransactWriteItem {
+	pk, sk := createDomainCompositeKeys(domain)
+	return types.TransactWriteItem{
+		Put: &types.Put{
+			TableName: aws.String("Services"),
+			Item: map[string]types.AttributeValue{
+				"pk":    &types.AttributeValueMemberS{Value: pk},
+				"sk":    &types.AttributeValueMemberS{Value: sk},
+				"OrgID": &types.AttributeValueMemberS{Value: orgID},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(pk)"),
+		},
+	}
+}
+
+// deleteDomainClaim returns a transaction item that releases a domain claimed by an Org.
+func deleteDomainClaim(domain string) types.TransactWriteItem {
+	pk, sk := createDomainCompositeKeys(domain)
+	return types.TransactWriteItem{
+		Delete: &types.Delete{
+			TableName: aws.String("Services"),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: sk},
+			},
+		},
+	}
+}
+
+// isConditionFailed reports whether a transaction was canceled because the condition of the item at index failed.
+func isConditionFailed(err error, index int) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || index >= len(canceled.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(canceled.CancellationReasons[index].Code) == "ConditionalCheckFailed"
+}
+
+// CreateOrg creates a new Org in the DynamoDB table, claiming its domain if it has one.
+func (d *OrgDBClient) CreateOrg(ctx context.Context, Org *Org) error {
 	ksuid, err := utils.GenerateKSUID()
 	if err != nil {
-		return fmt.Errorf("failed to create ksuid: %v", err)
+		return fmt.Errorf("failed to create ksuid: %w", err)
 	}
 
 	Org.OrgID = ksuid
-	pk, sk := createOrgCompositeKeys(orgID)
+	Org.Version = 1
+	pk, sk := createOrgCompositeKeys(Org.OrgID)
 
 	av, err := attributevalue.MarshalMap(Org)
 	if err != nil {
-		return fmt.Errorf("failed to marshal Org: %v", err)
+		return fmt.Errorf("failed to marshal Org: %w", err)
 	}
 
 	item := map[string]types.AttributeValue{
@@ -72,21 +128,38 @@ func (d *OrgDBClient) CreateOrg(ctx context.Context, orgID, serviceID string, Or
 		item[k] = v
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String("Services"),
-		Item:      item,
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName:           aws.String("Services"),
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(pk)"),
+			},
+		},
+	}
+	if Org.Domain != "" {
+		items = append(items, putDomainClaim(Org.OrgID, Org.Domain))
 	}
 
-	_, err = d.Org.PutItem(ctx, input)
+	audit, err := auditPut(ctx, Org.OrgID, "organization.created", AuditTargetOrganization, Org.OrgID, nil, Org)
 	if err != nil {
-		return fmt.Errorf("failed to put item in DynamoDB: %v", err)
+		return err
+	}
+	items = append(items, audit)
+
+	_, err = d.Org.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 1) {
+			return ErrDomainTaken
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// GetOrg retrieves a Org by organization ID and Org ID from the DynamoDB table.
-func (d *OrgDBClient) GetOrg(ctx context.Context, orgID, serviceID, name string) (*Org, error) {
+// GetOrg retrieves a Org by Org ID from the DynamoDB table.
+func (d *OrgDBClient) GetOrg(ctx context.Context, orgID string) (*Org, error) {
 	pk, sk := createOrgCompositeKeys(orgID)
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String("Services"),
@@ -98,7 +171,7 @@ func (d *OrgDBClient) GetOrg(ctx context.Context, orgID, serviceID, name string)
 
 	result, err := d.Org.GetItem(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
@@ -108,21 +181,39 @@ func (d *OrgDBClient) GetOrg(ctx context.Context, orgID, serviceID, name string)
 	var Org Org
 	err = attributevalue.UnmarshalMap(result.Item, &Org)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
+	}
+
+	if Org.Deleted {
+		return nil, nil
 	}
 
 	return &Org, nil
 }
 
-// UpdateOrg updates the name, description, and updatedAt fields of an existing Org in the DynamoDB table.
-func (d *OrgDBClient) UpdateOrg(ctx context.Context, orgID, serviceID string, Org *Org) error {
-	pk, sk := createOrgCompositeKeys(orgID)
+// UpdateOrg updates the name, domain and stripeAccountId fields of an existing Org in the DynamoDB table. When the
+// domain changes, the new domain is claimed and the old one released in the same transaction as the update and its
+// audit event. The Org must still be at the version of Org, which is advanced to the next version. A *ConflictError is
+// returned otherwise.
+func (d *OrgDBClient) UpdateOrg(ctx context.Context, Org *Org) error {
+	current, err := d.GetOrg(ctx, Org.OrgID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "org", ID: Org.OrgID}
+	}
+	if current.Version != Org.Version {
+		return &ConflictError{Entity: "org", ID: Org.OrgID, Version: Org.Version}
+	}
+
+	pk, sk := createOrgCompositeKeys(Org.OrgID)
 
 	updateExpr := "SET #name = :name, #domain = :domain, #stripeAccountId = :stripeAccountId"
 	exprAttrNames := map[string]string{
-		"#name":            "name",
-		"#domain":          "domain",
-		"#stripeAccountId": "stripeAccountId",
+		"#name":            "Name",
+		"#domain":          "Domain",
+		"#stripeAccountId": "StripeAccountId",
 	}
 
 	exprAttrValues := map[string]types.AttributeValue{
@@ -131,74 +222,103 @@ func (d *OrgDBClient) UpdateOrg(ctx context.Context, orgID, serviceID string, Or
 		":stripeAccountId": &types.AttributeValueMemberS{Value: Org.StripeAccountId},
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	update := &types.Update{
 		TableName:                 aws.String("Services"),
 		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
 		UpdateExpression:          aws.String(updateExpr),
+		ConditionExpression:       aws.String("attribute_exists(pk)"),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 	}
+	withVersion(update, Org.Version)
+
+	items := []types.TransactWriteItem{{Update: update}}
+
+	if !strings.EqualFold(current.Domain, Org.Domain) {
+		if Org.Domain != "" {
+			items = append(items, putDomainClaim(Org.OrgID, Org.Domain))
+		}
+		if current.Domain != "" {
+			items = append(items, deleteDomainClaim(current.Domain))
+		}
+	}
 
-	_, err := d.Org.UpdateItem(ctx, input)
+	updated := *current
+	updated.Name = Org.Name
+	updated.Domain = Org.Domain
+	updated.StripeAccountId = Org.StripeAccountId
+	updated.Version = Org.Version + 1
+
+	audit, err := auditPut(ctx, Org.OrgID, "organization.updated", AuditTargetOrganization, Org.OrgID, current, &updated)
 	if err != nil {
-		return fmt.Errorf("failed to update item in DynamoDB: %v", err)
+		return err
 	}
+	items = append(items, audit)
 
+	_, err = d.Org.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "org", ID: Org.OrgID, Version: Org.Version}
+		}
+		if Org.Domain != "" && isConditionFailed(err, 1) {
+			return ErrDomainTaken
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	Org.Version = updated.Version
 	return nil
 }
 
-// DeleteOrg marks a Org as deleted by organization ID and Org ID in the DynamoDB table.
-func (d *OrgDBClient) DeleteOrg(ctx context.Context, orgID, serviceID, name string) error {
-	pk, sk := createOrgCompositeKeys(orgID)
-
-	update := map[string]types.AttributeValueUpdate{
-		"Deleted": {
-			Value:  &types.AttributeValueMemberBOOL{Value: true},
-			Action: types.AttributeActionPut,
-		},
+// DeleteOrg marks a Org as deleted by Org ID in the DynamoDB table and releases its domain. The Org must still be at
+// the given version, and a *ConflictError is returned otherwise.
+func (d *OrgDBClient) DeleteOrg(ctx context.Context, orgID string, version int64) error {
+	current, err := d.GetOrg(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "org", ID: orgID}
 	}
+	if current.Version != version {
+		return &ConflictError{Entity: "org", ID: orgID, Version: version}
+	}
+
+	pk, sk := createOrgCompositeKeys(orgID)
 
-	input := &dynamodb.UpdateItemInput{
+	update := &types.Update{
 		TableName: aws.String("Services"),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: pk},
 			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
-		AttributeUpdates:    update,
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
-	}
-
-	_, err := d.Org.UpdateItem(ctx, input)
-	if err != nil {
-		return fmt.Errorf("failed to delete item in DynamoDB: %v", err)
-	}
-
-	return nil
-}
-
-// ListOrgsByOrganization retrieves all Orgs for a specific organization from the DynamoDB table.
-func (d *OrgDBClient) ListOrgs(ctx context.Context, orgID, serviceID string) ([]Org, error) {
-	pk, _ := createOrgCompositeKeys(orgID)
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String("Services"),
-		KeyConditionExpression: aws.String("pk = :pk"),
+		UpdateExpression:         aws.String("SET #deleted = :true"),
+		ConditionExpression:      aws.String("attribute_exists(pk)"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "Deleted"},
 		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{
-				Value: pk,
-			},
+			":true": &types.AttributeValueMemberBOOL{Value: true},
 		},
 	}
+	withVersion(update, version)
 
-	result, err := d.Org.Query(ctx, input)
+	items := []types.TransactWriteItem{{Update: update}}
+	if current.Domain != "" {
+		items = append(items, deleteDomainClaim(current.Domain))
+	}
+
+	audit, err := auditPut(ctx, orgID, "organization.deleted", AuditTargetOrganization, orgID, current, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
+		return err
 	}
+	items = append(items, audit)
 
-	var Orgs []Org
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &Orgs)
+	_, err = d.Org.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "org", ID: orgID, Version: version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
-	return Orgs, nil
+	return nil
 }
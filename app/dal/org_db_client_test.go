@@ -0,0 +1,195 @@
+package dal_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	org := &dal.Org{
+		Name:    "Acme",
+		Domain:  "Acme.com",
+		OwnerID: "user1",
+	}
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 3, len(input.TransactItems))
+			put := input.TransactItems[0].Put
+			assert.Equal(t, "Org#"+org.OrgID, put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "user1", put.Item["OwnerID"].(*types.AttributeValueMemberS).Value)
+
+			claim := input.TransactItems[1].Put
+			assert.Equal(t, "Domain#acme.com", claim.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, org.OrgID, claim.Item["OrgID"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "attribute_not_exists(pk)", *claim.ConditionExpression)
+
+			event := auditEvent(t, input.TransactItems[2])
+			assert.Equal(t, "organization.created", event.Action)
+			assert.Equal(t, org.OrgID, event.OrgID)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.CreateOrg(context.Background(), org)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, org.OrgID)
+}
+
+func TestCreateOrg_DomainTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+			},
+		})
+
+	err := client.CreateOrg(context.Background(), &dal.Org{Name: "Acme", Domain: "acme.com"})
+	assert.ErrorIs(t, err, dal.ErrDomainTaken)
+}
+
+func TestGetOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Org{OrgID: "org1", Name: "Acme"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, "Org#org1", input.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Org#org1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.GetItemOutput{Item: item}, nil
+		})
+
+	result, err := client.GetOrg(context.Background(), "org1")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, "Acme", result.Name)
+}
+
+func TestGetOrg_Deleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Org{OrgID: "org1", Deleted: true})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	result, err := client.GetOrg(context.Background(), "org1")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestUpdateOrg_DomainChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Org{OrgID: "org1", Name: "Acme", Domain: "acme.com"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 4, len(input.TransactItems))
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "SET #name = :name, #domain = :domain, #stripeAccountId = :stripeAccountId, #version = :nextVersion", *update.UpdateExpression)
+			assert.Equal(t, "Domain", update.ExpressionAttributeNames["#domain"])
+			assert.Equal(t, "acme.io", update.ExpressionAttributeValues[":domain"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Domain#acme.io", input.TransactItems[1].Put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Domain#acme.com", input.TransactItems[2].Delete.Key["pk"].(*types.AttributeValueMemberS).Value)
+
+			event := auditEvent(t, input.TransactItems[3])
+			assert.Equal(t, "organization.updated", event.Action)
+			assert.Equal(t, `{"domain":"acme.com","version":0}`, event.Before)
+			assert.Equal(t, `{"domain":"acme.io","version":1}`, event.After)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.UpdateOrg(context.Background(), &dal.Org{OrgID: "org1", Name: "Acme", Domain: "acme.io"})
+	assert.NoError(t, err)
+}
+
+func TestUpdateOrg_SameDomain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Org{OrgID: "org1", Name: "Acme", Domain: "acme.com"})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			// The domain claim is left alone when only the case of the domain changes
+			assert.Equal(t, 2, len(input.TransactItems))
+			assert.Equal(t, "organization.updated", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.UpdateOrg(context.Background(), &dal.Org{OrgID: "org1", Name: "Acme Inc", Domain: "ACME.com"})
+	assert.NoError(t, err)
+}
+
+func TestDeleteOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewOrgDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Org{OrgID: "org1", Domain: "acme.com", Version: 4})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 3, len(input.TransactItems))
+			assert.Equal(t, "SET #deleted = :true, #version = :nextVersion", *input.TransactItems[0].Update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #version = :version", *input.TransactItems[0].Update.ConditionExpression)
+			assert.Equal(t, "Domain#acme.com", input.TransactItems[1].Delete.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "organization.deleted", auditEvent(t, input.TransactItems[2]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.DeleteOrg(context.Background(), "org1", 4)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,179 @@
+package dal
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MaxPageLimit is the largest number of items a page of a list operation holds, and the number it holds when no
+// limit is given.
+const MaxPageLimit = 100
+
+// ErrInvalidCursor is returned when a cursor was not returned by the same list operation, for the same parent.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects a page of a list operation. An empty cursor selects the first page.
+type Page struct {
+	Cursor string
+	Limit  int
+}
+
+// CursorCodec encodes the DynamoDB start keys of pages into opaque cursors. Cursors are signed, so that clients cannot
+// forge a start key, and bound to the partition they were read from, so that a cursor of one organization or service
+// cannot be used to list another.
+type CursorCodec struct {
+	key []byte
+}
+
+// NewCursorCodec creates a CursorCodec that signs cursors with a secret.
+func NewCursorCodec(secret string) *CursorCodec {
+	return &CursorCodec{key: []byte(secret)}
+}
+
+// cursorPayload is the signed content of a cursor.
+type cursorPayload struct {
+	Scope string            `json:"s"`
+	Key   map[string]string `json:"k"`
+}
+
+// encode returns the cursor of a start key read from a scope.
+func (c *CursorCodec) encode(scope string, key map[string]types.AttributeValue) (string, error) {
+	payload := cursorPayload{Scope: scope, Key: make(map[string]string, len(key))}
+	for name, value := range key {
+		s, ok := value.(*types.AttributeValueMemberS)
+		if !ok {
+			return "", fmt.Errorf("unsupported type of key attribute '%s'", name)
+		}
+		payload.Key[name] = s.Value
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(c.sign(data)), nil
+}
+
+// decode returns the start key of a cursor, which must have been encoded for the same scope.
+func (c *CursorCodec) decode(scope, cursor string) (map[string]types.AttributeValue, error) {
+	encodedData, encodedMAC, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encodedData)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if !hmac.Equal(mac, c.sign(data)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Scope != scope || len(payload.Key) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	key := make(map[string]types.AttributeValue, len(payload.Key))
+	for name, value := range payload.Key {
+		key[name] = &types.AttributeValueMemberS{Value: value}
+	}
+	return key, nil
+}
+
+// sign returns the HMAC-SHA256 of the content of a cursor.
+func (c *CursorCodec) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// queryPage runs a query from the start key of the cursor of a page until the page holds its limit of items, or the
+// query is exhausted. Items are dropped when keep returns false, such as items that were soft deleted. The partition
+// is the value of the partition key of the query, which the cursors of its pages are bound to. The returned cursor is
+// empty on the last page.
+func queryPage[T any](ctx context.Context, db DynamoDBAPI, cursors *CursorCodec, input *dynamodb.QueryInput, partition string, page Page, keep func(item *T) bool) ([]T, string, error) {
+	scope := aws.ToString(input.TableName) + "/" + aws.ToString(input.IndexName) + "/" + partition
+
+	limit := page.Limit
+	if limit <= 0 || limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	if page.Cursor != "" {
+		startKey, err := cursors.decode(scope, page.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = startKey
+	}
+
+	// Dropped and filtered items still count against the limit of a query, so pages are read until enough items
+	// are kept. The last page read ends at the last item kept, so the next page starts right after it.
+	results := []T{}
+	for {
+		input.Limit = aws.Int32(int32(limit - len(results)))
+
+		result, err := db.Query(ctx, input)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to query items in DynamoDB: %w", err)
+		}
+
+		var items []T
+		err = attributevalue.UnmarshalListOfMaps(result.Items, &items)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal items from DynamoDB: %w", err)
+		}
+		for i := range items {
+			if keep == nil || keep(&items[i]) {
+				results = append(results, items[i])
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return results, "", nil
+		}
+		if len(results) >= limit {
+			cursor, err := cursors.encode(scope, result.LastEvaluatedKey)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to encode cursor: %w", err)
+			}
+			return results, cursor, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// ListAll collects the items of every page of a list operation, for callers that need all of them at once.
+func ListAll[T any](list func(page Page) ([]T, string, error)) ([]T, error) {
+	results := []T{}
+	page := Page{Limit: MaxPageLimit}
+	for {
+		items, cursor, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, items...)
+
+		if cursor == "" {
+			return results, nil
+		}
+		page.Cursor = cursor
+	}
+}
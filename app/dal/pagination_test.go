@@ -0,0 +1,237 @@
+package dal_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// cursors signs the cursors of the clients under test.
+var cursors = dal.NewCursorCodec("cursor-secret")
+
+// apiKeyIndexKey returns the start key of a query of the Org-Service-Index after an API key.
+func apiKeyIndexKey(apiKeyID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"pk":     &types.AttributeValueMemberS{Value: "APIKey#" + apiKeyID},
+		"GSI1PK": &types.AttributeValueMemberS{Value: "Org#org1Service#serv1"},
+	}
+}
+
+func TestListAPIKeysByService_Pages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewAPIKeyDBClient(mockSvc, cursors)
+
+	key1, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key1"})
+	deleted, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key2", Deleted: true})
+	key3, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key3"})
+	key4, _ := attributevalue.MarshalMap(dal.APIKey{APIKeyID: "key4"})
+
+	// Deleted keys count against the limit of a query, so queries continue until the page is full
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, "Org-Service-Index", *input.IndexName)
+				assert.Equal(t, int32(2), *input.Limit)
+				assert.Nil(t, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{key1, deleted}, LastEvaluatedKey: apiKeyIndexKey("key2")}, nil
+			}),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, int32(1), *input.Limit)
+				assert.Equal(t, apiKeyIndexKey("key2"), input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{key3}, LastEvaluatedKey: apiKeyIndexKey("key3")}, nil
+			}),
+	)
+
+	apiKeys, cursor, err := client.ListAPIKeysByService(context.Background(), "org1", "serv1", dal.Page{Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, apiKeys, 2) {
+		assert.Equal(t, "key1", apiKeys[0].APIKeyID)
+		assert.Equal(t, "key3", apiKeys[1].APIKeyID)
+	}
+	assert.NotEmpty(t, cursor)
+	assert.NotContains(t, cursor, "key3")
+
+	// The cursor resumes after the last key of the page
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, apiKeyIndexKey("key3"), input.ExclusiveStartKey)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{key4}}, nil
+		})
+
+	apiKeys, cursor, err = client.ListAPIKeysByService(context.Background(), "org1", "serv1", dal.Page{Cursor: cursor, Limit: 2})
+	assert.NoError(t, err)
+	if assert.Len(t, apiKeys, 1) {
+		assert.Equal(t, "key4", apiKeys[0].APIKeyID)
+	}
+	assert.Empty(t, cursor)
+}
+
+func TestListActors_Pages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewActorDBClient(mockSvc, cursors)
+
+	actor1, _ := attributevalue.MarshalMap(dal.Actor{ExternalID: "actor1"})
+	actor2, _ := attributevalue.MarshalMap(dal.Actor{ExternalID: "actor2"})
+	lastKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "Org#org1Service#serv1Actor"},
+		"sk": &types.AttributeValueMemberS{Value: "Actor#actor1"},
+	}
+
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, int32(dal.MaxPageLimit), *input.Limit)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{actor1}, LastEvaluatedKey: lastKey}, nil
+			}),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				// Queries stopped by the 1 MB limit of DynamoDB are continued rather than truncated
+				assert.Equal(t, int32(dal.MaxPageLimit-1), *input.Limit)
+				assert.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{actor2}}, nil
+			}),
+	)
+
+	actors, cursor, err := client.ListActors(context.Background(), "org1", "serv1", dal.Page{})
+	assert.NoError(t, err)
+	assert.Len(t, actors, 2)
+	assert.Empty(t, cursor)
+}
+
+func TestListTiers_Pages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	tier1, _ := attributevalue.MarshalMap(dal.Tier{TierID: "tier1"})
+	tier2, _ := attributevalue.MarshalMap(dal.Tier{TierID: "tier2"})
+	tierKey := func(tierID string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: "Org#org1Service#serv1Tier"},
+			"sk": &types.AttributeValueMemberS{Value: "Tier#" + tierID},
+		}
+	}
+
+	gomock.InOrder(
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{tier1}, LastEvaluatedKey: tierKey("tier1")}, nil),
+		mockSvc.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				assert.Equal(t, tierKey("tier1"), input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{tier2}}, nil
+			}),
+	)
+
+	// Every page is collected for callers that need all the tiers
+	tiers, err := dal.ListAll(func(page dal.Page) ([]dal.Tier, string, error) {
+		return client.ListTiers(context.Background(), "org1", "serv1", dal.Page{Cursor: page.Cursor, Limit: 1})
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, tiers, 2) {
+		assert.Equal(t, "tier1", tiers[0].TierID)
+		assert.Equal(t, "tier2", tiers[1].TierID)
+	}
+}
+
+func TestListServicesByOrganization_Pages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	service1, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "serv1"})
+	lastKey := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "Org#org1"},
+		"sk": &types.AttributeValueMemberS{Value: "Service#serv1"},
+	}
+
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{service1}, LastEvaluatedKey: lastKey}, nil)
+
+	services, cursor, err := client.ListServicesByOrganization(context.Background(), "org1", dal.Page{Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, services, 1)
+	assert.NotEmpty(t, cursor)
+
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, lastKey, input.ExclusiveStartKey)
+			return &dynamodb.QueryOutput{}, nil
+		})
+
+	services, cursor, err = client.ListServicesByOrganization(context.Background(), "org1", dal.Page{Cursor: cursor, Limit: 1})
+	assert.NoError(t, err)
+	assert.Empty(t, services)
+	assert.Empty(t, cursor)
+}
+
+func TestListPages_InvalidCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	service1, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "serv1"})
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.QueryOutput{
+			Items: []map[string]types.AttributeValue{service1},
+			LastEvaluatedKey: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: "Org#org1"},
+				"sk": &types.AttributeValueMemberS{Value: "Service#serv1"},
+			},
+		}, nil)
+
+	_, cursor, err := client.ListServicesByOrganization(context.Background(), "org1", dal.Page{Limit: 1})
+	assert.NoError(t, err)
+
+	data, mac, _ := strings.Cut(cursor, ".")
+	forged := dal.NewServiceDBClient(mockSvc, dal.NewCursorCodec("other-secret"))
+	for name, page := range map[string]func() ([]dal.Service, string, error){
+		"malformed": func() ([]dal.Service, string, error) {
+			return client.ListServicesByOrganization(context.Background(), "org1", dal.Page{Cursor: "not a cursor"})
+		},
+		"tampered": func() ([]dal.Service, string, error) {
+			return client.ListServicesByOrganization(context.Background(), "org1", dal.Page{Cursor: data + "x." + mac})
+		},
+		"other organization": func() ([]dal.Service, string, error) {
+			return client.ListServicesByOrganization(context.Background(), "org2", dal.Page{Cursor: cursor})
+		},
+		"other secret": func() ([]dal.Service, string, error) {
+			return forged.ListServicesByOrganization(context.Background(), "org1", dal.Page{Cursor: cursor})
+		},
+	} {
+		_, _, err := page()
+		assert.True(t, errors.Is(err, dal.ErrInvalidCursor), name)
+	}
+}
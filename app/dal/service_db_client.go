@@ -3,6 +3,7 @@ package dal
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/payloadops/lanyard/app/utils"
@@ -20,32 +21,77 @@ type ServiceManager interface {
 	CreateService(ctx context.Context, orgID string, service *Service) error
 	GetService(ctx context.Context, orgID string, serviceID string) (*Service, error)
 	UpdateService(ctx context.Context, orgID string, service *Service) error
-	DeleteService(ctx context.Context, orgID string, serviceID string) error
-	ListServicesByOrganization(ctx context.Context, orgID string) ([]Service, error)
+	MarkServiceDeleting(ctx context.Context, orgID string, serviceID string, version int64) error
+	DeleteService(ctx context.Context, orgID string, serviceID string, version int64) error
+	StageDeleteService(ctx context.Context, unit *UnitOfWork, orgID string, serviceID string, version int64) error
+	ListServicesByOrganization(ctx context.Context, orgID string, page Page) ([]Service, string, error)
 }
 
 // Ensure ServiceDBClient implements the ServiceManager interface
 var _ ServiceManager = &ServiceDBClient{}
 
+const (
+	// LeakPolicyRevoke revokes API keys of a service when they are reported leaked. Services without a policy use it.
+	LeakPolicyRevoke = "revoke"
+	// LeakPolicyQuarantine quarantines API keys of a service when they are reported leaked, keeping them for their
+	// owners to investigate.
+	LeakPolicyQuarantine = "quarantine"
+)
+
+const (
+	// ServiceStatusActive is the status of services that API keys and actors may be created in. Services stored
+	// without a status are active.
+	ServiceStatusActive = "active"
+	// ServiceStatusDeleting is the status of services that are being deleted. No API key or actor may be created in a
+	// deleting service, so that none is left behind once its API keys and actors are deleted along with it.
+	ServiceStatusDeleting = "deleting"
+)
+
 // Service represents a service in the system.
 type Service struct {
-	ServiceID   string `json:"serviceId"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Deleted     bool   `json:"deleted"`
-	CreatedAt   string `json:"createdAt"`
-	UpdatedAt   string `json:"updatedAt"`
+	ServiceID        string `json:"serviceId"`
+	Name             string `json:"name"`
+	Description      string `json:"description"`
+	MaxKeyTTLSeconds int64  `json:"maxKeyTtlSeconds"`
+	KeyPrefix        string `json:"keyPrefix"`
+	LeakPolicy       string `json:"leakPolicy"`
+	Status           string `json:"status"`
+	Deleted          bool   `json:"deleted"`
+	CreatedAt        string `json:"createdAt"`
+	UpdatedAt        string `json:"updatedAt"`
+	Version          int64  `json:"version"`
+}
+
+// Deleting reports whether the service is being deleted.
+func (s *Service) Deleting() bool {
+	return s.Status == ServiceStatusDeleting
+}
+
+// ServiceUnavailableError is returned when an API key or actor is created in a service that was deleted or started
+// being deleted since it was read. It matches ErrConflict.
+type ServiceUnavailableError struct {
+	ID string
+}
+
+func (e *ServiceUnavailableError) Error() string {
+	return fmt.Sprintf("service '%s' is deleted or being deleted", e.ID)
+}
+
+func (e *ServiceUnavailableError) Unwrap() error {
+	return ErrConflict
 }
 
 // ServiceDBClient is a client for interacting with DynamoDB for service-related operations.
 type ServiceDBClient struct {
 	service DynamoDBAPI
+	cursors *CursorCodec
 }
 
-// NewServiceDBClient creates a new ServiceDBClient.
-func NewServiceDBClient(service DynamoDBAPI) *ServiceDBClient {
+// NewServiceDBClient creates a new ServiceDBClient. The cursors of its list operations are signed by the codec.
+func NewServiceDBClient(service DynamoDBAPI, cursors *CursorCodec) *ServiceDBClient {
 	return &ServiceDBClient{
-		service: service,
+		service: withClassifiedErrors(service),
+		cursors: cursors,
 	}
 }
 
@@ -54,11 +100,32 @@ func createServiceCompositeKeys(orgID, serviceID string) (string, string) {
 	return "Org#" + orgID, "Service#" + serviceID
 }
 
+// serviceAvailableCheck returns a transaction item that checks that a service exists and is neither deleted nor being
+// deleted, so that API keys and actors are only created in services that keep them.
+func serviceAvailableCheck(orgID, serviceID string) types.TransactWriteItem {
+	pk, sk := createServiceCompositeKeys(orgID, serviceID)
+	return types.TransactWriteItem{
+		ConditionCheck: &types.ConditionCheck{
+			TableName: aws.String("Services"),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: sk},
+			},
+			ConditionExpression:      aws.String("attribute_exists(pk) AND (attribute_not_exists(#deleted) OR #deleted = :false) AND (attribute_not_exists(#status) OR #status <> :deleting)"),
+			ExpressionAttributeNames: map[string]string{"#deleted": "Deleted", "#status": "Status"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":false":    &types.AttributeValueMemberBOOL{Value: false},
+				":deleting": &types.AttributeValueMemberS{Value: ServiceStatusDeleting},
+			},
+		},
+	}
+}
+
 // CreateService creates a new service in the DynamoDB table.
 func (d *ServiceDBClient) CreateService(ctx context.Context, orgID string, service *Service) error {
 	ksuid, err := utils.GenerateKSUID()
 	if err != nil {
-		return fmt.Errorf("failed to create ksuid: %v", err)
+		return fmt.Errorf("failed to create ksuid: %w", err)
 	}
 
 	service.ServiceID = ksuid
@@ -67,10 +134,12 @@ func (d *ServiceDBClient) CreateService(ctx context.Context, orgID string, servi
 	now := time.Now().UTC().Format(time.RFC3339)
 	service.CreatedAt = now
 	service.UpdatedAt = now
+	service.Version = 1
+	service.Status = ServiceStatusActive
 
 	av, err := attributevalue.MarshalMap(service)
 	if err != nil {
-		return fmt.Errorf("failed to marshal service: %v", err)
+		return fmt.Errorf("failed to marshal service: %w", err)
 	}
 
 	item := map[string]types.AttributeValue{
@@ -81,14 +150,24 @@ func (d *ServiceDBClient) CreateService(ctx context.Context, orgID string, servi
 		item[k] = v
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String("Services"),
-		Item:      item,
+	audit, err := auditPut(ctx, orgID, "service.created", AuditTargetService, service.ServiceID, nil, service)
+	if err != nil {
+		return err
 	}
 
-	_, err = d.service.PutItem(ctx, input)
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName: aws.String("Services"),
+				Item:      item,
+			},
+		},
+		audit,
+	}
+
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to put item in DynamoDB: %v", err)
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
@@ -107,7 +186,7 @@ func (d *ServiceDBClient) GetService(ctx context.Context, orgID, serviceID strin
 
 	result, err := d.service.GetItem(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
@@ -117,7 +196,7 @@ func (d *ServiceDBClient) GetService(ctx context.Context, orgID, serviceID strin
 	var service Service
 	err = attributevalue.UnmarshalMap(result.Item, &service)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
 	}
 
 	if service.Deleted {
@@ -127,105 +206,203 @@ func (d *ServiceDBClient) GetService(ctx context.Context, orgID, serviceID strin
 	return &service, nil
 }
 
-// UpdateService updates the name, description, and updatedAt fields of an existing service in the DynamoDB table.
+// UpdateService updates the name, description, maxKeyTtlSeconds, keyPrefix and updatedAt fields of an existing service in the DynamoDB table.
+// The service must still be at the version of service, which is advanced to the next version. A *ConflictError is
+// returned otherwise.
 func (d *ServiceDBClient) UpdateService(ctx context.Context, orgID string, service *Service) error {
+	current, err := d.GetService(ctx, orgID, service.ServiceID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "service", ID: service.ServiceID}
+	}
+	if current.Version != service.Version {
+		return &ConflictError{Entity: "service", ID: service.ServiceID, Version: service.Version}
+	}
+
 	pk, sk := createServiceCompositeKeys(orgID, service.ServiceID)
 	service.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
 
-	updateExpr := "SET #name = :name, #description = :description, #updatedAt = :updatedAt"
+	updateExpr := "SET #name = :name, #description = :description, #maxKeyTTLSeconds = :maxKeyTTLSeconds, #keyPrefix = :keyPrefix, #leakPolicy = :leakPolicy, #updatedAt = :updatedAt"
 	exprAttrNames := map[string]string{
-		"#name":        "Name",
-		"#description": "Description",
-		"#updatedAt":   "UpdatedAt",
+		"#name":             "Name",
+		"#description":      "Description",
+		"#maxKeyTTLSeconds": "MaxKeyTTLSeconds",
+		"#keyPrefix":        "KeyPrefix",
+		"#leakPolicy":       "LeakPolicy",
+		"#updatedAt":        "UpdatedAt",
 	}
 
 	exprAttrValues := map[string]types.AttributeValue{
-		":name":        &types.AttributeValueMemberS{Value: service.Name},
-		":description": &types.AttributeValueMemberS{Value: service.Description},
-		":updatedAt":   &types.AttributeValueMemberS{Value: service.UpdatedAt},
+		":name":             &types.AttributeValueMemberS{Value: service.Name},
+		":description":      &types.AttributeValueMemberS{Value: service.Description},
+		":maxKeyTTLSeconds": &types.AttributeValueMemberN{Value: strconv.FormatInt(service.MaxKeyTTLSeconds, 10)},
+		":keyPrefix":        &types.AttributeValueMemberS{Value: service.KeyPrefix},
+		":leakPolicy":       &types.AttributeValueMemberS{Value: service.LeakPolicy},
+		":updatedAt":        &types.AttributeValueMemberS{Value: service.UpdatedAt},
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	updated := *current
+	updated.Name = service.Name
+	updated.Description = service.Description
+	updated.MaxKeyTTLSeconds = service.MaxKeyTTLSeconds
+	updated.KeyPrefix = service.KeyPrefix
+	updated.LeakPolicy = service.LeakPolicy
+	updated.UpdatedAt = service.UpdatedAt
+	updated.Version = service.Version + 1
+
+	audit, err := auditPut(ctx, orgID, "service.updated", AuditTargetService, service.ServiceID, current, &updated)
+	if err != nil {
+		return err
+	}
+
+	update := &types.Update{
 		TableName:                 aws.String("Services"),
 		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 	}
+	withVersion(update, service.Version)
+
+	items := []types.TransactWriteItem{{Update: update}, audit}
 
-	_, err := d.service.UpdateItem(ctx, input)
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to update item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "service", ID: service.ServiceID, Version: service.Version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
+	service.Version = updated.Version
 	return nil
 }
 
-// DeleteService marks a service as deleted by organization ID and service ID in the DynamoDB table.
-func (d *ServiceDBClient) DeleteService(ctx context.Context, orgID, serviceID string) error {
-	pk, sk := createServiceCompositeKeys(orgID, serviceID)
-	now := time.Now().UTC().Format(time.RFC3339)
+// MarkServiceDeleting starts deleting a service: the service becomes deleting, so that no API key or actor can be
+// created in it anymore, and its API keys and actors are then deleted along with it with StageDeleteService. A
+// *ConflictError is returned if the service is no longer at the given version, which is advanced to the next version.
+func (d *ServiceDBClient) MarkServiceDeleting(ctx context.Context, orgID, serviceID string, version int64) error {
+	current, err := d.GetService(ctx, orgID, serviceID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "service", ID: serviceID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "service", ID: serviceID, Version: version}
+	}
 
-	update := map[string]types.AttributeValueUpdate{
-		"Deleted": {
-			Value:  &types.AttributeValueMemberBOOL{Value: true},
-			Action: types.AttributeActionPut,
-		},
-		"UpdatedAt": {
-			Value:  &types.AttributeValueMemberS{Value: now},
-			Action: types.AttributeActionPut,
-		},
+	updated := *current
+	updated.Status = ServiceStatusDeleting
+	updated.Version = version + 1
+
+	audit, err := auditPut(ctx, orgID, "service.deleting", AuditTargetService, serviceID, current, &updated)
+	if err != nil {
+		return err
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	pk, sk := createServiceCompositeKeys(orgID, serviceID)
+	update := &types.Update{
 		TableName: aws.String("Services"),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: pk},
 			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
-		AttributeUpdates:    update,
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		UpdateExpression:         aws.String("SET #status = :deleting"),
+		ConditionExpression:      aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":deleting": &types.AttributeValueMemberS{Value: ServiceStatusDeleting},
+		},
 	}
+	withVersion(update, version)
+
+	items := []types.TransactWriteItem{{Update: update}, audit}
 
-	_, err := d.service.UpdateItem(ctx, input)
+	_, err = d.service.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to delete item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "service", ID: serviceID, Version: version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// ListServicesByOrganization retrieves all services for a specific organization from the DynamoDB table.
-func (d *ServiceDBClient) ListServicesByOrganization(ctx context.Context, orgID string) ([]Service, error) {
-	pk, _ := createServiceCompositeKeys(orgID, "")
-	input := &dynamodb.QueryInput{
-		TableName:              aws.String("Services"),
-		KeyConditionExpression: aws.String("pk = :pk"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":pk": &types.AttributeValueMemberS{
-				Value: pk,
-			},
-		},
+// DeleteService marks a service as deleted by organization ID and service ID in the DynamoDB table. The service must
+// still be at the given version, and a *ConflictError is returned otherwise.
+func (d *ServiceDBClient) DeleteService(ctx context.Context, orgID, serviceID string, version int64) error {
+	unit := NewUnitOfWork()
+	if err := d.StageDeleteService(ctx, unit, orgID, serviceID, version); err != nil {
+		return err
 	}
+	return commitUnit(ctx, d.service, unit)
+}
 
-	result, err := d.service.Query(ctx, input)
+// StageDeleteService adds the deletion of a service to a unit of work, along with its audit record. The service must
+// still be at the given version when the unit of work is committed, and a *ConflictError is returned otherwise.
+func (d *ServiceDBClient) StageDeleteService(ctx context.Context, unit *UnitOfWork, orgID, serviceID string, version int64) error {
+	current, err := d.GetService(ctx, orgID, serviceID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "service", ID: serviceID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "service", ID: serviceID, Version: version}
 	}
 
-	var services []Service
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &services)
+	pk, sk := createServiceCompositeKeys(orgID, serviceID)
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	audit, err := auditPut(ctx, orgID, "service.deleted", AuditTargetService, serviceID, current, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
+		return err
 	}
 
-	results := []Service{}
-	for _, service := range services {
-		if service.Deleted {
-			continue
-		}
-		results = append(results, service)
+	update := &types.Update{
+		TableName: aws.String("Services"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+		UpdateExpression:         aws.String("SET #deleted = :true, #updatedAt = :updatedAt"),
+		ConditionExpression:      aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "Deleted", "#updatedAt": "UpdatedAt"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true":      &types.AttributeValueMemberBOOL{Value: true},
+			":updatedAt": &types.AttributeValueMemberS{Value: now},
+		},
+	}
+	withVersion(update, version)
+
+	conflict := &ConflictError{Entity: "service", ID: serviceID, Version: version}
+	return unit.write(conflict, []types.TransactWriteItem{{Update: update}, audit})
+}
+
+// ListServicesByOrganization retrieves a page of the services of an organization from the DynamoDB table, along with
+// the cursor of the next page.
+func (d *ServiceDBClient) ListServicesByOrganization(ctx context.Context, orgID string, page Page) ([]Service, string, error) {
+	pk, sk := createServiceCompositeKeys(orgID, "")
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{
+				Value: pk,
+			},
+			":sk": &types.AttributeValueMemberS{
+				Value: sk,
+			},
+		},
 	}
 
-	return services, nil
+	return queryPage(ctx, d.service, d.cursors, input, pk, page, func(service *Service) bool {
+		return !service.Deleted
+	})
 }
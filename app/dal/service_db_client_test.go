@@ -20,7 +20,7 @@ func TestCreateService(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewServiceDBClient(mockSvc)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
 
 	service := &dal.Service{
 		Name:        "Service1",
@@ -28,8 +28,12 @@ func TestCreateService(t *testing.T) {
 	}
 
 	mockSvc.EXPECT().
-		PutItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.PutItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, "Org#org1", input.TransactItems[0].Put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "service.created", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
 	err := client.CreateService(context.Background(), "org1", service)
 	assert.NoError(t, err)
@@ -40,7 +44,7 @@ func TestGetService(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewServiceDBClient(mockSvc)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
 
 	service := dal.Service{
 		ServiceID:   "proj1",
@@ -67,46 +71,166 @@ func TestUpdateService(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewServiceDBClient(mockSvc)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
 
 	service := &dal.Service{
-		ServiceID:   "proj1",
-		Name:        "Service1",
-		Description: "Description1",
+		ServiceID:        "proj1",
+		Name:             "Service1",
+		Description:      "Description1",
+		MaxKeyTTLSeconds: 86400,
+		KeyPrefix:        "acme",
+		LeakPolicy:       dal.LeakPolicyQuarantine,
 	}
 
+	item, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "proj1", Name: "Service1", Description: "Description1", LeakPolicy: dal.LeakPolicyRevoke})
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, transaction *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			input := transaction.TransactItems[0].Update
 			assert.Equal(t, "Org#org1", input.Key["pk"].(*types.AttributeValueMemberS).Value)
 			assert.Equal(t, "Service#proj1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
 			assert.Equal(t, "Service1", input.ExpressionAttributeValues[":name"].(*types.AttributeValueMemberS).Value)
 			assert.Equal(t, "Description1", input.ExpressionAttributeValues[":description"].(*types.AttributeValueMemberS).Value)
 			assert.NotEmpty(t, input.ExpressionAttributeValues[":updatedAt"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "SET #name = :name, #description = :description, #updatedAt = :updatedAt", *input.UpdateExpression)
+			assert.Equal(t, "SET #name = :name, #description = :description, #maxKeyTTLSeconds = :maxKeyTTLSeconds, #keyPrefix = :keyPrefix, #leakPolicy = :leakPolicy, #updatedAt = :updatedAt, #version = :nextVersion", *input.UpdateExpression)
 			assert.Equal(t, "Name", input.ExpressionAttributeNames["#name"])
 			assert.Equal(t, "Description", input.ExpressionAttributeNames["#description"])
+			assert.Equal(t, "86400", input.ExpressionAttributeValues[":maxKeyTTLSeconds"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "MaxKeyTTLSeconds", input.ExpressionAttributeNames["#maxKeyTTLSeconds"])
+			assert.Equal(t, "acme", input.ExpressionAttributeValues[":keyPrefix"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "KeyPrefix", input.ExpressionAttributeNames["#keyPrefix"])
+			assert.Equal(t, "quarantine", input.ExpressionAttributeValues[":leakPolicy"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "LeakPolicy", input.ExpressionAttributeNames["#leakPolicy"])
 			assert.Equal(t, "UpdatedAt", input.ExpressionAttributeNames["#updatedAt"])
-			return &dynamodb.UpdateItemOutput{}, nil
+
+			event := auditEvent(t, transaction.TransactItems[1])
+			assert.Equal(t, "service.updated", event.Action)
+			assert.Contains(t, event.Before, `"leakPolicy":"revoke"`)
+			assert.Contains(t, event.After, `"leakPolicy":"quarantine"`)
+			assert.NotContains(t, event.After, "Service1")
+			return &dynamodb.TransactWriteItemsOutput{}, nil
 		})
 
 	err := client.UpdateService(context.Background(), "org1", service)
 	assert.NoError(t, err)
 }
 
+func TestUpdateService_Version(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "proj1", Name: "Service1", Version: 5})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(3)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, transaction *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			input := transaction.TransactItems[0].Update
+			assert.Equal(t, "#version = :version", *input.ConditionExpression)
+			assert.Equal(t, "Version", input.ExpressionAttributeNames["#version"])
+			assert.Equal(t, "5", input.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "6", input.ExpressionAttributeValues[":nextVersion"].(*types.AttributeValueMemberN).Value)
+
+			event := auditEvent(t, transaction.TransactItems[1])
+			assert.Contains(t, event.Before, `"version":5`)
+			assert.Contains(t, event.After, `"version":6`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	service := &dal.Service{ServiceID: "proj1", Name: "Service2", Version: 5}
+	err := client.UpdateService(context.Background(), "org1", service)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), service.Version)
+
+	// The service was changed since version 4 was read
+	var conflict *dal.ConflictError
+	err = client.UpdateService(context.Background(), "org1", &dal.Service{ServiceID: "proj1", Version: 4})
+	assert.ErrorAs(t, err, &conflict)
+
+	// The service was changed between the read and the write
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	service = &dal.Service{ServiceID: "proj1", Version: 5}
+	err = client.UpdateService(context.Background(), "org1", service)
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "proj1", conflict.ID)
+	assert.Equal(t, int64(5), service.Version)
+}
+
 func TestDeleteService(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewServiceDBClient(mockSvc)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
 
+	item, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "proj1", Name: "Service1"})
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.UpdateItemOutput{}, nil)
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
 
-	err := client.DeleteService(context.Background(), "org1", "proj1")
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, "SET #deleted = :true, #updatedAt = :updatedAt, #version = :nextVersion", *input.TransactItems[0].Update.UpdateExpression)
+			assert.Equal(t, "service.deleted", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.DeleteService(context.Background(), "org1", "proj1", 0)
+	assert.NoError(t, err)
+}
+
+func TestMarkServiceDeleting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "proj1", Name: "Service1", Status: dal.ServiceStatusActive, Version: 2})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			update := input.TransactItems[0].Update
+			assert.Equal(t, "SET #status = :deleting, #version = :nextVersion", *update.UpdateExpression)
+			assert.Equal(t, dal.ServiceStatusDeleting, update.ExpressionAttributeValues[":deleting"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "3", update.ExpressionAttributeValues[":nextVersion"].(*types.AttributeValueMemberN).Value)
+
+			event := auditEvent(t, input.TransactItems[1])
+			assert.Equal(t, "service.deleting", event.Action)
+			assert.Contains(t, event.After, `"status":"deleting"`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.MarkServiceDeleting(context.Background(), "org1", "proj1", 2)
 	assert.NoError(t, err)
+
+	// The service was changed between the read and the write
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	err = client.MarkServiceDeleting(context.Background(), "org1", "proj1", 2)
+	var conflict *dal.ConflictError
+	assert.ErrorAs(t, err, &conflict)
 }
 
 func TestListServicesByOrganization(t *testing.T) {
@@ -114,7 +238,7 @@ func TestListServicesByOrganization(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewServiceDBClient(mockSvc)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
 
 	service := dal.Service{
 		ServiceID:   "proj1",
@@ -130,9 +254,33 @@ func TestListServicesByOrganization(t *testing.T) {
 		Query(gomock.Any(), gomock.Any()).
 		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil)
 
-	result, err := client.ListServicesByOrganization(context.Background(), "org1")
+	result, _, err := client.ListServicesByOrganization(context.Background(), "org1", dal.Page{})
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
 	assert.Equal(t, 1, len(result))
 	assert.Equal(t, "Service1", result[0].Name)
 }
+
+func TestListServicesByOrganization_SkipsDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewServiceDBClient(mockSvc, cursors)
+
+	active, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "serv1", Name: "Service1"})
+	deleted, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "serv2", Name: "Service2", Deleted: true})
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "pk = :pk AND begins_with(sk, :sk)", *input.KeyConditionExpression)
+			assert.Equal(t, "Org#org1", input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Service#", input.ExpressionAttributeValues[":sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{active, deleted}}, nil
+		})
+
+	result, _, err := client.ListServicesByOrganization(context.Background(), "org1", dal.Page{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "serv1", result[0].ServiceID)
+}
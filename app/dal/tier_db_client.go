@@ -18,54 +18,118 @@ import (
 // TierManager defines the operations available for managing Tiers.
 type TierManager interface {
 	CreateTier(ctx context.Context, orgID, serviceID string, Tier *Tier) error
-	GetTier(ctx context.Context, orgID, serviceID string, name string) (*Tier, error)
+	GetTier(ctx context.Context, orgID, serviceID string, tierID string) (*Tier, error)
 	UpdateTier(ctx context.Context, orgID, serviceID string, Tier *Tier) error
-	DeleteTier(ctx context.Context, orgID, serviceID string, name string) error
-	ListTiers(ctx context.Context, orgID, serviceID string) ([]Tier, error)
+	DeleteTier(ctx context.Context, orgID, serviceID string, tierID string, version int64) error
+	RetireTier(ctx context.Context, orgID, serviceID, tierID string, version int64, reassignTo string) error
+	ReassignTierActors(ctx context.Context, orgID, serviceID, tierID, reassignTo string, actorExternalIDs []string) error
+	ListTiers(ctx context.Context, orgID, serviceID string, page Page) ([]Tier, string, error)
 }
 
+// MaxReassignedActors is the largest number of actors ReassignTierActors can move at once. The actor updates, the
+// checks of both tiers and the audit event share one transaction, which DynamoDB limits to 100 items. Tiers with more
+// actors are retired in several batches.
+const MaxReassignedActors = 97
+
+const (
+	// TierStatusActive is the status of tiers that actors may be assigned to. Tiers stored without a status are
+	// active.
+	TierStatusActive = "active"
+	// TierStatusRetiring is the status of tiers that are being deleted. No actor may be assigned to a retiring tier,
+	// and its actors are moved to the tier it retires to before it is deleted.
+	TierStatusRetiring = "retiring"
+)
+
 // Ensure TierDBClient implements the TierManager interface
 var _ TierManager = &TierDBClient{}
 
 // PricingTier represents an Tier's basic billing info in the system.
 type Tier struct {
 	TierID              string  `json:"tierId"`
-	Name                string  `json:"tierId"`
+	Name                string  `json:"name"`
 	DefaultRequestLimit int     `json:"defaultRequestLimit"`
 	Interval            int     `json:"interval"`
 	OveragePrice        float32 `json:"overagePrice"`
+	Status              string  `json:"status"`
+	ReassignTo          string  `json:"reassignTo"`
+	Deleted             bool    `json:"deleted"`
+	Version             int64   `json:"version"`
+}
+
+// Retiring reports whether the tier is being deleted.
+func (t *Tier) Retiring() bool {
+	return t.Status == TierStatusRetiring
+}
+
+// TierUnavailableError is returned when an actor is assigned to a tier that was deleted or started retiring since it
+// was read. It matches ErrConflict.
+type TierUnavailableError struct {
+	ID string
+}
+
+func (e *TierUnavailableError) Error() string {
+	return fmt.Sprintf("pricing tier '%s' is deleted or retiring", e.ID)
+}
+
+func (e *TierUnavailableError) Unwrap() error {
+	return ErrConflict
 }
 
 // TierDBClient is a client for interacting with DynamoDB for Tier-related operations.
 type TierDBClient struct {
-	Tier DynamoDBAPI
+	Tier    DynamoDBAPI
+	cursors *CursorCodec
 }
 
-// NewTierDBClient creates a new TierDBClient.
-func NewTierDBClient(Tier DynamoDBAPI) *TierDBClient {
+// NewTierDBClient creates a new TierDBClient. The cursors of its list operations are signed by the codec.
+func NewTierDBClient(Tier DynamoDBAPI, cursors *CursorCodec) *TierDBClient {
 	return &TierDBClient{
-		Tier: Tier,
+		Tier:    withClassifiedErrors(Tier),
+		cursors: cursors,
 	}
 }
 
 // createTierCompositeKeys generates the partition key (pk) and sort key (sk) for a Tier.
-func createTierCompositeKeys(orgID, serviceID, TierName string) (string, string) {
-	return "Org#" + orgID + "Service#" + serviceID + "Tier", "Tier#" + TierName
+func createTierCompositeKeys(orgID, serviceID, tierID string) (string, string) {
+	return "Org#" + orgID + "Service#" + serviceID + "Tier", "Tier#" + tierID
+}
+
+// tierAssignableCheck returns a transaction item that checks that a Tier exists and is neither deleted nor retiring,
+// so that actors can be assigned to it.
+func tierAssignableCheck(orgID, serviceID, tierID string) types.TransactWriteItem {
+	pk, sk := createTierCompositeKeys(orgID, serviceID, tierID)
+	return types.TransactWriteItem{
+		ConditionCheck: &types.ConditionCheck{
+			TableName: aws.String("Services"),
+			Key: map[string]types.AttributeValue{
+				"pk": &types.AttributeValueMemberS{Value: pk},
+				"sk": &types.AttributeValueMemberS{Value: sk},
+			},
+			ConditionExpression:      aws.String("attribute_exists(pk) AND (attribute_not_exists(#deleted) OR #deleted = :false) AND (attribute_not_exists(#status) OR #status <> :retiring)"),
+			ExpressionAttributeNames: map[string]string{"#deleted": "Deleted", "#status": "Status"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":false":    &types.AttributeValueMemberBOOL{Value: false},
+				":retiring": &types.AttributeValueMemberS{Value: TierStatusRetiring},
+			},
+		},
+	}
 }
 
 // CreateTier creates a new Tier in the DynamoDB table.
 func (d *TierDBClient) CreateTier(ctx context.Context, orgID, serviceID string, Tier *Tier) error {
 	ksuid, err := utils.GenerateKSUID()
 	if err != nil {
-		return fmt.Errorf("failed to create ksuid: %v", err)
+		return fmt.Errorf("failed to create ksuid: %w", err)
 	}
 
 	Tier.TierID = ksuid
-	pk, sk := createTierCompositeKeys(orgID, serviceID, Tier.Name)
+	Tier.Version = 1
+	Tier.Status = TierStatusActive
+	pk, sk := createTierCompositeKeys(orgID, serviceID, Tier.TierID)
 
 	av, err := attributevalue.MarshalMap(Tier)
 	if err != nil {
-		return fmt.Errorf("failed to marshal Tier: %v", err)
+		return fmt.Errorf("failed to marshal Tier: %w", err)
 	}
 
 	item := map[string]types.AttributeValue{
@@ -76,22 +140,32 @@ func (d *TierDBClient) CreateTier(ctx context.Context, orgID, serviceID string,
 		item[k] = v
 	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String("Services"),
-		Item:      item,
+	audit, err := auditPut(ctx, orgID, "pricing_tier.created", AuditTargetPricingTier, Tier.TierID, nil, Tier)
+	if err != nil {
+		return err
 	}
 
-	_, err = d.Tier.PutItem(ctx, input)
+	items := []types.TransactWriteItem{
+		{
+			Put: &types.Put{
+				TableName: aws.String("Services"),
+				Item:      item,
+			},
+		},
+		audit,
+	}
+
+	_, err = d.Tier.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to put item in DynamoDB: %v", err)
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
 // GetTier retrieves a Tier by organization ID and Tier ID from the DynamoDB table.
-func (d *TierDBClient) GetTier(ctx context.Context, orgID, serviceID, name string) (*Tier, error) {
-	pk, sk := createTierCompositeKeys(orgID, serviceID, name)
+func (d *TierDBClient) GetTier(ctx context.Context, orgID, serviceID, tierID string) (*Tier, error) {
+	pk, sk := createTierCompositeKeys(orgID, serviceID, tierID)
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String("Services"),
 		Key: map[string]types.AttributeValue{
@@ -102,7 +176,7 @@ func (d *TierDBClient) GetTier(ctx context.Context, orgID, serviceID, name strin
 
 	result, err := d.Tier.GetItem(ctx, input)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
 	}
 
 	if result.Item == nil {
@@ -112,75 +186,275 @@ func (d *TierDBClient) GetTier(ctx context.Context, orgID, serviceID, name strin
 	var Tier Tier
 	err = attributevalue.UnmarshalMap(result.Item, &Tier)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
+	}
+
+	if Tier.Deleted {
+		return nil, nil
 	}
 
 	return &Tier, nil
 }
 
-// UpdateTier updates the name, description, and updatedAt fields of an existing Tier in the DynamoDB table.
+// UpdateTier updates the name, defaultRequestLimit and overagePrice fields of an existing Tier in the DynamoDB table.
+// The Tier must still be at the version of Tier, which is advanced to the next version. A *ConflictError is returned
+// otherwise.
 func (d *TierDBClient) UpdateTier(ctx context.Context, orgID, serviceID string, Tier *Tier) error {
-	pk, sk := createTierCompositeKeys(orgID, serviceID, Tier.Name)
+	current, err := d.GetTier(ctx, orgID, serviceID, Tier.TierID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "tier", ID: Tier.TierID}
+	}
+	if current.Version != Tier.Version {
+		return &ConflictError{Entity: "tier", ID: Tier.TierID, Version: Tier.Version}
+	}
+
+	pk, sk := createTierCompositeKeys(orgID, serviceID, Tier.TierID)
 
 	updateExpr := "SET #name = :name, #defaultRequestLimit = :defaultRequestLimit, #overagePrice = :overagePrice"
 	exprAttrNames := map[string]string{
-		"#name":                "name",
-		"#defaultRequestLimit": "defaultRequestLimit",
-		"#overagePrice":        "overagePrice",
+		"#name":                "Name",
+		"#defaultRequestLimit": "DefaultRequestLimit",
+		"#overagePrice":        "OveragePrice",
 	}
 
 	exprAttrValues := map[string]types.AttributeValue{
 		":name":                &types.AttributeValueMemberS{Value: Tier.Name},
 		":defaultRequestLimit": &types.AttributeValueMemberN{Value: strconv.Itoa(Tier.DefaultRequestLimit)},
+		":overagePrice":        &types.AttributeValueMemberN{Value: strconv.FormatFloat(float64(Tier.OveragePrice), 'f', -1, 32)},
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	updated := *current
+	updated.Name = Tier.Name
+	updated.DefaultRequestLimit = Tier.DefaultRequestLimit
+	updated.OveragePrice = Tier.OveragePrice
+	updated.Version = Tier.Version + 1
+
+	audit, err := auditPut(ctx, orgID, "pricing_tier.updated", AuditTargetPricingTier, Tier.TierID, current, &updated)
+	if err != nil {
+		return err
+	}
+
+	update := &types.Update{
 		TableName:                 aws.String("Services"),
 		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
 		UpdateExpression:          aws.String(updateExpr),
 		ExpressionAttributeNames:  exprAttrNames,
 		ExpressionAttributeValues: exprAttrValues,
 	}
+	withVersion(update, Tier.Version)
+
+	items := []types.TransactWriteItem{{Update: update}, audit}
 
-	_, err := d.Tier.UpdateItem(ctx, input)
+	_, err = d.Tier.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to update item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "tier", ID: Tier.TierID, Version: Tier.Version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
+	Tier.Version = updated.Version
 	return nil
 }
 
-// DeleteTier marks a Tier as deleted by organization ID and Tier ID in the DynamoDB table.
-func (d *TierDBClient) DeleteTier(ctx context.Context, orgID, serviceID, name string) error {
-	pk, sk := createTierCompositeKeys(orgID, serviceID, name)
+// DeleteTier marks a Tier as deleted by organization ID and Tier ID in the DynamoDB table. The Tier must still be at
+// the given version, and a *ConflictError is returned otherwise.
+func (d *TierDBClient) DeleteTier(ctx context.Context, orgID, serviceID, tierID string, version int64) error {
+	current, err := d.GetTier(ctx, orgID, serviceID, tierID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "tier", ID: tierID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "tier", ID: tierID, Version: version}
+	}
 
-	update := map[string]types.AttributeValueUpdate{
-		"Deleted": {
-			Value:  &types.AttributeValueMemberBOOL{Value: true},
-			Action: types.AttributeActionPut,
+	pk, sk := createTierCompositeKeys(orgID, serviceID, tierID)
+
+	audit, err := auditPut(ctx, orgID, "pricing_tier.deleted", AuditTargetPricingTier, tierID, current, nil)
+	if err != nil {
+		return err
+	}
+
+	update := &types.Update{
+		TableName: aws.String("Services"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
+		UpdateExpression:         aws.String("SET #deleted = :true"),
+		ConditionExpression:      aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		ExpressionAttributeNames: map[string]string{"#deleted": "Deleted"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":true": &types.AttributeValueMemberBOOL{Value: true},
+		},
+	}
+	withVersion(update, version)
+
+	items := []types.TransactWriteItem{{Update: update}, audit}
+
+	_, err = d.Tier.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "tier", ID: tierID, Version: version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// RetireTier starts deleting a Tier that is still assigned to actors: the Tier becomes retiring, so that no actor can
+// be assigned to it anymore, and its actors are then moved to reassignTo with ReassignTierActors before it is deleted
+// with DeleteTier. The transaction is canceled if reassignTo is not an active Tier, and a *ConflictError is returned
+// if the Tier is no longer at the given version, which is advanced to the next version.
+func (d *TierDBClient) RetireTier(ctx context.Context, orgID, serviceID, tierID string, version int64, reassignTo string) error {
+	current, err := d.GetTier(ctx, orgID, serviceID, tierID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return &NotFoundError{Entity: "tier", ID: tierID}
+	}
+	if current.Version != version {
+		return &ConflictError{Entity: "tier", ID: tierID, Version: version}
+	}
+
+	updated := *current
+	updated.Status = TierStatusRetiring
+	updated.ReassignTo = reassignTo
+	updated.Version = version + 1
+
+	audit, err := auditPut(ctx, orgID, "pricing_tier.retiring", AuditTargetPricingTier, tierID, current, &updated)
+	if err != nil {
+		return err
 	}
 
-	input := &dynamodb.UpdateItemInput{
+	pk, sk := createTierCompositeKeys(orgID, serviceID, tierID)
+	update := &types.Update{
 		TableName: aws.String("Services"),
 		Key: map[string]types.AttributeValue{
 			"pk": &types.AttributeValueMemberS{Value: pk},
 			"sk": &types.AttributeValueMemberS{Value: sk},
 		},
-		AttributeUpdates:    update,
-		ConditionExpression: aws.String("attribute_exists(pk) AND attribute_exists(sk)"),
+		UpdateExpression:         aws.String("SET #status = :retiring, #reassignTo = :reassignTo"),
+		ConditionExpression:      aws.String("attribute_exists(pk)"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status", "#reassignTo": "ReassignTo"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":retiring":   &types.AttributeValueMemberS{Value: TierStatusRetiring},
+			":reassignTo": &types.AttributeValueMemberS{Value: reassignTo},
+		},
+	}
+	withVersion(update, version)
+
+	items := []types.TransactWriteItem{
+		tierAssignableCheck(orgID, serviceID, reassignTo),
+		{Update: update},
+		audit,
 	}
 
-	_, err := d.Tier.UpdateItem(ctx, input)
+	_, err = d.Tier.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
 	if err != nil {
-		return fmt.Errorf("failed to delete item in DynamoDB: %v", err)
+		if isConditionFailed(err, 0) {
+			return &TierUnavailableError{ID: reassignTo}
+		}
+		if isConditionFailed(err, 1) {
+			return &ConflictError{Entity: "tier", ID: tierID, Version: version}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
 	}
 
 	return nil
 }
 
-// ListTiersByOrganization retrieves all Tiers for a specific organization from the DynamoDB table.
-func (d *TierDBClient) ListTiers(ctx context.Context, orgID, serviceID string) ([]Tier, error) {
+// ReassignTierActors moves the given actors from a retiring Tier to the Tier it retires to in a single transaction,
+// which is canceled if the Tier is no longer retiring to reassignTo, reassignTo is no longer active, or any of the
+// actors has been moved off the Tier in the meantime. The audit event records which actors were moved.
+func (d *TierDBClient) ReassignTierActors(ctx context.Context, orgID, serviceID, tierID, reassignTo string, actorExternalIDs []string) error {
+	if len(actorExternalIDs) > MaxReassignedActors {
+		return fmt.Errorf("cannot reassign more than %d actors in one transaction", MaxReassignedActors)
+	}
+
+	reassignment := struct {
+		ReassignedTo     string   `json:"reassignedTo"`
+		ReassignedActors []string `json:"reassignedActors"`
+	}{reassignTo, actorExternalIDs}
+	audit, err := auditPut(ctx, orgID, "pricing_tier.actors_reassigned", AuditTargetPricingTier, tierID, nil, reassignment)
+	if err != nil {
+		return err
+	}
+
+	tierPK, tierSK := createTierCompositeKeys(orgID, serviceID, tierID)
+	items := []types.TransactWriteItem{
+		{
+			ConditionCheck: &types.ConditionCheck{
+				TableName: aws.String("Services"),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: tierPK},
+					"sk": &types.AttributeValueMemberS{Value: tierSK},
+				},
+				ConditionExpression:      aws.String("#status = :retiring AND #reassignTo = :reassignTo"),
+				ExpressionAttributeNames: map[string]string{"#status": "Status", "#reassignTo": "ReassignTo"},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":retiring":   &types.AttributeValueMemberS{Value: TierStatusRetiring},
+					":reassignTo": &types.AttributeValueMemberS{Value: reassignTo},
+				},
+			},
+		},
+		tierAssignableCheck(orgID, serviceID, reassignTo),
+	}
+
+	for _, externalID := range actorExternalIDs {
+		actorPK, actorSK := createActorCompositeKeys(orgID, serviceID, externalID)
+		items = append(items, types.TransactWriteItem{
+			Update: &types.Update{
+				TableName: aws.String("Services"),
+				Key: map[string]types.AttributeValue{
+					"pk": &types.AttributeValueMemberS{Value: actorPK},
+					"sk": &types.AttributeValueMemberS{Value: actorSK},
+				},
+				UpdateExpression:    aws.String("SET #billingInfo.#tierId = :reassignTo, #tierPK = :tierPK ADD #version :one"),
+				ConditionExpression: aws.String("#billingInfo.#tierId = :tierId"),
+				ExpressionAttributeNames: map[string]string{
+					"#billingInfo": "BillingInfo",
+					"#tierId":      "TierID",
+					"#tierPK":      "TierPK",
+					"#version":     "Version",
+				},
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":tierId":     &types.AttributeValueMemberS{Value: tierID},
+					":reassignTo": &types.AttributeValueMemberS{Value: reassignTo},
+					":tierPK":     &types.AttributeValueMemberS{Value: createActorTierGSI(orgID, serviceID, reassignTo)},
+					":one":        &types.AttributeValueMemberN{Value: "1"},
+				},
+			},
+		})
+	}
+
+	items = append(items, audit)
+
+	_, err = d.Tier.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		if isConditionFailed(err, 0) {
+			return &ConflictError{Entity: "tier", ID: tierID}
+		}
+		if isConditionFailed(err, 1) {
+			return &TierUnavailableError{ID: reassignTo}
+		}
+		return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// ListTiers retrieves a page of the pricing tiers of a service from the DynamoDB table, along with the cursor of the
+// next page.
+func (d *TierDBClient) ListTiers(ctx context.Context, orgID, serviceID string, page Page) ([]Tier, string, error) {
 	pk, _ := createTierCompositeKeys(orgID, serviceID, "")
 	input := &dynamodb.QueryInput{
 		TableName:              aws.String("Services"),
@@ -192,16 +466,7 @@ func (d *TierDBClient) ListTiers(ctx context.Context, orgID, serviceID string) (
 		},
 	}
 
-	result, err := d.Tier.Query(ctx, input)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query items in DynamoDB: %v", err)
-	}
-
-	var Tiers []Tier
-	err = attributevalue.UnmarshalListOfMaps(result.Items, &Tiers)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %v", err)
-	}
-
-	return Tiers, nil
+	return queryPage(ctx, d.Tier, d.cursors, input, pk, page, func(tier *Tier) bool {
+		return !tier.Deleted
+	})
 }
@@ -19,7 +19,7 @@ func TestCreateTier(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewTierDBClient(mockSvc)
+	client := dal.NewTierDBClient(mockSvc, cursors)
 
 	Tier := &dal.Tier{
 		TierID:              "12342341234",
@@ -30,8 +30,12 @@ func TestCreateTier(t *testing.T) {
 	}
 
 	mockSvc.EXPECT().
-		PutItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.PutItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, "Org#org1Service#serv1Tier", input.TransactItems[0].Put.Item["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "pricing_tier.created", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
 	err := client.CreateTier(context.Background(), "org1", "serv1", Tier)
 	assert.NoError(t, err)
@@ -42,7 +46,7 @@ func TestGetTier(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewTierDBClient(mockSvc)
+	client := dal.NewTierDBClient(mockSvc, cursors)
 
 	Tier := dal.Tier{
 		TierID:              "12342341234",
@@ -60,7 +64,25 @@ func TestGetTier(t *testing.T) {
 	result, err := client.GetTier(context.Background(), "org1", "serv1", "Tier1")
 	assert.NoError(t, err)
 	assert.NotNil(t, result)
-	assert.Equal(t, "Tier1", result.TierID)
+	assert.Equal(t, "12342341234", result.TierID)
+	assert.Equal(t, "Steve", result.Name)
+}
+
+func TestGetTier_Deleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Tier{TierID: "12342341234", Deleted: true})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	result, err := client.GetTier(context.Background(), "org1", "serv1", "12342341234")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
 }
 
 func TestUpdateTier(t *testing.T) {
@@ -68,7 +90,7 @@ func TestUpdateTier(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewTierDBClient(mockSvc)
+	client := dal.NewTierDBClient(mockSvc, cursors)
 
 	Tier := &dal.Tier{
 		TierID:              "12342341234",
@@ -78,17 +100,30 @@ func TestUpdateTier(t *testing.T) {
 		Interval:            1,
 	}
 
+	item, _ := attributevalue.MarshalMap(dal.Tier{TierID: "12342341234", Name: "Steve", DefaultRequestLimit: 1000})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		DoAndReturn(func(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
-			assert.Equal(t, "Service#serv1", input.Key["pk"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "Tier#Tier1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "12342341234", input.ExpressionAttributeValues[":externalId"].(*types.AttributeValueMemberS).Value)
-			assert.Equal(t, "1000000", input.ExpressionAttributeValues[":monthlyRequestLimit"].(*types.AttributeValueMemberN).Value)
-			assert.Equal(t, "SET #externalId = :externalId, #monthlyRequestLimit = :monthlyRequestLimit", *input.UpdateExpression)
-			assert.Equal(t, "ExternalId", input.ExpressionAttributeNames["#externalId"])
-			assert.Equal(t, "MonthlyRequestLimit", input.ExpressionAttributeNames["#monthlyRequestLimit"])
-			return &dynamodb.UpdateItemOutput{}, nil
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, transaction *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			input := transaction.TransactItems[0].Update
+			assert.Equal(t, "Org#org1Service#serv1Tier", input.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Tier#12342341234", input.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Steve", input.ExpressionAttributeValues[":name"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "1000000", input.ExpressionAttributeValues[":defaultRequestLimit"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "1", input.ExpressionAttributeValues[":overagePrice"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "SET #name = :name, #defaultRequestLimit = :defaultRequestLimit, #overagePrice = :overagePrice, #version = :nextVersion", *input.UpdateExpression)
+			assert.Equal(t, "DefaultRequestLimit", input.ExpressionAttributeNames["#defaultRequestLimit"])
+			assert.Equal(t, "OveragePrice", input.ExpressionAttributeNames["#overagePrice"])
+
+			event := auditEvent(t, transaction.TransactItems[1])
+			assert.Equal(t, "pricing_tier.updated", event.Action)
+			assert.Contains(t, event.Before, `"defaultRequestLimit":1000`)
+			assert.Contains(t, event.After, `"defaultRequestLimit":1000000`)
+			assert.NotContains(t, event.After, "Steve")
+			return &dynamodb.TransactWriteItemsOutput{}, nil
 		})
 
 	err := client.UpdateTier(context.Background(), "org1", "serv1", Tier)
@@ -100,22 +135,135 @@ func TestDeleteTier(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewTierDBClient(mockSvc)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Tier{TierID: "Tier1", Name: "Steve", DefaultRequestLimit: 1000})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
 
 	mockSvc.EXPECT().
-		UpdateItem(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.UpdateItemOutput{}, nil)
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, "SET #deleted = :true, #version = :nextVersion", *input.TransactItems[0].Update.UpdateExpression)
+			assert.Equal(t, "pricing_tier.deleted", auditEvent(t, input.TransactItems[1]).Action)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
 
-	err := client.DeleteTier(context.Background(), "org1", "serv1", "Tier1")
+	err := client.DeleteTier(context.Background(), "org1", "serv1", "Tier1", 0)
 	assert.NoError(t, err)
 }
 
+func TestRetireTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	item, _ := attributevalue.MarshalMap(dal.Tier{TierID: "tier1", Name: "Steve", Status: dal.TierStatusActive, Version: 3})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil).
+		Times(2)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 3, len(input.TransactItems))
+
+			// The tier that actors move to must be active
+			check := input.TransactItems[0].ConditionCheck
+			assert.Equal(t, "Tier#tier2", check.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Contains(t, *check.ConditionExpression, "#status <> :retiring")
+
+			update := input.TransactItems[1].Update
+			assert.Equal(t, "Tier#tier1", update.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "SET #status = :retiring, #reassignTo = :reassignTo, #version = :nextVersion", *update.UpdateExpression)
+			assert.Equal(t, "attribute_exists(pk) AND #version = :version", *update.ConditionExpression)
+			assert.Equal(t, "tier2", update.ExpressionAttributeValues[":reassignTo"].(*types.AttributeValueMemberS).Value)
+
+			event := auditEvent(t, input.TransactItems[2])
+			assert.Equal(t, "pricing_tier.retiring", event.Action)
+			assert.Contains(t, event.After, `"status":"retiring"`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.RetireTier(context.Background(), "org1", "serv1", "tier1", 3, "tier2")
+	assert.NoError(t, err)
+
+	// The tier that actors move to was deleted or started retiring
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	err = client.RetireTier(context.Background(), "org1", "serv1", "tier1", 3, "tier2")
+	var unavailable *dal.TierUnavailableError
+	assert.ErrorAs(t, err, &unavailable)
+	assert.Equal(t, "tier2", unavailable.ID)
+}
+
+func TestReassignTierActors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			assert.Equal(t, 5, len(input.TransactItems))
+
+			// The tier must still be retiring to the same tier
+			retiring := input.TransactItems[0].ConditionCheck
+			assert.Equal(t, "Tier#tier1", retiring.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "#status = :retiring AND #reassignTo = :reassignTo", *retiring.ConditionExpression)
+
+			check := input.TransactItems[1].ConditionCheck
+			assert.Equal(t, "Org#org1Service#serv1Tier", check.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Tier#tier2", check.Key["sk"].(*types.AttributeValueMemberS).Value)
+
+			for i, externalID := range []string{"actor1", "actor2"} {
+				update := input.TransactItems[i+2].Update
+				assert.Equal(t, "Org#org1Service#serv1Actor", update.Key["pk"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "Actor#"+externalID, update.Key["sk"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "SET #billingInfo.#tierId = :reassignTo, #tierPK = :tierPK ADD #version :one", *update.UpdateExpression)
+				assert.Equal(t, "#billingInfo.#tierId = :tierId", *update.ConditionExpression)
+				assert.Equal(t, "tier1", update.ExpressionAttributeValues[":tierId"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "tier2", update.ExpressionAttributeValues[":reassignTo"].(*types.AttributeValueMemberS).Value)
+				assert.Equal(t, "Org#org1Service#serv1Tier#tier2", update.ExpressionAttributeValues[":tierPK"].(*types.AttributeValueMemberS).Value)
+			}
+
+			event := auditEvent(t, input.TransactItems[4])
+			assert.Equal(t, "pricing_tier.actors_reassigned", event.Action)
+			assert.Equal(t, "tier1", event.TargetID)
+			assert.Contains(t, event.After, `"reassignedTo":"tier2"`)
+			assert.Contains(t, event.After, `"reassignedActors":["actor1","actor2"]`)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		})
+
+	err := client.ReassignTierActors(context.Background(), "org1", "serv1", "tier1", "tier2", []string{"actor1", "actor2"})
+	assert.NoError(t, err)
+}
+
+func TestReassignTierActors_TooManyActors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTierDBClient(mockSvc, cursors)
+
+	err := client.ReassignTierActors(context.Background(), "org1", "serv1", "tier1", "tier2", make([]string, dal.MaxReassignedActors+1))
+	assert.Error(t, err)
+}
+
 func TestListTiersByServiceanization(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
-	client := dal.NewTierDBClient(mockSvc)
+	client := dal.NewTierDBClient(mockSvc, cursors)
 
 	Tier := dal.Tier{
 		TierID:              "12342341234",
@@ -126,13 +274,15 @@ func TestListTiersByServiceanization(t *testing.T) {
 	}
 
 	item, _ := attributevalue.MarshalMap(Tier)
+	deleted, _ := attributevalue.MarshalMap(dal.Tier{TierID: "deleted", Deleted: true})
 	mockSvc.EXPECT().
 		Query(gomock.Any(), gomock.Any()).
-		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil)
+		Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item, deleted}}, nil)
 
-	result, err := client.ListTiers(context.Background(), "org1", "serv1")
+	result, cursor, err := client.ListTiers(context.Background(), "org1", "serv1", dal.Page{})
 	assert.NoError(t, err)
+	assert.Empty(t, cursor)
 	assert.NotNil(t, result)
 	assert.Equal(t, 1, len(result))
-	assert.Equal(t, "Tier1", result[0].TierID)
+	assert.Equal(t, "12342341234", result[0].TierID)
 }
@@ -0,0 +1,175 @@
+package dal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/mock_unit_of_work.go "github.com/payloadops/lanyard/app/dal" TransactionManager
+
+// MaxTransactionItems is the number of items that a DynamoDB transaction writes at most.
+const MaxTransactionItems = 100
+
+// TransactionManager defines the operations available for committing units of work.
+type TransactionManager interface {
+	Commit(ctx context.Context, unit *UnitOfWork) error
+}
+
+// Ensure TransactionDBClient implements the TransactionManager interface
+var _ TransactionManager = &TransactionDBClient{}
+
+// UnitOfWork collects the writes of several managers so that they are committed together. The items of a write are
+// always committed in the same transaction, and writes are committed in the order they were added, in as few
+// transactions as MaxTransactionItems allows. A unit of work with more items than that spans several transactions,
+// which are not committed atomically.
+type UnitOfWork struct {
+	writes []unitWrite
+	items  int
+}
+
+// unitWrite is a write of a unit of work, whose items are committed in the same transaction.
+type unitWrite struct {
+	items []types.TransactWriteItem
+	// conflict is returned instead of the canceled transaction when the condition of the first item fails.
+	conflict error
+}
+
+// NewUnitOfWork creates an empty UnitOfWork.
+func NewUnitOfWork() *UnitOfWork {
+	return &UnitOfWork{}
+}
+
+// Put adds a put of an item to the unit of work.
+func (u *UnitOfWork) Put(put *types.Put) {
+	u.writes = append(u.writes, unitWrite{items: []types.TransactWriteItem{{Put: put}}})
+	u.items++
+}
+
+// Update adds an update of an item to the unit of work.
+func (u *UnitOfWork) Update(update *types.Update) {
+	u.writes = append(u.writes, unitWrite{items: []types.TransactWriteItem{{Update: update}}})
+	u.items++
+}
+
+// ConditionCheck adds a condition check of an item to the unit of work. As the check guards no other write of the
+// unit, it only fails the transaction that it is committed in. Checks that guard other writes are added along with
+// them by Write.
+func (u *UnitOfWork) ConditionCheck(check *types.ConditionCheck) {
+	u.writes = append(u.writes, unitWrite{items: []types.TransactWriteItem{{ConditionCheck: check}}})
+	u.items++
+}
+
+// Write adds items to the unit of work that are committed in the same transaction.
+func (u *UnitOfWork) Write(items ...types.TransactWriteItem) error {
+	return u.write(nil, items)
+}
+
+// write adds items to the unit of work that are committed in the same transaction. The conflict error is returned if
+// the condition of the first item fails.
+func (u *UnitOfWork) write(conflict error, items []types.TransactWriteItem) error {
+	if len(items) > MaxTransactionItems {
+		return fmt.Errorf("a write of %d items exceeds the limit of %d items of a transaction", len(items), MaxTransactionItems)
+	}
+
+	u.writes = append(u.writes, unitWrite{items: items, conflict: conflict})
+	u.items += len(items)
+	return nil
+}
+
+// Len returns the number of items that the unit of work writes.
+func (u *UnitOfWork) Len() int {
+	return u.items
+}
+
+// transactions splits the writes of the unit of work into transactions, in order.
+func (u *UnitOfWork) transactions() [][]unitWrite {
+	var transactions [][]unitWrite
+	var transaction []unitWrite
+	items := 0
+	for _, write := range u.writes {
+		if items+len(write.items) > MaxTransactionItems {
+			transactions = append(transactions, transaction)
+			transaction, items = nil, 0
+		}
+		transaction = append(transaction, write)
+		items += len(write.items)
+	}
+	if len(transaction) > 0 {
+		transactions = append(transactions, transaction)
+	}
+	return transactions
+}
+
+// PartialCommitError is returned when a unit of work that spans several transactions fails after some of them were
+// committed. The committed transactions are not rolled back, so the writes added first to the unit of work are
+// persisted while the others are not.
+type PartialCommitError struct {
+	Committed int
+	Total     int
+	Err       error
+}
+
+func (e *PartialCommitError) Error() string {
+	return fmt.Sprintf("committed %d of %d transactions: %v", e.Committed, e.Total, e.Err)
+}
+
+func (e *PartialCommitError) Unwrap() error {
+	return e.Err
+}
+
+// commitUnit commits the writes of a unit of work. A *PartialCommitError is returned when a transaction other than the
+// first one fails.
+func commitUnit(ctx context.Context, client DynamoDBAPI, unit *UnitOfWork) error {
+	transactions := unit.transactions()
+	for i, transaction := range transactions {
+		var items []types.TransactWriteItem
+		for _, write := range transaction {
+			items = append(items, write.items...)
+		}
+
+		_, err := client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err != nil {
+			err = transactionError(transaction, err)
+			if i > 0 {
+				return &PartialCommitError{Committed: i, Total: len(transactions), Err: err}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transactionError returns the error of a failed transaction, which is the conflict error of the write whose
+// condition failed when it has one.
+func transactionError(transaction []unitWrite, err error) error {
+	index := 0
+	for _, write := range transaction {
+		if write.conflict != nil && isConditionFailed(err, index) {
+			return write.conflict
+		}
+		index += len(write.items)
+	}
+	return fmt.Errorf("failed to write transaction in DynamoDB: %w", err)
+}
+
+// TransactionDBClient is a client for committing units of work to DynamoDB.
+type TransactionDBClient struct {
+	client DynamoDBAPI
+}
+
+// NewTransactionDBClient creates a new TransactionDBClient.
+func NewTransactionDBClient(client DynamoDBAPI) *TransactionDBClient {
+	return &TransactionDBClient{
+		client: withClassifiedErrors(client),
+	}
+}
+
+// Commit commits the writes of a unit of work in as few transactions as possible. A *PartialCommitError is returned
+// when the unit of work spans several transactions and fails after committing some of them.
+func (d *TransactionDBClient) Commit(ctx context.Context, unit *UnitOfWork) error {
+	return commitUnit(ctx, d.client, unit)
+}
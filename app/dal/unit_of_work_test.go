@@ -0,0 +1,158 @@
+package dal_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// put returns a transaction item that puts an item with the given key.
+func put(key string) types.TransactWriteItem {
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName: aws.String("Things"),
+		Item:      map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: key}},
+	}}
+}
+
+// putKey returns the key of the item that a transaction item puts.
+func putKey(item types.TransactWriteItem) string {
+	return item.Put.Item["pk"].(*types.AttributeValueMemberS).Value
+}
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTransactionDBClient(mockSvc)
+
+	// 60 writes of two items each are committed in a full transaction of 50 writes and one of the 10 remaining writes,
+	// without splitting the items of a write
+	unit := dal.NewUnitOfWork()
+	for i := 0; i < 60; i++ {
+		assert.NoError(t, unit.Write(put(fmt.Sprintf("thing%d", i)), put(fmt.Sprintf("thing%d#audit", i))))
+	}
+	assert.Equal(t, 120, unit.Len())
+
+	var committed []int
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.TransactWriteItemsInput, opts ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			committed = append(committed, len(input.TransactItems))
+			assert.Equal(t, fmt.Sprintf("thing%d", len(committed)*50-50), putKey(input.TransactItems[0]))
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}).
+		Times(2)
+
+	err := client.Commit(context.Background(), unit)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{100, 20}, committed)
+
+	// An empty unit of work commits nothing
+	err = client.Commit(context.Background(), dal.NewUnitOfWork())
+	assert.NoError(t, err)
+}
+
+func TestUnitOfWork_WriteTooLarge(t *testing.T) {
+	items := make([]types.TransactWriteItem, dal.MaxTransactionItems+1)
+	for i := range items {
+		items[i] = put(fmt.Sprintf("thing%d", i))
+	}
+
+	unit := dal.NewUnitOfWork()
+	assert.Error(t, unit.Write(items...))
+	assert.Equal(t, 0, unit.Len())
+
+	assert.NoError(t, unit.Write(items[:dal.MaxTransactionItems]...))
+	assert.Equal(t, dal.MaxTransactionItems, unit.Len())
+}
+
+func TestUnitOfWork_PartialCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewTransactionDBClient(mockSvc)
+
+	unit := dal.NewUnitOfWork()
+	for i := 0; i < dal.MaxTransactionItems+1; i++ {
+		unit.Put(put(fmt.Sprintf("thing%d", i)).Put)
+	}
+
+	// The first transaction is committed, and the second one is throttled
+	gomock.InOrder(
+		mockSvc.EXPECT().TransactWriteItems(gomock.Any(), gomock.Any()).Return(&dynamodb.TransactWriteItemsOutput{}, nil),
+		mockSvc.EXPECT().TransactWriteItems(gomock.Any(), gomock.Any()).Return(nil, &types.ProvisionedThroughputExceededException{}),
+	)
+
+	err := client.Commit(context.Background(), unit)
+	var partial *dal.PartialCommitError
+	assert.ErrorAs(t, err, &partial)
+	assert.Equal(t, 1, partial.Committed)
+	assert.Equal(t, 2, partial.Total)
+	assert.ErrorIs(t, err, dal.ErrThrottled)
+
+	// A unit of work that fails in its first transaction leaves nothing committed
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("dynamodb error"))
+
+	err = client.Commit(context.Background(), unit)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &partial))
+}
+
+func TestUnitOfWork_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	serviceClient := dal.NewServiceDBClient(mockSvc, cursors)
+	client := dal.NewTransactionDBClient(mockSvc)
+
+	item, _ := attributevalue.MarshalMap(dal.Service{ServiceID: "serv1", Version: 2})
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+	unit := dal.NewUnitOfWork()
+	unit.Put(put("thing1").Put)
+	assert.NoError(t, serviceClient.StageDeleteService(context.Background(), unit, "org1", "serv1", 2))
+	assert.Equal(t, 3, unit.Len())
+
+	// The service was changed between the read and the write
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, &types.TransactionCanceledException{
+			CancellationReasons: []types.CancellationReason{
+				{Code: aws.String("None")},
+				{Code: aws.String("ConditionalCheckFailed")},
+				{Code: aws.String("None")},
+			},
+		})
+
+	err := client.Commit(context.Background(), unit)
+	var conflict *dal.ConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "serv1", conflict.ID)
+
+	// The condition of a put that guards no other write is a failed precondition
+	mockSvc.EXPECT().
+		TransactWriteItems(gomock.Any(), gomock.Any()).
+		Return(nil, conditionFailed())
+
+	err = client.Commit(context.Background(), unit)
+	assert.False(t, errors.As(err, &conflict))
+	assert.ErrorIs(t, err, dal.ErrPrecondition)
+}
@@ -0,0 +1,167 @@
+package dal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+//go:generate mockgen -package=mocks -destination=mocks/mock_usage_db_client.go "github.com/payloadops/lanyard/app/dal" UsageManager
+
+// UsageManager defines the operations available for metering usage.
+type UsageManager interface {
+	IncrementUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject, subjectID string, count, overage int64) error
+	GetUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject, subjectID string) (*Usage, error)
+	ListUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject) ([]Usage, error)
+}
+
+// Ensure UsageDBClient implements the UsageManager interface
+var _ UsageManager = &UsageDBClient{}
+
+// UsageSubject is a string enum for what a usage counter is counting requests for.
+type UsageSubject string
+
+const (
+	UsageSubjectActor  UsageSubject = "Actor"
+	UsageSubjectAPIKey UsageSubject = "APIKey"
+)
+
+// Usage represents the number of authorized requests made by an actor or API key in a calendar month, and how
+// many of them exceeded the actor's monthly request limit.
+type Usage struct {
+	OrgID     string       `json:"orgId"`
+	ServiceID string       `json:"serviceId"`
+	Period    string       `json:"period"`
+	Subject   UsageSubject `json:"subject"`
+	SubjectID string       `json:"subjectId"`
+	Count     int64        `json:"count"`
+	Overage   int64        `json:"overage"`
+	UpdatedAt string       `json:"updatedAt"`
+}
+
+// UsageDBClient is a client for interacting with DynamoDB for usage-related operations.
+type UsageDBClient struct {
+	service DynamoDBAPI
+}
+
+// NewUsageDBClient creates a new UsageDBClient.
+func NewUsageDBClient(service DynamoDBAPI) *UsageDBClient {
+	return &UsageDBClient{
+		service: withClassifiedErrors(service),
+	}
+}
+
+// createUsageCompositeKeys generates the partition key (pk) and sort key (sk) for a usage counter.
+func createUsageCompositeKeys(orgID, serviceID, period string, subject UsageSubject, subjectID string) (string, string) {
+	return "Org#" + orgID + "Service#" + serviceID + "Usage#" + period, string(subject) + "#" + subjectID
+}
+
+// IncrementUsage atomically adds count and overage to a usage counter in the DynamoDB table, creating it if it does
+// not exist.
+func (d *UsageDBClient) IncrementUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject, subjectID string, count, overage int64) error {
+	pk, sk := createUsageCompositeKeys(orgID, serviceID, period, subject, subjectID)
+
+	updateExpr := "ADD #count :count, #overage :overage SET #orgId = :orgId, #serviceId = :serviceId, #period = :period, #subject = :subject, #subjectId = :subjectId, #updatedAt = :updatedAt"
+	exprAttrNames := map[string]string{
+		"#count":     "Count",
+		"#overage":   "Overage",
+		"#orgId":     "OrgID",
+		"#serviceId": "ServiceID",
+		"#period":    "Period",
+		"#subject":   "Subject",
+		"#subjectId": "SubjectID",
+		"#updatedAt": "UpdatedAt",
+	}
+
+	exprAttrValues := map[string]types.AttributeValue{
+		":count":     &types.AttributeValueMemberN{Value: strconv.FormatInt(count, 10)},
+		":overage":   &types.AttributeValueMemberN{Value: strconv.FormatInt(overage, 10)},
+		":orgId":     &types.AttributeValueMemberS{Value: orgID},
+		":serviceId": &types.AttributeValueMemberS{Value: serviceID},
+		":period":    &types.AttributeValueMemberS{Value: period},
+		":subject":   &types.AttributeValueMemberS{Value: string(subject)},
+		":subjectId": &types.AttributeValueMemberS{Value: subjectID},
+		":updatedAt": &types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+	}
+
+	input := &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("Services"),
+		Key:                       map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: pk}, "sk": &types.AttributeValueMemberS{Value: sk}},
+		UpdateExpression:          aws.String(updateExpr),
+		ExpressionAttributeNames:  exprAttrNames,
+		ExpressionAttributeValues: exprAttrValues,
+	}
+
+	_, err := d.service.UpdateItem(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to update item in DynamoDB: %w", err)
+	}
+
+	return nil
+}
+
+// GetUsage retrieves a usage counter from the DynamoDB table.
+func (d *UsageDBClient) GetUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject, subjectID string) (*Usage, error) {
+	pk, sk := createUsageCompositeKeys(orgID, serviceID, period, subject, subjectID)
+	input := &dynamodb.GetItemInput{
+		TableName: aws.String("Services"),
+		Key: map[string]types.AttributeValue{
+			"pk": &types.AttributeValueMemberS{Value: pk},
+			"sk": &types.AttributeValueMemberS{Value: sk},
+		},
+	}
+
+	result, err := d.service.GetItem(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get item from DynamoDB: %w", err)
+	}
+
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var usage Usage
+	err = attributevalue.UnmarshalMap(result.Item, &usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal item from DynamoDB: %w", err)
+	}
+
+	return &usage, nil
+}
+
+// ListUsage retrieves every usage counter of the given subject type for a service in a period from the DynamoDB table.
+func (d *UsageDBClient) ListUsage(ctx context.Context, orgID, serviceID, period string, subject UsageSubject) ([]Usage, error) {
+	pk, sk := createUsageCompositeKeys(orgID, serviceID, period, subject, "")
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String("Services"),
+		KeyConditionExpression: aws.String("pk = :pk AND begins_with(sk, :sk)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pk},
+			":sk": &types.AttributeValueMemberS{Value: sk},
+		},
+	}
+
+	var usages []Usage
+	paginator := dynamodb.NewQueryPaginator(d.service, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query items in DynamoDB: %w", err)
+		}
+
+		var items []Usage
+		err = attributevalue.UnmarshalListOfMaps(page.Items, &items)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal items from DynamoDB: %w", err)
+		}
+		usages = append(usages, items...)
+	}
+
+	return usages, nil
+}
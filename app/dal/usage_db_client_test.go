@@ -0,0 +1,119 @@
+package dal_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIncrementUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewUsageDBClient(mockSvc)
+
+	mockSvc.EXPECT().
+		UpdateItem(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.UpdateItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			assert.Equal(t, "Services", *input.TableName)
+			assert.Equal(t, "Org#org1Service#serv1Usage#2024-01", input.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Actor#actor1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
+			assert.Contains(t, *input.UpdateExpression, "ADD #count :count, #overage :overage")
+			assert.Equal(t, "Count", input.ExpressionAttributeNames["#count"])
+			assert.Equal(t, "Overage", input.ExpressionAttributeNames["#overage"])
+			assert.Equal(t, "42", input.ExpressionAttributeValues[":count"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "2", input.ExpressionAttributeValues[":overage"].(*types.AttributeValueMemberN).Value)
+			assert.Equal(t, "Actor", input.ExpressionAttributeValues[":subject"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "actor1", input.ExpressionAttributeValues[":subjectId"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.UpdateItemOutput{}, nil
+		})
+
+	err := client.IncrementUsage(context.Background(), "org1", "serv1", "2024-01", dal.UsageSubjectActor, "actor1", 42, 2)
+	assert.NoError(t, err)
+}
+
+func TestGetUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewUsageDBClient(mockSvc)
+
+	usage := dal.Usage{
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Period:    "2024-01",
+		Subject:   dal.UsageSubjectAPIKey,
+		SubjectID: "key1",
+		Count:     7,
+	}
+
+	item, _ := attributevalue.MarshalMap(usage)
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.GetItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			assert.Equal(t, "Org#org1Service#serv1Usage#2024-01", input.Key["pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "APIKey#key1", input.Key["sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.GetItemOutput{Item: item}, nil
+		})
+
+	result, err := client.GetUsage(context.Background(), "org1", "serv1", "2024-01", dal.UsageSubjectAPIKey, "key1")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Equal(t, int64(7), result.Count)
+}
+
+func TestGetUsage_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewUsageDBClient(mockSvc)
+
+	mockSvc.EXPECT().
+		GetItem(gomock.Any(), gomock.Any()).
+		Return(&dynamodb.GetItemOutput{}, nil)
+
+	result, err := client.GetUsage(context.Background(), "org1", "serv1", "2024-01", dal.UsageSubjectActor, "actor1")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+}
+
+func TestListUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := mocks.NewMockDynamoDBAPI(ctrl)
+	client := dal.NewUsageDBClient(mockSvc)
+
+	usage := dal.Usage{
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Period:    "2024-01",
+		Subject:   dal.UsageSubjectActor,
+		SubjectID: "actor1",
+		Count:     12,
+		Overage:   2,
+	}
+
+	item, _ := attributevalue.MarshalMap(usage)
+	mockSvc.EXPECT().
+		Query(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, input *dynamodb.QueryInput, opts ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			assert.Equal(t, "Org#org1Service#serv1Usage#2024-01", input.ExpressionAttributeValues[":pk"].(*types.AttributeValueMemberS).Value)
+			assert.Equal(t, "Actor#", input.ExpressionAttributeValues[":sk"].(*types.AttributeValueMemberS).Value)
+			return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}}, nil
+		})
+
+	result, err := client.ListUsage(context.Background(), "org1", "serv1", "2024-01", dal.UsageSubjectActor)
+	assert.NoError(t, err)
+	assert.Equal(t, []dal.Usage{usage}, result)
+}
@@ -0,0 +1,53 @@
+package dal
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ConflictError is returned when an entity is updated or deleted at a version it is no longer at, because it was
+// changed or deleted since that version was read. It matches ErrConflict.
+type ConflictError struct {
+	Entity  string
+	ID      string
+	Version int64
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s '%s' was changed since version %d", e.Entity, e.ID, e.Version)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// withVersion makes an update conditional on the item being at the given version, and sets the item to the next
+// version. Items written before entities were versioned have no version, and are at version 0. The update expression
+// must be a SET clause.
+func withVersion(update *types.Update, version int64) {
+	condition := "attribute_not_exists(#version)"
+	if version != 0 {
+		condition = "#version = :version"
+	}
+	if update.ConditionExpression != nil {
+		condition = aws.ToString(update.ConditionExpression) + " AND " + condition
+	}
+	update.ConditionExpression = aws.String(condition)
+	update.UpdateExpression = aws.String(aws.ToString(update.UpdateExpression) + ", #version = :nextVersion")
+
+	if update.ExpressionAttributeNames == nil {
+		update.ExpressionAttributeNames = map[string]string{}
+	}
+	update.ExpressionAttributeNames["#version"] = "Version"
+
+	if update.ExpressionAttributeValues == nil {
+		update.ExpressionAttributeValues = map[string]types.AttributeValue{}
+	}
+	if version != 0 {
+		update.ExpressionAttributeValues[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+	update.ExpressionAttributeValues[":nextVersion"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+//go:generate mockgen -source=events.go -package=mocks -destination=mocks/mock_events.go
+
+// Type identifies what happened to a resource.
+type Type string
+
+const (
+	// APIKeyExpired is emitted when an API key transitions to the expired status.
+	APIKeyExpired Type = "api_key.expired"
+	// APIKeyLeaked is emitted when a token of a live API key is reported leaked, after the key was revoked or
+	// quarantined according to the policy of its service.
+	APIKeyLeaked Type = "api_key.leaked"
+)
+
+// Event is a notable change of a resource, published for consumers outside of the request that caused it.
+type Event struct {
+	Type      Type   `json:"type"`
+	OrgID     string `json:"orgId"`
+	ServiceID string `json:"serviceId"`
+	APIKeyID  string `json:"apiKeyId,omitempty"`
+	// Action is what was done to the resource in response, such as revoking a leaked API key.
+	Action     string    `json:"action,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// Publisher delivers events to their consumers.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// Ensure LogPublisher implements the Publisher interface
+var _ Publisher = &LogPublisher{}
+
+// LogPublisher publishes events as structured log entries. It delivers nothing to the owners of the resources, so
+// it is only used when no webhook is configured, and events then only reach whoever reads the logs.
+type LogPublisher struct {
+	logger *zap.Logger
+}
+
+// NewLogPublisher creates a new LogPublisher.
+func NewLogPublisher(logger *zap.Logger) *LogPublisher {
+	return &LogPublisher{
+		logger: logger,
+	}
+}
+
+// Publish logs the event.
+func (p *LogPublisher) Publish(ctx context.Context, event Event) error {
+	p.logger.Info("event",
+		zap.String("type", string(event.Type)),
+		zap.String("orgID", event.OrgID),
+		zap.String("serviceID", event.ServiceID),
+		zap.String("apiKeyID", event.APIKeyID),
+		zap.String("action", event.Action),
+		zap.Time("occurredAt", event.OccurredAt),
+	)
+	return nil
+}
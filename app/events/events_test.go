@@ -0,0 +1,55 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/events"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogPublisher_Publish(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	publisher := events.NewLogPublisher(zap.New(core))
+
+	err := publisher.Publish(context.Background(), events.Event{
+		Type:       events.APIKeyExpired,
+		OrgID:      "org1",
+		ServiceID:  "serv1",
+		APIKeyID:   "key1",
+		OccurredAt: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "api_key.expired", fields["type"])
+	assert.Equal(t, "org1", fields["orgID"])
+	assert.Equal(t, "serv1", fields["serviceID"])
+	assert.Equal(t, "key1", fields["apiKeyID"])
+}
+
+func TestLogPublisher_PublishAction(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	publisher := events.NewLogPublisher(zap.New(core))
+
+	err := publisher.Publish(context.Background(), events.Event{
+		Type:       events.APIKeyLeaked,
+		OrgID:      "org1",
+		ServiceID:  "serv1",
+		APIKeyID:   "key1",
+		Action:     "revoked",
+		OccurredAt: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "api_key.leaked", fields["type"])
+	assert.Equal(t, "revoked", fields["action"])
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// WebhookTimestampHeader is the request header with the Unix time at which an event delivery was signed.
+	WebhookTimestampHeader = "X-Lanyard-Timestamp"
+	// WebhookSignatureHeader is the request header with the signature of an event delivery.
+	WebhookSignatureHeader = "X-Lanyard-Signature"
+)
+
+// webhookSignatureVersion prefixes the hex encoded signatures of event deliveries.
+const webhookSignatureVersion = "v1="
+
+// webhookTimeout bounds how long a single delivery attempt may take.
+const webhookTimeout = 5 * time.Second
+
+// webhookAttempts is how many times an event is delivered before publishing it fails.
+const webhookAttempts = 3
+
+// webhookRetryDelay is the delay before the second delivery attempt, doubled before every further attempt.
+const webhookRetryDelay = 200 * time.Millisecond
+
+// SignWebhook signs the body of an event delivery at the given time. The signature is the HMAC-SHA256 of the Unix
+// timestamp and the body joined by a dot, keyed with the webhook secret, so receivers can verify that a delivery
+// is authentic and recent.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+	return webhookSignatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Ensure WebhookPublisher implements the Publisher interface
+var _ Publisher = &WebhookPublisher{}
+
+// WebhookPublisher publishes events by POSTing them as JSON to a webhook, signed with a shared secret. Deliveries
+// that fail with a network error or a 429 or 5xx response are retried a few times before Publish fails.
+type WebhookPublisher struct {
+	url    string
+	secret string
+	client *http.Client
+	logger *zap.Logger
+}
+
+// NewWebhookPublisher creates a new WebhookPublisher for the given URL and signing secret.
+func NewWebhookPublisher(url, secret string, logger *zap.Logger) *WebhookPublisher {
+	return &WebhookPublisher{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: webhookTimeout},
+		logger: logger,
+	}
+}
+
+// Publish delivers the event to the webhook.
+func (p *WebhookPublisher) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delay := webhookRetryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := p.deliver(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookAttempts {
+			return fmt.Errorf("failed to deliver event %s: %w", event.Type, err)
+		}
+
+		p.logger.Warn("failed to deliver event, retrying",
+			zap.String("type", string(event.Type)),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("failed to deliver event %s: %w", event.Type, ctx.Err())
+		}
+		delay *= 2
+	}
+}
+
+// deliver makes a single delivery attempt, and reports whether a failed attempt may be retried.
+func (p *WebhookPublisher) deliver(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	now := time.Now()
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(p.secret, now, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWebhookPublisher_Publish(t *testing.T) {
+	var received events.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		// The delivery is signed with the webhook secret
+		unix, err := strconv.ParseInt(r.Header.Get(events.WebhookTimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, events.SignWebhook("secret", time.Unix(unix, 0), body), r.Header.Get(events.WebhookSignatureHeader))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher := events.NewWebhookPublisher(server.URL, "secret", zap.NewNop())
+	err := publisher.Publish(context.Background(), events.Event{
+		Type:       events.APIKeyLeaked,
+		OrgID:      "org1",
+		ServiceID:  "serv1",
+		APIKeyID:   "key1",
+		Action:     "revoked",
+		OccurredAt: time.Now(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, events.APIKeyLeaked, received.Type)
+	assert.Equal(t, "key1", received.APIKeyID)
+	assert.Equal(t, "revoked", received.Action)
+}
+
+func TestWebhookPublisher_Retry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		attempts int32
+		fails    bool
+	}{
+		{name: "Recovers from a server error", statuses: []int{http.StatusServiceUnavailable, http.StatusOK}, attempts: 2},
+		{name: "Gives up after repeated server errors", statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError}, attempts: 3, fails: true},
+		{name: "Client errors are not retried", statuses: []int{http.StatusBadRequest}, attempts: 1, fails: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempt := attempts.Add(1)
+				w.WriteHeader(tt.statuses[attempt-1])
+			}))
+			defer server.Close()
+
+			publisher := events.NewWebhookPublisher(server.URL, "secret", zap.NewNop())
+			err := publisher.Publish(context.Background(), events.Event{Type: events.APIKeyExpired, OrgID: "org1"})
+			if tt.fails {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.attempts, attempts.Load())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package expiry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/events"
+	"go.uber.org/zap"
+)
+
+// DefaultSweepInterval is how often expired API keys are swept when no interval is configured.
+const DefaultSweepInterval = 5 * time.Minute
+
+// Sweeper transitions API keys whose expiry has passed to the expired status and emits an event for each of them.
+// Expired keys are rejected as soon as their expiry passes whether or not they have been swept; sweeping makes the
+// expiry visible to consumers of the events and of the API. Several instances may sweep concurrently, since each key
+// is only transitioned, and its event only emitted, once.
+type Sweeper struct {
+	apiKeyClient dal.APIKeyManager
+	publisher    events.Publisher
+	logger       *zap.Logger
+}
+
+// NewSweeper creates a new Sweeper.
+func NewSweeper(apiKeyClient dal.APIKeyManager, publisher events.Publisher, logger *zap.Logger) *Sweeper {
+	return &Sweeper{
+		apiKeyClient: apiKeyClient,
+		publisher:    publisher,
+		logger:       logger,
+	}
+}
+
+// Sweep expires every API key whose expiry has passed and returns the number of keys it transitioned. Keys are read
+// and expired a page at a time, so that a large backlog of expired keys is never held in memory at once.
+func (s *Sweeper) Sweep(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+
+	var errs []error
+	expired := 0
+	page := dal.Page{Limit: dal.MaxPageLimit}
+	for {
+		apiKeys, cursor, err := s.apiKeyClient.ListExpiredAPIKeys(ctx, now, page)
+		if err != nil {
+			errs = append(errs, err)
+			return expired, errors.Join(errs...)
+		}
+
+		for _, apiKey := range apiKeys {
+			transitioned, err := s.apiKeyClient.ExpireAPIKey(ctx, apiKey.APIKeyID)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !transitioned {
+				continue
+			}
+			expired++
+
+			err = s.publisher.Publish(ctx, events.Event{
+				Type:       events.APIKeyExpired,
+				OrgID:      apiKey.OrgID,
+				ServiceID:  apiKey.ServiceID,
+				APIKeyID:   apiKey.APIKeyID,
+				OccurredAt: now,
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		if cursor == "" {
+			return expired, errors.Join(errs...)
+		}
+		page.Cursor = cursor
+	}
+}
+
+// Run sweeps expired API keys every interval until the context is cancelled.
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired, err := s.Sweep(ctx)
+			if err != nil {
+				s.logger.Error("failed to sweep expired API keys", zap.Error(err))
+			}
+			if expired > 0 {
+				s.logger.Info("expired API keys", zap.Int("count", expired))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
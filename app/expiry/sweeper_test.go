@@ -0,0 +1,90 @@
+package expiry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/events"
+	eventmocks "github.com/payloadops/lanyard/app/events/mocks"
+	"github.com/payloadops/lanyard/app/expiry"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestSweeper_Sweep(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockPublisher := eventmocks.NewMockPublisher(ctrl)
+	sweeper := expiry.NewSweeper(mockAPIKeyClient, mockPublisher, zap.NewNop())
+
+	ctx := context.Background()
+	// Every page of expired keys is swept
+	gomock.InOrder(
+		mockAPIKeyClient.EXPECT().ListExpiredAPIKeys(ctx, gomock.Any(), dal.Page{Limit: dal.MaxPageLimit}).Return([]dal.APIKey{
+			{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv1"},
+			{APIKeyID: "key2", OrgID: "org1", ServiceID: "serv1"},
+		}, "cursor1", nil),
+		mockAPIKeyClient.EXPECT().ListExpiredAPIKeys(ctx, gomock.Any(), dal.Page{Cursor: "cursor1", Limit: dal.MaxPageLimit}).Return([]dal.APIKey{
+			{APIKeyID: "key3", OrgID: "org1", ServiceID: "serv2"},
+		}, "", nil),
+	)
+
+	// key2 was expired concurrently by another instance, so only key1 and key3 emit an event
+	mockAPIKeyClient.EXPECT().ExpireAPIKey(ctx, "key1").Return(true, nil)
+	mockAPIKeyClient.EXPECT().ExpireAPIKey(ctx, "key2").Return(false, nil)
+	mockAPIKeyClient.EXPECT().ExpireAPIKey(ctx, "key3").Return(true, nil)
+
+	var published []events.Event
+	mockPublisher.EXPECT().Publish(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, event events.Event) error {
+		published = append(published, event)
+		return nil
+	}).Times(2)
+
+	expired, err := sweeper.Sweep(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, expired)
+
+	assert.Len(t, published, 2)
+	assert.Equal(t, events.APIKeyExpired, published[0].Type)
+	assert.Equal(t, "key1", published[0].APIKeyID)
+	assert.Equal(t, "org1", published[0].OrgID)
+	assert.Equal(t, "serv1", published[0].ServiceID)
+	assert.WithinDuration(t, time.Now(), published[0].OccurredAt, time.Minute)
+	assert.Equal(t, "key3", published[1].APIKeyID)
+	assert.Equal(t, "serv2", published[1].ServiceID)
+}
+
+func TestSweeper_Sweep_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockPublisher := eventmocks.NewMockPublisher(ctrl)
+	sweeper := expiry.NewSweeper(mockAPIKeyClient, mockPublisher, zap.NewNop())
+
+	ctx := context.Background()
+	mockAPIKeyClient.EXPECT().ListExpiredAPIKeys(ctx, gomock.Any(), gomock.Any()).Return(nil, "", errors.New("query failed"))
+
+	_, err := sweeper.Sweep(ctx)
+	assert.Error(t, err)
+
+	// A key that fails to expire does not stop the others from being swept
+	mockAPIKeyClient.EXPECT().ListExpiredAPIKeys(ctx, gomock.Any(), gomock.Any()).Return([]dal.APIKey{
+		{APIKeyID: "key1"},
+		{APIKeyID: "key2"},
+	}, "", nil)
+	mockAPIKeyClient.EXPECT().ExpireAPIKey(ctx, "key1").Return(false, errors.New("update failed"))
+	mockAPIKeyClient.EXPECT().ExpireAPIKey(ctx, "key2").Return(true, nil)
+	mockPublisher.EXPECT().Publish(ctx, gomock.Any()).Return(nil)
+
+	expired, err := sweeper.Sweep(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, expired)
+}
@@ -0,0 +1,145 @@
+package ipblock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"go.uber.org/zap"
+)
+
+// DefaultRefreshInterval is how often the blocks are reloaded from storage when no interval is configured.
+const DefaultRefreshInterval = time.Minute
+
+// Matcher matches caller IP addresses against the blocks of each service, which it keeps in memory in a prefix trie
+// per service. The blocks are reloaded from storage periodically by Run, and the blocks of a single service are
+// reloaded by Reload as soon as they are changed through this instance. A nil Matcher blocks nothing.
+type Matcher struct {
+	blockedIPClient dal.BlockedIPManager
+	logger          *zap.Logger
+
+	mu       sync.RWMutex
+	services map[string]*trie
+}
+
+// NewMatcher creates a new Matcher. Nothing is blocked until the blocks are loaded by Refresh.
+func NewMatcher(blockedIPClient dal.BlockedIPManager, logger *zap.Logger) *Matcher {
+	return &Matcher{
+		blockedIPClient: blockedIPClient,
+		logger:          logger,
+		services:        map[string]*trie{},
+	}
+}
+
+// Refresh reloads the blocks of every service from storage.
+func (m *Matcher) Refresh(ctx context.Context) error {
+	blocks, err := m.blockedIPClient.ListBlockedIPs(ctx)
+	if err != nil {
+		return err
+	}
+
+	services := map[string]*trie{}
+	for _, block := range blocks {
+		t, ok := services[block.ServiceID]
+		if !ok {
+			t = &trie{}
+			services[block.ServiceID] = t
+		}
+		m.insert(t, block)
+	}
+
+	m.mu.Lock()
+	m.services = services
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Reload reloads the blocks of a single service from storage.
+func (m *Matcher) Reload(ctx context.Context, serviceID string) error {
+	blocks, err := dal.ListAll(func(page dal.Page) ([]dal.BlockedIP, string, error) {
+		return m.blockedIPClient.ListBlockedIPsByService(ctx, serviceID, page)
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &trie{}
+	for _, block := range blocks {
+		m.insert(t, block)
+	}
+
+	m.mu.Lock()
+	m.services[serviceID] = t
+	m.mu.Unlock()
+
+	return nil
+}
+
+// Run refreshes the blocks at every interval until the context is done.
+func (m *Matcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil {
+				m.logger.Error("failed to refresh blocked IPs", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Match returns the block of a service that matches an IP address at the given time, or nil when the address is not
+// blocked. When several blocks match, the one with the most specific range is returned. The address may carry a
+// port, as in the remote address of a request, and addresses that cannot be parsed match nothing.
+func (m *Matcher) Match(serviceID, ipAddress string, now time.Time) *dal.BlockedIP {
+	if m == nil {
+		return nil
+	}
+
+	addr, err := ParseAddr(ipAddress)
+	if err != nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	t := m.services[serviceID]
+	m.mu.RUnlock()
+
+	if t == nil {
+		return nil
+	}
+	return t.match(addr, now)
+}
+
+// insert adds a block loaded from storage to a trie, skipping blocks that cannot be parsed.
+func (m *Matcher) insert(t *trie, block dal.BlockedIP) {
+	prefix, err := ParsePrefix(block.IPAddress)
+	if err != nil {
+		m.logger.Error("failed to parse blocked IP",
+			zap.String("serviceID", block.ServiceID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	e := &entry{block: block}
+	if block.Expiry != "" {
+		e.expiry, err = time.Parse(time.RFC3339, block.Expiry)
+		if err != nil {
+			m.logger.Error("failed to parse blocked IP expiry",
+				zap.String("serviceID", block.ServiceID),
+				zap.String("ipAddress", block.IPAddress),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
+	t.insert(prefix, e)
+}
@@ -0,0 +1,106 @@
+package ipblock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/ipblock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockBlockedIPClient.EXPECT().ListBlockedIPs(gomock.Any()).Return([]dal.BlockedIP{
+		{ServiceID: "serv1", IPAddress: "203.0.113.0/24", Reason: "Range"},
+		{ServiceID: "serv1", IPAddress: "203.0.113.7", Reason: "Address"},
+		{ServiceID: "serv1", IPAddress: "198.51.100.0/24", Reason: "Expired", Expiry: "2024-06-01T12:00:00Z"},
+		{ServiceID: "serv1", IPAddress: "192.0.2.0/24", Reason: "Until tomorrow", Expiry: "2024-06-02T12:00:00Z"},
+		{ServiceID: "serv1", IPAddress: "2001:db8::/32", Reason: "IPv6 range"},
+		{ServiceID: "serv1", IPAddress: "not an address", Reason: "Invalid"},
+		{ServiceID: "serv2", IPAddress: "0.0.0.0/0", Reason: "Everything"},
+	}, nil)
+
+	matcher := ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop())
+	require.NoError(t, matcher.Refresh(context.Background()))
+
+	tests := []struct {
+		name      string
+		serviceID string
+		ipAddress string
+		reason    string
+	}{
+		{"Address in range", "serv1", "203.0.113.8", "Range"},
+		{"Most specific block", "serv1", "203.0.113.7", "Address"},
+		{"Address with port", "serv1", "203.0.113.7:4321", "Address"},
+		{"IPv4-mapped address", "serv1", "::ffff:203.0.113.7", "Address"},
+		{"Expired block", "serv1", "198.51.100.1", ""},
+		{"Unexpired block", "serv1", "192.0.2.1", "Until tomorrow"},
+		{"IPv6 address", "serv1", "2001:db8::1", "IPv6 range"},
+		{"IPv6 address with port", "serv1", "[2001:db8::1]:4321", "IPv6 range"},
+		{"Address outside of blocks", "serv1", "203.0.114.1", ""},
+		{"IPv6 address outside of blocks", "serv1", "2001:db9::1", ""},
+		{"Block of another service", "serv2", "203.0.114.1", "Everything"},
+		{"IPv6 address with IPv4 block", "serv2", "2001:db8::1", ""},
+		{"Service without blocks", "serv3", "203.0.113.7", ""},
+		{"Invalid address", "serv2", "unknown", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := matcher.Match(tt.serviceID, tt.ipAddress, now)
+			if tt.reason == "" {
+				assert.Nil(t, block)
+				return
+			}
+
+			require.NotNil(t, block)
+			assert.Equal(t, tt.reason, block.Reason)
+		})
+	}
+}
+
+func TestMatcher_Reload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockBlockedIPClient.EXPECT().ListBlockedIPs(gomock.Any()).Return([]dal.BlockedIP{
+		{ServiceID: "serv1", IPAddress: "203.0.113.7"},
+		{ServiceID: "serv2", IPAddress: "203.0.113.7"},
+	}, nil)
+
+	matcher := ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop())
+	require.NoError(t, matcher.Refresh(context.Background()))
+
+	// Reloading a service replaces its blocks and leaves the other services alone
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(gomock.Any(), "serv1", gomock.Any()).Return([]dal.BlockedIP{
+		{ServiceID: "serv1", IPAddress: "198.51.100.1"},
+	}, "", nil)
+	require.NoError(t, matcher.Reload(context.Background(), "serv1"))
+
+	assert.Nil(t, matcher.Match("serv1", "203.0.113.7", now))
+	assert.NotNil(t, matcher.Match("serv1", "198.51.100.1", now))
+	assert.NotNil(t, matcher.Match("serv2", "203.0.113.7", now))
+
+	// Failed loads keep the blocks in place
+	mockBlockedIPClient.EXPECT().ListBlockedIPs(gomock.Any()).Return(nil, errors.New("unavailable"))
+	assert.Error(t, matcher.Refresh(context.Background()))
+	assert.NotNil(t, matcher.Match("serv2", "203.0.113.7", now))
+}
+
+func TestMatcher_Nil(t *testing.T) {
+	var matcher *ipblock.Matcher
+	assert.Nil(t, matcher.Match("serv1", "203.0.113.7", time.Now()))
+}
@@ -0,0 +1,130 @@
+// Package ipblock matches the IP addresses of callers against the IP addresses and CIDR ranges blocked for each
+// service.
+package ipblock
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+)
+
+// Normalize parses an IP address or CIDR range and returns it in its canonical form. Single addresses are returned
+// without a prefix length, ranges are returned with their host bits cleared, and IPv4-mapped IPv6 addresses are
+// returned in IPv4 notation, so that each address or range has a single canonical form.
+func Normalize(value string) (string, error) {
+	prefix, err := ParsePrefix(value)
+	if err != nil {
+		return "", err
+	}
+
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String(), nil
+	}
+	return prefix.String(), nil
+}
+
+// ParsePrefix parses an IP address or CIDR range into a prefix with its host bits cleared.
+func ParsePrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		addr, err := netip.ParseAddr(value)
+		if err != nil || addr.Zone() != "" {
+			return netip.Prefix{}, fmt.Errorf("invalid IP address '%s'", value)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR range '%s'", value)
+	}
+	if prefix.Addr().Is4In6() {
+		return netip.Prefix{}, fmt.Errorf("CIDR range '%s' must use IPv4 notation", value)
+	}
+	return prefix.Masked(), nil
+}
+
+// ParseAddr parses the IP address of a caller, which may carry a port as in the remote address of a request.
+func ParseAddr(value string) (netip.Addr, error) {
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("invalid IP address '%s'", value)
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+// entry is a block stored in the trie, with its parsed expiry.
+type entry struct {
+	block  dal.BlockedIP
+	expiry time.Time
+}
+
+// node is a node of the trie. The path from the root to a node spells the bits of a prefix, and the node holds the
+// block of that prefix, if any.
+type node struct {
+	children [2]*node
+	entry    *entry
+}
+
+// trie is a binary prefix trie over the bits of IP addresses, with separate roots for IPv4 and IPv6. Matching an
+// address walks at most one node per bit of the address, whatever the number of blocks.
+type trie struct {
+	v4 node
+	v6 node
+}
+
+// insert adds a block for a prefix, replacing any block of the same prefix.
+func (t *trie) insert(prefix netip.Prefix, e *entry) {
+	n := t.root(prefix.Addr())
+	bytes := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := bytes[i/8] >> (7 - i%8) & 1
+		if n.children[bit] == nil {
+			n.children[bit] = &node{}
+		}
+		n = n.children[bit]
+	}
+	n.entry = e
+}
+
+// match returns the block of the longest prefix containing the address that has not expired at the given time.
+func (t *trie) match(addr netip.Addr, now time.Time) *dal.BlockedIP {
+	var matched *entry
+	n := t.root(addr)
+	bytes := addr.AsSlice()
+	for i := 0; ; i++ {
+		if n.entry != nil && (n.entry.expiry.IsZero() || now.Before(n.entry.expiry)) {
+			matched = n.entry
+		}
+		if i == len(bytes)*8 {
+			break
+		}
+
+		n = n.children[bytes[i/8]>>(7-i%8)&1]
+		if n == nil {
+			break
+		}
+	}
+
+	if matched == nil {
+		return nil
+	}
+	block := matched.block
+	return &block
+}
+
+// root returns the root of the trie for the family of an address.
+func (t *trie) root(addr netip.Addr) *node {
+	if addr.Is4() {
+		return &t.v4
+	}
+	return &t.v6
+}
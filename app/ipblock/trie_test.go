@@ -0,0 +1,44 @@
+package ipblock_test
+
+import (
+	"testing"
+
+	"github.com/payloadops/lanyard/app/ipblock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+		valid    bool
+	}{
+		{"203.0.113.7", "203.0.113.7", true},
+		{" 203.0.113.7 ", "203.0.113.7", true},
+		{"203.0.113.7/32", "203.0.113.7", true},
+		{"203.0.113.7/24", "203.0.113.0/24", true},
+		{"0.0.0.0/0", "0.0.0.0/0", true},
+		{"::ffff:203.0.113.7", "203.0.113.7", true},
+		{"2001:DB8::1", "2001:db8::1", true},
+		{"2001:db8::1/32", "2001:db8::/32", true},
+		{"", "", false},
+		{"203.0.113", "", false},
+		{"203.0.113.7/33", "", false},
+		{"::ffff:203.0.113.0/120", "", false},
+		{"fe80::1%eth0", "", false},
+		{"example.com", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			normalized, err := ipblock.Normalize(tt.value)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
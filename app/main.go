@@ -11,14 +11,24 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/go-redis/redis/v8"
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/cache"
 	"github.com/payloadops/lanyard/app/client"
+	"github.com/payloadops/lanyard/app/clientip"
 	"github.com/payloadops/lanyard/app/config"
 	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/events"
+	"github.com/payloadops/lanyard/app/expiry"
+	"github.com/payloadops/lanyard/app/ipblock"
 	"github.com/payloadops/lanyard/app/logging"
 	"github.com/payloadops/lanyard/app/metrics"
 	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/ratelimit"
 	"github.com/payloadops/lanyard/app/service"
 	"github.com/payloadops/lanyard/app/tracing"
+	"github.com/payloadops/lanyard/app/usage"
 	"go.uber.org/zap"
 )
 
@@ -32,6 +42,18 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	// Tokens are parsed with the same rules, so an invalid prefix would issue tokens that can never be used
+	for _, value := range []string{cfg.APIKeys.TokenPrefix, cfg.APIKeys.TokenEnvironment} {
+		if err := apitoken.ValidatePrefix(value); err != nil {
+			log.Fatalf("Invalid API key token configuration: %v", err)
+		}
+	}
+
+	// Unsigned events could be forged by anyone who finds the webhook
+	if cfg.Events.WebhookURL != "" && cfg.Events.WebhookSecret == "" {
+		log.Fatalf("EVENTS_WEBHOOK_SECRET is required with EVENTS_WEBHOOK_URL")
+	}
+
 	// Initialize service logging
 	logger, err := logging.NewLogger(cfg)
 	if err != nil {
@@ -92,32 +114,179 @@ func main() {
 		// Create cache instance
 		cache := cache.NewRedisCache(redisClient)
 	*/
-	// TODO: Initialize a real redis cache, when elasticace is present...
-	// cache := cache.NewNoopCache()
+	// Share rate limit and usage state through redis when configured, otherwise each instance keeps its own
+	var cacheClient cache.Cache = cache.NewNoopCache()
+	if cfg.RedisEndpoint != "" {
+		cacheClient = cache.NewRedisCache(redis.NewClient(&redis.Options{
+			Addr: cfg.RedisEndpoint,
+		}))
+	}
+	limiter := ratelimit.NewCacheLimiter(cacheClient, logger)
 
 	// Initialize database clients
-	serviceDBClient := dal.NewServiceDBClient(dynamoClient)
-	apiKeyDBClient := dal.NewAPIKeyDBClient(dynamoClient)
+	cursors := dal.NewCursorCodec(cfg.Pagination.CursorSecret)
+	orgDBClient := dal.NewOrgDBClient(dynamoClient)
+	serviceDBClient := dal.NewServiceDBClient(dynamoClient, cursors)
+	apiKeyDBClient := dal.NewAPIKeyDBClient(dynamoClient, cursors)
+	actorDBClient := dal.NewActorDBClient(dynamoClient, cursors)
+	tierDBClient := dal.NewTierDBClient(dynamoClient, cursors)
+	usageDBClient := dal.NewUsageDBClient(dynamoClient)
+	blockedIPDBClient := dal.NewBlockedIPDBClient(dynamoClient, cursors)
+	leakReportDBClient := dal.NewLeakReportDBClient(dynamoClient)
+	auditEventDBClient := dal.NewAuditEventDBClient(dynamoClient, cursors)
+	transactionDBClient := dal.NewTransactionDBClient(dynamoClient)
+
+	// Meter usage in the background, flushing pending counts to the database periodically
+	meter := usage.NewMeter(usageDBClient, actorDBClient, tierDBClient, cacheClient, logger)
+	meterCtx, stopMeter := context.WithCancel(context.Background())
+	meterDone := make(chan struct{})
+	go func() {
+		meter.Run(meterCtx, usage.DefaultFlushInterval)
+		close(meterDone)
+	}()
+
+	// Notify the owners of API keys of changes made outside of their requests through the webhook when configured.
+	// Without one, events are only written to the log
+	var publisher events.Publisher = events.NewLogPublisher(logger)
+	if cfg.Events.WebhookURL != "" {
+		publisher = events.NewWebhookPublisher(cfg.Events.WebhookURL, cfg.Events.WebhookSecret, logger)
+	} else {
+		logger.Warn("No events webhook configured, events are only logged")
+	}
+
+	// Expire API keys whose expiry has passed in the background
+	sweeper := expiry.NewSweeper(apiKeyDBClient, publisher, logger)
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go sweeper.Run(sweeperCtx, cfg.APIKeys.ExpirySweepInterval)
+
+	// Load the blocked IPs of every service, refreshing them in the background
+	blocklist := ipblock.NewMatcher(blockedIPDBClient, logger)
+	blocklistCtx, stopBlocklist := context.WithCancel(context.Background())
+	defer stopBlocklist()
+	if err := blocklist.Refresh(blocklistCtx); err != nil {
+		logger.Error("Failed to load blocked IPs", zap.Error(err))
+	}
+	go blocklist.Run(blocklistCtx, cfg.Blocklist.RefreshInterval)
 
-	// Initialize the healtcheck service
+	// Resolve client IP addresses through the trusted proxies only, so that clients cannot spoof them
+	clientIPs, err := clientip.NewResolver(cfg.Proxy.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Failed to parse trusted proxies", zap.Error(err))
+	}
+
+	// Load the public keys of asymmetric token issuers when configured, refreshing them in the background
+	var jwks *auth.JWKS
+	jwksCtx, stopJWKS := context.WithCancel(context.Background())
+	defer stopJWKS()
+	if cfg.JWT.JWKSURL != "" {
+		jwks = auth.NewJWKS(cfg.JWT.JWKSURL, logger)
+		if err := jwks.Refresh(jwksCtx); err != nil {
+			logger.Error("Failed to load JWKS", zap.Error(err))
+		}
+		go jwks.Run(jwksCtx, cfg.JWT.JWKSRefreshInterval)
+	}
+
+	// Initialize the api services
 	HealthCheckAPIService := service.NewHealthCheckAPIService(logger)
+	OrganizationsAPIService := service.NewOrganizationsAPIService(
+		cfg,
+		orgDBClient,
+		serviceDBClient,
+		actorDBClient,
+		apiKeyDBClient,
+		transactionDBClient,
+		logger,
+	)
+	ServicesAPIService := service.NewServicesAPIService(
+		serviceDBClient,
+		apiKeyDBClient,
+		transactionDBClient,
+		logger,
+	)
 	APIKeysAPIService := service.NewAPIKeysAPIService(
+		cfg,
+		apiKeyDBClient,
+		serviceDBClient,
+		actorDBClient,
+		limiter,
+		meter,
+		blocklist,
+		logger,
+	)
+	BlockedIPsAPIService := service.NewBlockedIPsAPIService(
+		blockedIPDBClient,
+		serviceDBClient,
+		blocklist,
+		logger,
+	)
+	LeaksAPIService := service.NewLeaksAPIService(
+		cfg,
+		apiKeyDBClient,
+		serviceDBClient,
+		leakReportDBClient,
+		transactionDBClient,
+		publisher,
+		logger,
+	)
+	ActorsAPIService := service.NewActorsAPIService(
+		actorDBClient,
+		serviceDBClient,
+		tierDBClient,
 		apiKeyDBClient,
+		transactionDBClient,
+		logger,
+	)
+	PricingTierAPIService := service.NewPricingTierAPIService(
+		tierDBClient,
 		serviceDBClient,
+		actorDBClient,
+		logger,
+	)
+	UsageAPIService := service.NewUsageAPIService(
+		usageDBClient,
+		serviceDBClient,
+		actorDBClient,
+		tierDBClient,
+		apiKeyDBClient,
+		logger,
+	)
+	AuditAPIService := service.NewAuditAPIService(
+		auditEventDBClient,
 		logger,
 	)
 
 	// Initialize controllers
 	HealthCheckAPIController := openapi.NewHealthCheckAPIController(HealthCheckAPIService)
+	OrganizationsAPIController := openapi.NewOrganizationsAPIController(OrganizationsAPIService)
+	ServicesAPIController := openapi.NewServicesAPIController(ServicesAPIService)
 	APIKeysAPIController := openapi.NewAPIKeysAPIController(APIKeysAPIService)
+	BlockedIPsAPIController := openapi.NewBlockedIPsAPIController(BlockedIPsAPIService)
+	LeaksAPIController := openapi.NewLeaksAPIController(LeaksAPIService)
+	ActorsAPIController := openapi.NewActorsAPIController(ActorsAPIService)
+	PricingTierAPIController := openapi.NewPricingTierAPIController(PricingTierAPIService)
+	UsageAPIController := openapi.NewUsageAPIController(UsageAPIService)
+	AuditAPIController := openapi.NewAuditAPIController(AuditAPIService)
 
 	// Initialize router
 	router := openapi.NewRouter(
 		cfg,
 		logger,
 		apiKeyDBClient,
+		serviceDBClient,
+		jwks,
+		blocklist,
+		clientIPs,
 		HealthCheckAPIController,
+		OrganizationsAPIController,
+		ServicesAPIController,
 		APIKeysAPIController,
+		BlockedIPsAPIController,
+		LeaksAPIController,
+		ActorsAPIController,
+		PricingTierAPIController,
+		UsageAPIController,
+		AuditAPIController,
 	)
 
 	// Initialize server
@@ -152,5 +321,9 @@ func main() {
 		logger.Fatal("Server forced to shutdown: %v", zap.Error(err))
 	}
 
+	// Flush usage recorded by in-flight requests before exiting
+	stopMeter()
+	<-meterDone
+
 	logger.Info("Server exiting")
 }
@@ -25,6 +25,7 @@ type APIKeysAPIRouter interface {
 	GenerateApiKey(http.ResponseWriter, *http.Request)
 	GetApiKey(http.ResponseWriter, *http.Request)
 	ListApiKeys(http.ResponseWriter, *http.Request)
+	RotateApiKey(http.ResponseWriter, *http.Request)
 	UpdateApiKey(http.ResponseWriter, *http.Request)
 }
 
@@ -32,6 +33,8 @@ type APIKeysAPIRouter interface {
 // The ActorsAPIRouter implementation should parse necessary information from the http request,
 // pass the data to a ActorsAPIServicer to perform the required actions, then write the service results to the http response.
 type ActorsAPIRouter interface {
+	ListActorApiKeys(http.ResponseWriter, *http.Request)
+	RevokeActorApiKeys(http.ResponseWriter, *http.Request)
 	ServicesServiceIdActorsActorExternalIdDelete(http.ResponseWriter, *http.Request)
 	ServicesServiceIdActorsActorExternalIdGet(http.ResponseWriter, *http.Request)
 	ServicesServiceIdActorsActorExternalIdPut(http.ResponseWriter, *http.Request)
@@ -39,6 +42,24 @@ type ActorsAPIRouter interface {
 	ServicesServiceIdActorsPost(http.ResponseWriter, *http.Request)
 }
 
+// AuditAPIRouter defines the required methods for binding the api requests to a responses for the AuditAPI
+// The AuditAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a AuditAPIServicer to perform the required actions, then write the service results to the http response.
+type AuditAPIRouter interface {
+	ListAuditEvents(http.ResponseWriter, *http.Request)
+}
+
+// BlockedIPsAPIRouter defines the required methods for binding the api requests to a responses for the BlockedIPsAPI
+// The BlockedIPsAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a BlockedIPsAPIServicer to perform the required actions, then write the service results to the http response.
+type BlockedIPsAPIRouter interface {
+	BlockIp(http.ResponseWriter, *http.Request)
+	GetBlockedIp(http.ResponseWriter, *http.Request)
+	ListBlockedIps(http.ResponseWriter, *http.Request)
+	UnblockIp(http.ResponseWriter, *http.Request)
+	UpdateBlockedIp(http.ResponseWriter, *http.Request)
+}
+
 // HealthCheckAPIRouter defines the required methods for binding the api requests to a responses for the HealthCheckAPI
 // The HealthCheckAPIRouter implementation should parse necessary information from the http request,
 // pass the data to a HealthCheckAPIServicer to perform the required actions, then write the service results to the http response.
@@ -46,6 +67,13 @@ type HealthCheckAPIRouter interface {
 	HealthCheck(http.ResponseWriter, *http.Request)
 }
 
+// LeaksAPIRouter defines the required methods for binding the api requests to a responses for the LeaksAPI
+// The LeaksAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a LeaksAPIServicer to perform the required actions, then write the service results to the http response.
+type LeaksAPIRouter interface {
+	ReportLeaks(http.ResponseWriter, *http.Request)
+}
+
 // OrganizationsAPIRouter defines the required methods for binding the api requests to a responses for the OrganizationsAPI
 // The OrganizationsAPIRouter implementation should parse necessary information from the http request,
 // pass the data to a OrganizationsAPIServicer to perform the required actions, then write the service results to the http response.
@@ -78,17 +106,26 @@ type ServicesAPIRouter interface {
 	UpdateService(http.ResponseWriter, *http.Request)
 }
 
+// UsageAPIRouter defines the required methods for binding the api requests to a responses for the UsageAPI
+// The UsageAPIRouter implementation should parse necessary information from the http request,
+// pass the data to a UsageAPIServicer to perform the required actions, then write the service results to the http response.
+type UsageAPIRouter interface {
+	GetActorUsage(http.ResponseWriter, *http.Request)
+	GetServiceUsage(http.ResponseWriter, *http.Request)
+}
+
 // APIKeysAPIServicer defines the api actions for the APIKeysAPI service
 // This interface intended to stay up to date with the openapi yaml used to generate it,
 // while the service implementation can be ignored with the .openapi-generator-ignore file
 // and updated with the logic required for the API.
 type APIKeysAPIServicer interface {
 	AuthApiKey(context.Context, string, string, AuthApiKeyRequest) (ImplResponse, error)
-	DeleteApiKey(context.Context, string, string) (ImplResponse, error)
+	DeleteApiKey(context.Context, string, string, string) (ImplResponse, error)
 	GenerateApiKey(context.Context, string, ApiKeyInput) (ImplResponse, error)
 	GetApiKey(context.Context, string, string) (ImplResponse, error)
-	ListApiKeys(context.Context, string) (ImplResponse, error)
-	UpdateApiKey(context.Context, string, string, ApiKeyInput) (ImplResponse, error)
+	ListApiKeys(context.Context, string, string, int32) (ImplResponse, error)
+	RotateApiKey(context.Context, string, string, RotateApiKeyRequest) (ImplResponse, error)
+	UpdateApiKey(context.Context, string, string, string, ApiKeyInput) (ImplResponse, error)
 }
 
 // ActorsAPIServicer defines the api actions for the ActorsAPI service
@@ -96,13 +133,35 @@ type APIKeysAPIServicer interface {
 // while the service implementation can be ignored with the .openapi-generator-ignore file
 // and updated with the logic required for the API.
 type ActorsAPIServicer interface {
-	ServicesServiceIdActorsActorExternalIdDelete(context.Context, string, string) (ImplResponse, error)
+	ListActorApiKeys(context.Context, string, string, string, int32) (ImplResponse, error)
+	RevokeActorApiKeys(context.Context, string, string) (ImplResponse, error)
+	ServicesServiceIdActorsActorExternalIdDelete(context.Context, string, string, string) (ImplResponse, error)
 	ServicesServiceIdActorsActorExternalIdGet(context.Context, string, string) (ImplResponse, error)
-	ServicesServiceIdActorsActorExternalIdPut(context.Context, string, string, ActorInput) (ImplResponse, error)
-	ServicesServiceIdActorsGet(context.Context, string) (ImplResponse, error)
+	ServicesServiceIdActorsActorExternalIdPut(context.Context, string, string, string, ActorInput) (ImplResponse, error)
+	ServicesServiceIdActorsGet(context.Context, string, string, int32) (ImplResponse, error)
 	ServicesServiceIdActorsPost(context.Context, string, ActorInput) (ImplResponse, error)
 }
 
+// AuditAPIServicer defines the api actions for the AuditAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type AuditAPIServicer interface {
+	ListAuditEvents(context.Context, string, string, string, string, string, string, string, int32) (ImplResponse, error)
+}
+
+// BlockedIPsAPIServicer defines the api actions for the BlockedIPsAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type BlockedIPsAPIServicer interface {
+	BlockIp(context.Context, string, BlockedIpAddressInput) (ImplResponse, error)
+	GetBlockedIp(context.Context, string, string) (ImplResponse, error)
+	ListBlockedIps(context.Context, string, string, int32) (ImplResponse, error)
+	UnblockIp(context.Context, string, string) (ImplResponse, error)
+	UpdateBlockedIp(context.Context, string, string, BlockedIpAddressUpdate) (ImplResponse, error)
+}
+
 // HealthCheckAPIServicer defines the api actions for the HealthCheckAPI service
 // This interface intended to stay up to date with the openapi yaml used to generate it,
 // while the service implementation can be ignored with the .openapi-generator-ignore file
@@ -111,14 +170,22 @@ type HealthCheckAPIServicer interface {
 	HealthCheck(context.Context) (ImplResponse, error)
 }
 
+// LeaksAPIServicer defines the api actions for the LeaksAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type LeaksAPIServicer interface {
+	ReportLeaks(context.Context, LeakReportInput) (ImplResponse, error)
+}
+
 // OrganizationsAPIServicer defines the api actions for the OrganizationsAPI service
 // This interface intended to stay up to date with the openapi yaml used to generate it,
 // while the service implementation can be ignored with the .openapi-generator-ignore file
 // and updated with the logic required for the API.
 type OrganizationsAPIServicer interface {
-	OrganizationsOrganizationIdDelete(context.Context, string) (ImplResponse, error)
+	OrganizationsOrganizationIdDelete(context.Context, string, string) (ImplResponse, error)
 	OrganizationsOrganizationIdGet(context.Context, string) (ImplResponse, error)
-	OrganizationsOrganizationIdPut(context.Context, string, OrganizationInput) (ImplResponse, error)
+	OrganizationsOrganizationIdPut(context.Context, string, string, OrganizationInput) (ImplResponse, error)
 	OrganizationsPost(context.Context, OrganizationInput) (ImplResponse, error)
 }
 
@@ -127,11 +194,11 @@ type OrganizationsAPIServicer interface {
 // while the service implementation can be ignored with the .openapi-generator-ignore file
 // and updated with the logic required for the API.
 type PricingTierAPIServicer interface {
-	ServicesServiceIdPricingTiersGet(context.Context, string) (ImplResponse, error)
+	ServicesServiceIdPricingTiersGet(context.Context, string, string, int32) (ImplResponse, error)
 	ServicesServiceIdPricingTiersPost(context.Context, string, PricingTierInput) (ImplResponse, error)
-	ServicesServiceIdPricingTiersTierIdDelete(context.Context, string, string) (ImplResponse, error)
+	ServicesServiceIdPricingTiersTierIdDelete(context.Context, string, string, string, string) (ImplResponse, error)
 	ServicesServiceIdPricingTiersTierIdGet(context.Context, string, string) (ImplResponse, error)
-	ServicesServiceIdPricingTiersTierIdPut(context.Context, string, string, PricingTierInput) (ImplResponse, error)
+	ServicesServiceIdPricingTiersTierIdPut(context.Context, string, string, string, PricingTierInput) (ImplResponse, error)
 }
 
 // ServicesAPIServicer defines the api actions for the ServicesAPI service
@@ -140,8 +207,17 @@ type PricingTierAPIServicer interface {
 // and updated with the logic required for the API.
 type ServicesAPIServicer interface {
 	CreateService(context.Context, ServiceInput) (ImplResponse, error)
-	DeleteService(context.Context, string) (ImplResponse, error)
+	DeleteService(context.Context, string, string) (ImplResponse, error)
 	GetService(context.Context, string) (ImplResponse, error)
-	ListServices(context.Context) (ImplResponse, error)
-	UpdateService(context.Context, string, ServiceInput) (ImplResponse, error)
+	ListServices(context.Context, string, int32) (ImplResponse, error)
+	UpdateService(context.Context, string, string, ServiceInput) (ImplResponse, error)
+}
+
+// UsageAPIServicer defines the api actions for the UsageAPI service
+// This interface intended to stay up to date with the openapi yaml used to generate it,
+// while the service implementation can be ignored with the .openapi-generator-ignore file
+// and updated with the logic required for the API.
+type UsageAPIServicer interface {
+	GetActorUsage(context.Context, string, string, string, string, string) (ImplResponse, error)
+	GetServiceUsage(context.Context, string, string, string, string) (ImplResponse, error)
 }
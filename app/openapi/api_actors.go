@@ -52,6 +52,16 @@ func NewActorsAPIController(s ActorsAPIServicer, opts ...ActorsAPIOption) Router
 // Routes returns all the api routes for the ActorsAPIController
 func (c *ActorsAPIController) Routes() Routes {
 	return Routes{
+		"ListActorApiKeys": Route{
+			strings.ToUpper("Get"),
+			"/v1/services/{serviceId}/actors/{actorExternalId}/keys",
+			c.ListActorApiKeys,
+		},
+		"RevokeActorApiKeys": Route{
+			strings.ToUpper("Delete"),
+			"/v1/services/{serviceId}/actors/{actorExternalId}/keys",
+			c.RevokeActorApiKeys,
+		},
 		"ServicesServiceIdActorsActorExternalIdDelete": Route{
 			strings.ToUpper("Delete"),
 			"/v1/services/{serviceId}/actors/{actorExternalId}",
@@ -80,6 +90,78 @@ func (c *ActorsAPIController) Routes() Routes {
 	}
 }
 
+// ListActorApiKeys - List the API keys bound to an actor
+func (c *ActorsAPIController) ListActorApiKeys(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	serviceIdParam := chi.URLParam(r, "serviceId")
+	if serviceIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
+		return
+	}
+	actorExternalIdParam := chi.URLParam(r, "actorExternalId")
+	if actorExternalIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"actorExternalId"}, nil)
+		return
+	}
+	var cursorParam string
+	if query.Has("cursor") {
+		param := query.Get("cursor")
+
+		cursorParam = param
+	} else {
+	}
+	var limitParam int32
+	if query.Has("limit") {
+		param, err := parseNumericParameter[int32](
+			query.Get("limit"),
+			WithParse[int32](parseInt32),
+			WithMinimum[int32](1),
+			WithMaximum[int32](100),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+
+		limitParam = param
+	} else {
+	}
+	result, err := c.service.ListActorApiKeys(r.Context(), serviceIdParam, actorExternalIdParam, cursorParam, limitParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
+}
+
+// RevokeActorApiKeys - Revoke every API key bound to an actor
+func (c *ActorsAPIController) RevokeActorApiKeys(w http.ResponseWriter, r *http.Request) {
+	serviceIdParam := chi.URLParam(r, "serviceId")
+	if serviceIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
+		return
+	}
+	actorExternalIdParam := chi.URLParam(r, "actorExternalId")
+	if actorExternalIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"actorExternalId"}, nil)
+		return
+	}
+	result, err := c.service.RevokeActorApiKeys(r.Context(), serviceIdParam, actorExternalIdParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
+}
+
 // ServicesServiceIdActorsActorExternalIdDelete - Remove an actor from a service
 func (c *ActorsAPIController) ServicesServiceIdActorsActorExternalIdDelete(w http.ResponseWriter, r *http.Request) {
 	serviceIdParam := chi.URLParam(r, "serviceId")
@@ -92,14 +174,15 @@ func (c *ActorsAPIController) ServicesServiceIdActorsActorExternalIdDelete(w htt
 		c.errorHandler(w, r, &RequiredError{"actorExternalId"}, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdActorsActorExternalIdDelete(r.Context(), serviceIdParam, actorExternalIdParam)
+	ifMatchParam := r.Header.Get("If-Match")
+	result, err := c.service.ServicesServiceIdActorsActorExternalIdDelete(r.Context(), serviceIdParam, actorExternalIdParam, ifMatchParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdActorsActorExternalIdGet - Get the actor
@@ -121,7 +204,7 @@ func (c *ActorsAPIController) ServicesServiceIdActorsActorExternalIdGet(w http.R
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdActorsActorExternalIdPut - Update an actor
@@ -136,6 +219,7 @@ func (c *ActorsAPIController) ServicesServiceIdActorsActorExternalIdPut(w http.R
 		c.errorHandler(w, r, &RequiredError{"actorExternalId"}, nil)
 		return
 	}
+	ifMatchParam := r.Header.Get("If-Match")
 	actorInputParam := ActorInput{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -151,31 +235,59 @@ func (c *ActorsAPIController) ServicesServiceIdActorsActorExternalIdPut(w http.R
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdActorsActorExternalIdPut(r.Context(), serviceIdParam, actorExternalIdParam, actorInputParam)
+	result, err := c.service.ServicesServiceIdActorsActorExternalIdPut(r.Context(), serviceIdParam, actorExternalIdParam, ifMatchParam, actorInputParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdActorsGet - Retrieve all actors associated with a service
 func (c *ActorsAPIController) ServicesServiceIdActorsGet(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
 	serviceIdParam := chi.URLParam(r, "serviceId")
 	if serviceIdParam == "" {
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdActorsGet(r.Context(), serviceIdParam)
+	var cursorParam string
+	if query.Has("cursor") {
+		param := query.Get("cursor")
+
+		cursorParam = param
+	} else {
+	}
+	var limitParam int32
+	if query.Has("limit") {
+		param, err := parseNumericParameter[int32](
+			query.Get("limit"),
+			WithParse[int32](parseInt32),
+			WithMinimum[int32](1),
+			WithMaximum[int32](100),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+
+		limitParam = param
+	} else {
+	}
+	result, err := c.service.ServicesServiceIdActorsGet(r.Context(), serviceIdParam, cursorParam, limitParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdActorsPost - Add an actor to a service
@@ -207,5 +319,5 @@ func (c *ActorsAPIController) ServicesServiceIdActorsPost(w http.ResponseWriter,
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
@@ -13,6 +13,8 @@ package openapi
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 
@@ -77,6 +79,11 @@ func (c *APIKeysAPIController) Routes() Routes {
 			"/v1/services/{serviceId}/keys",
 			c.ListApiKeys,
 		},
+		"RotateApiKey": Route{
+			strings.ToUpper("Post"),
+			"/v1/services/{serviceId}/keys/{keyId}/rotate",
+			c.RotateApiKey,
+		},
 		"UpdateApiKey": Route{
 			strings.ToUpper("Put"),
 			"/v1/services/{serviceId}/keys/{keyId}",
@@ -119,7 +126,7 @@ func (c *APIKeysAPIController) AuthApiKey(w http.ResponseWriter, r *http.Request
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // DeleteApiKey - Delete a specific API key
@@ -134,14 +141,15 @@ func (c *APIKeysAPIController) DeleteApiKey(w http.ResponseWriter, r *http.Reque
 		c.errorHandler(w, r, &RequiredError{"keyId"}, nil)
 		return
 	}
-	result, err := c.service.DeleteApiKey(r.Context(), serviceIdParam, keyIdParam)
+	ifMatchParam := r.Header.Get("If-Match")
+	result, err := c.service.DeleteApiKey(r.Context(), serviceIdParam, keyIdParam, ifMatchParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // GenerateApiKey - Generate a new API key with specific scopes for a service
@@ -173,7 +181,7 @@ func (c *APIKeysAPIController) GenerateApiKey(w http.ResponseWriter, r *http.Req
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // GetApiKey - Retrieve a specific API key
@@ -195,24 +203,89 @@ func (c *APIKeysAPIController) GetApiKey(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ListApiKeys - List all API keys for a service
 func (c *APIKeysAPIController) ListApiKeys(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
 	serviceIdParam := chi.URLParam(r, "serviceId")
 	if serviceIdParam == "" {
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
 		return
 	}
-	result, err := c.service.ListApiKeys(r.Context(), serviceIdParam)
+	var cursorParam string
+	if query.Has("cursor") {
+		param := query.Get("cursor")
+
+		cursorParam = param
+	} else {
+	}
+	var limitParam int32
+	if query.Has("limit") {
+		param, err := parseNumericParameter[int32](
+			query.Get("limit"),
+			WithParse[int32](parseInt32),
+			WithMinimum[int32](1),
+			WithMaximum[int32](100),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+
+		limitParam = param
+	} else {
+	}
+	result, err := c.service.ListApiKeys(r.Context(), serviceIdParam, cursorParam, limitParam)
+	// If an error occurred, encode the error with the status code
+	if err != nil {
+		c.errorHandler(w, r, err, &result)
+		return
+	}
+	// If no error, encode the body and the result code
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
+}
+
+// RotateApiKey - Rotate the secret of an API key
+func (c *APIKeysAPIController) RotateApiKey(w http.ResponseWriter, r *http.Request) {
+	serviceIdParam := chi.URLParam(r, "serviceId")
+	if serviceIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
+		return
+	}
+	keyIdParam := chi.URLParam(r, "keyId")
+	if keyIdParam == "" {
+		c.errorHandler(w, r, &RequiredError{"keyId"}, nil)
+		return
+	}
+	rotateApiKeyRequestParam := RotateApiKeyRequest{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&rotateApiKeyRequestParam); err != nil && !errors.Is(err, io.EOF) {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	if err := AssertRotateApiKeyRequestRequired(rotateApiKeyRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	if err := AssertRotateApiKeyRequestConstraints(rotateApiKeyRequestParam); err != nil {
+		c.errorHandler(w, r, err, nil)
+		return
+	}
+	result, err := c.service.RotateApiKey(r.Context(), serviceIdParam, keyIdParam, rotateApiKeyRequestParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // UpdateApiKey - Update an API key's scopes
@@ -227,6 +300,7 @@ func (c *APIKeysAPIController) UpdateApiKey(w http.ResponseWriter, r *http.Reque
 		c.errorHandler(w, r, &RequiredError{"keyId"}, nil)
 		return
 	}
+	ifMatchParam := r.Header.Get("If-Match")
 	apiKeyInputParam := ApiKeyInput{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -242,12 +316,12 @@ func (c *APIKeysAPIController) UpdateApiKey(w http.ResponseWriter, r *http.Reque
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.UpdateApiKey(r.Context(), serviceIdParam, keyIdParam, apiKeyInputParam)
+	result, err := c.service.UpdateApiKey(r.Context(), serviceIdParam, keyIdParam, ifMatchParam, apiKeyInputParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
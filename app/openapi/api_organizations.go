@@ -82,14 +82,15 @@ func (c *OrganizationsAPIController) OrganizationsOrganizationIdDelete(w http.Re
 		c.errorHandler(w, r, &RequiredError{"organizationId"}, nil)
 		return
 	}
-	result, err := c.service.OrganizationsOrganizationIdDelete(r.Context(), organizationIdParam)
+	ifMatchParam := r.Header.Get("If-Match")
+	result, err := c.service.OrganizationsOrganizationIdDelete(r.Context(), organizationIdParam, ifMatchParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // OrganizationsOrganizationIdGet - Get the organization
@@ -106,7 +107,7 @@ func (c *OrganizationsAPIController) OrganizationsOrganizationIdGet(w http.Respo
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // OrganizationsOrganizationIdPut - Update an organization
@@ -116,6 +117,7 @@ func (c *OrganizationsAPIController) OrganizationsOrganizationIdPut(w http.Respo
 		c.errorHandler(w, r, &RequiredError{"organizationId"}, nil)
 		return
 	}
+	ifMatchParam := r.Header.Get("If-Match")
 	organizationInputParam := OrganizationInput{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -131,14 +133,14 @@ func (c *OrganizationsAPIController) OrganizationsOrganizationIdPut(w http.Respo
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.OrganizationsOrganizationIdPut(r.Context(), organizationIdParam, organizationInputParam)
+	result, err := c.service.OrganizationsOrganizationIdPut(r.Context(), organizationIdParam, ifMatchParam, organizationInputParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // OrganizationsPost - Creates an organization
@@ -165,5 +167,5 @@ func (c *OrganizationsAPIController) OrganizationsPost(w http.ResponseWriter, r
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
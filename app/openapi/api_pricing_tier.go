@@ -82,19 +82,47 @@ func (c *PricingTierAPIController) Routes() Routes {
 
 // ServicesServiceIdPricingTiersGet - Retrieve the pricing tier for a service
 func (c *PricingTierAPIController) ServicesServiceIdPricingTiersGet(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
 	serviceIdParam := chi.URLParam(r, "serviceId")
 	if serviceIdParam == "" {
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdPricingTiersGet(r.Context(), serviceIdParam)
+	var cursorParam string
+	if query.Has("cursor") {
+		param := query.Get("cursor")
+
+		cursorParam = param
+	} else {
+	}
+	var limitParam int32
+	if query.Has("limit") {
+		param, err := parseNumericParameter[int32](
+			query.Get("limit"),
+			WithParse[int32](parseInt32),
+			WithMinimum[int32](1),
+			WithMaximum[int32](100),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+
+		limitParam = param
+	} else {
+	}
+	result, err := c.service.ServicesServiceIdPricingTiersGet(r.Context(), serviceIdParam, cursorParam, limitParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdPricingTiersPost - Assign a pricing tier to a service
@@ -126,11 +154,16 @@ func (c *PricingTierAPIController) ServicesServiceIdPricingTiersPost(w http.Resp
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdPricingTiersTierIdDelete - Remove a pricing tier from a service
 func (c *PricingTierAPIController) ServicesServiceIdPricingTiersTierIdDelete(w http.ResponseWriter, r *http.Request) {
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
 	serviceIdParam := chi.URLParam(r, "serviceId")
 	if serviceIdParam == "" {
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
@@ -141,14 +174,22 @@ func (c *PricingTierAPIController) ServicesServiceIdPricingTiersTierIdDelete(w h
 		c.errorHandler(w, r, &RequiredError{"tierId"}, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdPricingTiersTierIdDelete(r.Context(), serviceIdParam, tierIdParam)
+	var reassignToParam string
+	if query.Has("reassignTo") {
+		param := query.Get("reassignTo")
+
+		reassignToParam = param
+	} else {
+	}
+	ifMatchParam := r.Header.Get("If-Match")
+	result, err := c.service.ServicesServiceIdPricingTiersTierIdDelete(r.Context(), serviceIdParam, tierIdParam, reassignToParam, ifMatchParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdPricingTiersTierIdGet - Get the pricing tier for a service
@@ -170,7 +211,7 @@ func (c *PricingTierAPIController) ServicesServiceIdPricingTiersTierIdGet(w http
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ServicesServiceIdPricingTiersTierIdPut - Update the pricing tier for a service
@@ -185,6 +226,7 @@ func (c *PricingTierAPIController) ServicesServiceIdPricingTiersTierIdPut(w http
 		c.errorHandler(w, r, &RequiredError{"tierId"}, nil)
 		return
 	}
+	ifMatchParam := r.Header.Get("If-Match")
 	pricingTierInputParam := PricingTierInput{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -200,12 +242,12 @@ func (c *PricingTierAPIController) ServicesServiceIdPricingTiersTierIdPut(w http
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.ServicesServiceIdPricingTiersTierIdPut(r.Context(), serviceIdParam, tierIdParam, pricingTierInputParam)
+	result, err := c.service.ServicesServiceIdPricingTiersTierIdPut(r.Context(), serviceIdParam, tierIdParam, ifMatchParam, pricingTierInputParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
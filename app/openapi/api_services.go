@@ -104,7 +104,7 @@ func (c *ServicesAPIController) CreateService(w http.ResponseWriter, r *http.Req
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // DeleteService - Delete a service
@@ -114,14 +114,15 @@ func (c *ServicesAPIController) DeleteService(w http.ResponseWriter, r *http.Req
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
 		return
 	}
-	result, err := c.service.DeleteService(r.Context(), serviceIdParam)
+	ifMatchParam := r.Header.Get("If-Match")
+	result, err := c.service.DeleteService(r.Context(), serviceIdParam, ifMatchParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // GetService - Retrieve a service by ID
@@ -138,19 +139,47 @@ func (c *ServicesAPIController) GetService(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // ListServices - List all services
 func (c *ServicesAPIController) ListServices(w http.ResponseWriter, r *http.Request) {
-	result, err := c.service.ListServices(r.Context())
+	query, err := parseQuery(r.URL.RawQuery)
+	if err != nil {
+		c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+		return
+	}
+	var cursorParam string
+	if query.Has("cursor") {
+		param := query.Get("cursor")
+
+		cursorParam = param
+	} else {
+	}
+	var limitParam int32
+	if query.Has("limit") {
+		param, err := parseNumericParameter[int32](
+			query.Get("limit"),
+			WithParse[int32](parseInt32),
+			WithMinimum[int32](1),
+			WithMaximum[int32](100),
+		)
+		if err != nil {
+			c.errorHandler(w, r, &ParsingError{Err: err}, nil)
+			return
+		}
+
+		limitParam = param
+	} else {
+	}
+	result, err := c.service.ListServices(r.Context(), cursorParam, limitParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
 
 // UpdateService - Update a service
@@ -160,6 +189,7 @@ func (c *ServicesAPIController) UpdateService(w http.ResponseWriter, r *http.Req
 		c.errorHandler(w, r, &RequiredError{"serviceId"}, nil)
 		return
 	}
+	ifMatchParam := r.Header.Get("If-Match")
 	serviceInputParam := ServiceInput{}
 	d := json.NewDecoder(r.Body)
 	d.DisallowUnknownFields()
@@ -175,12 +205,12 @@ func (c *ServicesAPIController) UpdateService(w http.ResponseWriter, r *http.Req
 		c.errorHandler(w, r, err, nil)
 		return
 	}
-	result, err := c.service.UpdateService(r.Context(), serviceIdParam, serviceInputParam)
+	result, err := c.service.UpdateService(r.Context(), serviceIdParam, ifMatchParam, serviceInputParam)
 	// If an error occurred, encode the error with the status code
 	if err != nil {
 		c.errorHandler(w, r, err, &result)
 		return
 	}
 	// If no error, encode the body and the result code
-	EncodeJSONResponse(result.Body, &result.Code, w)
+	EncodeJSONResponse(result.Body, &result.Code, result.Headers, w)
 }
@@ -37,17 +37,24 @@ func (e *RequiredError) Error() string {
 // you would like errors to be handled differently from the DefaultErrorHandler
 type ErrorHandler func(w http.ResponseWriter, r *http.Request, err error, result *ImplResponse)
 
-// DefaultErrorHandler defines the default logic on how to handle errors from the controller. Any errors from parsing
-// request params will return a StatusBadRequest. Otherwise, the error code originating from the servicer will be used.
+// DefaultErrorHandler defines the default logic on how to handle errors from the controller. Errors are described by
+// problem details. Any errors from parsing request params will return a StatusBadRequest. Otherwise, the error code
+// originating from the servicer will be used, or the one the error translates to when there is none.
 func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error, result *ImplResponse) {
-	if _, ok := err.(*ParsingError); ok {
+	var parsingErr *ParsingError
+	var requiredErr *RequiredError
+	switch {
+	case errors.As(err, &parsingErr):
 		// Handle parsing errors
-		_ = EncodeJSONResponse(Error{err.Error()}, func(i int) *int { return &i }(http.StatusBadRequest), w)
-	} else if _, ok := err.(*RequiredError); ok {
+		_ = EncodeProblemResponse(r, err, http.StatusBadRequest, nil, w)
+	case errors.As(err, &requiredErr):
 		// Handle missing required errors
-		_ = EncodeJSONResponse(Error{err.Error()}, func(i int) *int { return &i }(http.StatusUnprocessableEntity), w)
-	} else {
+		_ = EncodeProblemResponse(r, err, http.StatusUnprocessableEntity, nil, w)
+	case result != nil && result.Code >= http.StatusBadRequest:
+		// Handle errors of the servicer
+		_ = EncodeProblemResponse(r, err, result.Code, result.Headers, w)
+	default:
 		// Handle all other errors
-		_ = EncodeJSONResponse(Error{err.Error()}, &result.Code, w)
+		_ = EncodeProblemResponse(r, err, ErrorStatus(err), nil, w)
 	}
 }
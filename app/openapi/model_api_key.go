@@ -20,13 +20,19 @@ type ApiKey struct {
 	// Unique identifier for the API key
 	Id string `json:"id,omitempty"`
 
-	// The API key token
+	// The API key secret. Only returned when the key is generated or rotated; it is stored hashed and cannot be retrieved again
 	Secret string `json:"secret,omitempty"`
 
+	// The API key token, which combines a prefix, the environment, the key ID, the secret and a checksum in a single string such as 'lny_live_{keyId}_{secret}_{checksum}'. Only returned when the key is generated or rotated
+	Token string `json:"token,omitempty"`
+
+	// Timestamp until which the secret replaced by the last rotation remains valid
+	PreviousSecretExpiry time.Time `json:"previousSecretExpiry,omitempty"`
+
 	// List of roles granted by this API key
 	Roles []string `json:"roles,omitempty"`
 
-	// List of scopes or permissions granted by this API key
+	// List of scopes granted by this API key. Scopes are colon separated segments such as 'billing:invoices:read', and a trailing wildcard such as 'billing:*' grants every scope below its prefix. Scopes are normalized to lower case.
 	Scopes []string `json:"scopes,omitempty"`
 
 	// The actor ID this API key is associated with
@@ -46,10 +52,27 @@ type ApiKey struct {
 
 	// Optional expiration date for the API key
 	Expiry time.Time `json:"expiry,omitempty"`
+
+	// Status of the API key. Keys transition to expired once their expiration date has passed, and to quarantined when their token is reported leaked
+	Status string `json:"status,omitempty"`
+
+	// Rate limits enforced when authorizing requests made with this API key
+	RateLimits []RateLimit `json:"rateLimits,omitempty"`
+
+	// IP addresses and CIDR ranges, in IPv4 or IPv6 notation, from which this API key may be used. Keys without allowed CIDR ranges may be used from any IP address
+	AllowedCidrs []string `json:"allowedCidrs,omitempty"`
+
+	// Origins such as 'https://app.example.com' from which this API key may be used, checked against the Origin or Referer of requests. The leftmost label of a host may be a wildcard such as 'https://*.example.com'. Keys without allowed origins may be used from any origin
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
 }
 
 // AssertApiKeyRequired checks if the required fields are not zero-ed
 func AssertApiKeyRequired(obj ApiKey) error {
+	for _, el := range obj.RateLimits {
+		if err := AssertRateLimitRequired(el); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -20,24 +20,35 @@ type ApiKeyInput struct {
 	// List of roles granted by this API key
 	Roles []string `json:"roles,omitempty"`
 
-	// List of scopes or permissions granted by this API key
+	// List of scopes granted by this API key. Scopes are colon separated segments such as 'billing:invoices:read', and a trailing wildcard such as 'billing:*' grants every scope below its prefix. Scopes are normalized to lower case.
 	Scopes []string `json:"scopes,omitempty"`
 
-	// The actor ID this API key is associated with
-	ActorExternalId string `json:"actorExternalId"`
+	// The external ID of the actor of the service this API key is bound to. Only used when the key is generated. Keys bound to an actor can be listed and revoked with it
+	ActorExternalId string `json:"actorExternalId,omitempty"`
 
 	// Name of the API key
 	Name string `json:"name"`
 
-	// Optional expiration date for the API key
+	// Optional expiration date for the API key. Mutually exclusive with ttlSeconds
 	Expiry time.Time `json:"expiry,omitempty"`
+
+	// Optional lifetime of the API key in seconds, from which its expiration date is computed. Mutually exclusive with expiry
+	TtlSeconds int64 `json:"ttlSeconds,omitempty"`
+
+	// Rate limits enforced when authorizing requests made with this API key
+	RateLimits []RateLimitInput `json:"rateLimits,omitempty"`
+
+	// IP addresses and CIDR ranges, in IPv4 or IPv6 notation, from which this API key may be used. Keys without allowed CIDR ranges may be used from any IP address
+	AllowedCidrs []string `json:"allowedCidrs,omitempty"`
+
+	// Origins such as 'https://app.example.com' from which this API key may be used, checked against the Origin or Referer of requests. The leftmost label of a host may be a wildcard such as 'https://*.example.com'. Keys without allowed origins may be used from any origin
+	AllowedOrigins []string `json:"allowedOrigins,omitempty"`
 }
 
 // AssertApiKeyInputRequired checks if the required fields are not zero-ed
 func AssertApiKeyInputRequired(obj ApiKeyInput) error {
 	elements := map[string]interface{}{
-		"actorExternalId": obj.ActorExternalId,
-		"name":            obj.Name,
+		"name": obj.Name,
 	}
 	for name, el := range elements {
 		if isZero := IsZeroValue(el); isZero {
@@ -45,6 +56,11 @@ func AssertApiKeyInputRequired(obj ApiKeyInput) error {
 		}
 	}
 
+	for _, el := range obj.RateLimits {
+		if err := AssertRateLimitInputRequired(el); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -16,11 +16,23 @@ type AuthApiKey200Response struct {
 
 	Message string `json:"message,omitempty"`
 
+	// Requests remaining under the most constrained rate limit
 	Remaining int32 `json:"remaining,omitempty"`
+
+	RateLimit AuthApiKey200ResponseRateLimit `json:"rateLimit,omitempty"`
+
+	// Whether the request exceeds the actor's monthly request limit and is billed as overage
+	Overage bool `json:"overage,omitempty"`
+
+	// Which secret of the API key was presented. The previous secret of a rotated key is accepted until its grace period ends
+	SecretVersion string `json:"secretVersion,omitempty"`
 }
 
 // AssertAuthApiKey200ResponseRequired checks if the required fields are not zero-ed
 func AssertAuthApiKey200ResponseRequired(obj AuthApiKey200Response) error {
+	if err := AssertAuthApiKey200ResponseRateLimitRequired(obj.RateLimit); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -12,10 +12,14 @@
 package openapi
 
 type AuthApiKey200ResponseRateLimit struct {
+
+	// Seconds until the quota is fully replenished
 	Reset int32 `json:"reset,omitempty"`
 
+	// The number of allowed requests in the rate limit window
 	Limit int32 `json:"limit,omitempty"`
 
+	// Requests remaining in the rate limit window
 	Remaining int32 `json:"remaining,omitempty"`
 }
 
@@ -16,14 +16,23 @@ type AuthApiKeyRequest struct {
 	// The API key provided by the client
 	Secret string `json:"secret,omitempty"`
 
+	// The API key token provided by the client, used instead of secret. Its key ID must match the keyId of the path
+	Token string `json:"token,omitempty"`
+
 	//
 	ActorExternalId string `json:"actorExternalId,omitempty"`
 
 	// The API key provided by the client
 	RequiredRoles []string `json:"requiredRoles,omitempty"`
 
-	// The API key provided by the client
+	// Scopes the API key must be granted, either directly or through a wildcard scope
 	RequiredScopes []string `json:"requiredScopes,omitempty"`
+
+	// IP address of the client making the request, checked against the allowed CIDR ranges of the API key. It is only believed from callers authenticated with another key of the service that is not bound to an actor, such as a backend forwarding its client. The address of any other caller is checked instead.
+	ClientIp string `json:"clientIp,omitempty"`
+
+	// Origin or Referer header of the request, checked against the allowed origins of the API key
+	Origin string `json:"origin,omitempty"`
 }
 
 // AssertAuthApiKeyRequestRequired checks if the required fields are not zero-ed
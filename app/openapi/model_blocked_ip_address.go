@@ -18,14 +18,20 @@ import (
 // BlockedIpAddress - Information of blocked IP address and reason
 type BlockedIpAddress struct {
 
-	// IP address to be blocked
+	// IP address or CIDR range to be blocked, in its canonical form
 	IpAddress string `json:"ipAddress,omitempty"`
 
 	// Reason why the IP address was blocked
 	Reason string `json:"reason,omitempty"`
 
+	// Optional date after which the IP address is no longer blocked
+	Expiry time.Time `json:"expiry,omitempty"`
+
 	// The date when the block was created
 	CreatedAt time.Time `json:"createdAt,omitempty"`
+
+	// The date when the block was last updated
+	UpdatedAt time.Time `json:"updatedAt,omitempty"`
 }
 
 // AssertBlockedIpAddressRequired checks if the required fields are not zero-ed
@@ -11,18 +11,34 @@
 
 package openapi
 
+import (
+	"time"
+)
+
 // BlockedIpAddressInput - Information of blocked IP address and reason
 type BlockedIpAddressInput struct {
 
-	// IP address to be blocked
-	IpAddress string `json:"ipAddress,omitempty"`
+	// IP address or CIDR range to be blocked
+	IpAddress string `json:"ipAddress"`
 
 	// Reason why the IP address was blocked
 	Reason string `json:"reason,omitempty"`
+
+	// Optional date after which the IP address is no longer blocked
+	Expiry time.Time `json:"expiry,omitempty"`
 }
 
 // AssertBlockedIpAddressInputRequired checks if the required fields are not zero-ed
 func AssertBlockedIpAddressInputRequired(obj BlockedIpAddressInput) error {
+	elements := map[string]interface{}{
+		"ipAddress": obj.IpAddress,
+	}
+	for name, el := range elements {
+		if isZero := IsZeroValue(el); isZero {
+			return &RequiredError{Field: name}
+		}
+	}
+
 	return nil
 }
 
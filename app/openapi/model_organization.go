@@ -25,6 +25,12 @@ type Organization struct {
 
 	//
 	StripeAccountId string `json:"stripeAccountId,omitempty"`
+
+	// The ID of the user who created the organization
+	OwnerId string `json:"ownerId,omitempty"`
+
+	// A session token for the owner, scoped to the organization. Only returned when the organization is created
+	SessionToken string `json:"sessionToken,omitempty"`
 }
 
 // AssertOrganizationRequired checks if the required fields are not zero-ed
@@ -14,6 +14,9 @@ package openapi
 // PricingTier - Represents a pricing tier for API usage, defining limits and features associated with the service.
 type PricingTier struct {
 
+	// The unique ID of the pricing tier
+	Id string `json:"id,omitempty"`
+
 	// The name of the pricing tier (e.g., Free, Pro, Enterprise)
 	Name string `json:"name,omitempty"`
 
@@ -22,6 +25,9 @@ type PricingTier struct {
 
 	// The price per extra request beyond the monthly limit
 	OveragePrice float32 `json:"overagePrice,omitempty"`
+
+	// The status of the pricing tier. A retiring pricing tier is being removed, and no actor can be assigned to it
+	Status string `json:"status,omitempty"`
 }
 
 // AssertPricingTierRequired checks if the required fields are not zero-ed
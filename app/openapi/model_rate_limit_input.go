@@ -22,6 +22,12 @@ type RateLimitInput struct {
 
 	// Time window for the rate limit, specified in ISO duration format (e.g., '1h', '30m')
 	Window string `json:"window,omitempty"`
+
+	// The algorithm used to enforce the rate limit
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// The identity requests are counted against
+	Scope string `json:"scope,omitempty"`
 }
 
 // AssertRateLimitInputRequired checks if the required fields are not zero-ed
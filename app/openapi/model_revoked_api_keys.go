@@ -11,18 +11,19 @@
 
 package openapi
 
-type Error struct {
+// RevokedApiKeys - The API keys revoked for an actor
+type RevokedApiKeys struct {
 
-	// Message describing the error that occurred
-	Error string `json:"error,omitempty"`
+	// IDs of the API keys that were revoked
+	RevokedKeyIds []string `json:"revokedKeyIds,omitempty"`
 }
 
-// AssertErrorRequired checks if the required fields are not zero-ed
-func AssertErrorRequired(obj Error) error {
+// AssertRevokedApiKeysRequired checks if the required fields are not zero-ed
+func AssertRevokedApiKeysRequired(obj RevokedApiKeys) error {
 	return nil
 }
 
-// AssertErrorConstraints checks if the values respects the defined constraints
-func AssertErrorConstraints(obj Error) error {
+// AssertRevokedApiKeysConstraints checks if the values respects the defined constraints
+func AssertRevokedApiKeysConstraints(obj RevokedApiKeys) error {
 	return nil
 }
@@ -26,6 +26,15 @@ type Service struct {
 	// A brief description of the service
 	Description string `json:"description,omitempty"`
 
+	// Maximum lifetime in seconds of the API keys of the service. When set, every API key of the service expires
+	MaxKeyTtlSeconds int64 `json:"maxKeyTtlSeconds,omitempty"`
+
+	// Prefix of the tokens of the API keys of the service, such as 'acme' in 'acme_live_...'. Defaults to the prefix configured for the deployment
+	KeyPrefix string `json:"keyPrefix,omitempty"`
+
+	// What happens to API keys of the service whose tokens are reported leaked. Defaults to revoke
+	LeakPolicy string `json:"leakPolicy,omitempty"`
+
 	// Timestamp when the service was created
 	CreatedAt time.Time `json:"createdAt,omitempty"`
 
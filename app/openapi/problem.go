@@ -0,0 +1,140 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+)
+
+// ProblemContentType is the media type of the problem details that describe errors, as defined by RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// throttledRetryAfter is the number of seconds after which a request throttled by DynamoDB can be retried.
+const throttledRetryAfter = "1"
+
+// ErrorCode is a stable identifier of a kind of error. Clients can rely on it, unlike on the detail of a problem.
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest     ErrorCode = "invalid_request"
+	ErrorCodeUnauthenticated    ErrorCode = "unauthenticated"
+	ErrorCodePermissionDenied   ErrorCode = "permission_denied"
+	ErrorCodeNotFound           ErrorCode = "not_found"
+	ErrorCodeConflict           ErrorCode = "conflict"
+	ErrorCodePreconditionFailed ErrorCode = "precondition_failed"
+	ErrorCodeUnprocessable      ErrorCode = "unprocessable_entity"
+	ErrorCodeRateLimited        ErrorCode = "rate_limited"
+	ErrorCodeInternal           ErrorCode = "internal_error"
+	ErrorCodeThrottled          ErrorCode = "throttled"
+)
+
+// errorCodes maps the status of a response to the code of its error.
+var errorCodes = map[int]ErrorCode{
+	http.StatusBadRequest:          ErrorCodeInvalidRequest,
+	http.StatusUnauthorized:        ErrorCodeUnauthenticated,
+	http.StatusForbidden:           ErrorCodePermissionDenied,
+	http.StatusNotFound:            ErrorCodeNotFound,
+	http.StatusConflict:            ErrorCodeConflict,
+	http.StatusPreconditionFailed:  ErrorCodePreconditionFailed,
+	http.StatusUnprocessableEntity: ErrorCodeUnprocessable,
+	http.StatusTooManyRequests:     ErrorCodeRateLimited,
+	http.StatusInternalServerError: ErrorCodeInternal,
+	http.StatusServiceUnavailable:  ErrorCodeThrottled,
+}
+
+// ErrorResponse translates an error to the response of a servicer, which has the status returned by ErrorStatus.
+func ErrorResponse(err error) (ImplResponse, error) {
+	return Response(ErrorStatus(err), nil), err
+}
+
+// ErrorStatus returns the status of the response to an error. Errors of the data access layer get the status of the
+// sentinel error they match, and all other errors are internal errors.
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, dal.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, dal.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, dal.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, dal.ErrThrottled):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, dal.ErrInvalidCursor):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorCode returns the code of the error of a response with the given status.
+func errorCode(status int) ErrorCode {
+	if code, ok := errorCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return ErrorCodeInternal
+	}
+	return ErrorCodeInvalidRequest
+}
+
+// problemDetail explains an error to the client. Internal errors are not explained, and neither are the messages of
+// DynamoDB errors, which are explained by the sentinel error they match instead.
+func problemDetail(status int, err error) string {
+	if err == nil || status >= http.StatusInternalServerError {
+		return ""
+	}
+
+	var notFound *dal.NotFoundError
+	var conflict *dal.ConflictError
+	switch {
+	case errors.As(err, &notFound):
+		return notFound.Error()
+	case errors.As(err, &conflict):
+		return conflict.Error()
+	}
+	for _, sentinel := range []error{dal.ErrNotFound, dal.ErrConflict, dal.ErrPrecondition, dal.ErrInvalidCursor} {
+		if errors.Is(err, sentinel) {
+			return sentinel.Error()
+		}
+	}
+	return err.Error()
+}
+
+// NewProblem returns the problem details that describe the error of a request, which fails with the given status.
+func NewProblem(r *http.Request, status int, err error) Problem {
+	return Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    int32(status),
+		Detail:    problemDetail(status, err),
+		Instance:  r.URL.Path,
+		Code:      string(errorCode(status)),
+		RequestId: middleware.GetReqID(r.Context()),
+	}
+}
+
+// EncodeProblemResponse writes the problem details that describe the error of a request, with the given status and
+// headers. Requests throttled by DynamoDB are told when to retry.
+func EncodeProblemResponse(r *http.Request, err error, status int, headers map[string][]string, w http.ResponseWriter) error {
+	wHeader := w.Header()
+	for key, values := range headers {
+		for _, value := range values {
+			wHeader.Add(key, value)
+		}
+	}
+	if errors.Is(err, dal.ErrThrottled) {
+		wHeader.Set("Retry-After", throttledRetryAfter)
+	}
+
+	return encodeProblem(NewProblem(r, status, err), w)
+}
+
+// encodeProblem writes problem details with their status.
+func encodeProblem(problem Problem, w http.ResponseWriter) error {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(int(problem.Status))
+	return json.NewEncoder(w).Encode(problem)
+}
@@ -0,0 +1,131 @@
+package openapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Not found", err: &dal.NotFoundError{Entity: "service", ID: "serv1"}, code: http.StatusNotFound},
+		{name: "Version conflict", err: &dal.ConflictError{Entity: "service", ID: "serv1", Version: 2}, code: http.StatusConflict},
+		{name: "Failed condition", err: fmt.Errorf("failed to write transaction: %w", dal.ErrPrecondition), code: http.StatusPreconditionFailed},
+		{name: "Throttled", err: fmt.Errorf("failed to get item: %w", dal.ErrThrottled), code: http.StatusServiceUnavailable},
+		{name: "Invalid cursor", err: dal.ErrInvalidCursor, code: http.StatusBadRequest},
+		{name: "Other error", err: errors.New("dynamodb error"), code: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := openapi.ErrorResponse(tt.err)
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.code, response.Code)
+		})
+	}
+}
+
+func TestDefaultErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		result     *openapi.ImplResponse
+		code       int
+		errorCode  openapi.ErrorCode
+		detail     string
+		retryAfter string
+	}{
+		{
+			name:      "Error of the servicer",
+			err:       errors.New("service not found"),
+			result:    &openapi.ImplResponse{Code: http.StatusNotFound},
+			code:      http.StatusNotFound,
+			errorCode: openapi.ErrorCodeNotFound,
+			detail:    "service not found",
+		},
+		{
+			name:      "Version conflict",
+			err:       &dal.ConflictError{Entity: "service", ID: "serv1", Version: 2},
+			result:    &openapi.ImplResponse{Code: http.StatusPreconditionFailed},
+			code:      http.StatusPreconditionFailed,
+			errorCode: openapi.ErrorCodePreconditionFailed,
+			detail:    "service 'serv1' was changed since version 2",
+		},
+		{
+			name:      "Failed condition",
+			err:       fmt.Errorf("failed to write transaction: operation error DynamoDB: %w", dal.ErrPrecondition),
+			code:      http.StatusPreconditionFailed,
+			errorCode: openapi.ErrorCodePreconditionFailed,
+			detail:    "precondition failed",
+		},
+		{
+			name:       "Throttled",
+			err:        fmt.Errorf("failed to get item: %w", dal.ErrThrottled),
+			result:     &openapi.ImplResponse{Code: http.StatusServiceUnavailable},
+			code:       http.StatusServiceUnavailable,
+			errorCode:  openapi.ErrorCodeThrottled,
+			retryAfter: "1",
+		},
+		{
+			name:      "Internal error",
+			err:       errors.New("failed to get item from DynamoDB: connection reset"),
+			result:    &openapi.ImplResponse{Code: http.StatusInternalServerError},
+			code:      http.StatusInternalServerError,
+			errorCode: openapi.ErrorCodeInternal,
+		},
+		{
+			name:      "Parsing error",
+			err:       &openapi.ParsingError{Err: errors.New("invalid character")},
+			code:      http.StatusBadRequest,
+			errorCode: openapi.ErrorCodeInvalidRequest,
+			detail:    "invalid character",
+		},
+		{
+			name:      "Missing field",
+			err:       &openapi.RequiredError{Field: "name"},
+			code:      http.StatusUnprocessableEntity,
+			errorCode: openapi.ErrorCodeUnprocessable,
+			detail:    "required field 'name' is zero value.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/services/serv1", nil)
+			r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
+			w := httptest.NewRecorder()
+
+			openapi.DefaultErrorHandler(w, r, tt.err, tt.result)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, openapi.ProblemContentType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.retryAfter, w.Header().Get("Retry-After"))
+
+			var problem openapi.Problem
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+			assert.Equal(t, openapi.Problem{
+				Type:      "about:blank",
+				Title:     http.StatusText(tt.code),
+				Status:    int32(tt.code),
+				Detail:    tt.detail,
+				Instance:  "/v1/services/serv1",
+				Code:      string(tt.errorCode),
+				RequestId: "req-1",
+			}, problem)
+		})
+	}
+}
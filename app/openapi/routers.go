@@ -16,8 +16,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/clientip"
 	"github.com/payloadops/lanyard/app/config"
 	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/ipblock"
 )
 
 // requestTimeout defines the time that a handler will take to process the request before timing out.
@@ -42,26 +45,30 @@ const errMsgRequiredMissing = "required parameter is missing"
 const errMsgMinValueConstraint = "provided parameter is not respecting minimum value constraint"
 const errMsgMaxValueConstraint = "provided parameter is not respecting maximum value constraint"
 
-// NewRouter creates a new router for any number of api routers
-func NewRouter(cfg *config.Config, logger *zap.Logger, apiKeyDBClient *dal.APIKeyDBClient, routers ...Router) chi.Router {
+// NewRouter creates a new router for any number of api routers. API keys are rejected when their service was deleted.
+// The JWKS verifies asymmetric JWTs and the blocklist rejects API keys used from blocked IP addresses, and either may
+// be nil. The client IP resolver decides which forwarded addresses to believe, and trusts no proxy when nil.
+func NewRouter(cfg *config.Config, logger *zap.Logger, apiKeyManager dal.APIKeyManager, serviceManager dal.ServiceManager, jwks *auth.JWKS, blocklist *ipblock.Matcher, clientIPs *clientip.Resolver, routers ...Router) chi.Router {
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(clientIPs.Middleware)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.Timeout(requestTimeout))
 	router.Use(middleware.AllowContentType("application/json"))
 
+	// Authenticate each route with the security schemes of its operation, leaving open routes such as the health check,
+	// and check that the role of the caller grants the permission the operation requires
+	middlewares := securityMiddlewares(cfg, logger, apiKeyManager, serviceManager, jwks, blocklist)
 	for _, api := range routers {
-		for _, route := range api.Routes() {
-			var handler http.Handler
-			handler = route.HandlerFunc
-			// Skip auth middleware for health check
-			if route.Pattern == "/v1/health" {
-				router.Method(route.Method, route.Pattern, handler)
-			} else {
-				router.Method(route.Method, route.Pattern, (handler))
+		for name, route := range api.Routes() {
+			var handler http.Handler = route.HandlerFunc
+			schemes := routeSecurity(name)
+			if requiresRole(schemes) {
+				handler = permissionHandler(handler, RoutePermissions[name], logger)
 			}
+			handler = securityHandler(handler, schemes, middlewares)
+			router.Method(route.Method, route.Pattern, handler)
 		}
 	}
 
@@ -69,8 +76,13 @@ func NewRouter(cfg *config.Config, logger *zap.Logger, apiKeyDBClient *dal.APIKe
 }
 
 // EncodeJSONResponse uses the json encoder to write an interface to the http response with an optional status code
-func EncodeJSONResponse(i interface{}, status *int, w http.ResponseWriter) error {
+func EncodeJSONResponse(i interface{}, status *int, headers map[string][]string, w http.ResponseWriter) error {
 	wHeader := w.Header()
+	for key, values := range headers {
+		for _, value := range values {
+			wHeader.Add(key, value)
+		}
+	}
 
 	f, ok := i.(*os.File)
 	if ok {
@@ -0,0 +1,418 @@
+package openapi_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contextRouter serves a few of the real routes with a handler that echoes the authenticated identity.
+type contextRouter struct{}
+
+func (contextRouter) Routes() openapi.Routes {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var parts []string
+		for _, key := range []string{"orgID", "serviceID", "userID", "partnerID"} {
+			if value, ok := r.Context().Value(key).(string); ok {
+				parts = append(parts, key+"="+value)
+			}
+		}
+		_, _ = w.Write([]byte(strings.Join(parts, ",")))
+	}
+
+	return openapi.Routes{
+		"HealthCheck":       openapi.Route{Method: http.MethodGet, Pattern: "/v1/health", HandlerFunc: handler},
+		"ListServices":      openapi.Route{Method: http.MethodGet, Pattern: "/v1/services", HandlerFunc: handler},
+		"CreateService":     openapi.Route{Method: http.MethodPost, Pattern: "/v1/services", HandlerFunc: handler},
+		"Unlisted":          openapi.Route{Method: http.MethodGet, Pattern: "/v1/unlisted", HandlerFunc: handler},
+		"AuthApiKey":        openapi.Route{Method: http.MethodPost, Pattern: "/v1/services/{serviceId}/key/{keyId}/auth", HandlerFunc: handler},
+		"OrganizationsPost": openapi.Route{Method: http.MethodPost, Pattern: "/v1/organizations", HandlerFunc: handler},
+		"ReportLeaks":       openapi.Route{Method: http.MethodPost, Pattern: "/v1/leaks", HandlerFunc: handler},
+	}
+}
+
+func TestNewRouter_Security(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfg := &config.Config{
+		JWTSecret: "secret",
+		APIKeys:   config.APIKeysConfig{SecretPepper: "pepper", TokenEnvironment: "live"},
+		Leaks:     config.LeaksConfig{PartnerSecrets: map[string]string{"scanner": "scanner-secret"}, SignatureTolerance: time.Minute},
+	}
+
+	secretHash, err := utils.HashSecret("keySecret", cfg.APIKeys.SecretPepper)
+	require.NoError(t, err)
+	tokenSecret, err := apitoken.GenerateSecret()
+	require.NoError(t, err)
+	tokenSecretHash, err := utils.HashSecret(tokenSecret, cfg.APIKeys.SecretPepper)
+	require.NoError(t, err)
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockAPIKeyClient.EXPECT().GetAPIKey(gomock.Any(), "key1").Return(&dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Secret:    secretHash,
+	}, nil).AnyTimes()
+	mockAPIKeyClient.EXPECT().GetAPIKey(gomock.Any(), "key3").Return(&dal.APIKey{
+		APIKeyID:  "key3",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Secret:    tokenSecretHash,
+	}, nil).AnyTimes()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockServiceClient.EXPECT().GetService(gomock.Any(), "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil).AnyTimes()
+
+	sessionToken, err := auth.NewSessionToken(cfg, "org1", "user1", auth.RoleViewer)
+	require.NoError(t, err)
+	adminToken, err := auth.NewSessionToken(cfg, "org1", "user1", auth.RoleAdmin)
+	require.NoError(t, err)
+	operatorToken, err := auth.NewSessionToken(cfg, "", "user1", "")
+	require.NoError(t, err)
+	apiKey := base64.StdEncoding.EncodeToString([]byte("key1:keySecret"))
+	apiKeyToken := apitoken.Token{Prefix: "lny", Environment: "live", KeyID: "key3", Secret: tokenSecret}.String()
+	now := time.Now()
+	partnerHeaders := map[string]string{
+		auth.PartnerHeader:          "scanner",
+		auth.PartnerTimestampHeader: strconv.FormatInt(now.Unix(), 10),
+		auth.PartnerSignatureHeader: auth.SignPartnerRequest("scanner-secret", now, nil),
+	}
+
+	server := httptest.NewServer(openapi.NewRouter(cfg, zap.NewNop(), mockAPIKeyClient, mockServiceClient, nil, nil, nil, contextRouter{}))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		authHeader     string
+		headers        map[string]string
+		expectedStatus int
+		expectedBody   string
+		// expectedProblem holds the fields of the problem details of a rejected request that are checked
+		expectedProblem *openapi.Problem
+	}{
+		{
+			name:           "Health check is open",
+			method:         http.MethodGet,
+			path:           "/v1/health",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Management route with session token",
+			method:         http.MethodGet,
+			path:           "/v1/services",
+			authHeader:     "Bearer " + sessionToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "orgID=org1,userID=user1",
+		},
+		{
+			name:           "Management route without credentials",
+			method:         http.MethodGet,
+			path:           "/v1/services",
+			expectedStatus: http.StatusUnauthorized,
+			expectedProblem: &openapi.Problem{
+				Detail: "missing Authorization header",
+				Code:   "unauthenticated",
+			},
+		},
+		{
+			name:           "Management route with API key",
+			method:         http.MethodGet,
+			path:           "/v1/services",
+			authHeader:     "Basic " + apiKey,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Management route with API key token",
+			method:         http.MethodGet,
+			path:           "/v1/services",
+			authHeader:     "Bearer " + apiKeyToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Management route with operator token",
+			method:         http.MethodGet,
+			path:           "/v1/services",
+			authHeader:     "Bearer " + operatorToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Management route with role granting the permission",
+			method:         http.MethodPost,
+			path:           "/v1/services",
+			authHeader:     "Bearer " + adminToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "orgID=org1,userID=user1",
+		},
+		{
+			name:           "Management route with role missing the permission",
+			method:         http.MethodPost,
+			path:           "/v1/services",
+			authHeader:     "Bearer " + sessionToken,
+			expectedStatus: http.StatusForbidden,
+			expectedProblem: &openapi.Problem{
+				Detail:             "role 'viewer' does not grant permission 'services:write'",
+				Code:               "permission_denied",
+				Role:               "viewer",
+				RequiredPermission: "services:write",
+			},
+		},
+		{
+			name:           "Management route without a permission",
+			method:         http.MethodGet,
+			path:           "/v1/unlisted",
+			authHeader:     "Bearer " + adminToken,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Auth route with API key",
+			method:         http.MethodPost,
+			path:           "/v1/services/serv1/key/key2/auth",
+			authHeader:     "Basic " + apiKey,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "orgID=org1,serviceID=serv1",
+		},
+		{
+			name:           "Auth route with API key token",
+			method:         http.MethodPost,
+			path:           "/v1/services/serv1/key/key2/auth",
+			authHeader:     "Bearer " + apiKeyToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "orgID=org1,serviceID=serv1",
+		},
+		{
+			name:           "Auth route with API key token of another environment",
+			method:         http.MethodPost,
+			path:           "/v1/services/serv1/key/key2/auth",
+			authHeader:     "Bearer " + apitoken.Token{Prefix: "lny", Environment: "test", KeyID: "key3", Secret: tokenSecret}.String(),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Auth route with wrong API key secret",
+			method:         http.MethodPost,
+			path:           "/v1/services/serv1/key/key2/auth",
+			authHeader:     "Basic " + base64.StdEncoding.EncodeToString([]byte("key1:wrongSecret")),
+			expectedStatus: http.StatusUnauthorized,
+			expectedProblem: &openapi.Problem{
+				Detail: "invalid API key",
+				Code:   "unauthenticated",
+			},
+		},
+		{
+			name:           "Auth route with session token",
+			method:         http.MethodPost,
+			path:           "/v1/services/serv1/key/key2/auth",
+			authHeader:     "Bearer " + sessionToken,
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Organization creation with operator token",
+			method:         http.MethodPost,
+			path:           "/v1/organizations",
+			authHeader:     "Bearer " + operatorToken,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "userID=user1",
+		},
+		{
+			name:           "Organization creation without credentials",
+			method:         http.MethodPost,
+			path:           "/v1/organizations",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Leak report with partner signature",
+			method:         http.MethodPost,
+			path:           "/v1/leaks",
+			headers:        partnerHeaders,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "partnerID=scanner",
+		},
+		{
+			name:           "Leak report without partner signature",
+			method:         http.MethodPost,
+			path:           "/v1/leaks",
+			authHeader:     "Bearer " + adminToken,
+			expectedStatus: http.StatusUnauthorized,
+			expectedProblem: &openapi.Problem{
+				Detail: "missing partner signature",
+				Code:   "unauthenticated",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			require.NoError(t, err)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+
+			// Rejected requests are described with problem details, like the errors of the handlers
+			if resp.StatusCode >= http.StatusBadRequest {
+				assert.Equal(t, openapi.ProblemContentType, resp.Header.Get("Content-Type"))
+
+				var problem openapi.Problem
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+				assert.Equal(t, int32(tt.expectedStatus), problem.Status)
+				assert.Equal(t, tt.path, problem.Instance)
+				assert.NotEmpty(t, problem.RequestId)
+				if tt.expectedProblem != nil {
+					assert.Equal(t, tt.expectedProblem.Detail, problem.Detail)
+					assert.Equal(t, tt.expectedProblem.Code, problem.Code)
+					assert.Equal(t, tt.expectedProblem.Role, problem.Role)
+					assert.Equal(t, tt.expectedProblem.RequiredPermission, problem.RequiredPermission)
+				}
+			}
+		})
+	}
+}
+
+// specDocument is the part of the OpenAPI document that declares security requirements.
+type specDocument struct {
+	Security []map[string][]string               `yaml:"security"`
+	Paths    map[string]map[string]specOperation `yaml:"paths"`
+}
+
+type specOperation struct {
+	Security   *[]map[string][]string `yaml:"security"`
+	Permission auth.Permission        `yaml:"x-permission"`
+}
+
+// TestRouteSecurity_MatchesSpec fails when the security or permission of a route drifts from its operation in the
+// spec, or when RouteSecurity or RoutePermissions list a route that does not exist.
+func TestRouteSecurity_MatchesSpec(t *testing.T) {
+	for _, file := range []string{"../api/openapi.yaml", "../../spec/spec/spec.v1.yaml"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			var spec specDocument
+			require.NoError(t, yaml.Unmarshal(data, &spec))
+
+			routers := []openapi.Router{
+				openapi.NewHealthCheckAPIController(nil),
+				openapi.NewOrganizationsAPIController(nil),
+				openapi.NewServicesAPIController(nil),
+				openapi.NewAPIKeysAPIController(nil),
+				openapi.NewBlockedIPsAPIController(nil),
+				openapi.NewLeaksAPIController(nil),
+				openapi.NewActorsAPIController(nil),
+				openapi.NewPricingTierAPIController(nil),
+				openapi.NewUsageAPIController(nil),
+				openapi.NewAuditAPIController(nil),
+			}
+
+			names := map[string]bool{}
+			operations := 0
+			for _, router := range routers {
+				for name, route := range router.Routes() {
+					names[name] = true
+
+					// The servers of the document include the version prefix of the routes
+					path := strings.TrimPrefix(route.Pattern, "/v1")
+					operation, ok := spec.Paths[path][strings.ToLower(route.Method)]
+					if !assert.True(t, ok, "route %s has no operation in the spec", name) {
+						continue
+					}
+					operations++
+
+					requirements := spec.Security
+					if operation.Security != nil {
+						requirements = *operation.Security
+					}
+
+					var expected []openapi.SecurityScheme
+					for _, requirement := range requirements {
+						for scheme := range requirement {
+							expected = append(expected, openapi.SecurityScheme(scheme))
+						}
+					}
+
+					schemes, ok := openapi.RouteSecurity[name]
+					if !ok {
+						schemes = []openapi.SecurityScheme{openapi.BearerAuth}
+					}
+					assert.ElementsMatch(t, expected, schemes, "security of route %s", name)
+
+					// Every route of a user needs the permission of its operation, otherwise it is denied to every role
+					permission, hasPermission := openapi.RoutePermissions[name]
+					assert.Equal(t, contains(schemes, openapi.BearerAuth), hasPermission, "permission of route %s", name)
+					assert.Equal(t, operation.Permission, permission, "permission of route %s", name)
+				}
+			}
+
+			// Every operation of the spec is served
+			specOperations := 0
+			for _, methods := range spec.Paths {
+				specOperations += len(methods)
+			}
+			assert.Equal(t, specOperations, operations, "operations of the spec without a route")
+
+			for name := range openapi.RouteSecurity {
+				assert.True(t, names[name], "RouteSecurity lists unknown route %s", name)
+			}
+			for name := range openapi.RoutePermissions {
+				assert.True(t, names[name], "RoutePermissions lists unknown route %s", name)
+			}
+		})
+	}
+}
+
+func contains(schemes []openapi.SecurityScheme, scheme openapi.SecurityScheme) bool {
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEncodeJSONResponse_Headers(t *testing.T) {
+	w := httptest.NewRecorder()
+	status := http.StatusOK
+
+	err := openapi.EncodeJSONResponse(map[string]string{"id": "serv1"}, &status, map[string][]string{"ETag": {`"3"`}}, w)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	assert.Equal(t, "application/json; charset=UTF-8", w.Header().Get("Content-Type"))
+}
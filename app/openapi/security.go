@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/ipblock"
+)
+
+// SecurityScheme names one of the security schemes declared in the OpenAPI document.
+type SecurityScheme string
+
+const (
+	// ApiKeyAuth authenticates a service with the token of one of its API keys, or with its ID and secret using HTTP
+	// Basic auth.
+	ApiKeyAuth SecurityScheme = "ApiKeyAuth"
+	// BearerAuth authenticates a user with a JWT scoped to an organization.
+	BearerAuth SecurityScheme = "BearerAuth"
+	// OperatorBearerAuth authenticates a user with a JWT that does not need to be scoped to an organization.
+	OperatorBearerAuth SecurityScheme = "OperatorBearerAuth"
+	// PartnerSignatureAuth authenticates a partner, such as a secret scanning service, with a signature of the request.
+	PartnerSignatureAuth SecurityScheme = "PartnerSignatureAuth"
+)
+
+// RouteSecurity maps each route to the security requirements of its operation in the OpenAPI document. Any one of
+// the listed schemes authenticates a request, and routes with no schemes are open. Routes that are not listed
+// require BearerAuth, like the document's global security requirement.
+// TestRouteSecurity_MatchesSpec fails when it drifts from the document.
+var RouteSecurity = map[string][]SecurityScheme{
+	"HealthCheck":       {},
+	"AuthApiKey":        {ApiKeyAuth},
+	"OrganizationsPost": {OperatorBearerAuth},
+	"ReportLeaks":       {PartnerSignatureAuth},
+}
+
+// RoutePermissions maps each route authenticated with BearerAuth to the permission its operation requires, which the
+// OpenAPI document names in the x-permission extension of the operation. The role of the caller must grant the
+// permission, otherwise the request is denied before it reaches the handler. Routes authenticated with BearerAuth that
+// are not listed are denied to every role. TestRouteSecurity_MatchesSpec fails when it drifts from the document.
+var RoutePermissions = map[string]auth.Permission{
+	"OrganizationsOrganizationIdGet":    auth.PermissionOrganizationRead,
+	"OrganizationsOrganizationIdPut":    auth.PermissionOrganizationWrite,
+	"OrganizationsOrganizationIdDelete": auth.PermissionOrganizationDelete,
+
+	"ListServices":  auth.PermissionServicesRead,
+	"GetService":    auth.PermissionServicesRead,
+	"CreateService": auth.PermissionServicesWrite,
+	"UpdateService": auth.PermissionServicesWrite,
+	"DeleteService": auth.PermissionServicesWrite,
+
+	"ListBlockedIps":  auth.PermissionServicesRead,
+	"GetBlockedIp":    auth.PermissionServicesRead,
+	"BlockIp":         auth.PermissionServicesWrite,
+	"UpdateBlockedIp": auth.PermissionServicesWrite,
+	"UnblockIp":       auth.PermissionServicesWrite,
+
+	"ListApiKeys":    auth.PermissionAPIKeysRead,
+	"GetApiKey":      auth.PermissionAPIKeysRead,
+	"GenerateApiKey": auth.PermissionAPIKeysWrite,
+	"UpdateApiKey":   auth.PermissionAPIKeysWrite,
+	"RotateApiKey":   auth.PermissionAPIKeysWrite,
+	"DeleteApiKey":   auth.PermissionAPIKeysWrite,
+
+	"ListActorApiKeys":   auth.PermissionAPIKeysRead,
+	"RevokeActorApiKeys": auth.PermissionAPIKeysWrite,
+
+	"ServicesServiceIdActorsGet":                   auth.PermissionActorsRead,
+	"ServicesServiceIdActorsActorExternalIdGet":    auth.PermissionActorsRead,
+	"ServicesServiceIdActorsPost":                  auth.PermissionActorsWrite,
+	"ServicesServiceIdActorsActorExternalIdPut":    auth.PermissionActorsWrite,
+	"ServicesServiceIdActorsActorExternalIdDelete": auth.PermissionActorsWrite,
+
+	"ServicesServiceIdPricingTiersGet":          auth.PermissionPricingTiersRead,
+	"ServicesServiceIdPricingTiersTierIdGet":    auth.PermissionPricingTiersRead,
+	"ServicesServiceIdPricingTiersPost":         auth.PermissionPricingTiersWrite,
+	"ServicesServiceIdPricingTiersTierIdPut":    auth.PermissionPricingTiersWrite,
+	"ServicesServiceIdPricingTiersTierIdDelete": auth.PermissionPricingTiersWrite,
+
+	"GetServiceUsage": auth.PermissionUsageRead,
+	"GetActorUsage":   auth.PermissionUsageRead,
+
+	"ListAuditEvents": auth.PermissionAuditRead,
+}
+
+// routeSecurity returns the security requirements of a route.
+func routeSecurity(name string) []SecurityScheme {
+	schemes, ok := RouteSecurity[name]
+	if !ok {
+		return []SecurityScheme{BearerAuth}
+	}
+	return schemes
+}
+
+// requiresRole reports whether the callers of a route are users whose role is checked, which is the case for routes
+// authenticated with BearerAuth.
+func requiresRole(schemes []SecurityScheme) bool {
+	for _, scheme := range schemes {
+		if scheme == BearerAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// writeProblem describes the error of a request rejected by an authentication middleware with problem details, like
+// the errors of the handlers.
+func writeProblem(w http.ResponseWriter, r *http.Request, err error, status int) {
+	_ = EncodeProblemResponse(r, err, status, nil, w)
+}
+
+// securityMiddlewares builds the authentication middleware of each security scheme.
+func securityMiddlewares(cfg *config.Config, logger *zap.Logger, apiKeyManager dal.APIKeyManager, serviceManager dal.ServiceManager, jwks *auth.JWKS, blocklist *ipblock.Matcher) map[SecurityScheme]func(http.Handler) http.Handler {
+	return map[SecurityScheme]func(http.Handler) http.Handler{
+		ApiKeyAuth:           auth.APIKeyAuthMiddleware(cfg, logger, writeProblem, apiKeyManager, serviceManager, blocklist),
+		BearerAuth:           auth.JWTAuthMiddleware(cfg, logger, writeProblem, jwks),
+		OperatorBearerAuth:   auth.JWTSubjectAuthMiddleware(cfg, logger, writeProblem, jwks),
+		PartnerSignatureAuth: auth.PartnerSignatureMiddleware(cfg, logger, writeProblem),
+	}
+}
+
+// securityHandler wraps a handler with the authentication of its security schemes. When several schemes are allowed,
+// the one matching the type of the Authorization header is used.
+func securityHandler(handler http.Handler, schemes []SecurityScheme, middlewares map[SecurityScheme]func(http.Handler) http.Handler) http.Handler {
+	if len(schemes) == 0 {
+		return handler
+	}
+
+	if len(schemes) == 1 {
+		return middlewares[schemes[0]](handler)
+	}
+
+	handlers := make(map[SecurityScheme]http.Handler, len(schemes))
+	for _, scheme := range schemes {
+		handlers[scheme] = middlewares[scheme](handler)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		for _, scheme := range schemes {
+			if scheme == ApiKeyAuth && !auth.IsAPIKeyAuthorization(authHeader) {
+				continue
+			}
+			if scheme != ApiKeyAuth && (!strings.HasPrefix(authHeader, "Bearer ") || auth.IsAPIKeyAuthorization(authHeader)) {
+				continue
+			}
+
+			handlers[scheme].ServeHTTP(w, r)
+			return
+		}
+
+		// None of the schemes match, so let the first one reject the request
+		handlers[schemes[0]].ServeHTTP(w, r)
+	})
+}
+
+// permissionHandler wraps a handler with a check that the role of the caller grants a permission. Denials are
+// reported with problem details naming the role and the missing permission.
+func permissionHandler(handler http.Handler, permission auth.Permission, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value("role").(auth.Role)
+		if !role.Can(permission) {
+			logger.Warn("permission denied",
+				zap.String("requestID", middleware.GetReqID(r.Context())),
+				zap.String("role", string(role)),
+				zap.String("permission", string(permission)),
+			)
+
+			problem := NewProblem(r, http.StatusForbidden, fmt.Errorf("role '%s' does not grant permission '%s'", role, permission))
+			problem.Role = string(role)
+			problem.RequiredPermission = string(permission)
+			_ = encodeProblem(problem, w)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/cache"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// Each script receives the counter key as KEYS[1] and the current time in milliseconds, the window in
+// milliseconds, the limit and a unique request member as ARGV[1..4]. Each returns {allowed, remaining, reset}
+// with reset in milliseconds.
+const (
+	fixedWindowScript = `
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		local count = redis.call('INCR', KEYS[1])
+		if count == 1 then
+			redis.call('PEXPIRE', KEYS[1], window)
+		end
+		local ttl = redis.call('PTTL', KEYS[1])
+		if ttl < 0 then
+			redis.call('PEXPIRE', KEYS[1], window)
+			ttl = window
+		end
+		local allowed = 0
+		if count <= limit then
+			allowed = 1
+		end
+		return {allowed, math.max(limit - count, 0), ttl}
+	`
+
+	slidingWindowLogScript = `
+		local now = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local limit = tonumber(ARGV[3])
+		redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+		local count = redis.call('ZCARD', KEYS[1])
+		local allowed = 0
+		if count < limit then
+			redis.call('ZADD', KEYS[1], now, ARGV[4])
+			count = count + 1
+			allowed = 1
+		end
+		local reset = 0
+		local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+		if newest[2] then
+			reset = tonumber(newest[2]) + window - now
+			redis.call('PEXPIRE', KEYS[1], reset)
+		end
+		return {allowed, limit - count, reset}
+	`
+
+	tokenBucketScript = `
+		local now = tonumber(ARGV[1])
+		local window = tonumber(ARGV[2])
+		local capacity = tonumber(ARGV[3])
+		local rate = capacity / window
+		local state = redis.call('HMGET', KEYS[1], 'tokens', 'updatedAt')
+		local tokens = tonumber(state[1])
+		local updatedAt = tonumber(state[2])
+		if tokens == nil or updatedAt == nil then
+			tokens = capacity
+			updatedAt = now
+		end
+		tokens = math.min(capacity, tokens + math.max(now - updatedAt, 0) * rate)
+		local allowed = 0
+		if tokens >= 1 then
+			tokens = tokens - 1
+			allowed = 1
+		end
+		redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updatedAt', now)
+		redis.call('PEXPIRE', KEYS[1], window)
+		return {allowed, math.floor(tokens), math.ceil((capacity - tokens) / rate)}
+	`
+)
+
+// Ensure CacheLimiter implements the Limiter interface
+var _ Limiter = &CacheLimiter{}
+
+// CacheLimiter implements the Limiter interface with Lua scripts evaluated atomically by the cache, so limits
+// are shared by every instance using the same cache. When the cache cannot evaluate scripts, requests are
+// counted by a process-local MemoryLimiter instead.
+type CacheLimiter struct {
+	cache    cache.Cache
+	fallback *MemoryLimiter
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+// NewCacheLimiter creates a new CacheLimiter.
+func NewCacheLimiter(cache cache.Cache, logger *zap.Logger) *CacheLimiter {
+	return &CacheLimiter{
+		cache:    cache,
+		fallback: NewMemoryLimiter(),
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Allow consumes a single request from the rule's quota for the given identity.
+func (c *CacheLimiter) Allow(ctx context.Context, rule Rule, identity Identity) (Result, error) {
+	key, ok := rule.key(identity)
+	if !ok {
+		return Result{Allowed: true, Limit: rule.Limit, Remaining: rule.Limit}, nil
+	}
+
+	var script string
+	switch rule.Algorithm {
+	case SlidingWindowLog:
+		script = slidingWindowLogScript
+	case TokenBucket:
+		script = tokenBucketScript
+	default:
+		script = fixedWindowScript
+	}
+
+	member, err := utils.GenerateKSUID()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to create ksuid: %v", err)
+	}
+
+	value, err := c.cache.Eval(ctx, script, []string{key}, c.now().UnixMilli(), rule.Window.Milliseconds(), rule.Limit, member)
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotSupported) {
+			c.logger.Warn("failed to evaluate rate limit script, falling back to local limiter",
+				zap.String("requestID", middleware.GetReqID(ctx)),
+				zap.Error(err),
+			)
+		}
+
+		return c.fallback.Allow(ctx, rule, identity)
+	}
+
+	return parseScriptResult(rule, value)
+}
+
+// parseScriptResult converts the {allowed, remaining, reset} reply of a rate limit script into a Result.
+func parseScriptResult(rule Rule, value interface{}) (Result, error) {
+	values, ok := value.([]interface{})
+	if !ok || len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result: %v", value)
+	}
+
+	parsed := make([]int64, len(values))
+	for i, v := range values {
+		n, ok := v.(int64)
+		if !ok {
+			return Result{}, fmt.Errorf("unexpected rate limit script result: %v", value)
+		}
+		parsed[i] = n
+	}
+
+	return Result{
+		Allowed:   parsed[0] == 1,
+		Limit:     rule.Limit,
+		Remaining: max(parsed[1], 0),
+		Reset:     time.Duration(parsed[2]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/payloadops/lanyard/app/cache"
+	"github.com/payloadops/lanyard/app/cache/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestCacheLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	identity := Identity{OrgID: "org1", ServiceID: "serv1", KeyID: "key1"}
+
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		script    string
+		reply     interface{}
+		expected  Result
+	}{
+		{
+			name:      "Fixed window",
+			algorithm: FixedWindow,
+			script:    fixedWindowScript,
+			reply:     []interface{}{int64(1), int64(4), int64(30000)},
+			expected:  Result{Allowed: true, Limit: 5, Remaining: 4, Reset: 30 * time.Second},
+		},
+		{
+			name:      "Sliding window log",
+			algorithm: SlidingWindowLog,
+			script:    slidingWindowLogScript,
+			reply:     []interface{}{int64(0), int64(0), int64(1500)},
+			expected:  Result{Allowed: false, Limit: 5, Remaining: 0, Reset: 1500 * time.Millisecond},
+		},
+		{
+			name:      "Token bucket",
+			algorithm: TokenBucket,
+			script:    tokenBucketScript,
+			reply:     []interface{}{int64(1), int64(2), int64(36000)},
+			expected:  Result{Allowed: true, Limit: 5, Remaining: 2, Reset: 36 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRedisClient := mocks.NewMockCmdable(ctrl)
+			limiter := NewCacheLimiter(cache.NewRedisCache(mockRedisClient), zap.NewNop())
+			limiter.now = func() time.Time { return now }
+
+			rule := Rule{Name: "minute", Algorithm: tt.algorithm, Scope: ScopeKey, Limit: 5, Window: time.Minute}
+			ctx := context.Background()
+
+			mockRedisClient.EXPECT().
+				Eval(ctx, tt.script, []string{"RateLimit#" + string(tt.algorithm) + "#Key#key1#minute"}, now.UnixMilli(), int64(60000), int64(5), gomock.Any()).
+				Return(redis.NewCmdResult(tt.reply, nil))
+
+			result, err := limiter.Allow(ctx, rule, identity)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCacheLimiter_Allow_UnexpectedReply(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCache := mocks.NewMockCache(ctrl)
+	limiter := NewCacheLimiter(mockCache, zap.NewNop())
+	rule := Rule{Name: "minute", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 5, Window: time.Minute}
+
+	mockCache.EXPECT().
+		Eval(gomock.Any(), fixedWindowScript, gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("OK", nil)
+
+	_, err := limiter.Allow(context.Background(), rule, Identity{KeyID: "key1"})
+	assert.Error(t, err)
+}
+
+func TestCacheLimiter_Allow_Fallback(t *testing.T) {
+	rule := Rule{Name: "minute", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 1, Window: time.Minute}
+	identity := Identity{KeyID: "key1"}
+
+	t.Run("Noop cache", func(t *testing.T) {
+		limiter := NewCacheLimiter(cache.NewNoopCache(), zap.NewNop())
+
+		result, err := limiter.Allow(context.Background(), rule, identity)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, err = limiter.Allow(context.Background(), rule, identity)
+		assert.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+
+	t.Run("Cache error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockCache := mocks.NewMockCache(ctrl)
+		limiter := NewCacheLimiter(mockCache, zap.NewNop())
+
+		mockCache.EXPECT().
+			Eval(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("connection refused")).
+			Times(2)
+
+		result, err := limiter.Allow(context.Background(), rule, identity)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+
+		result, err = limiter.Allow(context.Background(), rule, identity)
+		assert.NoError(t, err)
+		assert.False(t, result.Allowed)
+	})
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval represents the number of calls between sweeps of expired counters.
+const sweepInterval = 1024
+
+// Ensure MemoryLimiter implements the Limiter interface
+var _ Limiter = &MemoryLimiter{}
+
+// memoryCounter holds the state of a single rate limit counter.
+type memoryCounter struct {
+	count     int64       // fixed window
+	log       []time.Time // sliding window log
+	tokens    float64     // token bucket
+	updatedAt time.Time   // token bucket
+	expiresAt time.Time
+}
+
+// MemoryLimiter implements the Limiter interface using process-local state. Limits are enforced per process,
+// so it is only suitable as a fallback or for single instance deployments.
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	calls    int
+	now      func() time.Time
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter.
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		counters: make(map[string]*memoryCounter),
+		now:      time.Now,
+	}
+}
+
+// Allow consumes a single request from the rule's quota for the given identity.
+func (m *MemoryLimiter) Allow(ctx context.Context, rule Rule, identity Identity) (Result, error) {
+	key, ok := rule.key(identity)
+	if !ok {
+		return Result{Allowed: true, Limit: rule.Limit, Remaining: rule.Limit}, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+
+	counter, ok := m.counters[key]
+	if !ok || !now.Before(counter.expiresAt) {
+		counter = &memoryCounter{tokens: float64(rule.Limit), updatedAt: now}
+		m.counters[key] = counter
+	}
+
+	switch rule.Algorithm {
+	case SlidingWindowLog:
+		return m.allowSlidingWindowLog(counter, rule, now), nil
+	case TokenBucket:
+		return m.allowTokenBucket(counter, rule, now), nil
+	default:
+		return m.allowFixedWindow(counter, rule, now), nil
+	}
+}
+
+// allowFixedWindow increments the counter of the current window, which starts with its first request.
+func (m *MemoryLimiter) allowFixedWindow(counter *memoryCounter, rule Rule, now time.Time) Result {
+	if counter.count == 0 {
+		counter.expiresAt = now.Add(rule.Window)
+	}
+	counter.count++
+
+	return Result{
+		Allowed:   counter.count <= rule.Limit,
+		Limit:     rule.Limit,
+		Remaining: max(rule.Limit-counter.count, 0),
+		Reset:     counter.expiresAt.Sub(now),
+	}
+}
+
+// allowSlidingWindowLog drops requests older than the window and records the request if there is capacity.
+func (m *MemoryLimiter) allowSlidingWindowLog(counter *memoryCounter, rule Rule, now time.Time) Result {
+	cutoff := now.Add(-rule.Window)
+	i := 0
+	for i < len(counter.log) && !counter.log[i].After(cutoff) {
+		i++
+	}
+	counter.log = counter.log[i:]
+
+	allowed := int64(len(counter.log)) < rule.Limit
+	if allowed {
+		counter.log = append(counter.log, now)
+	}
+
+	var reset time.Duration
+	if len(counter.log) > 0 {
+		newest := counter.log[len(counter.log)-1]
+		reset = newest.Add(rule.Window).Sub(now)
+		counter.expiresAt = newest.Add(rule.Window)
+	}
+
+	return Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: rule.Limit - int64(len(counter.log)),
+		Reset:     reset,
+	}
+}
+
+// allowTokenBucket refills the bucket for the time elapsed since the last request and spends a token.
+func (m *MemoryLimiter) allowTokenBucket(counter *memoryCounter, rule Rule, now time.Time) Result {
+	capacity := float64(rule.Limit)
+	rate := capacity / float64(rule.Window)
+
+	counter.tokens = math.Min(capacity, counter.tokens+float64(now.Sub(counter.updatedAt))*rate)
+	counter.updatedAt = now
+	counter.expiresAt = now.Add(rule.Window)
+
+	allowed := counter.tokens >= 1
+	if allowed {
+		counter.tokens--
+	}
+
+	return Result{
+		Allowed:   allowed,
+		Limit:     rule.Limit,
+		Remaining: int64(math.Floor(counter.tokens)),
+		Reset:     time.Duration(math.Ceil((capacity - counter.tokens) / rate)),
+	}
+}
+
+// sweep periodically removes expired counters so that idle identities do not accumulate.
+func (m *MemoryLimiter) sweep(now time.Time) {
+	m.calls++
+	if m.calls < sweepInterval {
+		return
+	}
+	m.calls = 0
+
+	for key, counter := range m.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(m.counters, key)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestMemoryLimiter creates a MemoryLimiter whose clock is advanced manually.
+func newTestMemoryLimiter() (*MemoryLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter()
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+func TestMemoryLimiter_FixedWindow(t *testing.T) {
+	limiter, now := newTestMemoryLimiter()
+	rule := Rule{Name: "minute", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 2, Window: time.Minute}
+	identity := Identity{KeyID: "key1"}
+	ctx := context.Background()
+
+	result, err := limiter.Allow(ctx, rule, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, result)
+
+	*now = now.Add(10 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 50 * time.Second}, result)
+
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	// Other keys have their own window
+	result, _ = limiter.Allow(ctx, rule, Identity{KeyID: "key2"})
+	assert.True(t, result.Allowed)
+
+	// A new window starts once the previous one has elapsed
+	*now = now.Add(50 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, result)
+}
+
+func TestMemoryLimiter_SlidingWindowLog(t *testing.T) {
+	limiter, now := newTestMemoryLimiter()
+	rule := Rule{Name: "minute", Algorithm: SlidingWindowLog, Scope: ScopeKey, Limit: 2, Window: time.Minute}
+	identity := Identity{KeyID: "key1"}
+	ctx := context.Background()
+
+	result, _ := limiter.Allow(ctx, rule, identity)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute}, result)
+
+	*now = now.Add(30 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute}, result)
+
+	*now = now.Add(20 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 40*time.Second, result.Reset)
+
+	// The first request leaves the window, freeing a single slot
+	*now = now.Add(10 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute}, result)
+
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.False(t, result.Allowed)
+}
+
+func TestMemoryLimiter_TokenBucket(t *testing.T) {
+	limiter, now := newTestMemoryLimiter()
+	rule := Rule{Name: "minute", Algorithm: TokenBucket, Scope: ScopeKey, Limit: 6, Window: time.Minute}
+	identity := Identity{KeyID: "key1"}
+	ctx := context.Background()
+
+	for i := 5; i >= 0; i-- {
+		result, _ := limiter.Allow(ctx, rule, identity)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, int64(i), result.Remaining)
+	}
+
+	result, _ := limiter.Allow(ctx, rule, identity)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	// One token is refilled every 10 seconds
+	*now = now.Add(10 * time.Second)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(0), result.Remaining)
+
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.False(t, result.Allowed)
+
+	// The bucket never holds more than its capacity
+	*now = now.Add(time.Hour)
+	result, _ = limiter.Allow(ctx, rule, identity)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, int64(5), result.Remaining)
+	assert.Equal(t, 10*time.Second, result.Reset)
+}
+
+func TestMemoryLimiter_Sweep(t *testing.T) {
+	limiter, now := newTestMemoryLimiter()
+	rule := Rule{Name: "second", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 1, Window: time.Second}
+	ctx := context.Background()
+
+	_, _ = limiter.Allow(ctx, rule, Identity{KeyID: "idle"})
+	*now = now.Add(time.Minute)
+	for i := 0; i < sweepInterval; i++ {
+		_, _ = limiter.Allow(ctx, rule, Identity{KeyID: "active"})
+	}
+
+	_, ok := limiter.counters["RateLimit#fixed_window#Key#idle#second"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Algorithm is a string enum for the supported rate limiting algorithms.
+type Algorithm string
+
+const (
+	// FixedWindow counts requests in consecutive windows that start with the first request.
+	FixedWindow Algorithm = "fixed_window"
+	// SlidingWindowLog records every request and counts those within the trailing window.
+	SlidingWindowLog Algorithm = "sliding_window_log"
+	// TokenBucket refills a bucket of limit tokens evenly over the window and spends one token per request.
+	TokenBucket Algorithm = "token_bucket"
+)
+
+// Scope is a string enum for the identity a rate limit is counted against.
+type Scope string
+
+const (
+	// ScopeKey counts requests per API key.
+	ScopeKey Scope = "key"
+	// ScopeActor counts requests per actor across all of its API keys.
+	ScopeActor Scope = "actor"
+	// ScopeService counts requests per service across all of its API keys.
+	ScopeService Scope = "service"
+)
+
+// Limiter defines the operations available for consuming rate limits.
+type Limiter interface {
+	// Allow consumes a single request from the rule's quota for the given identity.
+	Allow(ctx context.Context, rule Rule, identity Identity) (Result, error)
+}
+
+// Rule represents a single rate limit.
+type Rule struct {
+	Name      string
+	Algorithm Algorithm
+	Scope     Scope
+	Limit     int64
+	Window    time.Duration
+}
+
+// Identity holds the identifiers a request is counted against, one of which is selected by the rule's scope.
+type Identity struct {
+	OrgID     string
+	ServiceID string
+	ActorID   string
+	KeyID     string
+}
+
+// Result represents the outcome of consuming from a rate limit.
+type Result struct {
+	Allowed   bool
+	Limit     int64
+	Remaining int64
+	// Reset is the time until the quota is fully replenished.
+	Reset time.Duration
+}
+
+// ParseRule validates a rate limit definition and converts it into a Rule. An empty algorithm defaults to
+// FixedWindow and an empty scope defaults to ScopeKey.
+func ParseRule(name, algorithm, scope string, limit int64, window string) (Rule, error) {
+	if name == "" {
+		return Rule{}, fmt.Errorf("rate limit name is required")
+	}
+
+	if limit <= 0 {
+		return Rule{}, fmt.Errorf("rate limit '%s' must have a positive limit", name)
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return Rule{}, fmt.Errorf("rate limit '%s' has an invalid window: %v", name, err)
+	}
+	if duration < time.Millisecond {
+		return Rule{}, fmt.Errorf("rate limit '%s' must have a window of at least 1ms", name)
+	}
+
+	rule := Rule{
+		Name:      name,
+		Algorithm: Algorithm(algorithm),
+		Scope:     Scope(scope),
+		Limit:     limit,
+		Window:    duration,
+	}
+
+	switch rule.Algorithm {
+	case "":
+		rule.Algorithm = FixedWindow
+	case FixedWindow, SlidingWindowLog, TokenBucket:
+	default:
+		return Rule{}, fmt.Errorf("rate limit '%s' has an unknown algorithm '%s'", name, algorithm)
+	}
+
+	switch rule.Scope {
+	case "":
+		rule.Scope = ScopeKey
+	case ScopeKey, ScopeActor, ScopeService:
+	default:
+		return Rule{}, fmt.Errorf("rate limit '%s' has an unknown scope '%s'", name, scope)
+	}
+
+	return rule, nil
+}
+
+// subject returns the identifier the rule is counted against, or false if the identity has none for its scope.
+func (r Rule) subject(identity Identity) (string, bool) {
+	switch r.Scope {
+	case ScopeKey:
+		return "Key#" + identity.KeyID, identity.KeyID != ""
+	case ScopeActor:
+		return "Org#" + identity.OrgID + "Service#" + identity.ServiceID + "Actor#" + identity.ActorID, identity.ActorID != ""
+	case ScopeService:
+		return "Org#" + identity.OrgID + "Service#" + identity.ServiceID, identity.ServiceID != ""
+	default:
+		return "", false
+	}
+}
+
+// key generates the storage key for the rule's counter for the given identity.
+func (r Rule) key(identity Identity) (string, bool) {
+	subject, ok := r.subject(identity)
+	if !ok {
+		return "", false
+	}
+
+	return "RateLimit#" + string(r.Algorithm) + "#" + subject + "#" + r.Name, true
+}
+
+// Check consumes from every rule for the identity. If a rule denies the request, its result is returned
+// along with the rule and no further rules are consumed. Otherwise the result with the least remaining quota
+// is returned. Rules whose scope does not apply to the identity, such as actor limits on a key without an
+// actor, are skipped. A nil rule is returned when no rule applied.
+func Check(ctx context.Context, limiter Limiter, rules []Rule, identity Identity) (Result, *Rule, error) {
+	var (
+		tightest     Result
+		tightestRule *Rule
+	)
+
+	for i := range rules {
+		rule := rules[i]
+		if _, ok := rule.subject(identity); !ok {
+			continue
+		}
+
+		result, err := limiter.Allow(ctx, rule, identity)
+		if err != nil {
+			return Result{}, nil, fmt.Errorf("failed to check rate limit '%s': %v", rule.Name, err)
+		}
+
+		if !result.Allowed {
+			return result, &rule, nil
+		}
+
+		if tightestRule == nil || result.Remaining < tightest.Remaining {
+			tightest = result
+			tightestRule = &rule
+		}
+	}
+
+	return tightest, tightestRule, nil
+}
@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		scope     string
+		limit     int64
+		window    string
+		expected  Rule
+		expectErr bool
+	}{
+		{
+			name:     "defaults",
+			limit:    10,
+			window:   "1m",
+			expected: Rule{Name: "defaults", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 10, Window: time.Minute},
+		},
+		{
+			name:      "explicit",
+			algorithm: "token_bucket",
+			scope:     "actor",
+			limit:     5,
+			window:    "1h30m",
+			expected:  Rule{Name: "explicit", Algorithm: TokenBucket, Scope: ScopeActor, Limit: 5, Window: 90 * time.Minute},
+		},
+		{name: "zero limit", limit: 0, window: "1m", expectErr: true},
+		{name: "negative limit", limit: -1, window: "1m", expectErr: true},
+		{name: "invalid window", limit: 1, window: "P1D", expectErr: true},
+		{name: "tiny window", limit: 1, window: "1us", expectErr: true},
+		{name: "unknown algorithm", algorithm: "leaky_bucket", limit: 1, window: "1m", expectErr: true},
+		{name: "unknown scope", scope: "org", limit: 1, window: "1m", expectErr: true},
+		{name: "", limit: 1, window: "1m", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.name, tt.algorithm, tt.scope, tt.limit, tt.window)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, rule)
+		})
+	}
+}
+
+func TestRuleKey(t *testing.T) {
+	identity := Identity{OrgID: "org1", ServiceID: "serv1", ActorID: "actor1", KeyID: "key1"}
+
+	key, ok := Rule{Name: "minute", Algorithm: FixedWindow, Scope: ScopeKey}.key(identity)
+	assert.True(t, ok)
+	assert.Equal(t, "RateLimit#fixed_window#Key#key1#minute", key)
+
+	key, ok = Rule{Name: "minute", Algorithm: TokenBucket, Scope: ScopeActor}.key(identity)
+	assert.True(t, ok)
+	assert.Equal(t, "RateLimit#token_bucket#Org#org1Service#serv1Actor#actor1#minute", key)
+
+	key, ok = Rule{Name: "minute", Algorithm: SlidingWindowLog, Scope: ScopeService}.key(identity)
+	assert.True(t, ok)
+	assert.Equal(t, "RateLimit#sliding_window_log#Org#org1Service#serv1#minute", key)
+
+	_, ok = Rule{Name: "minute", Algorithm: FixedWindow, Scope: ScopeActor}.key(Identity{OrgID: "org1", ServiceID: "serv1", KeyID: "key1"})
+	assert.False(t, ok)
+}
+
+func TestCheck(t *testing.T) {
+	identity := Identity{OrgID: "org1", ServiceID: "serv1", KeyID: "key1"}
+	generous := Rule{Name: "generous", Algorithm: FixedWindow, Scope: ScopeKey, Limit: 10, Window: time.Minute}
+	strict := Rule{Name: "strict", Algorithm: FixedWindow, Scope: ScopeService, Limit: 2, Window: time.Minute}
+	actor := Rule{Name: "actor", Algorithm: FixedWindow, Scope: ScopeActor, Limit: 1, Window: time.Minute}
+
+	t.Run("No rules", func(t *testing.T) {
+		result, rule, err := Check(context.Background(), NewMemoryLimiter(), nil, identity)
+		assert.NoError(t, err)
+		assert.Nil(t, rule)
+		assert.Equal(t, Result{}, result)
+	})
+
+	t.Run("Returns tightest allowed result", func(t *testing.T) {
+		limiter := NewMemoryLimiter()
+		result, rule, err := Check(context.Background(), limiter, []Rule{generous, strict, actor}, identity)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, "strict", rule.Name)
+		assert.Equal(t, int64(1), result.Remaining)
+	})
+
+	t.Run("Returns first denial", func(t *testing.T) {
+		limiter := NewMemoryLimiter()
+		for i := 0; i < 2; i++ {
+			_, _, err := Check(context.Background(), limiter, []Rule{generous, strict}, identity)
+			assert.NoError(t, err)
+		}
+
+		result, rule, err := Check(context.Background(), limiter, []Rule{generous, strict}, identity)
+		assert.NoError(t, err)
+		assert.False(t, result.Allowed)
+		assert.Equal(t, "strict", rule.Name)
+		assert.Equal(t, int64(0), result.Remaining)
+	})
+}
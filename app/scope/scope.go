@@ -0,0 +1,155 @@
+// Package scope defines the grammar of the scopes granted to API keys and how granted scopes cover required ones.
+//
+// A scope is a colon separated list of segments, optionally ending with a wildcard:
+//
+//	scope    = "*" / segment *( ":" segment ) [ ":*" ]
+//	segment  = ( lower / digit ) *( lower / digit / "-" / "_" / "." )
+//
+// For example "billing", "billing:invoices:read" and "billing:*". Scopes are case-insensitive and are normalized
+// to lower case. A scope ending with a wildcard covers every scope below its prefix, so "billing:*" covers
+// "billing:invoices" and "billing:invoices:*" but not "billing" itself or "billing-admin". The scope "*" covers
+// every scope. Every other scope only covers itself.
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Wildcard is the segment that covers every scope below its prefix.
+const Wildcard = "*"
+
+// separator separates the segments of a scope.
+const separator = ":"
+
+// MaxLength is the maximum length of a scope.
+const MaxLength = 128
+
+// MaxSegments is the maximum number of segments of a scope, including a trailing wildcard.
+const MaxSegments = 8
+
+// Normalize validates a scope against the grammar and returns it in its canonical form.
+func Normalize(scope string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(scope))
+	if normalized == "" {
+		return "", errors.New("scope is empty")
+	}
+	if len(normalized) > MaxLength {
+		return "", fmt.Errorf("scope '%s' is longer than %d characters", scope, MaxLength)
+	}
+
+	segments := strings.Split(normalized, separator)
+	if len(segments) > MaxSegments {
+		return "", fmt.Errorf("scope '%s' has more than %d segments", scope, MaxSegments)
+	}
+
+	for i, segment := range segments {
+		if segment == Wildcard {
+			if i != len(segments)-1 {
+				return "", fmt.Errorf("scope '%s' has a wildcard before its last segment", scope)
+			}
+			continue
+		}
+
+		if !validSegment(segment) {
+			return "", fmt.Errorf("scope '%s' has an invalid segment '%s'", scope, segment)
+		}
+	}
+
+	return normalized, nil
+}
+
+// NormalizeAll normalizes a list of scopes, removing duplicates while keeping their order.
+func NormalizeAll(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		value, err := Normalize(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[value] {
+			seen[value] = true
+			normalized = append(normalized, value)
+		}
+	}
+
+	return normalized, nil
+}
+
+// Covers reports whether a granted scope covers a required scope. Both scopes are expected to be normalized.
+func Covers(granted, required string) bool {
+	if granted == required {
+		return true
+	}
+
+	if granted == Wildcard {
+		return required != ""
+	}
+
+	prefix, ok := strings.CutSuffix(granted, separator+Wildcard)
+	if !ok {
+		return false
+	}
+
+	return strings.HasPrefix(required, prefix+separator)
+}
+
+// Missing returns the required scopes that are not covered by any of the granted scopes. Granted scopes that do not
+// follow the grammar, such as those of keys created before it was enforced, are matched exactly as they were stored
+// and cover no other scope. Required scopes that do not follow it are only covered by such a scope.
+func Missing(granted, required []string) []string {
+	normalizedGranted := make([]string, 0, len(granted))
+	legacyGranted := make(map[string]bool)
+	for _, scope := range granted {
+		if value, err := Normalize(scope); err == nil {
+			normalizedGranted = append(normalizedGranted, value)
+		} else {
+			legacyGranted[scope] = true
+		}
+	}
+
+	var missing []string
+	for _, scope := range required {
+		if legacyGranted[scope] {
+			continue
+		}
+
+		value, err := Normalize(scope)
+		if err != nil || !coveredByAny(normalizedGranted, value) {
+			missing = append(missing, scope)
+		}
+	}
+
+	return missing
+}
+
+// coveredByAny reports whether any of the granted scopes covers the required scope.
+func coveredByAny(granted []string, required string) bool {
+	for _, scope := range granted {
+		if Covers(scope, required) {
+			return true
+		}
+	}
+	return false
+}
+
+// validSegment reports whether a segment follows the grammar.
+func validSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	for i, r := range segment {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case i > 0 && (r == '-' || r == '_' || r == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,218 @@
+package scope_test
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/payloadops/lanyard/app/scope"
+	"github.com/stretchr/testify/assert"
+)
+
+// genScope is a random scope that follows the grammar. Segments are drawn from a small alphabet so that random
+// scopes often share prefixes, including prefixes that end in the middle of a segment.
+type genScope string
+
+func (genScope) Generate(r *rand.Rand, size int) reflect.Value {
+	alphabet := []string{"a", "b", "a-", "ab", "a.b"}
+	segments := make([]string, 1+r.Intn(4))
+	for i := range segments {
+		segments[i] = alphabet[r.Intn(len(alphabet))]
+		if strings.HasSuffix(segments[i], "-") {
+			segments[i] += "c"
+		}
+	}
+
+	switch r.Intn(5) {
+	case 0:
+		segments = append(segments, scope.Wildcard)
+	case 1:
+		segments = []string{scope.Wildcard}
+	}
+
+	return reflect.ValueOf(genScope(strings.Join(segments, ":")))
+}
+
+// genPrefix is a random scope without a wildcard.
+type genPrefix string
+
+func (genPrefix) Generate(r *rand.Rand, size int) reflect.Value {
+	for {
+		value := genScope("").Generate(r, size).Interface().(genScope)
+		if !strings.Contains(string(value), scope.Wildcard) {
+			return reflect.ValueOf(genPrefix(value))
+		}
+	}
+}
+
+func checkProperty(t *testing.T, property interface{}) {
+	t.Helper()
+	if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		scope    string
+		expected string
+		valid    bool
+	}{
+		{"billing", "billing", true},
+		{" Billing:Invoices:READ ", "billing:invoices:read", true},
+		{"billing:*", "billing:*", true},
+		{"*", "*", true},
+		{"api.v2:read_only", "api.v2:read_only", true},
+		{"pricing-tiers:read", "pricing-tiers:read", true},
+		{"", "", false},
+		{"   ", "", false},
+		{"billing:", "", false},
+		{":billing", "", false},
+		{"billing::read", "", false},
+		{"billing:*:read", "", false},
+		{"*:read", "", false},
+		{"billing:inv*", "", false},
+		{"-billing", "", false},
+		{"billing read", "", false},
+		{"billing/read", "", false},
+		{"a:b:c:d:e:f:g:h:i", "", false},
+		{strings.Repeat("a", scope.MaxLength+1), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope, func(t *testing.T) {
+			normalized, err := scope.Normalize(tt.scope)
+			if !tt.valid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	normalized, err := scope.NormalizeAll([]string{"billing:read", "Billing:Read", "usage:*"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing:read", "usage:*"}, normalized)
+
+	_, err = scope.NormalizeAll([]string{"billing:read", "billing:"})
+	assert.Error(t, err)
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		granted  string
+		required string
+		covers   bool
+	}{
+		{"billing:invoices:read", "billing:invoices:read", true},
+		{"billing:*", "billing:invoices:read", true},
+		{"billing:*", "billing:invoices", true},
+		{"billing:*", "billing:invoices:*", true},
+		{"billing:*", "billing:*", true},
+		{"billing:*", "billing", false},
+		{"billing:*", "billing-admin:read", false},
+		{"billing:*", "usage:read", false},
+		{"billing:invoices:*", "billing:*", false},
+		{"billing", "billing:invoices", false},
+		{"billing:invoices:read", "billing:invoices:write", false},
+		{"*", "billing", true},
+		{"*", "billing:*", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.granted+" "+tt.required, func(t *testing.T) {
+			assert.Equal(t, tt.covers, scope.Covers(tt.granted, tt.required))
+		})
+	}
+}
+
+func TestMissing(t *testing.T) {
+	granted := []string{"billing:*", "usage:read", "Legacy Scope"}
+
+	assert.Empty(t, scope.Missing(granted, []string{"billing:invoices:read", "Usage:Read"}))
+	assert.Equal(t, []string{"usage:write", "billing", "not a scope"},
+		scope.Missing(granted, []string{"usage:write", "billing", "not a scope"}))
+}
+
+func TestMissing_LegacyScopes(t *testing.T) {
+	granted := []string{"billing:*", "Legacy Scope", "reports/*"}
+
+	// Scopes stored before the grammar was enforced only cover themselves, exactly as they were stored
+	assert.Empty(t, scope.Missing(granted, []string{"Legacy Scope", "reports/*"}))
+	assert.Equal(t, []string{"legacy scope", "reports/daily", "billing"},
+		scope.Missing(granted, []string{"legacy scope", "reports/daily", "billing"}))
+}
+
+func TestCovers_WildcardNeverGrantsMoreThanItsPrefix(t *testing.T) {
+	checkProperty(t, func(prefix genPrefix, required genScope) bool {
+		granted := string(prefix) + ":" + scope.Wildcard
+		if !scope.Covers(granted, string(required)) {
+			return true
+		}
+		return strings.HasPrefix(string(required), string(prefix)+":")
+	})
+}
+
+func TestCovers_WildcardGrantsEverythingBelowItsPrefix(t *testing.T) {
+	checkProperty(t, func(prefix genPrefix, suffix genScope) bool {
+		granted := string(prefix) + ":" + scope.Wildcard
+		return scope.Covers(granted, string(prefix)+":"+string(suffix))
+	})
+}
+
+func TestCovers_ScopeWithoutWildcardOnlyGrantsItself(t *testing.T) {
+	checkProperty(t, func(granted genPrefix, required genScope) bool {
+		return scope.Covers(string(granted), string(required)) == (string(granted) == string(required))
+	})
+}
+
+func TestCovers_Transitive(t *testing.T) {
+	checkProperty(t, func(a, b, c genScope) bool {
+		if scope.Covers(string(a), string(b)) && scope.Covers(string(b), string(c)) {
+			return scope.Covers(string(a), string(c))
+		}
+		return true
+	})
+}
+
+func TestNormalize_GeneratedScopesAreCanonical(t *testing.T) {
+	checkProperty(t, func(s genScope) bool {
+		normalized, err := scope.Normalize(strings.ToUpper(string(s)))
+		return err == nil && normalized == string(s)
+	})
+}
+
+func TestMissing_OnlyReportsUncoveredScopes(t *testing.T) {
+	checkProperty(t, func(granted, required []genScope) bool {
+		grantedScopes := make([]string, len(granted))
+		for i, s := range granted {
+			grantedScopes[i] = string(s)
+		}
+		requiredScopes := make([]string, len(required))
+		for i, s := range required {
+			requiredScopes[i] = string(s)
+		}
+
+		missing := map[string]bool{}
+		for _, s := range scope.Missing(grantedScopes, requiredScopes) {
+			missing[s] = true
+		}
+
+		for _, r := range requiredScopes {
+			covered := false
+			for _, g := range grantedScopes {
+				covered = covered || scope.Covers(g, r)
+			}
+			if covered == missing[r] {
+				return false
+			}
+		}
+		return true
+	})
+}
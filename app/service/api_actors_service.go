@@ -0,0 +1,584 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// ActorsAPIService is a service that implements the logic for the ActorsAPIServicer
+// This service should implement the business logic for every endpoint for the ActorsAPI API.
+type ActorsAPIService struct {
+	actorClient       dal.ActorManager
+	serviceClient     dal.ServiceManager
+	tierClient        dal.TierManager
+	apiKeyClient      dal.APIKeyManager
+	transactionClient dal.TransactionManager
+	logger            *zap.Logger
+}
+
+// NewActorsAPIService creates a default app service
+func NewActorsAPIService(actorClient dal.ActorManager, serviceClient dal.ServiceManager, tierClient dal.TierManager, apiKeyClient dal.APIKeyManager, transactionClient dal.TransactionManager, logger *zap.Logger) openapi.ActorsAPIServicer {
+	return &ActorsAPIService{
+		actorClient:       actorClient,
+		serviceClient:     serviceClient,
+		tierClient:        tierClient,
+		apiKeyClient:      apiKeyClient,
+		transactionClient: transactionClient,
+		logger:            logger,
+	}
+}
+
+// ListActorApiKeys - List the API keys bound to an actor
+func (s *ActorsAPIService) ListActorApiKeys(ctx context.Context, serviceId string, actorExternalId string, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	code, err := s.checkActor(ctx, requestID, orgID, serviceId, actorExternalId)
+	if err != nil {
+		return openapi.Response(code, nil), err
+	}
+
+	apiKeys, nextCursor, err := s.apiKeyClient.ListAPIKeysByActor(ctx, orgID, serviceId, actorExternalId, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list API keys",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	responses := make([]openapi.ApiKey, len(apiKeys))
+	for i := range apiKeys {
+		responses[i], err = toAPIKey(&apiKeys[i])
+		if err != nil {
+			s.logger.Error("failed to parse timestamp",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	return openapi.Response(http.StatusOK, openapi.ApiKeyList{ApiKeys: responses, NextCursor: nextCursor}), nil
+}
+
+// RevokeActorApiKeys - Revoke every API key bound to an actor
+func (s *ActorsAPIService) RevokeActorApiKeys(ctx context.Context, serviceId string, actorExternalId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	code, err := s.checkActor(ctx, requestID, orgID, serviceId, actorExternalId)
+	if err != nil {
+		return openapi.Response(code, nil), err
+	}
+
+	revoked, err := s.revokeActorAPIKeys(ctx, orgID, serviceId, actorExternalId)
+	if err != nil {
+		s.logger.Error("failed to revoke API keys",
+			zap.String("requestID", requestID),
+			zap.Strings("revokedKeyIDs", revoked),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusOK, openapi.RevokedApiKeys{RevokedKeyIds: revoked}), nil
+}
+
+// ServicesServiceIdActorsActorExternalIdDelete - Remove an actor from a service
+func (s *ActorsAPIService) ServicesServiceIdActorsActorExternalIdDelete(ctx context.Context, serviceId string, actorExternalId string, ifMatch string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	// Check if the actor exists
+	actor, err := s.actorClient.GetActor(ctx, orgID, serviceId, actorExternalId)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if actor == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("actor not found")
+	}
+	if !matchesETag(ifMatch, actor.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	// Revoke the actor's API keys along with the actor. The keys are committed first, so that a delete that spans
+	// several transactions and fails part way can be retried
+	apiKeys, err := dal.ListAll(func(page dal.Page) ([]dal.APIKey, string, error) {
+		return s.apiKeyClient.ListAPIKeysByActor(ctx, orgID, serviceId, actorExternalId, page)
+	})
+	if err != nil {
+		s.logger.Error("failed to list API keys",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	unit := dal.NewUnitOfWork()
+	for _, apiKey := range apiKeys {
+		err = s.apiKeyClient.StageDeleteAPIKey(ctx, unit, orgID, serviceId, apiKey.APIKeyID, apiKey.Version)
+		if err != nil {
+			s.logger.Error("failed to stage API key delete",
+				zap.String("requestID", requestID),
+				zap.String("keyID", apiKey.APIKeyID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	err = s.actorClient.StageDeleteActor(ctx, unit, orgID, serviceId, actorExternalId, actor.Version)
+	if err != nil {
+		s.logger.Error("failed to stage actor delete",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	err = s.transactionClient.Commit(ctx, unit)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to delete actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusNoContent, nil), nil
+}
+
+// ServicesServiceIdActorsActorExternalIdGet - Get the actor
+func (s *ActorsAPIService) ServicesServiceIdActorsActorExternalIdGet(ctx context.Context, serviceId string, actorExternalId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	actor, err := s.actorClient.GetActor(ctx, orgID, serviceId, actorExternalId)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if actor == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("actor not found")
+	}
+
+	response, err := toAPIActor(actor)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(actor.Version), response), nil
+}
+
+// ServicesServiceIdActorsActorExternalIdPut - Update an actor
+func (s *ActorsAPIService) ServicesServiceIdActorsActorExternalIdPut(ctx context.Context, serviceId string, actorExternalId string, ifMatch string, actorInput openapi.ActorInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// The external ID identifies the actor, so it cannot be changed
+	if actorInput.ExternalId != "" && actorInput.ExternalId != actorExternalId {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("externalId cannot be changed")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	actor, err := s.actorClient.GetActor(ctx, orgID, serviceId, actorExternalId)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if actor == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("actor not found")
+	}
+	if !matchesETag(ifMatch, actor.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	code, err := s.applyActorInput(ctx, orgID, serviceId, actor, actorInput)
+	if err != nil {
+		if code >= http.StatusInternalServerError {
+			s.logger.Error("failed to get pricing tier",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+		}
+		return openapi.Response(code, nil), err
+	}
+
+	err = s.actorClient.UpdateActor(ctx, orgID, serviceId, actor)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		if isTierUnavailable(err) {
+			return openapi.Response(http.StatusConflict, nil), err
+		}
+		s.logger.Error("failed to update actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response, err := toAPIActor(actor)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(actor.Version), response), nil
+}
+
+// ServicesServiceIdActorsGet - Retrieve all actors associated with a service
+func (s *ActorsAPIService) ServicesServiceIdActorsGet(ctx context.Context, serviceId string, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	actors, nextCursor, err := s.actorClient.ListActors(ctx, orgID, serviceId, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list actors",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	responses := make([]openapi.Actor, len(actors))
+	for i, actor := range actors {
+		response, err := toAPIActor(&actor)
+		if err != nil {
+			s.logger.Error("failed to parse timestamp",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		responses[i] = response
+	}
+
+	return openapi.Response(http.StatusOK, openapi.ActorList{Actors: responses, NextCursor: nextCursor}), nil
+}
+
+// ServicesServiceIdActorsPost - Add an actor to a service
+func (s *ActorsAPIService) ServicesServiceIdActorsPost(ctx context.Context, serviceId string, actorInput openapi.ActorInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	if actorInput.ExternalId == "" {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("externalId is required")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	existing, err := s.actorClient.GetActor(ctx, orgID, serviceId, actorInput.ExternalId)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if existing != nil {
+		return openapi.Response(http.StatusConflict, nil), errors.New("actor already exists")
+	}
+
+	actor := &dal.Actor{ExternalID: actorInput.ExternalId}
+	code, err := s.applyActorInput(ctx, orgID, serviceId, actor, actorInput)
+	if err != nil {
+		if code >= http.StatusInternalServerError {
+			s.logger.Error("failed to get pricing tier",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+		}
+		return openapi.Response(code, nil), err
+	}
+
+	err = s.actorClient.CreateActor(ctx, orgID, serviceId, actor)
+	if err != nil {
+		if isTierUnavailable(err) {
+			return openapi.Response(http.StatusConflict, nil), err
+		}
+		s.logger.Error("failed to create actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response, err := toAPIActor(actor)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusCreated, etagHeaders(actor.Version), response), nil
+}
+
+// checkActor checks that a service and one of its actors exist. The returned status code describes the failure when
+// an error is returned.
+func (s *ActorsAPIService) checkActor(ctx context.Context, requestID, orgID, serviceID, actorExternalID string) (int, error) {
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceID)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorStatus(err), err
+	}
+	if service == nil {
+		return http.StatusNotFound, errors.New("service not found")
+	}
+
+	actor, err := s.actorClient.GetActor(ctx, orgID, serviceID, actorExternalID)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorStatus(err), err
+	}
+	if actor == nil {
+		return http.StatusNotFound, errors.New("actor not found")
+	}
+
+	return http.StatusOK, nil
+}
+
+// revokeActorAPIKeys deletes every API key bound to an actor and returns the IDs of the deleted keys. Keys deleted
+// before a failure are returned along with the error, and a retry deletes the remaining ones.
+func (s *ActorsAPIService) revokeActorAPIKeys(ctx context.Context, orgID, serviceID, actorExternalID string) ([]string, error) {
+	apiKeys, err := dal.ListAll(func(page dal.Page) ([]dal.APIKey, string, error) {
+		return s.apiKeyClient.ListAPIKeysByActor(ctx, orgID, serviceID, actorExternalID, page)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revoked := []string{}
+	for _, apiKey := range apiKeys {
+		err = s.apiKeyClient.DeleteAPIKey(ctx, orgID, serviceID, apiKey.APIKeyID, apiKey.Version)
+		if err != nil {
+			return revoked, fmt.Errorf("failed to delete API key '%s': %w", apiKey.APIKeyID, err)
+		}
+		revoked = append(revoked, apiKey.APIKeyID)
+	}
+
+	return revoked, nil
+}
+
+// applyActorInput validates an actor input and copies it onto the actor, resolving its pricing tier. The returned
+// status code describes the failure when an error is returned.
+func (s *ActorsAPIService) applyActorInput(ctx context.Context, orgID, serviceID string, actor *dal.Actor, actorInput openapi.ActorInput) (int, error) {
+	if actorInput.MonthlyRequestLimit < 0 {
+		return http.StatusBadRequest, errors.New("monthlyRequestLimit must not be negative")
+	}
+
+	billingInfo := dal.BillingInfo{
+		TierID:           actorInput.BillingInfo.Tier,
+		IsTrialActive:    actorInput.BillingInfo.IsTrialActive,
+		IsTrialEligible:  actorInput.BillingInfo.IsTrialElgible,
+		StripeCustomerID: actorInput.BillingInfo.StripeCustomerId,
+		PaymentMethodID:  actorInput.BillingInfo.PaymentMethodId,
+	}
+	if !actorInput.BillingInfo.TrialExpiry.IsZero() {
+		billingInfo.TrialExpiry = actorInput.BillingInfo.TrialExpiry.UTC().Format(time.RFC3339)
+	}
+
+	if billingInfo.TierID != "" {
+		tier, err := s.tierClient.GetTier(ctx, orgID, serviceID, billingInfo.TierID)
+		if err != nil {
+			return openapi.ErrorStatus(err), err
+		}
+		if tier == nil {
+			return http.StatusBadRequest, fmt.Errorf("pricing tier '%s' not found", billingInfo.TierID)
+		}
+		if tier.Retiring() && billingInfo.TierID != actor.BillingInfo.TierID {
+			return http.StatusConflict, fmt.Errorf("pricing tier '%s' is retiring", billingInfo.TierID)
+		}
+	}
+
+	actor.MonthlyRequestLimit = int(actorInput.MonthlyRequestLimit)
+	actor.BillingInfo = billingInfo
+	return http.StatusOK, nil
+}
+
+// isTierUnavailable reports whether a write failed because it assigned an actor to a pricing tier that was deleted or
+// started retiring concurrently.
+func isTierUnavailable(err error) bool {
+	var unavailable *dal.TierUnavailableError
+	return errors.As(err, &unavailable)
+}
+
+// toAPIActor converts a stored actor into its API representation.
+func toAPIActor(actor *dal.Actor) (openapi.Actor, error) {
+	trialExpiry, err := utils.ParseTimestamp(actor.BillingInfo.TrialExpiry)
+	if err != nil {
+		return openapi.Actor{}, err
+	}
+
+	return openapi.Actor{
+		ExternalId:          actor.ExternalID,
+		MonthlyRequestLimit: int32(actor.MonthlyRequestLimit),
+		BillingInfo: openapi.BillingInfo{
+			Tier:             actor.BillingInfo.TierID,
+			StripeCustomerId: actor.BillingInfo.StripeCustomerID,
+			PaymentMethodId:  actor.BillingInfo.PaymentMethodID,
+			TrialExpiry:      trialExpiry,
+			IsTrialActive:    actor.BillingInfo.IsTrialActive,
+			IsTrialElgible:   actor.BillingInfo.IsTrialEligible,
+		},
+	}, nil
+}
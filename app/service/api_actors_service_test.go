@@ -0,0 +1,411 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestActorsAPIService_CreateActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mockTierClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	trialExpiry := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	actorInput := openapi.ActorInput{
+		ExternalId:          "actor1",
+		MonthlyRequestLimit: 1000,
+		BillingInfo: openapi.BillingInfo{
+			Tier:             "tier1",
+			StripeCustomerId: "cus_123",
+			PaymentMethodId:  "pm_123",
+			TrialExpiry:      trialExpiry,
+			IsTrialActive:    true,
+		},
+	}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(nil, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Name: "pro"}, nil)
+	mockActorClient.EXPECT().CreateActor(ctx, "org1", "serv1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID, serviceID string, actor *dal.Actor) error {
+		assert.Equal(t, "actor1", actor.ExternalID)
+		assert.Equal(t, 1000, actor.MonthlyRequestLimit)
+		assert.Equal(t, "tier1", actor.BillingInfo.TierID)
+		assert.Equal(t, "cus_123", actor.BillingInfo.StripeCustomerID)
+		assert.Equal(t, "pm_123", actor.BillingInfo.PaymentMethodID)
+		assert.Equal(t, "2024-06-01T00:00:00Z", actor.BillingInfo.TrialExpiry)
+		assert.True(t, actor.BillingInfo.IsTrialActive)
+		return nil
+	})
+
+	response, err := service.ServicesServiceIdActorsPost(ctx, "serv1", actorInput)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	created, ok := response.Body.(openapi.Actor)
+	assert.True(t, ok)
+	assert.Equal(t, "actor1", created.ExternalId)
+	assert.Equal(t, int32(1000), created.MonthlyRequestLimit)
+	assert.Equal(t, actorInput.BillingInfo, created.BillingInfo)
+}
+
+func TestActorsAPIService_CreateActor_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mockTierClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil).AnyTimes()
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(nil, nil).AnyTimes()
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "missing").Return(nil, nil).AnyTimes()
+
+	tests := []struct {
+		name       string
+		actorInput openapi.ActorInput
+	}{
+		{name: "Missing external ID", actorInput: openapi.ActorInput{}},
+		{name: "Negative limit", actorInput: openapi.ActorInput{ExternalId: "actor1", MonthlyRequestLimit: -1}},
+		{name: "Unknown tier", actorInput: openapi.ActorInput{ExternalId: "actor1", BillingInfo: openapi.BillingInfo{Tier: "missing"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.ServicesServiceIdActorsPost(ctx, "serv1", tt.actorInput)
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
+
+func TestActorsAPIService_CreateActor_Conflict(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil)
+
+	response, err := service.ServicesServiceIdActorsPost(ctx, "serv1", openapi.ActorInput{ExternalId: "actor1"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestActorsAPIService_CreateActor_RetiringTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mockTierClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	actorInput := openapi.ActorInput{ExternalId: "actor1", BillingInfo: openapi.BillingInfo{Tier: "tier1"}}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil).Times(2)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(nil, nil).Times(2)
+
+	// Actors cannot be assigned to a retiring tier
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Status: dal.TierStatusRetiring}, nil)
+
+	response, err := service.ServicesServiceIdActorsPost(ctx, "serv1", actorInput)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+
+	// The tier started retiring after it was read
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1"}, nil)
+	mockActorClient.EXPECT().CreateActor(ctx, "org1", "serv1", gomock.Any()).Return(&dal.TierUnavailableError{ID: "tier1"})
+
+	response, err = service.ServicesServiceIdActorsPost(ctx, "serv1", actorInput)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestActorsAPIService_CreateActor_ServiceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mocks.NewMockActorManager(ctrl), mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.ServicesServiceIdActorsPost(ctx, "serv1", openapi.ActorInput{ExternalId: "actor1"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestActorsAPIService_DeleteActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mockAPIKeyClient, mockTransactionClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// The keys bound to the actor are staged before the actor, and everything is committed together
+	gomock.InOrder(
+		mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil),
+		mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByActor(ctx, "org1", "serv1", "actor1", gomock.Any()).Return([]dal.APIKey{
+			{APIKeyID: "key1", ActorID: "actor1"},
+			{APIKeyID: "key4", ActorID: "actor1"},
+		}, "", nil),
+		mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil),
+		mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key4", int64(0)).Return(nil),
+		mockActorClient.EXPECT().StageDeleteActor(ctx, gomock.Any(), "org1", "serv1", "actor1", int64(0)).Return(nil),
+		mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(nil),
+	)
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdDelete(ctx, "serv1", "actor1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+}
+
+func TestActorsAPIService_DeleteActor_RevokeFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// Nothing is committed when the delete of a key cannot be staged
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil)
+	mockAPIKeyClient.EXPECT().ListAPIKeysByActor(ctx, "org1", "serv1", "actor1", gomock.Any()).Return([]dal.APIKey{{APIKeyID: "key1", ActorID: "actor1"}}, "", nil)
+	mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(errors.New("dynamodb error"))
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdDelete(ctx, "serv1", "actor1", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestActorsAPIService_DeleteActor_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(nil, nil)
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdDelete(ctx, "serv1", "actor1", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestActorsAPIService_ListActorApiKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil)
+	mockAPIKeyClient.EXPECT().ListAPIKeysByActor(ctx, "org1", "serv1", "actor1", dal.Page{Cursor: "cursor1", Limit: 1}).Return([]dal.APIKey{
+		{APIKeyID: "key1", ActorID: "actor1", Secret: "v1$salt$mac", CreatedAt: now, UpdatedAt: now},
+	}, "cursor2", nil)
+
+	response, err := service.ListActorApiKeys(ctx, "serv1", "actor1", "cursor1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	list, ok := response.Body.(openapi.ApiKeyList)
+	assert.True(t, ok)
+	if assert.Len(t, list.ApiKeys, 1) {
+		assert.Equal(t, "key1", list.ApiKeys[0].Id)
+		assert.Equal(t, "actor1", list.ApiKeys[0].ActorExternalId)
+		assert.Empty(t, list.ApiKeys[0].Secret)
+	}
+	assert.Equal(t, "cursor2", list.NextCursor)
+}
+
+func TestActorsAPIService_ListActorApiKeys_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(nil, nil)
+
+	response, err := service.ListActorApiKeys(ctx, "serv1", "actor1", "", 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestActorsAPIService_RevokeActorApiKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// Every page of keys is revoked, and the actor itself is kept
+	gomock.InOrder(
+		mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil),
+		mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByActor(ctx, "org1", "serv1", "actor1", dal.Page{Limit: dal.MaxPageLimit}).Return([]dal.APIKey{{APIKeyID: "key1"}}, "cursor1", nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByActor(ctx, "org1", "serv1", "actor1", dal.Page{Cursor: "cursor1", Limit: dal.MaxPageLimit}).Return([]dal.APIKey{{APIKeyID: "key2"}}, "", nil),
+		mockAPIKeyClient.EXPECT().DeleteAPIKey(ctx, "org1", "serv1", "key1", int64(0)).Return(nil),
+		mockAPIKeyClient.EXPECT().DeleteAPIKey(ctx, "org1", "serv1", "key2", int64(0)).Return(nil),
+	)
+
+	response, err := service.RevokeActorApiKeys(ctx, "serv1", "actor1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, openapi.RevokedApiKeys{RevokedKeyIds: []string{"key1", "key2"}}, response.Body)
+}
+
+func TestActorsAPIService_GetActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{
+		ExternalID:          "actor1",
+		MonthlyRequestLimit: 500,
+		BillingInfo:         dal.BillingInfo{TierID: "tier1", TrialExpiry: "2024-06-01T00:00:00Z"},
+	}, nil)
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdGet(ctx, "serv1", "actor1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	retrieved, ok := response.Body.(openapi.Actor)
+	assert.True(t, ok)
+	assert.Equal(t, "actor1", retrieved.ExternalId)
+	assert.Equal(t, int32(500), retrieved.MonthlyRequestLimit)
+	assert.Equal(t, "tier1", retrieved.BillingInfo.Tier)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), retrieved.BillingInfo.TrialExpiry)
+}
+
+func TestActorsAPIService_ListActors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().ListActors(ctx, "org1", "serv1", dal.Page{Cursor: "cursor1", Limit: 2}).Return([]dal.Actor{
+		{ExternalID: "actor1"},
+		{ExternalID: "actor2"},
+	}, "cursor2", nil)
+
+	response, err := service.ServicesServiceIdActorsGet(ctx, "serv1", "cursor1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	listed, ok := response.Body.(openapi.ActorList)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(listed.Actors))
+	assert.Equal(t, "actor1", listed.Actors[0].ExternalId)
+	assert.Equal(t, "actor2", listed.Actors[1].ExternalId)
+	assert.Equal(t, "cursor2", listed.NextCursor)
+}
+
+func TestActorsAPIService_UpdateActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	service := service.NewActorsAPIService(mockActorClient, mockServiceClient, mockTierClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	actor := &dal.Actor{ActorID: "id1", ExternalID: "actor1", MonthlyRequestLimit: 100, BillingInfo: dal.BillingInfo{TierID: "tier0"}}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(actor, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Name: "pro"}, nil)
+	mockActorClient.EXPECT().UpdateActor(ctx, "org1", "serv1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID, serviceID string, updated *dal.Actor) error {
+		assert.Equal(t, "id1", updated.ActorID)
+		assert.Equal(t, 2000, updated.MonthlyRequestLimit)
+		assert.Equal(t, "tier1", updated.BillingInfo.TierID)
+		return nil
+	})
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdPut(ctx, "serv1", "actor1", "", openapi.ActorInput{
+		MonthlyRequestLimit: 2000,
+		BillingInfo:         openapi.BillingInfo{Tier: "tier1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	updated, ok := response.Body.(openapi.Actor)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2000), updated.MonthlyRequestLimit)
+	assert.Equal(t, "tier1", updated.BillingInfo.Tier)
+}
+
+func TestActorsAPIService_UpdateActor_ExternalIDChanged(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewActorsAPIService(mocks.NewMockActorManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	response, err := service.ServicesServiceIdActorsActorExternalIdPut(ctx, "serv1", "actor1", "", openapi.ActorInput{ExternalId: "actor2"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
@@ -3,40 +3,304 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/allowlist"
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/config"
 	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/ipblock"
 	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/ratelimit"
+	"github.com/payloadops/lanyard/app/scope"
+	"github.com/payloadops/lanyard/app/usage"
 	"github.com/payloadops/lanyard/app/utils"
 	"go.uber.org/zap"
 )
 
-const (
-	// ApiKeyLength represents the length of an API key to generate
-	ApiKeyLength = 32
-)
-
 // APIKeysAPIService is a service that implements the logic for the APIKeysAPIServicer
 // This service should implement the business logic for every endpoint for the APIKeysAPI API.
 type APIKeysAPIService struct {
-	apiKeyClient  dal.APIKeyManager
-	serviceClient dal.ServiceManager
-	logger        *zap.Logger
+	apiKeyClient           dal.APIKeyManager
+	serviceClient          dal.ServiceManager
+	actorClient            dal.ActorManager
+	verifier               *auth.SecretVerifier
+	limiter                ratelimit.Limiter
+	meter                  *usage.Meter
+	blocklist              *ipblock.Matcher
+	rotationGracePeriod    time.Duration
+	maxRotationGracePeriod time.Duration
+	tokenPrefix            string
+	tokenEnvironment       string
+	logger                 *zap.Logger
 }
 
-// AuthApiKey implements openapi.APIKeysAPIServicer.
-func (s *APIKeysAPIService) AuthApiKey(context.Context, string, string, openapi.AuthApiKeyRequest) (openapi.ImplResponse, error) {
-	panic("unimplemented")
+// NewAPIKeysAPIService creates a default app service. The blocklist rejects authorizations requested from blocked IP
+// addresses and may be nil.
+func NewAPIKeysAPIService(cfg *config.Config, apiKeyClient dal.APIKeyManager, serviceClient dal.ServiceManager, actorClient dal.ActorManager, limiter ratelimit.Limiter, meter *usage.Meter, blocklist *ipblock.Matcher, logger *zap.Logger) openapi.APIKeysAPIServicer {
+	return &APIKeysAPIService{
+		apiKeyClient:           apiKeyClient,
+		serviceClient:          serviceClient,
+		actorClient:            actorClient,
+		verifier:               auth.NewSecretVerifier(cfg, logger, apiKeyClient),
+		limiter:                limiter,
+		meter:                  meter,
+		blocklist:              blocklist,
+		rotationGracePeriod:    cfg.APIKeys.RotationGracePeriod,
+		maxRotationGracePeriod: cfg.APIKeys.MaxRotationGracePeriod,
+		tokenPrefix:            cfg.APIKeys.TokenPrefix,
+		tokenEnvironment:       cfg.APIKeys.TokenEnvironment,
+		logger:                 logger,
+	}
 }
 
-// NewAPIKeysAPIService creates a default app service
-func NewAPIKeysAPIService(apiKeyClient dal.APIKeyManager, serviceClient dal.ServiceManager, logger *zap.Logger) openapi.APIKeysAPIServicer {
-	return &APIKeysAPIService{apiKeyClient: apiKeyClient, serviceClient: serviceClient, logger: logger}
+// AuthApiKey - Auth a request per given API key
+func (s *APIKeysAPIService) AuthApiKey(ctx context.Context, serviceId string, keyId string, authApiKeyRequest openapi.AuthApiKeyRequest) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// A service authenticated with its own API key may only authenticate keys of that service
+	if callerServiceID, ok := ctx.Value("serviceID").(string); ok && callerServiceID != serviceId {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	// Malformed tokens and tokens of other keys or deployments are rejected before any lookup
+	secret := authApiKeyRequest.Secret
+	if authApiKeyRequest.Token != "" {
+		token, err := apitoken.Parse(authApiKeyRequest.Token)
+		if err != nil || token.KeyID != keyId || token.Environment != s.tokenEnvironment {
+			return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "invalid API key")
+		}
+		secret = token.Secret
+	}
+
+	// Keys of a deleted service are as invalid as deleted keys
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "invalid API key")
+	}
+
+	apiKey, err := s.apiKeyClient.GetAPIKey(ctx, keyId)
+	if err != nil {
+		s.logger.Error("failed to get API key",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	// Keys from another service or organization are indistinguishable from missing keys
+	if apiKey == nil || apiKey.Deleted || apiKey.OrgID != orgID || apiKey.ServiceID != serviceId {
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "invalid API key")
+	}
+
+	// Reject blocked clients before verifying the secret, so that they cannot guess it. The blocklist applies to the
+	// same client as the allowlist, which is the end client reported by a service backend; the backend itself was
+	// checked against the blocklist when its own key was authenticated
+	clientIP := authClientIP(ctx, keyId, authApiKeyRequest.ClientIp)
+	if block := s.blocklist.Match(serviceId, clientIP, time.Now()); block != nil {
+		s.logger.Warn("request from blocked IP address",
+			zap.String("requestID", requestID),
+			zap.String("serviceID", serviceId),
+			zap.String("clientIP", clientIP),
+			zap.String("blockedIP", block.IPAddress),
+			zap.String("reason", block.Reason),
+		)
+		return s.denyApiKey(requestID, keyId, http.StatusForbidden, "IP address is blocked")
+	}
+
+	secretVersion, ok := s.verifier.Verify(ctx, apiKey, secret)
+	if !ok {
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "invalid API key")
+	}
+
+	expired, err := apiKey.Expired(time.Now())
+	if err != nil {
+		s.logger.Error("failed to check API key expiry",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if expired {
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "API key has expired")
+	}
+
+	if apiKey.Status == dal.APIKeyStatusQuarantined {
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "API key is quarantined")
+	}
+
+	if err := allowlist.Check(apiKey.AllowedCIDRs, apiKey.AllowedOrigins, clientIP, authApiKeyRequest.Origin); err != nil {
+		return s.denyApiKey(requestID, keyId, http.StatusForbidden, err.Error())
+	}
+
+	if authApiKeyRequest.ActorExternalId != "" && apiKey.ActorID != authApiKeyRequest.ActorExternalId {
+		return s.denyApiKey(requestID, keyId, http.StatusForbidden, "API key does not belong to actor")
+	}
+
+	if missing := scope.Missing(apiKey.Scopes, authApiKeyRequest.RequiredScopes); len(missing) > 0 {
+		return s.denyApiKey(requestID, keyId, http.StatusForbidden, "missing required scopes: "+strings.Join(missing, ", "))
+	}
+
+	if missing := missingValues(apiKey.Roles, authApiKeyRequest.RequiredRoles); len(missing) > 0 {
+		return s.denyApiKey(requestID, keyId, http.StatusForbidden, "missing required roles: "+strings.Join(missing, ", "))
+	}
+
+	rules, err := toRateLimitRules(apiKey.RateLimits)
+	if err != nil {
+		s.logger.Error("failed to parse rate limits",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	identity := ratelimit.Identity{
+		OrgID:     orgID,
+		ServiceID: serviceId,
+		ActorID:   apiKey.ActorID,
+		KeyID:     apiKey.APIKeyID,
+	}
+
+	result, rule, err := ratelimit.Check(ctx, s.limiter, rules, identity)
+	if err != nil {
+		s.logger.Error("failed to check rate limits",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response := openapi.AuthApiKey200Response{
+		Authorized:    true,
+		Message:       "authorized",
+		SecretVersion: string(secretVersion),
+	}
+
+	// Quota is only reported when a rate limit applied to the key
+	if rule != nil {
+		response.Remaining = int32(result.Remaining)
+		response.RateLimit = openapi.AuthApiKey200ResponseRateLimit{
+			Reset:     int32((result.Reset + time.Second - 1) / time.Second),
+			Limit:     int32(result.Limit),
+			Remaining: int32(result.Remaining),
+		}
+
+		if !result.Allowed {
+			s.logger.Warn("API key rate limited",
+				zap.String("requestID", requestID),
+				zap.String("keyID", keyId),
+				zap.String("rateLimit", rule.Name),
+			)
+
+			response.Authorized = false
+			response.Message = "rate limit exceeded: " + rule.Name
+			return openapi.Response(http.StatusTooManyRequests, response), nil
+		}
+	}
+
+	// The request is counted against the monthly limit last, once nothing else can deny it
+	decision, err := s.meter.Consume(ctx, orgID, serviceId, apiKey.ActorID, apiKey.APIKeyID)
+	if err != nil {
+		s.logger.Error("failed to check usage",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if !decision.Allowed {
+		return s.denyApiKey(requestID, keyId, http.StatusTooManyRequests, "monthly request limit exceeded")
+	}
+	response.Overage = decision.Overage
+
+	return openapi.Response(http.StatusOK, response), nil
+}
+
+// denyApiKey logs a rejected authorization attempt and builds the response returned to the caller.
+func (s *APIKeysAPIService) denyApiKey(requestID, keyID string, code int, message string) (openapi.ImplResponse, error) {
+	s.logger.Warn("API key authorization denied",
+		zap.String("requestID", requestID),
+		zap.String("keyID", keyID),
+		zap.String("reason", message),
+	)
+
+	return openapi.Response(code, openapi.AuthApiKey200Response{
+		Authorized: false,
+		Message:    message,
+	}), nil
+}
+
+// formatToken formats the token of an API key of a service, with the key prefix of the service if it has one.
+func (s *APIKeysAPIService) formatToken(service *dal.Service, keyID, secret string) string {
+	prefix := s.tokenPrefix
+	if service.KeyPrefix != "" {
+		prefix = service.KeyPrefix
+	}
+
+	return apitoken.Token{
+		Prefix:      prefix,
+		Environment: s.tokenEnvironment,
+		KeyID:       keyID,
+		Secret:      secret,
+	}.String()
+}
+
+// missingValues returns the required values that are not present in granted.
+func missingValues(granted, required []string) []string {
+	set := make(map[string]struct{}, len(granted))
+	for _, value := range granted {
+		set[value] = struct{}{}
+	}
+
+	var missing []string
+	for _, value := range required {
+		if _, ok := set[value]; !ok {
+			missing = append(missing, value)
+		}
+	}
+
+	return missing
+}
+
+// normalizeRoles trims the roles granted by an API key and removes duplicates. Roles are matched exactly against the
+// roles required by the auth endpoint, so blank roles are rejected rather than stored.
+func normalizeRoles(roles []string) ([]string, error) {
+	normalized := make([]string, 0, len(roles))
+	seen := make(map[string]struct{}, len(roles))
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			return nil, errors.New("roles cannot be blank")
+		}
+		if _, ok := seen[role]; ok {
+			continue
+		}
+		seen[role] = struct{}{}
+		normalized = append(normalized, role)
+	}
+	return normalized, nil
 }
 
 // DeleteApiKey - Delete a specific API key
-func (s *APIKeysAPIService) DeleteApiKey(ctx context.Context, serviceId string, keyId string) (openapi.ImplResponse, error) {
+func (s *APIKeysAPIService) DeleteApiKey(ctx context.Context, serviceId string, keyId string, ifMatch string) (openapi.ImplResponse, error) {
 	requestID := middleware.GetReqID(ctx)
 	orgID, ok := ctx.Value("orgID").(string)
 	if !ok || orgID == "" {
@@ -46,14 +310,14 @@ func (s *APIKeysAPIService) DeleteApiKey(ctx context.Context, serviceId string,
 		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
 	}
 
-	// Check if the service exists
+	// Keys of a deleted service are as invalid as deleted keys
 	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
 	if err != nil {
 		s.logger.Error("failed to get service",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 	if service == nil {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
@@ -66,19 +330,25 @@ func (s *APIKeysAPIService) DeleteApiKey(ctx context.Context, serviceId string,
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
-	if apiKey == nil {
+	if apiKey == nil || apiKey.OrgID != orgID || apiKey.ServiceID != serviceId {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
 	}
+	if !matchesETag(ifMatch, apiKey.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
 
-	err = s.apiKeyClient.DeleteAPIKey(ctx, orgID, serviceId, keyId)
+	err = s.apiKeyClient.DeleteAPIKey(ctx, orgID, serviceId, keyId, apiKey.Version)
 	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
 		s.logger.Error("failed to delete API key",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
 	return openapi.Response(http.StatusNoContent, nil), nil
@@ -95,72 +365,118 @@ func (s *APIKeysAPIService) GenerateApiKey(ctx context.Context, serviceId string
 		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
 	}
 
-	// Check if the service exists
+	// Keys of a deleted service are as invalid as deleted keys
 	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
 	if err != nil {
 		s.logger.Error("failed to get service",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 	if service == nil {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
 	}
 
-	keySecret, err := utils.GenerateSecret(ApiKeyLength)
+	scopes, err := scope.NormalizeAll(apiKeyInput.Scopes)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	roles, err := normalizeRoles(apiKeyInput.Roles)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	rateLimits, err := toDALRateLimits(apiKeyInput.RateLimits)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	allowedCIDRs, err := allowlist.NormalizeCIDRs(apiKeyInput.AllowedCidrs)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	allowedOrigins, err := allowlist.NormalizeOrigins(apiKeyInput.AllowedOrigins)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	expiry, err := resolveExpiry(apiKeyInput, service.MaxKeyTTLSeconds, time.Now())
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Keys may be bound to an actor of the service, so that they can be listed and revoked with it
+	if apiKeyInput.ActorExternalId != "" {
+		actor, err := s.actorClient.GetActor(ctx, orgID, serviceId, apiKeyInput.ActorExternalId)
+		if err != nil {
+			s.logger.Error("failed to get actor",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		if actor == nil {
+			return openapi.Response(http.StatusBadRequest, nil), fmt.Errorf("actor '%s' not found", apiKeyInput.ActorExternalId)
+		}
+	}
+
+	keySecret, err := apitoken.GenerateSecret()
 	if err != nil {
 		s.logger.Error("failed to generate API key",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
-	}
-
-	apiKey := dal.APIKey{
-		ServiceID: serviceId,
-		OrgID:     orgID,
-		Secret:    keySecret,
-		Scopes:    apiKeyInput.Scopes,
+		return openapi.ErrorResponse(err)
 	}
 
-	err = s.apiKeyClient.CreateAPIKey(ctx, &apiKey)
+	secretHash, err := s.verifier.HashSecret(keySecret)
 	if err != nil {
-		s.logger.Error("failed to create API key",
+		s.logger.Error("failed to hash API key secret",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
-	createdAt, err := utils.ParseTimestamp(apiKey.CreatedAt)
+	apiKey := dal.APIKey{
+		ServiceID:      serviceId,
+		OrgID:          orgID,
+		ActorID:        apiKeyInput.ActorExternalId,
+		Secret:         secretHash,
+		Scopes:         scopes,
+		Roles:          roles,
+		RateLimits:     rateLimits,
+		AllowedCIDRs:   allowedCIDRs,
+		AllowedOrigins: allowedOrigins,
+		Expiry:         expiry,
+	}
+
+	err = s.apiKeyClient.CreateAPIKey(ctx, &apiKey)
 	if err != nil {
-		s.logger.Error("failed to parse timestamp",
+		s.logger.Error("failed to create API key",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
-	updatedAt, err := utils.ParseTimestamp(apiKey.UpdatedAt)
+	response, err := toAPIKey(&apiKey)
 	if err != nil {
 		s.logger.Error("failed to parse timestamp",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
-	response := openapi.ApiKey{
-		ServiceId: serviceId,
-		Id:        apiKey.APIKeyID,
-		Secret:    apiKey.Secret,
-		Scopes:    apiKey.Scopes,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
-	}
+	// The raw secret is only ever returned here; only its hash is stored
+	response.Secret = keySecret
+	response.Token = s.formatToken(service, apiKey.APIKeyID, keySecret)
 
-	return openapi.Response(http.StatusCreated, response), nil
+	return openapi.ResponseWithHeaders(http.StatusCreated, etagHeaders(apiKey.Version), response), nil
 }
 
 // GetApiKey - Retrieve a specific API key
@@ -174,17 +490,17 @@ func (s *APIKeysAPIService) GetApiKey(ctx context.Context, serviceId string, key
 		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
 	}
 
-	// Check if the service exists
+	// Keys of a deleted service are as invalid as deleted keys
 	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
 	if err != nil {
 		s.logger.Error("failed to get service",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 	if service == nil {
-		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+		return s.denyApiKey(requestID, keyId, http.StatusUnauthorized, "invalid API key")
 	}
 
 	apiKey, err := s.apiKeyClient.GetAPIKey(ctx, keyId)
@@ -193,43 +509,26 @@ func (s *APIKeysAPIService) GetApiKey(ctx context.Context, serviceId string, key
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
-	if apiKey == nil {
+	if apiKey == nil || apiKey.OrgID != orgID || apiKey.ServiceID != serviceId {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
 	}
 
-	createdAt, err := utils.ParseTimestamp(apiKey.CreatedAt)
-	if err != nil {
-		s.logger.Error("failed to parse timestamp",
-			zap.String("requestID", requestID),
-			zap.Error(err),
-		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
-	}
-
-	updatedAt, err := utils.ParseTimestamp(apiKey.UpdatedAt)
+	response, err := toAPIKey(apiKey)
 	if err != nil {
 		s.logger.Error("failed to parse timestamp",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
-	response := openapi.ApiKey{
-		Id:        apiKey.APIKeyID,
-		Secret:    apiKey.Secret,
-		Scopes:    apiKey.Scopes,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
-	}
-
-	return openapi.Response(http.StatusOK, response), nil
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(apiKey.Version), response), nil
 }
 
 // ListApiKeys - List all API keys for a service
-func (s *APIKeysAPIService) ListApiKeys(ctx context.Context, serviceId string) (openapi.ImplResponse, error) {
+func (s *APIKeysAPIService) ListApiKeys(ctx context.Context, serviceId string, cursor string, limit int32) (openapi.ImplResponse, error) {
 	requestID := middleware.GetReqID(ctx)
 	orgID, ok := ctx.Value("orgID").(string)
 	if !ok || orgID == "" {
@@ -239,62 +538,173 @@ func (s *APIKeysAPIService) ListApiKeys(ctx context.Context, serviceId string) (
 		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
 	}
 
-	// Check if the service exists
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Keys of a deleted service are as invalid as deleted keys
 	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
 	if err != nil {
 		s.logger.Error("failed to get service",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 	if service == nil {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
 	}
 
-	apiKeys, err := s.apiKeyClient.ListAPIKeysByService(ctx, orgID, serviceId)
+	apiKeys, nextCursor, err := s.apiKeyClient.ListAPIKeysByService(ctx, orgID, serviceId, page)
 	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
 		s.logger.Error("failed to list API keys",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
 	responses := make([]openapi.ApiKey, len(apiKeys))
-	for i, apiKey := range apiKeys {
-		createdAt, err := utils.ParseTimestamp(apiKey.CreatedAt)
+	for i := range apiKeys {
+		responses[i], err = toAPIKey(&apiKeys[i])
 		if err != nil {
 			s.logger.Error("failed to parse timestamp",
 				zap.String("requestID", requestID),
 				zap.Error(err),
 			)
-			return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+			return openapi.ErrorResponse(err)
 		}
+	}
 
-		updatedAt, err := utils.ParseTimestamp(apiKey.UpdatedAt)
-		if err != nil {
-			s.logger.Error("failed to parse timestamp",
-				zap.String("requestID", requestID),
-				zap.Error(err),
-			)
-			return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
-		}
+	return openapi.Response(http.StatusOK, openapi.ApiKeyList{ApiKeys: responses, NextCursor: nextCursor}), nil
+}
 
-		responses[i] = openapi.ApiKey{
-			Id:        apiKey.APIKeyID,
-			Secret:    apiKey.Secret,
-			Scopes:    apiKey.Scopes,
-			CreatedAt: createdAt,
-			UpdatedAt: updatedAt,
-		}
+// RotateApiKey - Rotate the secret of an API key
+func (s *APIKeysAPIService) RotateApiKey(ctx context.Context, serviceId string, keyId string, rotateApiKeyRequest openapi.RotateApiKeyRequest) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Keys of a deleted service are as invalid as deleted keys
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	// Check if the API key exists
+	apiKey, err := s.apiKeyClient.GetAPIKey(ctx, keyId)
+	if err != nil {
+		s.logger.Error("failed to get API key",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if apiKey == nil || apiKey.OrgID != orgID || apiKey.ServiceID != serviceId {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
+	}
+
+	gracePeriod := s.rotationGracePeriod
+	if rotateApiKeyRequest.GracePeriodSeconds < 0 {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("gracePeriodSeconds must not be negative")
+	}
+	if rotateApiKeyRequest.GracePeriodSeconds > 0 {
+		gracePeriod = time.Duration(rotateApiKeyRequest.GracePeriodSeconds) * time.Second
+	}
+	if gracePeriod > s.maxRotationGracePeriod {
+		return openapi.Response(http.StatusBadRequest, nil), fmt.Errorf("gracePeriodSeconds exceeds the maximum of %d seconds", int64(s.maxRotationGracePeriod/time.Second))
+	}
+
+	// Expired keys are terminal and cannot be given a new secret
+	now := time.Now()
+	expired, err := apiKey.Expired(now)
+	if err != nil {
+		s.logger.Error("failed to check API key expiry",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if expired {
+		return openapi.Response(http.StatusConflict, nil), errors.New("API key has expired")
+	}
+
+	// Quarantined keys were reported leaked and are kept only for their owners to investigate
+	if apiKey.Status == dal.APIKeyStatusQuarantined {
+		return openapi.Response(http.StatusConflict, nil), errors.New("API key is quarantined")
+	}
+
+	keySecret, err := apitoken.GenerateSecret()
+	if err != nil {
+		s.logger.Error("failed to generate API key",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	secretHash, err := s.verifier.HashSecret(keySecret)
+	if err != nil {
+		s.logger.Error("failed to hash API key secret",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	previousSecretExpiry := now.Add(gracePeriod).UTC().Format(time.RFC3339)
+	rotated, err := s.apiKeyClient.RotateAPIKeySecret(ctx, keyId, apiKey.Secret, secretHash, previousSecretExpiry)
+	if err != nil {
+		s.logger.Error("failed to rotate API key secret",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if !rotated {
+		return openapi.Response(http.StatusConflict, nil), errors.New("API key secret was changed concurrently")
+	}
+
+	apiKey.PreviousSecret = apiKey.Secret
+	apiKey.PreviousSecretExpiry = previousSecretExpiry
+	apiKey.Secret = secretHash
+	apiKey.UpdatedAt = now.UTC().Format(time.RFC3339)
+	apiKey.Version++
+
+	response, err := toAPIKey(apiKey)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
 	}
 
-	return openapi.Response(http.StatusOK, responses), nil
+	// The new secret is only ever returned here; only its hash is stored
+	response.Secret = keySecret
+	response.Token = s.formatToken(service, apiKey.APIKeyID, keySecret)
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(apiKey.Version), response), nil
 }
 
 // UpdateApiKey - Update an API key's scopes
-func (s *APIKeysAPIService) UpdateApiKey(ctx context.Context, serviceId string, keyId string, apiKeyInput openapi.ApiKeyInput) (openapi.ImplResponse, error) {
+func (s *APIKeysAPIService) UpdateApiKey(ctx context.Context, serviceId string, keyId string, ifMatch string, apiKeyInput openapi.ApiKeyInput) (openapi.ImplResponse, error) {
 	requestID := middleware.GetReqID(ctx)
 	orgID, ok := ctx.Value("orgID").(string)
 	if !ok || orgID == "" {
@@ -304,14 +714,14 @@ func (s *APIKeysAPIService) UpdateApiKey(ctx context.Context, serviceId string,
 		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
 	}
 
-	// Check if the service exists
+	// Keys of a deleted service are as invalid as deleted keys
 	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
 	if err != nil {
 		s.logger.Error("failed to get service",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 	if service == nil {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
@@ -324,48 +734,246 @@ func (s *APIKeysAPIService) UpdateApiKey(ctx context.Context, serviceId string,
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
-	if apiKey == nil {
+	if apiKey == nil || apiKey.OrgID != orgID || apiKey.ServiceID != serviceId {
 		return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
 	}
+	if !matchesETag(ifMatch, apiKey.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	scopes, err := scope.NormalizeAll(apiKeyInput.Scopes)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	roles, err := normalizeRoles(apiKeyInput.Roles)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	rateLimits, err := toDALRateLimits(apiKeyInput.RateLimits)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	allowedCIDRs, err := allowlist.NormalizeCIDRs(apiKeyInput.AllowedCidrs)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	allowedOrigins, err := allowlist.NormalizeOrigins(apiKeyInput.AllowedOrigins)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Expired keys are terminal and cannot be extended
+	now := time.Now()
+	expired, err := apiKey.Expired(now)
+	if err != nil {
+		s.logger.Error("failed to check API key expiry",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if expired {
+		return openapi.Response(http.StatusConflict, nil), errors.New("API key has expired")
+	}
+
+	// Quarantined keys were reported leaked and are kept only for their owners to investigate
+	if apiKey.Status == dal.APIKeyStatusQuarantined {
+		return openapi.Response(http.StatusConflict, nil), errors.New("API key is quarantined")
+	}
+
+	expiry, err := resolveExpiry(apiKeyInput, service.MaxKeyTTLSeconds, now)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
 
 	// Update the API key with the new values
-	apiKey.Scopes = apiKeyInput.Scopes
+	apiKey.Scopes = scopes
+	apiKey.Roles = roles
+	apiKey.RateLimits = rateLimits
+	apiKey.AllowedCIDRs = allowedCIDRs
+	apiKey.AllowedOrigins = allowedOrigins
+	apiKey.Expiry = expiry
 	err = s.apiKeyClient.UpdateAPIKey(ctx, apiKey)
 	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
 		s.logger.Error("failed to update API key",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
 	}
 
-	createdAt, err := utils.ParseTimestamp(apiKey.CreatedAt)
+	response, err := toAPIKey(apiKey)
 	if err != nil {
 		s.logger.Error("failed to parse timestamp",
 			zap.String("requestID", requestID),
 			zap.Error(err),
 		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(apiKey.Version), response), nil
+}
+
+// resolveExpiry validates the expiry or TTL of an API key input against the maximum key lifetime of its service and
+// returns the expiry to store. Keys of services with a maximum lifetime default to expiring after it.
+func resolveExpiry(input openapi.ApiKeyInput, maxTTLSeconds int64, now time.Time) (string, error) {
+	if !input.Expiry.IsZero() && input.TtlSeconds != 0 {
+		return "", errors.New("expiry and ttlSeconds are mutually exclusive")
+	}
+	if input.TtlSeconds < 0 {
+		return "", errors.New("ttlSeconds must be positive")
+	}
+
+	expiry := input.Expiry
+	if input.TtlSeconds > 0 {
+		expiry = now.Add(time.Duration(input.TtlSeconds) * time.Second)
+	}
+
+	if maxTTLSeconds > 0 {
+		maxExpiry := now.Add(time.Duration(maxTTLSeconds) * time.Second)
+		if expiry.IsZero() {
+			expiry = maxExpiry
+		}
+		if expiry.After(maxExpiry) {
+			return "", fmt.Errorf("expiry exceeds the maximum key lifetime of the service of %d seconds", maxTTLSeconds)
+		}
+	}
+
+	if expiry.IsZero() {
+		return "", nil
+	}
+	if !expiry.After(now) {
+		return "", errors.New("expiry must be in the future")
+	}
+
+	return expiry.UTC().Format(time.RFC3339), nil
+}
+
+// toAPIKey converts a stored API key into its API representation, without its secret.
+func toAPIKey(apiKey *dal.APIKey) (openapi.ApiKey, error) {
+	createdAt, err := utils.ParseTimestamp(apiKey.CreatedAt)
+	if err != nil {
+		return openapi.ApiKey{}, err
 	}
 
 	updatedAt, err := utils.ParseTimestamp(apiKey.UpdatedAt)
 	if err != nil {
-		s.logger.Error("failed to parse timestamp",
-			zap.String("requestID", requestID),
-			zap.Error(err),
-		)
-		return openapi.Response(http.StatusInternalServerError, nil), errors.New("internal server error")
+		return openapi.ApiKey{}, err
 	}
 
-	response := openapi.ApiKey{
-		Id:        apiKey.APIKeyID,
-		Secret:    apiKey.Secret,
-		Scopes:    apiKey.Scopes,
-		CreatedAt: createdAt,
-		UpdatedAt: updatedAt,
+	expiry, err := utils.ParseTimestamp(apiKey.Expiry)
+	if err != nil {
+		return openapi.ApiKey{}, err
 	}
 
-	return openapi.Response(http.StatusOK, response), nil
+	previousSecretExpiry, err := utils.ParseTimestamp(apiKey.PreviousSecretExpiry)
+	if err != nil {
+		return openapi.ApiKey{}, err
+	}
+
+	// Keys are reported as expired as soon as their expiry passes, before the sweeper transitions them
+	status := dal.APIKeyStatusActive
+	switch {
+	case apiKey.Status == dal.APIKeyStatusExpired || (!expiry.IsZero() && !time.Now().Before(expiry)):
+		status = dal.APIKeyStatusExpired
+	case apiKey.Status == dal.APIKeyStatusQuarantined:
+		status = dal.APIKeyStatusQuarantined
+	}
+
+	return openapi.ApiKey{
+		ServiceId:            apiKey.ServiceID,
+		Id:                   apiKey.APIKeyID,
+		ActorExternalId:      apiKey.ActorID,
+		Scopes:               apiKey.Scopes,
+		Roles:                apiKey.Roles,
+		RateLimits:           toAPIRateLimits(apiKey.RateLimits),
+		AllowedCidrs:         apiKey.AllowedCIDRs,
+		AllowedOrigins:       apiKey.AllowedOrigins,
+		Expiry:               expiry,
+		Status:               status,
+		CreatedAt:            createdAt,
+		UpdatedAt:            updatedAt,
+		PreviousSecretExpiry: previousSecretExpiry,
+	}, nil
+}
+
+// toDALRateLimits validates rate limit inputs and converts them for storage, filling in default values.
+func toDALRateLimits(inputs []openapi.RateLimitInput) ([]dal.RateLimit, error) {
+	rateLimits := make([]dal.RateLimit, 0, len(inputs))
+	names := make(map[string]struct{}, len(inputs))
+	for _, input := range inputs {
+		rule, err := ratelimit.ParseRule(input.Name, input.Algorithm, input.Scope, int64(input.Limit), input.Window)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("duplicate rate limit '%s'", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		rateLimits = append(rateLimits, dal.RateLimit{
+			Name:      rule.Name,
+			Algorithm: string(rule.Algorithm),
+			Scope:     string(rule.Scope),
+			Limit:     rule.Limit,
+			Window:    input.Window,
+		})
+	}
+
+	return rateLimits, nil
+}
+
+// toAPIRateLimits converts stored rate limits into their API representation.
+func toAPIRateLimits(rateLimits []dal.RateLimit) []openapi.RateLimit {
+	responses := make([]openapi.RateLimit, len(rateLimits))
+	for i, rateLimit := range rateLimits {
+		responses[i] = openapi.RateLimit{
+			Name:      rateLimit.Name,
+			Limit:     int32(rateLimit.Limit),
+			Duration:  rateLimit.Window,
+			Algorithm: rateLimit.Algorithm,
+			Scope:     rateLimit.Scope,
+		}
+	}
+
+	return responses
+}
+
+// toRateLimitRules converts stored rate limits into rules that can be enforced.
+func toRateLimitRules(rateLimits []dal.RateLimit) ([]ratelimit.Rule, error) {
+	rules := make([]ratelimit.Rule, len(rateLimits))
+	for i, rateLimit := range rateLimits {
+		rule, err := ratelimit.ParseRule(rateLimit.Name, rateLimit.Algorithm, rateLimit.Scope, rateLimit.Limit, rateLimit.Window)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+
+	return rules, nil
+}
+
+// authClientIP returns the IP address that the blocklist and the allowlist of an API key are checked against on the
+// auth endpoint.
+// A service backend authenticated with a key of its own, which is not bound to an actor, vouches for the address of
+// the client it forwards. Any other caller could claim any address, so the address it reports is ignored, and the
+// client address of the request, resolved through the trusted proxies, is checked instead.
+func authClientIP(ctx context.Context, keyID, reported string) string {
+	clientIP, _ := ctx.Value("clientIP").(string)
+	callerKeyID, _ := ctx.Value("apiKeyID").(string)
+	callerActorID, _ := ctx.Value("actorID").(string)
+	if reported == "" || callerKeyID == "" || callerKeyID == keyID || callerActorID != "" {
+		return clientIP
+	}
+	return reported
 }
@@ -3,34 +3,57 @@ package service_test
 import (
 	"context"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/cache"
+	"github.com/payloadops/lanyard/app/config"
 	"github.com/payloadops/lanyard/app/dal"
 	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/ipblock"
 	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/ratelimit"
 	"github.com/payloadops/lanyard/app/service"
+	"github.com/payloadops/lanyard/app/usage"
+	"github.com/payloadops/lanyard/app/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"go.uber.org/zap"
 )
 
+var testConfig = &config.Config{
+	JWTSecret: "secret",
+	APIKeys: config.APIKeysConfig{
+		SecretPepper:           "pepper",
+		RotationGracePeriod:    24 * time.Hour,
+		MaxRotationGracePeriod: 7 * 24 * time.Hour,
+		TokenPrefix:            "lny",
+		TokenEnvironment:       "live",
+	},
+}
+
 func TestAPIKeysAPIService_DeleteApiKey(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
 	mockServiceClient := mocks.NewMockServiceManager(ctrl)
-	service := service.NewAPIKeysAPIService(mockAPIKeyClient, mockServiceClient, zap.NewNop())
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
 
 	ctx := context.WithValue(context.Background(), "orgID", "org1")
 	serviceID := "serv1"
 	keyID := "key1"
 
 	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
-	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(&dal.APIKey{ServiceID: serviceID}, nil)
-	mockAPIKeyClient.EXPECT().DeleteAPIKey(ctx, "org1", serviceID, keyID).Return(nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(&dal.APIKey{OrgID: "org1", ServiceID: serviceID}, nil)
+	mockAPIKeyClient.EXPECT().DeleteAPIKey(ctx, "org1", serviceID, keyID, int64(0)).Return(nil)
 
-	response, err := service.DeleteApiKey(ctx, serviceID, keyID)
+	response, err := service.DeleteApiKey(ctx, serviceID, keyID, "")
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusNoContent, response.Code)
 }
@@ -41,16 +64,23 @@ func TestAPIKeysAPIService_GenerateApiKey(t *testing.T) {
 
 	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
 	mockServiceClient := mocks.NewMockServiceManager(ctrl)
-	service := service.NewAPIKeysAPIService(mockAPIKeyClient, mockServiceClient, zap.NewNop())
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
 
 	ctx := context.WithValue(context.Background(), "orgID", "org1")
 	serviceID := "serv1"
 	apiKeyInput := openapi.ApiKeyInput{
-		Scopes: []string{"scope1", "scope2"},
+		Scopes:     []string{"scope1", "scope2"},
+		RateLimits: []openapi.RateLimitInput{{Name: "minute", Limit: 10, Window: "1m"}},
 	}
 
 	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
-	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).Return(nil)
+	var stored dal.APIKey
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, apiKey *dal.APIKey) error {
+		stored = *apiKey
+		return nil
+	})
 
 	response, err := service.GenerateApiKey(ctx, serviceID, apiKeyInput)
 	assert.NoError(t, err)
@@ -60,6 +90,148 @@ func TestAPIKeysAPIService_GenerateApiKey(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, serviceID, apiKey.ServiceId)
 	assert.Equal(t, apiKeyInput.Scopes, apiKey.Scopes)
+
+	// Only the hash of the returned secret is stored
+	assert.NotEmpty(t, apiKey.Secret)
+	assert.True(t, utils.IsSecretHash(stored.Secret))
+	assert.True(t, utils.VerifySecretHash(apiKey.Secret, stored.Secret, "pepper"))
+
+	// Rate limits are stored with their defaults filled in
+	assert.Equal(t, []dal.RateLimit{{Name: "minute", Algorithm: "fixed_window", Scope: "key", Limit: 10, Window: "1m"}}, stored.RateLimits)
+	assert.Equal(t, []openapi.RateLimit{{Name: "minute", Limit: 10, Duration: "1m", Algorithm: "fixed_window", Scope: "key"}}, apiKey.RateLimits)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Actor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ExternalID: "actor1"}, nil)
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, apiKey *dal.APIKey) error {
+		assert.Equal(t, "actor1", apiKey.ActorID)
+		apiKey.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		apiKey.UpdatedAt = apiKey.CreatedAt
+		return nil
+	})
+
+	response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{ActorExternalId: "actor1"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, "actor1", response.Body.(openapi.ApiKey).ActorExternalId)
+
+	// Keys cannot be bound to actors that do not exist
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor2").Return(nil, nil)
+
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{ActorExternalId: "actor2"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Token(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, apiKey *dal.APIKey) error {
+		apiKey.APIKeyID = "2V7ZyKcNwQ1iGp2nnGiAvEDhXk8"
+		apiKey.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		apiKey.UpdatedAt = apiKey.CreatedAt
+		return nil
+	}).Times(2)
+
+	tests := []struct {
+		name           string
+		service        *dal.Service
+		expectedPrefix string
+	}{
+		{"Deployment prefix", &dal.Service{}, "lny"},
+		{"Service prefix", &dal.Service{KeyPrefix: "acme"}, "acme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(tt.service, nil)
+
+			response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, response.Code)
+			apiKey := response.Body.(openapi.ApiKey)
+
+			// The token carries the key ID and the same secret as the one returned on its own
+			token, err := apitoken.Parse(apiKey.Token)
+			require.NoError(t, err)
+			assert.Equal(t, apitoken.Token{
+				Prefix:      tt.expectedPrefix,
+				Environment: "live",
+				KeyID:       "2V7ZyKcNwQ1iGp2nnGiAvEDhXk8",
+				Secret:      apiKey.Secret,
+			}, token)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_OtherOwner(t *testing.T) {
+	owners := []struct {
+		name   string
+		apiKey *dal.APIKey
+	}{
+		{name: "Other organization", apiKey: &dal.APIKey{APIKeyID: "key1", OrgID: "org2", ServiceID: "serv1"}},
+		{name: "Other service", apiKey: &dal.APIKey{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv2"}},
+	}
+	operations := []struct {
+		name string
+		call func(s openapi.APIKeysAPIServicer, ctx context.Context) (openapi.ImplResponse, error)
+	}{
+		{name: "Get", call: func(s openapi.APIKeysAPIServicer, ctx context.Context) (openapi.ImplResponse, error) {
+			return s.GetApiKey(ctx, "serv1", "key1")
+		}},
+		{name: "Update", call: func(s openapi.APIKeysAPIServicer, ctx context.Context) (openapi.ImplResponse, error) {
+			return s.UpdateApiKey(ctx, "serv1", "key1", "", openapi.ApiKeyInput{Scopes: []string{"scope1"}})
+		}},
+		{name: "Delete", call: func(s openapi.APIKeysAPIServicer, ctx context.Context) (openapi.ImplResponse, error) {
+			return s.DeleteApiKey(ctx, "serv1", "key1", "")
+		}},
+	}
+
+	for _, owner := range owners {
+		for _, operation := range operations {
+			t.Run(owner.name+"/"+operation.name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+				mockServiceClient := mocks.NewMockServiceManager(ctrl)
+				mockActorClient := mocks.NewMockActorManager(ctrl)
+				meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+				service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+				ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+				// Keys of other organizations and services are not found, and are neither returned nor written
+				mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+				mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(owner.apiKey, nil)
+
+				response, err := operation.call(service, ctx)
+				assert.Error(t, err)
+				assert.Equal(t, http.StatusNotFound, response.Code)
+				assert.Nil(t, response.Body)
+			})
+		}
+	}
 }
 
 func TestAPIKeysAPIService_GetApiKey(t *testing.T) {
@@ -68,14 +240,16 @@ func TestAPIKeysAPIService_GetApiKey(t *testing.T) {
 
 	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
 	mockServiceClient := mocks.NewMockServiceManager(ctrl)
-	service := service.NewAPIKeysAPIService(mockAPIKeyClient, mockServiceClient, zap.NewNop())
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
 
 	ctx := context.WithValue(context.Background(), "orgID", "org1")
 	serviceID := "serv1"
 	keyID := "key1"
 
 	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
-	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(&dal.APIKey{ServiceID: serviceID}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(&dal.APIKey{OrgID: "org1", ServiceID: serviceID, Secret: "v1$salt$mac"}, nil)
 
 	response, err := service.GetApiKey(ctx, serviceID, keyID)
 	assert.NoError(t, err)
@@ -84,6 +258,7 @@ func TestAPIKeysAPIService_GetApiKey(t *testing.T) {
 	apiKey, ok := response.Body.(openapi.ApiKey)
 	assert.True(t, ok)
 	assert.Equal(t, serviceID, apiKey.ServiceId)
+	assert.Empty(t, apiKey.Secret)
 }
 
 func TestAPIKeysAPIService_ListApiKeys(t *testing.T) {
@@ -92,28 +267,34 @@ func TestAPIKeysAPIService_ListApiKeys(t *testing.T) {
 
 	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
 	mockServiceClient := mocks.NewMockServiceManager(ctrl)
-	service := service.NewAPIKeysAPIService(mockAPIKeyClient, mockServiceClient, zap.NewNop())
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
 
 	ctx := context.WithValue(context.Background(), "orgID", "org1")
 	serviceID := "serv1"
 
 	apiKeys := []dal.APIKey{
-		{APIKeyID: "key1", ServiceID: serviceID},
-		{APIKeyID: "key2", ServiceID: serviceID},
+		{APIKeyID: "key1", ServiceID: serviceID, Secret: "v1$salt$mac"},
+		{APIKeyID: "key2", ServiceID: serviceID, Secret: "v1$salt$mac"},
 	}
 
 	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
-	mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", serviceID).Return(apiKeys, nil)
+	mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", serviceID, dal.Page{}).Return(apiKeys, "", nil)
 
-	response, err := service.ListApiKeys(ctx, serviceID)
+	response, err := service.ListApiKeys(ctx, serviceID, "", 0)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, response.Code)
 	assert.NotNil(t, response.Body)
-	keys, ok := response.Body.([]openapi.ApiKey)
+	list, ok := response.Body.(openapi.ApiKeyList)
 	assert.True(t, ok)
+	assert.Empty(t, list.NextCursor)
+	keys := list.ApiKeys
 	assert.Equal(t, 2, len(keys))
 	assert.Equal(t, "key1", keys[0].Id)
 	assert.Equal(t, "key2", keys[1].Id)
+	assert.Empty(t, keys[0].Secret)
+	assert.Empty(t, keys[1].Secret)
 }
 
 func TestAPIKeysAPIService_UpdateApiKey(t *testing.T) {
@@ -122,31 +303,1152 @@ func TestAPIKeysAPIService_UpdateApiKey(t *testing.T) {
 
 	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
 	mockServiceClient := mocks.NewMockServiceManager(ctrl)
-	service := service.NewAPIKeysAPIService(mockAPIKeyClient, mockServiceClient, zap.NewNop())
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
 
 	ctx := context.WithValue(context.Background(), "orgID", "org1")
 	serviceID := "serv1"
 	keyID := "key1"
 	apiKeyInput := openapi.ApiKeyInput{
-		Scopes: []string{"new-scope1", "new-scope2"},
+		Scopes:         []string{"new-scope1", "new-scope2"},
+		Roles:          []string{"billing"},
+		AllowedOrigins: []string{"https://app.example.com"},
 	}
 
 	apiKey := &dal.APIKey{
-		APIKeyID:  keyID,
-		ServiceID: serviceID,
-		Scopes:    []string{"old-scope1", "old-scope2"},
+		APIKeyID:     keyID,
+		OrgID:        "org1",
+		ServiceID:    serviceID,
+		Scopes:       []string{"old-scope1", "old-scope2"},
+		Roles:        []string{"admin"},
+		AllowedCIDRs: []string{"203.0.113.0/24"},
 	}
 
 	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
 	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(apiKey, nil)
-	mockAPIKeyClient.EXPECT().UpdateAPIKey(ctx, gomock.Any()).Return(nil)
+	mockAPIKeyClient.EXPECT().UpdateAPIKey(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, apiKey *dal.APIKey) error {
+		assert.Equal(t, []string{"billing"}, apiKey.Roles)
+		return nil
+	})
 
-	response, err := service.UpdateApiKey(ctx, serviceID, keyID, apiKeyInput)
+	response, err := service.UpdateApiKey(ctx, serviceID, keyID, "", apiKeyInput)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, response.Code)
 	assert.NotNil(t, response.Body)
 	updatedKey, ok := response.Body.(openapi.ApiKey)
 	assert.True(t, ok)
 	assert.Equal(t, apiKeyInput.Scopes, updatedKey.Scopes)
+	assert.Equal(t, apiKeyInput.Roles, updatedKey.Roles)
 	assert.Equal(t, serviceID, updatedKey.ServiceId)
+
+	// Restrictions are replaced, so that omitting them lifts them
+	assert.Empty(t, updatedKey.AllowedCidrs)
+	assert.Equal(t, []string{"https://app.example.com"}, updatedKey.AllowedOrigins)
+}
+
+func TestAPIKeysAPIService_AuthApiKey(t *testing.T) {
+	serviceID := "serv1"
+	keyID := "key1"
+	secretHash, _ := utils.HashSecret("secret", "pepper")
+	tokenSecret := strings.Repeat("s", apitoken.SecretLength)
+	tokenSecretHash, _ := utils.HashSecret(tokenSecret, "pepper")
+	validKey := func() *dal.APIKey {
+		return &dal.APIKey{
+			APIKeyID:  keyID,
+			OrgID:     "org1",
+			ServiceID: serviceID,
+			ActorID:   "actor1",
+			Secret:    secretHash,
+			Scopes:    []string{"read", "write"},
+			Roles:     []string{"admin"},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		apiKey             *dal.APIKey
+		request            openapi.AuthApiKeyRequest
+		expectedStatus     int
+		expectedAuthorized bool
+		expectedMessage    string
+	}{
+		{
+			name:   "Valid key",
+			apiKey: validKey(),
+			request: openapi.AuthApiKeyRequest{
+				Secret:          "secret",
+				ActorExternalId: "actor1",
+				RequiredScopes:  []string{"read"},
+				RequiredRoles:   []string{"admin"},
+			},
+			expectedStatus:     http.StatusOK,
+			expectedAuthorized: true,
+			expectedMessage:    "authorized",
+		},
+		{
+			name:            "Missing key",
+			apiKey:          nil,
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+		{
+			name: "Deleted key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Deleted = true
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+		{
+			name: "Key from another service",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.ServiceID = "serv2"
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+		{
+			name: "Key from another org",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.OrgID = "org2"
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+		{
+			name:            "Invalid secret",
+			apiKey:          validKey(),
+			request:         openapi.AuthApiKeyRequest{Secret: "wrong"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+		{
+			name: "Expired key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Expiry = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "API key has expired",
+		},
+		{
+			name: "Swept key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Status = dal.APIKeyStatusExpired
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "API key has expired",
+		},
+		{
+			name: "Quarantined key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Status = dal.APIKeyStatusQuarantined
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "API key is quarantined",
+		},
+		{
+			name: "Unexpired key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Expiry = time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+				return key
+			}(),
+			request:            openapi.AuthApiKeyRequest{Secret: "secret"},
+			expectedStatus:     http.StatusOK,
+			expectedAuthorized: true,
+			expectedMessage:    "authorized",
+		},
+		{
+			name:            "Wrong actor",
+			apiKey:          validKey(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", ActorExternalId: "actor2"},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "API key does not belong to actor",
+		},
+		{
+			name:            "Missing scopes",
+			apiKey:          validKey(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", RequiredScopes: []string{"read", "delete", "admin"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "missing required scopes: delete, admin",
+		},
+		{
+			name: "Wildcard scope",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Scopes = []string{"billing:*"}
+				return key
+			}(),
+			request:            openapi.AuthApiKeyRequest{Secret: "secret", RequiredScopes: []string{"billing:invoices:read"}},
+			expectedStatus:     http.StatusOK,
+			expectedAuthorized: true,
+			expectedMessage:    "authorized",
+		},
+		{
+			name: "Wildcard scope outside its prefix",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Scopes = []string{"billing:*"}
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", RequiredScopes: []string{"billing", "billing-admin:read"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "missing required scopes: billing, billing-admin:read",
+		},
+		{
+			name:            "Missing roles",
+			apiKey:          validKey(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", RequiredRoles: []string{"owner"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "missing required roles: owner",
+		},
+		{
+			name: "Valid token",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Secret = tokenSecretHash
+				return key
+			}(),
+			request: openapi.AuthApiKeyRequest{
+				Token: apitoken.Token{Prefix: "lny", Environment: "live", KeyID: keyID, Secret: tokenSecret}.String(),
+			},
+			expectedStatus:     http.StatusOK,
+			expectedAuthorized: true,
+			expectedMessage:    "authorized",
+		},
+		{
+			name: "Allowed client",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.AllowedCIDRs = []string{"203.0.113.0/24"}
+				key.AllowedOrigins = []string{"https://*.example.com"}
+				return key
+			}(),
+			request:            openapi.AuthApiKeyRequest{Secret: "secret", ClientIp: "203.0.113.7", Origin: "https://app.example.com/login"},
+			expectedStatus:     http.StatusOK,
+			expectedAuthorized: true,
+			expectedMessage:    "authorized",
+		},
+		{
+			name: "Client IP address outside of allowed ranges",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.AllowedCIDRs = []string{"203.0.113.0/24"}
+				key.AllowedOrigins = []string{"https://*.example.com"}
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", ClientIp: "198.51.100.7", Origin: "https://app.example.com"},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "client IP address is not allowed",
+		},
+		{
+			name: "Missing client IP address",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.AllowedCIDRs = []string{"203.0.113.0/24"}
+				key.AllowedOrigins = []string{"https://*.example.com"}
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", Origin: "https://app.example.com"},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "client IP address is required",
+		},
+		{
+			name: "Disallowed origin",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.AllowedCIDRs = []string{"203.0.113.0/24"}
+				key.AllowedOrigins = []string{"https://*.example.com"}
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "secret", ClientIp: "203.0.113.7", Origin: "https://example.org"},
+			expectedStatus:  http.StatusForbidden,
+			expectedMessage: "origin is not allowed",
+		},
+		{
+			name: "Restrictions checked after the secret",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.AllowedCIDRs = []string{"203.0.113.0/24"}
+				key.AllowedOrigins = []string{"https://*.example.com"}
+				return key
+			}(),
+			request:         openapi.AuthApiKeyRequest{Secret: "wrong", ClientIp: "198.51.100.7"},
+			expectedStatus:  http.StatusUnauthorized,
+			expectedMessage: "invalid API key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+			service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+			// The caller is a service backend, which vouches for the address of its client
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			ctx = context.WithValue(ctx, "apiKeyID", "backend1")
+
+			mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{}, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, keyID).Return(tt.apiKey, nil)
+			mockActorClient.EXPECT().GetActor(ctx, "org1", serviceID, "actor1").Return(nil, nil).AnyTimes()
+
+			response, err := service.AuthApiKey(ctx, serviceID, keyID, tt.request)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, response.Code)
+			body, ok := response.Body.(openapi.AuthApiKey200Response)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expectedAuthorized, body.Authorized)
+			assert.Equal(t, tt.expectedMessage, body.Message)
+			assert.Zero(t, body.Remaining)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_AuthApiKey_ReportedClientIP(t *testing.T) {
+	secretHash, _ := utils.HashSecret("secret", "pepper")
+
+	tests := []struct {
+		name           string
+		callerKeyID    string
+		callerActorID  string
+		clientIP       string
+		reportedIP     string
+		expectedStatus int
+	}{
+		{name: "Reported by a service backend", callerKeyID: "backend1", clientIP: "198.51.100.7", reportedIP: "203.0.113.7", expectedStatus: http.StatusOK},
+		{name: "Spoofed by a service backend's own client", callerKeyID: "backend1", clientIP: "203.0.113.7", reportedIP: "198.51.100.7", expectedStatus: http.StatusForbidden},
+		{name: "Spoofed with the checked key", callerKeyID: "key1", clientIP: "198.51.100.7", reportedIP: "203.0.113.7", expectedStatus: http.StatusForbidden},
+		{name: "Spoofed with another key of an actor", callerKeyID: "key2", callerActorID: "actor1", clientIP: "198.51.100.7", reportedIP: "203.0.113.7", expectedStatus: http.StatusForbidden},
+		{name: "Client address of the request", callerKeyID: "key1", clientIP: "203.0.113.7", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+			service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			ctx = context.WithValue(ctx, "clientIP", tt.clientIP)
+			ctx = context.WithValue(ctx, "apiKeyID", tt.callerKeyID)
+			ctx = context.WithValue(ctx, "actorID", tt.callerActorID)
+
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&dal.APIKey{
+				APIKeyID:     "key1",
+				OrgID:        "org1",
+				ServiceID:    "serv1",
+				Secret:       secretHash,
+				AllowedCIDRs: []string{"203.0.113.0/24"},
+			}, nil)
+
+			// Only a backend authenticated with a key of its own, not bound to an actor, may report the client address
+			response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret", ClientIp: tt.reportedIP})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, response.Code)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_AuthApiKey_InvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	secret := strings.Repeat("s", apitoken.SecretLength)
+	valid := apitoken.Token{Prefix: "lny", Environment: "live", KeyID: "key1", Secret: secret}.String()
+
+	// Invalid tokens are rejected without looking up the service or the key
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Malformed token", "lny_live_key1_secret"},
+		{"Invalid checksum", strings.Replace(valid, secret, strings.Repeat("t", apitoken.SecretLength), 1)},
+		{"Token of another key", apitoken.Token{Prefix: "lny", Environment: "live", KeyID: "key2", Secret: secret}.String()},
+		{"Token of another environment", apitoken.Token{Prefix: "lny", Environment: "test", KeyID: "key1", Secret: secret}.String()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Token: tt.token})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusUnauthorized, response.Code)
+			assert.Equal(t, "invalid API key", response.Body.(openapi.AuthApiKey200Response).Message)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_AuthApiKey_ServiceDeleted(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+	assert.Equal(t, "invalid API key", response.Body.(openapi.AuthApiKey200Response).Message)
+}
+
+func TestAPIKeysAPIService_AuthApiKey_OtherService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	ctx = context.WithValue(ctx, "serviceID", "serv2")
+
+	response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestAPIKeysAPIService_AuthApiKey_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	secretHash, _ := utils.HashSecret("secret", "pepper")
+	apiKey := &dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Secret:    secretHash,
+		RateLimits: []dal.RateLimit{
+			{Name: "minute", Algorithm: "fixed_window", Scope: "key", Limit: 2, Window: "1m"},
+			{Name: "hour", Algorithm: "token_bucket", Scope: "service", Limit: 100, Window: "1h"},
+		},
+	}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(3)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(apiKey, nil).Times(3)
+
+	for i := 1; i >= 0; i-- {
+		response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		body := response.Body.(openapi.AuthApiKey200Response)
+		assert.True(t, body.Authorized)
+		assert.Equal(t, int32(i), body.Remaining)
+		assert.Equal(t, int32(2), body.RateLimit.Limit)
+		assert.Equal(t, int32(i), body.RateLimit.Remaining)
+		assert.Equal(t, int32(60), body.RateLimit.Reset)
+	}
+
+	response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, response.Code)
+	body := response.Body.(openapi.AuthApiKey200Response)
+	assert.False(t, body.Authorized)
+	assert.Equal(t, "rate limit exceeded: minute", body.Message)
+	assert.Equal(t, int32(0), body.RateLimit.Remaining)
+}
+
+func TestAPIKeysAPIService_AuthApiKey_MonthlyLimit(t *testing.T) {
+	tests := []struct {
+		name            string
+		tier            *dal.Tier
+		expectedStatus  int
+		expectedOverage bool
+		expectedMessage string
+	}{
+		{
+			name:            "Tier bills overage",
+			tier:            &dal.Tier{Name: "pro", DefaultRequestLimit: 100, OveragePrice: 0.01},
+			expectedStatus:  http.StatusOK,
+			expectedOverage: true,
+			expectedMessage: "authorized",
+		},
+		{
+			name:            "Tier without overage price",
+			tier:            &dal.Tier{Name: "pro", DefaultRequestLimit: 100},
+			expectedStatus:  http.StatusTooManyRequests,
+			expectedMessage: "monthly request limit exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockUsageClient := mocks.NewMockUsageManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			mockTierClient := mocks.NewMockTierManager(ctrl)
+			meter := usage.NewMeter(mockUsageClient, mockActorClient, mockTierClient, cache.NewNoopCache(), zap.NewNop())
+			service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			secretHash, _ := utils.HashSecret("secret", "pepper")
+			apiKey := &dal.APIKey{
+				APIKeyID:  "key1",
+				OrgID:     "org1",
+				ServiceID: "serv1",
+				ActorID:   "actor1",
+				Secret:    secretHash,
+			}
+			actor := &dal.Actor{ActorID: "actor1", BillingInfo: dal.BillingInfo{TierID: "pro"}}
+
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(apiKey, nil)
+			mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(actor, nil)
+			mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "pro").Return(tt.tier, nil)
+			mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", gomock.Any(), dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 100}, nil)
+
+			response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, response.Code)
+			body := response.Body.(openapi.AuthApiKey200Response)
+			assert.Equal(t, tt.expectedStatus == http.StatusOK, body.Authorized)
+			assert.Equal(t, tt.expectedOverage, body.Overage)
+			assert.Equal(t, tt.expectedMessage, body.Message)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_InvalidRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+
+	response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		RateLimits: []openapi.RateLimitInput{{Name: "minute", Limit: 10, Window: "1m", Algorithm: "leaky_bucket"}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		RateLimits: []openapi.RateLimitInput{
+			{Name: "minute", Limit: 10, Window: "1m"},
+			{Name: "minute", Limit: 20, Window: "1m"},
+		},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Scopes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+
+	// Scopes are stored in their canonical form without duplicates
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, apiKey *dal.APIKey) error {
+		assert.Equal(t, []string{"billing:invoices:read", "usage:*"}, apiKey.Scopes)
+		return nil
+	})
+
+	response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		Scopes: []string{"Billing:Invoices:Read", "usage:*", "billing:invoices:read"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		Scopes: []string{"billing:*:read"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Roles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).AnyTimes()
+
+	// Roles are stored trimmed and without duplicates
+	var created dal.APIKey
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, apiKey *dal.APIKey) error {
+		apiKey.APIKeyID = "key1"
+		created = *apiKey
+		return nil
+	})
+
+	response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{Roles: []string{" admin ", "billing", "admin"}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, response.Code)
+	generated := response.Body.(openapi.ApiKey)
+	assert.Equal(t, []string{"admin", "billing"}, created.Roles)
+	assert.Equal(t, []string{"admin", "billing"}, generated.Roles)
+
+	// The stored roles satisfy the roles required by the auth endpoint
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&created, nil).Times(2)
+
+	response, err = service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: generated.Secret, RequiredRoles: []string{"admin"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, response.Body.(openapi.AuthApiKey200Response).Authorized)
+
+	response, err = service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: generated.Secret, RequiredRoles: []string{"owner"}})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, response.Code)
+
+	// Blank roles are rejected
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{Roles: []string{" "}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Allowlist(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(3)
+
+	// Allowed CIDR ranges and origins are stored in their canonical form without duplicates
+	mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, apiKey *dal.APIKey) error {
+		apiKey.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		apiKey.UpdatedAt = apiKey.CreatedAt
+		assert.Equal(t, []string{"203.0.113.0/24", "2001:db8::1"}, apiKey.AllowedCIDRs)
+		assert.Equal(t, []string{"https://app.example.com", "http://localhost:3000"}, apiKey.AllowedOrigins)
+		return nil
+	})
+
+	response, err := service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		AllowedCidrs:   []string{"203.0.113.7/24", "2001:DB8::1", "203.0.113.0/24"},
+		AllowedOrigins: []string{"https://App.Example.com:443/", "http://localhost:3000"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, []string{"203.0.113.0/24", "2001:db8::1"}, response.Body.(openapi.ApiKey).AllowedCidrs)
+
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		AllowedCidrs: []string{"203.0.113.0/33"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	response, err = service.GenerateApiKey(ctx, "serv1", openapi.ApiKeyInput{
+		AllowedOrigins: []string{"https://app.example.com/login"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestAPIKeysAPIService_GenerateApiKey_Expiry(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name           string
+		maxKeyTTL      int64
+		input          openapi.ApiKeyInput
+		expectedStatus int
+		expectedExpiry time.Time
+	}{
+		{
+			name:           "No expiry",
+			input:          openapi.ApiKeyInput{},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Absolute expiry",
+			input:          openapi.ApiKeyInput{Expiry: now.Add(time.Hour)},
+			expectedStatus: http.StatusCreated,
+			expectedExpiry: now.Add(time.Hour),
+		},
+		{
+			name:           "TTL",
+			input:          openapi.ApiKeyInput{TtlSeconds: 3600},
+			expectedStatus: http.StatusCreated,
+			expectedExpiry: now.Add(time.Hour),
+		},
+		{
+			name:           "Expiry and TTL",
+			input:          openapi.ApiKeyInput{Expiry: now.Add(time.Hour), TtlSeconds: 3600},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Negative TTL",
+			input:          openapi.ApiKeyInput{TtlSeconds: -1},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Expiry in the past",
+			input:          openapi.ApiKeyInput{Expiry: now.Add(-time.Hour)},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Defaults to the maximum TTL of the service",
+			maxKeyTTL:      86400,
+			input:          openapi.ApiKeyInput{},
+			expectedStatus: http.StatusCreated,
+			expectedExpiry: now.Add(24 * time.Hour),
+		},
+		{
+			name:           "TTL within the maximum TTL of the service",
+			maxKeyTTL:      86400,
+			input:          openapi.ApiKeyInput{TtlSeconds: 3600},
+			expectedStatus: http.StatusCreated,
+			expectedExpiry: now.Add(time.Hour),
+		},
+		{
+			name:           "TTL beyond the maximum TTL of the service",
+			maxKeyTTL:      86400,
+			input:          openapi.ApiKeyInput{TtlSeconds: 86401},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Expiry beyond the maximum TTL of the service",
+			maxKeyTTL:      86400,
+			input:          openapi.ApiKeyInput{Expiry: now.Add(48 * time.Hour)},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+			service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{MaxKeyTTLSeconds: tt.maxKeyTTL}, nil)
+
+			var stored dal.APIKey
+			if tt.expectedStatus == http.StatusCreated {
+				mockAPIKeyClient.EXPECT().CreateAPIKey(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, apiKey *dal.APIKey) error {
+					stored = *apiKey
+					return nil
+				})
+			}
+
+			response, err := service.GenerateApiKey(ctx, "serv1", tt.input)
+			assert.Equal(t, tt.expectedStatus, response.Code)
+			if tt.expectedStatus != http.StatusCreated {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if tt.expectedExpiry.IsZero() {
+				assert.Empty(t, stored.Expiry)
+				return
+			}
+
+			expiry, err := utils.ParseTimestamp(stored.Expiry)
+			assert.NoError(t, err)
+			assert.WithinDuration(t, tt.expectedExpiry, expiry, 5*time.Second)
+
+			apiKey := response.Body.(openapi.ApiKey)
+			assert.Equal(t, expiry, apiKey.Expiry)
+			assert.Equal(t, dal.APIKeyStatusActive, apiKey.Status)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_UpdateApiKey_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Expiry:    time.Now().Add(-time.Hour).UTC().Format(time.RFC3339),
+	}, nil)
+
+	// Expired keys cannot be brought back by extending their expiry
+	response, err := service.UpdateApiKey(ctx, "serv1", "key1", "", openapi.ApiKeyInput{TtlSeconds: 3600})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestAPIKeysAPIService_UpdateApiKey_Quarantined(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Status:    dal.APIKeyStatusQuarantined,
+	}, nil)
+
+	// Quarantined keys cannot be changed, only deleted
+	response, err := service.UpdateApiKey(ctx, "serv1", "key1", "", openapi.ApiKeyInput{})
+	assert.EqualError(t, err, "API key is quarantined")
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestAPIKeysAPIService_GetApiKey_Expired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	expiry := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Status:    dal.APIKeyStatusActive,
+		Expiry:    expiry.Format(time.RFC3339),
+	}, nil)
+
+	// Keys are reported as expired before the sweeper transitions them
+	response, err := service.GetApiKey(ctx, "serv1", "key1")
+	assert.NoError(t, err)
+	apiKey := response.Body.(openapi.ApiKey)
+	assert.Equal(t, dal.APIKeyStatusExpired, apiKey.Status)
+	assert.Equal(t, expiry, apiKey.Expiry)
+}
+
+func TestAPIKeysAPIService_RotateApiKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	oldHash, _ := utils.HashSecret("old", "pepper")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(&dal.APIKey{
+		APIKeyID:  "key1",
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		Secret:    oldHash,
+	}, nil)
+
+	var newHash, previousSecretExpiry string
+	mockAPIKeyClient.EXPECT().RotateAPIKeySecret(ctx, "key1", oldHash, gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, _, secret, expiry string) (bool, error) {
+			newHash, previousSecretExpiry = secret, expiry
+			return true, nil
+		})
+
+	response, err := service.RotateApiKey(ctx, "serv1", "key1", openapi.RotateApiKeyRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	apiKey := response.Body.(openapi.ApiKey)
+
+	// Only the hash of the new secret is stored, and the previous secret stays valid for the default grace period
+	assert.NotEmpty(t, apiKey.Secret)
+	assert.True(t, utils.VerifySecretHash(apiKey.Secret, newHash, "pepper"))
+	expiry, err := utils.ParseTimestamp(previousSecretExpiry)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiry, 5*time.Second)
+	assert.Equal(t, expiry, apiKey.PreviousSecretExpiry)
+}
+
+func TestAPIKeysAPIService_RotateApiKey_Rejected(t *testing.T) {
+	oldHash, _ := utils.HashSecret("old", "pepper")
+	validKey := func() *dal.APIKey {
+		return &dal.APIKey{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv1", Secret: oldHash}
+	}
+
+	tests := []struct {
+		name           string
+		apiKey         *dal.APIKey
+		request        openapi.RotateApiKeyRequest
+		rotated        bool
+		expectedStatus int
+	}{
+		{
+			name:           "Missing key",
+			apiKey:         nil,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "Key from another service",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.ServiceID = "serv2"
+				return key
+			}(),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Negative grace period",
+			apiKey:         validKey(),
+			request:        openapi.RotateApiKeyRequest{GracePeriodSeconds: -1},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Grace period beyond the maximum",
+			apiKey:         validKey(),
+			request:        openapi.RotateApiKeyRequest{GracePeriodSeconds: int64(8 * 24 * time.Hour / time.Second)},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Expired key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Status = dal.APIKeyStatusExpired
+				return key
+			}(),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "Quarantined key",
+			apiKey: func() *dal.APIKey {
+				key := validKey()
+				key.Status = dal.APIKeyStatusQuarantined
+				return key
+			}(),
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "Concurrent rotation",
+			apiKey:         validKey(),
+			rotated:        false,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+			service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(tt.apiKey, nil)
+			if tt.expectedStatus == http.StatusConflict && tt.apiKey.Status != dal.APIKeyStatusExpired && tt.apiKey.Status != dal.APIKeyStatusQuarantined {
+				mockAPIKeyClient.EXPECT().RotateAPIKeySecret(ctx, "key1", oldHash, gomock.Any(), gomock.Any()).Return(tt.rotated, nil)
+			}
+
+			response, err := service.RotateApiKey(ctx, "serv1", "key1", tt.request)
+			assert.Error(t, err)
+			assert.Equal(t, tt.expectedStatus, response.Code)
+		})
+	}
+}
+
+func TestAPIKeysAPIService_AuthApiKey_PreviousSecret(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, nil, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	currentHash, _ := utils.HashSecret("current", "pepper")
+	previousHash, _ := utils.HashSecret("previous", "pepper")
+	apiKey := &dal.APIKey{
+		APIKeyID:             "key1",
+		OrgID:                "org1",
+		ServiceID:            "serv1",
+		Secret:               currentHash,
+		PreviousSecret:       previousHash,
+		PreviousSecretExpiry: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+	}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(apiKey, nil).Times(2)
+	mockActorClient.EXPECT().GetActor(gomock.Any(), "org1", "serv1", "").Return(nil, nil).AnyTimes()
+
+	// Both secrets are accepted during the grace period, and the response reports which one was used
+	for _, secretVersion := range []string{"current", "previous"} {
+		response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: secretVersion})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.Code)
+		body := response.Body.(openapi.AuthApiKey200Response)
+		assert.True(t, body.Authorized)
+		assert.Equal(t, secretVersion, body.SecretVersion)
+	}
+}
+
+func TestAPIKeysAPIService_AuthApiKey_BlockedIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+
+	mockBlockedIPClient.EXPECT().ListBlockedIPs(gomock.Any()).Return([]dal.BlockedIP{
+		{ServiceID: "serv1", IPAddress: "203.0.113.0/24", Reason: "Abuse"},
+		{ServiceID: "serv1", IPAddress: "198.51.100.7", Reason: "Lifted", Expiry: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)},
+	}, nil)
+	blocklist := ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop())
+	assert.NoError(t, blocklist.Refresh(context.Background()))
+
+	service := service.NewAPIKeysAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockActorClient, ratelimit.NewMemoryLimiter(), meter, blocklist, zap.NewNop())
+
+	hash, _ := utils.HashSecret("secret", "pepper")
+	apiKey := &dal.APIKey{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv1", Secret: hash}
+	mockServiceClient.EXPECT().GetService(gomock.Any(), "org1", "serv1").Return(&dal.Service{}, nil).AnyTimes()
+	mockAPIKeyClient.EXPECT().GetAPIKey(gomock.Any(), "key1").Return(apiKey, nil).AnyTimes()
+	mockActorClient.EXPECT().GetActor(gomock.Any(), "org1", "serv1", "").Return(nil, nil).AnyTimes()
+
+	tests := []struct {
+		name         string
+		clientIP     string
+		expectedCode int
+	}{
+		{"Blocked IP address", "203.0.113.7", http.StatusForbidden},
+		{"IP address whose block has expired", "198.51.100.7", http.StatusOK},
+		{"Allowed IP address", "192.0.2.1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			ctx = context.WithValue(ctx, "clientIP", tt.clientIP)
+
+			response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, response.Code)
+			if tt.expectedCode == http.StatusForbidden {
+				assert.Equal(t, "IP address is blocked", response.Body.(openapi.AuthApiKey200Response).Message)
+			}
+		})
+	}
+	// A service backend reports the address of the client it forwards, which is the address that is blocked
+	reportedTests := []struct {
+		name         string
+		clientIP     string
+		reportedIP   string
+		expectedCode int
+	}{
+		{"Blocked reported client", "192.0.2.1", "203.0.113.7", http.StatusForbidden},
+		{"Allowed reported client of a blocked backend", "203.0.113.7", "192.0.2.1", http.StatusOK},
+	}
+
+	for _, tt := range reportedTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			ctx = context.WithValue(ctx, "clientIP", tt.clientIP)
+			ctx = context.WithValue(ctx, "apiKeyID", "backend1")
+
+			response, err := service.AuthApiKey(ctx, "serv1", "key1", openapi.AuthApiKeyRequest{Secret: "secret", ClientIp: tt.reportedIP})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, response.Code)
+			if tt.expectedCode == http.StatusForbidden {
+				assert.Equal(t, "IP address is blocked", response.Body.(openapi.AuthApiKey200Response).Message)
+			}
+		})
+	}
 }
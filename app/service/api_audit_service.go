@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// AuditAPIService is a service that implements the logic for the AuditAPIServicer
+// This service should implement the business logic for every endpoint for the AuditAPI API.
+type AuditAPIService struct {
+	auditClient dal.AuditEventManager
+	logger      *zap.Logger
+}
+
+// NewAuditAPIService creates a default app service
+func NewAuditAPIService(auditClient dal.AuditEventManager, logger *zap.Logger) openapi.AuditAPIServicer {
+	return &AuditAPIService{
+		auditClient: auditClient,
+		logger:      logger,
+	}
+}
+
+// ListAuditEvents - List the audit events of the organization
+func (s *AuditAPIService) ListAuditEvents(ctx context.Context, actorId string, action string, targetType string, targetId string, since string, until string, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	sinceTime, err := utils.ParseTimestamp(since)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("since must be an RFC 3339 timestamp")
+	}
+	untilTime, err := utils.ParseTimestamp(until)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("until must be an RFC 3339 timestamp")
+	}
+	if !sinceTime.IsZero() && !untilTime.IsZero() && !sinceTime.Before(untilTime) {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("since must be before until")
+	}
+
+	events, nextCursor, err := s.auditClient.ListAuditEvents(ctx, orgID, dal.AuditEventFilter{
+		ActorID:    actorId,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+		Since:      sinceTime,
+		Until:      untilTime,
+	}, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list audit events",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	list := openapi.AuditEventList{
+		Events:     make([]openapi.AuditEvent, len(events)),
+		NextCursor: nextCursor,
+	}
+	for i, event := range events {
+		list.Events[i], err = toAuditEvent(&event)
+		if err != nil {
+			s.logger.Error("failed to convert audit event",
+				zap.String("requestID", requestID),
+				zap.String("eventID", event.EventID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	return openapi.Response(http.StatusOK, list), nil
+}
+
+// toAuditEvent converts a stored audit event into its API representation.
+func toAuditEvent(event *dal.AuditEvent) (openapi.AuditEvent, error) {
+	createdAt, err := utils.ParseTimestamp(event.CreatedAt)
+	if err != nil {
+		return openapi.AuditEvent{}, err
+	}
+
+	before, err := decodeAuditFields(event.Before)
+	if err != nil {
+		return openapi.AuditEvent{}, err
+	}
+	after, err := decodeAuditFields(event.After)
+	if err != nil {
+		return openapi.AuditEvent{}, err
+	}
+
+	return openapi.AuditEvent{
+		Id:         event.EventID,
+		ActorType:  event.ActorType,
+		ActorId:    event.ActorID,
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetId:   event.TargetID,
+		Before:     before,
+		After:      after,
+		RequestId:  event.RequestID,
+		IpAddress:  event.IPAddress,
+		CreatedAt:  createdAt,
+	}, nil
+}
+
+// decodeAuditFields decodes the fields recorded in the before or after state of an audit event.
+func decodeAuditFields(encoded string) (map[string]interface{}, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(encoded), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
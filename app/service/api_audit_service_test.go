@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestAuditAPIService_ListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuditClient := mocks.NewMockAuditEventManager(ctrl)
+	service := service.NewAuditAPIService(mockAuditClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	since := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	mockAuditClient.EXPECT().
+		ListAuditEvents(ctx, "org1", dal.AuditEventFilter{
+			TargetType: dal.AuditTargetAPIKey,
+			Since:      since,
+		}, dal.Page{Cursor: "cursor1", Limit: 10}).
+		Return([]dal.AuditEvent{{
+			EventID:    "event1",
+			ActorType:  dal.AuditActorUser,
+			ActorID:    "user1",
+			Action:     "api_key.rotated",
+			TargetType: dal.AuditTargetAPIKey,
+			TargetID:   "key1",
+			Before:     `{"secret":"[redacted]"}`,
+			After:      `{"previousSecretExpiry":"2024-06-02T12:00:00Z","secret":"[redacted]"}`,
+			RequestID:  "req-1",
+			IPAddress:  "203.0.113.7",
+			CreatedAt:  "2024-06-01T12:00:00.000000000Z",
+		}}, "cursor2", nil)
+
+	response, err := service.ListAuditEvents(ctx, "", "", dal.AuditTargetAPIKey, "", "2024-06-01T00:00:00Z", "", "cursor1", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+
+	list := response.Body.(openapi.AuditEventList)
+	assert.Equal(t, "cursor2", list.NextCursor)
+	if assert.Len(t, list.Events, 1) {
+		event := list.Events[0]
+		assert.Equal(t, "event1", event.Id)
+		assert.Equal(t, "user1", event.ActorId)
+		assert.Equal(t, "api_key.rotated", event.Action)
+		assert.Equal(t, map[string]interface{}{"secret": "[redacted]"}, event.Before)
+		assert.Equal(t, "2024-06-02T12:00:00Z", event.After["previousSecretExpiry"])
+		assert.Equal(t, "203.0.113.7", event.IpAddress)
+		assert.True(t, time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC).Equal(event.CreatedAt))
+	}
+}
+
+func TestAuditAPIService_ListAuditEvents_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		since  string
+		until  string
+		cursor string
+		limit  int32
+		err    error
+	}{
+		{name: "Limit too large", limit: dal.MaxPageLimit + 1},
+		{name: "Malformed since", since: "yesterday"},
+		{name: "Malformed until", until: "2024-06-01"},
+		{name: "Empty range", since: "2024-06-02T00:00:00Z", until: "2024-06-01T00:00:00Z"},
+		{name: "Invalid cursor", cursor: "cursor1", err: dal.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAuditClient := mocks.NewMockAuditEventManager(ctrl)
+			service := service.NewAuditAPIService(mockAuditClient, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			if tt.err != nil {
+				mockAuditClient.EXPECT().ListAuditEvents(ctx, "org1", gomock.Any(), gomock.Any()).Return(nil, "", tt.err)
+			}
+
+			response, err := service.ListAuditEvents(ctx, "", "", "", "", tt.since, tt.until, tt.cursor, tt.limit)
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
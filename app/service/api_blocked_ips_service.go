@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/ipblock"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// MaxBlockReasonLength is the maximum length of the reason of a blocked IP.
+const MaxBlockReasonLength = 256
+
+// BlockedIPsAPIService is a service that implements the logic for the BlockedIPsAPIServicer
+// This service should implement the business logic for every endpoint for the BlockedIPsAPI API.
+type BlockedIPsAPIService struct {
+	blockedIPClient dal.BlockedIPManager
+	serviceClient   dal.ServiceManager
+	blocklist       *ipblock.Matcher
+	logger          *zap.Logger
+}
+
+// NewBlockedIPsAPIService creates a default app service. Changes to the blocked IPs of a service are applied to the
+// blocklist right away, and reach the blocklists of other instances when they are next refreshed.
+func NewBlockedIPsAPIService(blockedIPClient dal.BlockedIPManager, serviceClient dal.ServiceManager, blocklist *ipblock.Matcher, logger *zap.Logger) openapi.BlockedIPsAPIServicer {
+	return &BlockedIPsAPIService{
+		blockedIPClient: blockedIPClient,
+		serviceClient:   serviceClient,
+		blocklist:       blocklist,
+		logger:          logger,
+	}
+}
+
+// BlockIp - Block an IP address or CIDR range
+func (s *BlockedIPsAPIService) BlockIp(ctx context.Context, serviceId string, blockedIpAddressInput openapi.BlockedIpAddressInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	ipAddress, err := ipblock.Normalize(blockedIpAddressInput.IpAddress)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	expiry, err := validateBlock(blockedIpAddressInput.Reason, blockedIpAddressInput.Expiry, time.Now())
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	blockedIP := &dal.BlockedIP{
+		ServiceID: serviceId,
+		IPAddress: ipAddress,
+		Reason:    blockedIpAddressInput.Reason,
+		Expiry:    expiry,
+	}
+
+	created, err := s.blockedIPClient.CreateBlockedIP(ctx, blockedIP)
+	if err != nil {
+		s.logger.Error("failed to create blocked IP",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if !created {
+		return openapi.Response(http.StatusConflict, nil), fmt.Errorf("IP address '%s' is already blocked", ipAddress)
+	}
+
+	s.reloadBlocklist(ctx, requestID, serviceId)
+
+	response, err := toBlockedIPAddress(blockedIP)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusCreated, response), nil
+}
+
+// GetBlockedIp - Retrieve a blocked IP address or CIDR range
+func (s *BlockedIPsAPIService) GetBlockedIp(ctx context.Context, serviceId string, ipAddress string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	normalized, err := ipblock.Normalize(ipAddress)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	blockedIP, err := s.blockedIPClient.GetBlockedIP(ctx, serviceId, normalized)
+	if err != nil {
+		s.logger.Error("failed to get blocked IP",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if blockedIP == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("blocked IP not found")
+	}
+
+	response, err := toBlockedIPAddress(blockedIP)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusOK, response), nil
+}
+
+// ListBlockedIps - List the blocked IP addresses and CIDR ranges of a service
+func (s *BlockedIPsAPIService) ListBlockedIps(ctx context.Context, serviceId string, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	blockedIPs, nextCursor, err := s.blockedIPClient.ListBlockedIPsByService(ctx, serviceId, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list blocked IPs",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	responses := make([]openapi.BlockedIpAddress, 0, len(blockedIPs))
+	for _, blockedIP := range blockedIPs {
+		response, err := toBlockedIPAddress(&blockedIP)
+		if err != nil {
+			s.logger.Error("failed to parse timestamp",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		responses = append(responses, response)
+	}
+
+	return openapi.Response(http.StatusOK, openapi.BlockedIpAddressList{BlockedIpAddresses: responses, NextCursor: nextCursor}), nil
+}
+
+// UnblockIp - Unblock an IP address or CIDR range
+func (s *BlockedIPsAPIService) UnblockIp(ctx context.Context, serviceId string, ipAddress string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	normalized, err := ipblock.Normalize(ipAddress)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	deleted, err := s.blockedIPClient.DeleteBlockedIP(ctx, serviceId, normalized)
+	if err != nil {
+		s.logger.Error("failed to delete blocked IP",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if !deleted {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("blocked IP not found")
+	}
+
+	s.reloadBlocklist(ctx, requestID, serviceId)
+
+	return openapi.Response(http.StatusNoContent, nil), nil
+}
+
+// UpdateBlockedIp - Update the reason or expiry of a blocked IP address or CIDR range
+func (s *BlockedIPsAPIService) UpdateBlockedIp(ctx context.Context, serviceId string, ipAddress string, blockedIpAddressUpdate openapi.BlockedIpAddressUpdate) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	normalized, err := ipblock.Normalize(ipAddress)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	expiry, err := validateBlock(blockedIpAddressUpdate.Reason, blockedIpAddressUpdate.Expiry, time.Now())
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	blockedIP, err := s.blockedIPClient.GetBlockedIP(ctx, serviceId, normalized)
+	if err != nil {
+		s.logger.Error("failed to get blocked IP",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if blockedIP == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("blocked IP not found")
+	}
+
+	blockedIP.Reason = blockedIpAddressUpdate.Reason
+	blockedIP.Expiry = expiry
+
+	updated, err := s.blockedIPClient.UpdateBlockedIP(ctx, blockedIP)
+	if err != nil {
+		s.logger.Error("failed to update blocked IP",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if !updated {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("blocked IP not found")
+	}
+
+	s.reloadBlocklist(ctx, requestID, serviceId)
+
+	response, err := toBlockedIPAddress(blockedIP)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusOK, response), nil
+}
+
+// reloadBlocklist applies changes to the blocked IPs of a service to the blocklist. Failures are only logged, since
+// the change is stored and the blocklist picks it up when it is next refreshed.
+func (s *BlockedIPsAPIService) reloadBlocklist(ctx context.Context, requestID, serviceID string) {
+	if err := s.blocklist.Reload(ctx, serviceID); err != nil {
+		s.logger.Error("failed to reload blocked IPs",
+			zap.String("requestID", requestID),
+			zap.String("serviceID", serviceID),
+			zap.Error(err),
+		)
+	}
+}
+
+// validateBlock validates the reason and expiry of a blocked IP and returns the expiry to store.
+func validateBlock(reason string, expiry time.Time, now time.Time) (string, error) {
+	if len(reason) > MaxBlockReasonLength {
+		return "", fmt.Errorf("reason must not be longer than %d characters", MaxBlockReasonLength)
+	}
+
+	if expiry.IsZero() {
+		return "", nil
+	}
+	if !expiry.After(now) {
+		return "", errors.New("expiry must be in the future")
+	}
+
+	return expiry.UTC().Format(time.RFC3339), nil
+}
+
+// toBlockedIPAddress converts a stored blocked IP into its API representation.
+func toBlockedIPAddress(blockedIP *dal.BlockedIP) (openapi.BlockedIpAddress, error) {
+	createdAt, err := utils.ParseTimestamp(blockedIP.CreatedAt)
+	if err != nil {
+		return openapi.BlockedIpAddress{}, err
+	}
+
+	updatedAt, err := utils.ParseTimestamp(blockedIP.UpdatedAt)
+	if err != nil {
+		return openapi.BlockedIpAddress{}, err
+	}
+
+	expiry, err := utils.ParseTimestamp(blockedIP.Expiry)
+	if err != nil {
+		return openapi.BlockedIpAddress{}, err
+	}
+
+	return openapi.BlockedIpAddress{
+		IpAddress: blockedIP.IPAddress,
+		Reason:    blockedIP.Reason,
+		Expiry:    expiry,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}, nil
+}
@@ -0,0 +1,252 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/ipblock"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestBlockedIPsAPIService_BlockIp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	blocklist := ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop())
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, blocklist, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	var stored dal.BlockedIP
+	mockBlockedIPClient.EXPECT().CreateBlockedIP(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, blockedIP *dal.BlockedIP) (bool, error) {
+		blockedIP.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		blockedIP.UpdatedAt = blockedIP.CreatedAt
+		stored = *blockedIP
+		return true, nil
+	})
+
+	// The blocklist is reloaded right away, so that the block applies to the next request
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(ctx, "serv1", gomock.Any()).DoAndReturn(func(ctx context.Context, serviceID string, page dal.Page) ([]dal.BlockedIP, string, error) {
+		return []dal.BlockedIP{stored}, "", nil
+	})
+
+	response, err := service.BlockIp(ctx, "serv1", openapi.BlockedIpAddressInput{
+		IpAddress: "203.0.113.7/24",
+		Reason:    "Credential stuffing",
+		Expiry:    expiry,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	body := response.Body.(openapi.BlockedIpAddress)
+	assert.Equal(t, "203.0.113.0/24", body.IpAddress)
+	assert.Equal(t, "Credential stuffing", body.Reason)
+	assert.True(t, expiry.Equal(body.Expiry))
+	assert.Equal(t, "serv1", stored.ServiceID)
+
+	block := blocklist.Match("serv1", "203.0.113.42", time.Now())
+	if assert.NotNil(t, block) {
+		assert.Equal(t, "Credential stuffing", block.Reason)
+	}
+}
+
+func TestBlockedIPsAPIService_BlockIp_Rejected(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        openapi.BlockedIpAddressInput
+		created      bool
+		expectedCode int
+	}{
+		{
+			name:         "Malformed IP address",
+			input:        openapi.BlockedIpAddressInput{IpAddress: "203.0.113"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Expiry in the past",
+			input:        openapi.BlockedIpAddressInput{IpAddress: "203.0.113.7", Expiry: time.Now().Add(-time.Minute)},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Already blocked",
+			input:        openapi.BlockedIpAddressInput{IpAddress: "203.0.113.7"},
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+			if tt.expectedCode == http.StatusConflict {
+				mockBlockedIPClient.EXPECT().CreateBlockedIP(ctx, gomock.Any()).Return(false, nil)
+			}
+
+			response, err := service.BlockIp(ctx, "serv1", tt.input)
+			assert.Error(t, err)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
+
+func TestBlockedIPsAPIService_ListBlockedIps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(ctx, "serv1", dal.Page{Cursor: "cursor1", Limit: 2}).Return([]dal.BlockedIP{
+		{ServiceID: "serv1", IPAddress: "203.0.113.7", CreatedAt: now, UpdatedAt: now},
+		{ServiceID: "serv1", IPAddress: "2001:db8::/32", CreatedAt: now, UpdatedAt: now},
+	}, "cursor2", nil)
+
+	response, err := service.ListBlockedIps(ctx, "serv1", "cursor1", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	body := response.Body.(openapi.BlockedIpAddressList)
+	assert.Equal(t, 2, len(body.BlockedIpAddresses))
+	assert.Equal(t, "2001:db8::/32", body.BlockedIpAddresses[1].IpAddress)
+	assert.Equal(t, "cursor2", body.NextCursor)
+}
+
+func TestBlockedIPsAPIService_ListBlockedIps_InvalidPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	response, err := service.ListBlockedIps(ctx, "serv1", "", dal.MaxPageLimit+1)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(ctx, "serv1", gomock.Any()).Return(nil, "", dal.ErrInvalidCursor)
+
+	response, err = service.ListBlockedIps(ctx, "serv1", "forged", 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestBlockedIPsAPIService_GetBlockedIp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	// IP addresses are looked up in their canonical form
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+	mockBlockedIPClient.EXPECT().GetBlockedIP(ctx, "serv1", "203.0.113.0/24").Return(&dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.0/24", CreatedAt: now, UpdatedAt: now}, nil)
+	mockBlockedIPClient.EXPECT().GetBlockedIP(ctx, "serv1", "198.51.100.7").Return(nil, nil)
+
+	response, err := service.GetBlockedIp(ctx, "serv1", "203.0.113.7/24")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "203.0.113.0/24", response.Body.(openapi.BlockedIpAddress).IpAddress)
+
+	response, err = service.GetBlockedIp(ctx, "serv1", "198.51.100.7")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestBlockedIPsAPIService_UpdateBlockedIp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	now := time.Now().UTC().Format(time.RFC3339)
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockBlockedIPClient.EXPECT().GetBlockedIP(ctx, "serv1", "203.0.113.7").Return(&dal.BlockedIP{ServiceID: "serv1", IPAddress: "203.0.113.7", Reason: "Abuse", CreatedAt: now, UpdatedAt: now}, nil)
+	mockBlockedIPClient.EXPECT().UpdateBlockedIP(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, blockedIP *dal.BlockedIP) (bool, error) {
+		assert.Equal(t, "Abuse, lifted tomorrow", blockedIP.Reason)
+		assert.Equal(t, expiry.Format(time.RFC3339), blockedIP.Expiry)
+		return true, nil
+	})
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(ctx, "serv1", gomock.Any()).Return(nil, "", nil)
+
+	response, err := service.UpdateBlockedIp(ctx, "serv1", "203.0.113.7", openapi.BlockedIpAddressUpdate{
+		Reason: "Abuse, lifted tomorrow",
+		Expiry: expiry,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.True(t, expiry.Equal(response.Body.(openapi.BlockedIpAddress).Expiry))
+}
+
+func TestBlockedIPsAPIService_UnblockIp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+	mockBlockedIPClient.EXPECT().DeleteBlockedIP(ctx, "serv1", "203.0.113.7").Return(true, nil)
+	mockBlockedIPClient.EXPECT().DeleteBlockedIP(ctx, "serv1", "198.51.100.7").Return(false, nil)
+	mockBlockedIPClient.EXPECT().ListBlockedIPsByService(ctx, "serv1", gomock.Any()).Return(nil, "", nil)
+
+	response, err := service.UnblockIp(ctx, "serv1", "203.0.113.7")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	response, err = service.UnblockIp(ctx, "serv1", "198.51.100.7")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestBlockedIPsAPIService_ServiceNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBlockedIPClient := mocks.NewMockBlockedIPManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewBlockedIPsAPIService(mockBlockedIPClient, mockServiceClient, ipblock.NewMatcher(mockBlockedIPClient, zap.NewNop()), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.ListBlockedIps(ctx, "serv1", "", 0)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
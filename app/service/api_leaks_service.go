@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/events"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// MaxLeakedTokens is the maximum number of tokens a single leak report may carry.
+const MaxLeakedTokens = 100
+
+const (
+	// leakStatusInactive is the status of reported tokens whose key was already deleted, expired or quarantined.
+	leakStatusInactive = "inactive"
+	// leakStatusInvalid is the status of reported tokens that are malformed, from another deployment, or whose secret
+	// does not match their key.
+	leakStatusInvalid = "invalid"
+)
+
+// LeaksAPIService is a service that implements the logic for the LeaksAPIServicer
+// This service should implement the business logic for every endpoint for the LeaksAPI API.
+type LeaksAPIService struct {
+	apiKeyClient      dal.APIKeyManager
+	serviceClient     dal.ServiceManager
+	leakReportClient  dal.LeakReportManager
+	transactionClient dal.TransactionManager
+	publisher         events.Publisher
+	verifier          *auth.SecretVerifier
+	tokenEnvironment  string
+	logger            *zap.Logger
+}
+
+// NewLeaksAPIService creates a default app service. The owners of leaked keys are notified through the publisher.
+func NewLeaksAPIService(cfg *config.Config, apiKeyClient dal.APIKeyManager, serviceClient dal.ServiceManager, leakReportClient dal.LeakReportManager, transactionClient dal.TransactionManager, publisher events.Publisher, logger *zap.Logger) openapi.LeaksAPIServicer {
+	return &LeaksAPIService{
+		apiKeyClient:      apiKeyClient,
+		serviceClient:     serviceClient,
+		leakReportClient:  leakReportClient,
+		transactionClient: transactionClient,
+		publisher:         publisher,
+		verifier:          auth.NewSecretVerifier(cfg, logger, apiKeyClient),
+		tokenEnvironment:  cfg.APIKeys.TokenEnvironment,
+		logger:            logger,
+	}
+}
+
+// ReportLeaks - Report leaked API key tokens
+func (s *LeaksAPIService) ReportLeaks(ctx context.Context, leakReportInput openapi.LeakReportInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	partnerID, ok := ctx.Value("partnerID").(string)
+	if !ok || partnerID == "" {
+		s.logger.Error("partnerID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusUnauthorized, nil), errors.New("partner not authenticated")
+	}
+
+	if len(leakReportInput.Tokens) > MaxLeakedTokens {
+		return openapi.Response(http.StatusBadRequest, nil), fmt.Errorf("no more than %d tokens may be reported at once", MaxLeakedTokens)
+	}
+
+	// Every action is idempotent, so a failed report can be retried in full
+	results := make([]openapi.LeakReportResult, len(leakReportInput.Tokens))
+	for i, leaked := range leakReportInput.Tokens {
+		result, err := s.reportLeak(ctx, partnerID, leaked)
+		if err != nil {
+			s.logger.Error("failed to report leaked token",
+				zap.String("requestID", requestID),
+				zap.String("partnerID", partnerID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		results[i] = result
+	}
+
+	return openapi.Response(http.StatusOK, openapi.LeakReport{Results: results}), nil
+}
+
+// reportLeak revokes or quarantines the key of a leaked token according to the policy of its service, records the
+// report in the same transaction and notifies the owner of the key. Tokens that were already reported keep the outcome of their first report.
+func (s *LeaksAPIService) reportLeak(ctx context.Context, partnerID string, leaked openapi.LeakedToken) (openapi.LeakReportResult, error) {
+	requestID := middleware.GetReqID(ctx)
+
+	// Malformed tokens and tokens of other deployments are rejected before any lookup
+	token, err := apitoken.Parse(leaked.Token)
+	if err != nil || token.Environment != s.tokenEnvironment {
+		return openapi.LeakReportResult{Status: leakStatusInvalid}, nil
+	}
+
+	fingerprint := fingerprintToken(leaked.Token)
+	report, err := s.leakReportClient.GetLeakReport(ctx, fingerprint)
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+	if report != nil {
+		return toLeakReportResult(report)
+	}
+
+	apiKey, err := s.apiKeyClient.GetAPIKey(ctx, token.KeyID)
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+	if apiKey == nil || apiKey.Deleted {
+		return openapi.LeakReportResult{Status: leakStatusInactive}, nil
+	}
+
+	// Only the holder of the secret can report a key, so that key IDs alone cannot be used to revoke keys
+	if _, ok := s.verifier.Verify(ctx, apiKey, token.Secret); !ok {
+		return openapi.LeakReportResult{Status: leakStatusInvalid}, nil
+	}
+
+	expired, err := apiKey.Expired(time.Now())
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+	if expired || apiKey.Status == dal.APIKeyStatusQuarantined {
+		return openapi.LeakReportResult{Status: leakStatusInactive}, nil
+	}
+
+	service, err := s.serviceClient.GetService(ctx, apiKey.OrgID, apiKey.ServiceID)
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+
+	action := dal.LeakActionRevoked
+	if service != nil && service.LeakPolicy == dal.LeakPolicyQuarantine {
+		action = dal.LeakActionQuarantined
+	}
+
+	report = &dal.LeakReport{
+		Fingerprint: fingerprint,
+		OrgID:       apiKey.OrgID,
+		ServiceID:   apiKey.ServiceID,
+		APIKeyID:    apiKey.APIKeyID,
+		PartnerID:   partnerID,
+		URL:         leaked.Url,
+		Action:      action,
+	}
+
+	// The report is committed along with the action on the key, so that a report is never recorded for a key that is
+	// still live, and a key is never revoked without the report that a retry would find
+	unit := dal.NewUnitOfWork()
+	if err := s.leakReportClient.StageCreateLeakReport(ctx, unit, report); err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+	switch action {
+	case dal.LeakActionQuarantined:
+		err = s.apiKeyClient.StageQuarantineAPIKey(ctx, unit, apiKey.APIKeyID)
+	default:
+		err = s.apiKeyClient.StageDeleteAPIKey(ctx, unit, apiKey.OrgID, apiKey.ServiceID, apiKey.APIKeyID, apiKey.Version)
+	}
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+
+	err = s.transactionClient.Commit(ctx, unit)
+	if errors.Is(err, dal.ErrLeakReported) {
+		// The same token was reported concurrently, and its owner was notified by that report
+		existing, err := s.leakReportClient.GetLeakReport(ctx, fingerprint)
+		if err != nil {
+			return openapi.LeakReportResult{}, err
+		}
+		if existing != nil {
+			return toLeakReportResult(existing)
+		}
+		return toLeakReportResult(report)
+	}
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+
+	s.logger.Warn("leaked API key reported",
+		zap.String("requestID", requestID),
+		zap.String("partnerID", partnerID),
+		zap.String("orgID", apiKey.OrgID),
+		zap.String("serviceID", apiKey.ServiceID),
+		zap.String("keyID", apiKey.APIKeyID),
+		zap.String("url", leaked.Url),
+		zap.String("action", action),
+	)
+
+	// The key was already revoked or quarantined, so a failed notification must not fail the report
+	err = s.publisher.Publish(ctx, events.Event{
+		Type:       events.APIKeyLeaked,
+		OrgID:      apiKey.OrgID,
+		ServiceID:  apiKey.ServiceID,
+		APIKeyID:   apiKey.APIKeyID,
+		Action:     action,
+		OccurredAt: time.Now(),
+	})
+	if err != nil {
+		s.logger.Error("failed to publish event",
+			zap.String("requestID", requestID),
+			zap.String("keyID", apiKey.APIKeyID),
+			zap.Error(err),
+		)
+	}
+
+	return toLeakReportResult(report)
+}
+
+// fingerprintToken returns the hex encoded SHA-256 of a token, which identifies its reports without storing it.
+func fingerprintToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// toLeakReportResult converts a stored leak report into the API representation of its outcome.
+func toLeakReportResult(report *dal.LeakReport) (openapi.LeakReportResult, error) {
+	reportedAt, err := utils.ParseTimestamp(report.CreatedAt)
+	if err != nil {
+		return openapi.LeakReportResult{}, err
+	}
+
+	return openapi.LeakReportResult{
+		Status:     report.Action,
+		ReportedAt: reportedAt,
+	}, nil
+}
@@ -0,0 +1,319 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/events"
+	eventmocks "github.com/payloadops/lanyard/app/events/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/payloadops/lanyard/app/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+// leakedToken returns a token for the given key and secret of the test deployment.
+func leakedToken(keyID, secret string) string {
+	return apitoken.Token{Prefix: "lny", Environment: "live", KeyID: keyID, Secret: secret}.String()
+}
+
+// liveKey returns a live API key with the given secret.
+func liveKey(t *testing.T, secret string) *dal.APIKey {
+	hash, err := utils.HashSecret(secret, "pepper")
+	require.NoError(t, err)
+
+	return &dal.APIKey{
+		OrgID:     "org1",
+		ServiceID: "serv1",
+		APIKeyID:  "key1",
+		Secret:    hash,
+		Status:    dal.APIKeyStatusActive,
+	}
+}
+
+func TestLeaksAPIService_ReportLeaks(t *testing.T) {
+	secret := strings.Repeat("s", apitoken.SecretLength)
+	token := leakedToken("key1", secret)
+
+	tests := []struct {
+		name           string
+		leakPolicy     string
+		expectedAction string
+	}{
+		{"Service without a policy", "", dal.LeakActionRevoked},
+		{"Service that revokes leaked keys", dal.LeakPolicyRevoke, dal.LeakActionRevoked},
+		{"Service that quarantines leaked keys", dal.LeakPolicyQuarantine, dal.LeakActionQuarantined},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockLeakReportClient := mocks.NewMockLeakReportManager(ctrl)
+			mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+			mockPublisher := eventmocks.NewMockPublisher(ctrl)
+			service := service.NewLeaksAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockLeakReportClient, mockTransactionClient, mockPublisher, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+			mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(nil, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(liveKey(t, secret), nil)
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", LeakPolicy: tt.leakPolicy}, nil)
+			// The report is committed in the same unit of work as the action on the key
+			var staged *dal.UnitOfWork
+			if tt.expectedAction == dal.LeakActionQuarantined {
+				mockAPIKeyClient.EXPECT().StageQuarantineAPIKey(ctx, gomock.Any(), "key1").DoAndReturn(func(ctx context.Context, unit *dal.UnitOfWork, apiKeyID string) error {
+					assert.Same(t, staged, unit)
+					return nil
+				})
+			} else {
+				mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).DoAndReturn(func(ctx context.Context, unit *dal.UnitOfWork, orgID, serviceID, apiKeyID string, version int64) error {
+					assert.Same(t, staged, unit)
+					return nil
+				})
+			}
+			mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, unit *dal.UnitOfWork) error {
+				assert.Same(t, staged, unit)
+				return nil
+			})
+			mockLeakReportClient.EXPECT().StageCreateLeakReport(ctx, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, unit *dal.UnitOfWork, report *dal.LeakReport) error {
+				staged = unit
+				// The token itself is never stored
+				assert.Len(t, report.Fingerprint, 64)
+				assert.NotContains(t, report.Fingerprint, secret)
+				assert.Equal(t, "org1", report.OrgID)
+				assert.Equal(t, "serv1", report.ServiceID)
+				assert.Equal(t, "key1", report.APIKeyID)
+				assert.Equal(t, "scanner", report.PartnerID)
+				assert.Equal(t, "https://example.com/commit/1", report.URL)
+				assert.Equal(t, tt.expectedAction, report.Action)
+				report.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+				return nil
+			})
+			mockPublisher.EXPECT().Publish(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, event events.Event) error {
+				assert.Equal(t, events.APIKeyLeaked, event.Type)
+				assert.Equal(t, "org1", event.OrgID)
+				assert.Equal(t, "serv1", event.ServiceID)
+				assert.Equal(t, "key1", event.APIKeyID)
+				assert.Equal(t, tt.expectedAction, event.Action)
+				return nil
+			})
+
+			response, err := service.ReportLeaks(ctx, openapi.LeakReportInput{Tokens: []openapi.LeakedToken{{Token: token, Url: "https://example.com/commit/1"}}})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.Code)
+			results := response.Body.(openapi.LeakReport).Results
+			require.Len(t, results, 1)
+			assert.Equal(t, tt.expectedAction, results[0].Status)
+			assert.False(t, results[0].ReportedAt.IsZero())
+		})
+	}
+}
+
+func TestLeaksAPIService_ReportLeaks_Rereported(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockLeakReportClient := mocks.NewMockLeakReportManager(ctrl)
+	mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+	mockPublisher := eventmocks.NewMockPublisher(ctrl)
+	service := service.NewLeaksAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockLeakReportClient, mockTransactionClient, mockPublisher, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+	secret := strings.Repeat("s", apitoken.SecretLength)
+	input := openapi.LeakReportInput{Tokens: []openapi.LeakedToken{{Token: leakedToken("key1", secret)}}}
+
+	// The first report revokes the key and records the report
+	var recorded *dal.LeakReport
+	gomock.InOrder(
+		mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(nil, nil),
+		mockLeakReportClient.EXPECT().StageCreateLeakReport(ctx, gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, unit *dal.UnitOfWork, report *dal.LeakReport) error {
+			report.CreatedAt = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			recorded = report
+			return nil
+		}),
+	)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(liveKey(t, secret), nil)
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil).Times(1)
+	mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(nil).Times(1)
+	mockPublisher.EXPECT().Publish(ctx, gomock.Any()).Return(nil).Times(1)
+
+	first, err := service.ReportLeaks(ctx, input)
+	require.NoError(t, err)
+
+	// Reporting the token again finds the recorded report, and neither revokes the key nor notifies its owner again
+	mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, fingerprint string) (*dal.LeakReport, error) {
+		assert.Equal(t, recorded.Fingerprint, fingerprint)
+		return recorded, nil
+	})
+
+	second, err := service.ReportLeaks(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, first.Body, second.Body)
+	assert.Equal(t, dal.LeakActionRevoked, second.Body.(openapi.LeakReport).Results[0].Status)
+}
+
+func TestLeaksAPIService_ReportLeaks_ConcurrentReport(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockLeakReportClient := mocks.NewMockLeakReportManager(ctrl)
+	mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+	mockPublisher := eventmocks.NewMockPublisher(ctrl)
+	service := service.NewLeaksAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockLeakReportClient, mockTransactionClient, mockPublisher, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+	secret := strings.Repeat("s", apitoken.SecretLength)
+	reportedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	existing := &dal.LeakReport{APIKeyID: "key1", PartnerID: "other", Action: dal.LeakActionRevoked, CreatedAt: reportedAt.Format(time.RFC3339)}
+
+	gomock.InOrder(
+		mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(nil, nil),
+		mockLeakReportClient.EXPECT().StageCreateLeakReport(ctx, gomock.Any(), gomock.Any()).Return(nil),
+		mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(existing, nil),
+	)
+	mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(liveKey(t, secret), nil)
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil)
+	mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(dal.ErrLeakReported)
+
+	// The owner was notified by the report that won the race
+	response, err := service.ReportLeaks(ctx, openapi.LeakReportInput{Tokens: []openapi.LeakedToken{{Token: leakedToken("key1", secret)}}})
+	assert.NoError(t, err)
+	result := response.Body.(openapi.LeakReport).Results[0]
+	assert.Equal(t, dal.LeakActionRevoked, result.Status)
+	assert.Equal(t, reportedAt, result.ReportedAt)
+}
+
+func TestLeaksAPIService_ReportLeaks_NotLive(t *testing.T) {
+	secret := strings.Repeat("s", apitoken.SecretLength)
+	valid := leakedToken("key1", secret)
+
+	tests := []struct {
+		name           string
+		token          string
+		apiKey         *dal.APIKey
+		lookup         bool
+		expectedStatus string
+	}{
+		{"Malformed token", "not-a-token", nil, false, "invalid"},
+		{"Invalid checksum", strings.Replace(valid, "_key1_", "_key2_", 1), nil, false, "invalid"},
+		{"Token of another deployment", apitoken.Token{Prefix: "lny", Environment: "test", KeyID: "key1", Secret: secret}.String(), nil, false, "invalid"},
+		{"Unknown key", valid, nil, true, "inactive"},
+		{"Wrong secret", valid, liveKey(t, strings.Repeat("t", apitoken.SecretLength)), true, "invalid"},
+		{"Deleted key", valid, func() *dal.APIKey {
+			key := liveKey(t, secret)
+			key.Deleted = true
+			return key
+		}(), true, "inactive"},
+		{"Expired key", valid, func() *dal.APIKey {
+			key := liveKey(t, secret)
+			key.Expiry = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+			return key
+		}(), true, "inactive"},
+		{"Quarantined key", valid, func() *dal.APIKey {
+			key := liveKey(t, secret)
+			key.Status = dal.APIKeyStatusQuarantined
+			return key
+		}(), true, "inactive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockLeakReportClient := mocks.NewMockLeakReportManager(ctrl)
+			mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+			mockPublisher := eventmocks.NewMockPublisher(ctrl)
+			service := service.NewLeaksAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockLeakReportClient, mockTransactionClient, mockPublisher, zap.NewNop())
+
+			// Tokens that fail to parse are rejected before any lookup, and nothing is revoked or recorded
+			ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+			if tt.lookup {
+				mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(nil, nil)
+				mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(tt.apiKey, nil)
+			}
+
+			response, err := service.ReportLeaks(ctx, openapi.LeakReportInput{Tokens: []openapi.LeakedToken{{Token: tt.token}}})
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.Code)
+			result := response.Body.(openapi.LeakReport).Results[0]
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.True(t, result.ReportedAt.IsZero())
+		})
+	}
+}
+
+func TestLeaksAPIService_ReportLeaks_TooManyTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokens := make([]openapi.LeakedToken, service.MaxLeakedTokens+1)
+	service := service.NewLeaksAPIService(testConfig, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockLeakReportManager(ctrl), mocks.NewMockTransactionManager(ctrl), eventmocks.NewMockPublisher(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+	response, err := service.ReportLeaks(ctx, openapi.LeakReportInput{Tokens: tokens})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestLeaksAPIService_ReportLeaks_Error(t *testing.T) {
+	tests := []struct {
+		name         string
+		commitErr    error
+		expectedCode int
+	}{
+		{"Report write failed", errors.New("db error"), http.StatusInternalServerError},
+		{"Key changed since it was read", &dal.ConflictError{Entity: "API key", ID: "key1"}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockLeakReportClient := mocks.NewMockLeakReportManager(ctrl)
+			mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+			mockPublisher := eventmocks.NewMockPublisher(ctrl)
+			service := service.NewLeaksAPIService(testConfig, mockAPIKeyClient, mockServiceClient, mockLeakReportClient, mockTransactionClient, mockPublisher, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "partnerID", "scanner")
+			secret := strings.Repeat("s", apitoken.SecretLength)
+			mockLeakReportClient.EXPECT().GetLeakReport(ctx, gomock.Any()).Return(nil, nil)
+			mockAPIKeyClient.EXPECT().GetAPIKey(ctx, "key1").Return(liveKey(t, secret), nil)
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+			mockLeakReportClient.EXPECT().StageCreateLeakReport(ctx, gomock.Any(), gomock.Any()).Return(nil)
+			mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil)
+			mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(tt.commitErr)
+
+			// Neither the report nor the revocation is committed, so the key is still live when the report is retried,
+			// and its owner is not notified
+			response, err := service.ReportLeaks(ctx, openapi.LeakReportInput{Tokens: []openapi.LeakedToken{{Token: leakedToken("key1", secret)}}})
+			assert.Equal(t, tt.commitErr, err)
+			assert.Equal(t, tt.expectedCode, response.Code)
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/config"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"go.uber.org/zap"
+)
+
+// OrganizationsAPIService is a service that implements the logic for the OrganizationsAPIServicer
+// This service should implement the business logic for every endpoint for the OrganizationsAPI API.
+type OrganizationsAPIService struct {
+	cfg               *config.Config
+	orgClient         dal.OrgManager
+	serviceClient     dal.ServiceManager
+	actorClient       dal.ActorManager
+	apiKeyClient      dal.APIKeyManager
+	transactionClient dal.TransactionManager
+	logger            *zap.Logger
+}
+
+// NewOrganizationsAPIService creates a default app service
+func NewOrganizationsAPIService(cfg *config.Config, orgClient dal.OrgManager, serviceClient dal.ServiceManager, actorClient dal.ActorManager, apiKeyClient dal.APIKeyManager, transactionClient dal.TransactionManager, logger *zap.Logger) openapi.OrganizationsAPIServicer {
+	return &OrganizationsAPIService{
+		cfg:               cfg,
+		orgClient:         orgClient,
+		serviceClient:     serviceClient,
+		actorClient:       actorClient,
+		apiKeyClient:      apiKeyClient,
+		transactionClient: transactionClient,
+		logger:            logger,
+	}
+}
+
+// OrganizationsOrganizationIdDelete - Remove an organization
+func (s *OrganizationsAPIService) OrganizationsOrganizationIdDelete(ctx context.Context, organizationId string, ifMatch string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	org, code, err := s.getCallerOrg(ctx, organizationId)
+	if err != nil {
+		return openapi.Response(code, nil), err
+	}
+	if !matchesETag(ifMatch, org.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	// Remove everything the organization owns before the organization itself, so that a failed delete can be retried
+	err = s.deleteServices(ctx, org.OrgID)
+	if err != nil {
+		s.logger.Error("failed to delete organization services",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	err = s.orgClient.DeleteOrg(ctx, org.OrgID, org.Version)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to delete organization",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusNoContent, nil), nil
+}
+
+// OrganizationsOrganizationIdGet - Get the organization
+func (s *OrganizationsAPIService) OrganizationsOrganizationIdGet(ctx context.Context, organizationId string) (openapi.ImplResponse, error) {
+	org, code, err := s.getCallerOrg(ctx, organizationId)
+	if err != nil {
+		return openapi.Response(code, nil), err
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(org.Version), toAPIOrganization(org)), nil
+}
+
+// OrganizationsOrganizationIdPut - Update an organization
+func (s *OrganizationsAPIService) OrganizationsOrganizationIdPut(ctx context.Context, organizationId string, ifMatch string, organizationInput openapi.OrganizationInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	err := validateOrganizationInput(organizationInput)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	org, code, err := s.getCallerOrg(ctx, organizationId)
+	if err != nil {
+		return openapi.Response(code, nil), err
+	}
+	if !matchesETag(ifMatch, org.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	org.Name = organizationInput.Name
+	org.Domain = normalizeDomain(organizationInput.Domain)
+	org.StripeAccountId = organizationInput.StripeAccountId
+
+	err = s.orgClient.UpdateOrg(ctx, org)
+	if err != nil {
+		if errors.Is(err, dal.ErrDomainTaken) {
+			return openapi.Response(http.StatusConflict, nil), fmt.Errorf("domain '%s' is already in use", org.Domain)
+		}
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+
+		s.logger.Error("failed to update organization",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(org.Version), toAPIOrganization(org)), nil
+}
+
+// OrganizationsPost - Creates an organization
+func (s *OrganizationsAPIService) OrganizationsPost(ctx context.Context, organizationInput openapi.OrganizationInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	userID, ok := ctx.Value("userID").(string)
+	if !ok || userID == "" {
+		s.logger.Error("userID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusUnauthorized, nil), errors.New("unauthorized")
+	}
+
+	err := validateOrganizationInput(organizationInput)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	org := &dal.Org{
+		Name:            organizationInput.Name,
+		Domain:          normalizeDomain(organizationInput.Domain),
+		StripeAccountId: organizationInput.StripeAccountId,
+		OwnerID:         userID,
+	}
+
+	err = s.orgClient.CreateOrg(ctx, org)
+	if err != nil {
+		if errors.Is(err, dal.ErrDomainTaken) {
+			return openapi.Response(http.StatusConflict, nil), fmt.Errorf("domain '%s' is already in use", org.Domain)
+		}
+
+		s.logger.Error("failed to create organization",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	// Give the owner a session for the new organization, since their current token is not scoped to it
+	sessionToken, err := auth.NewSessionToken(s.cfg, org.OrgID, userID, auth.RoleOwner)
+	if err != nil {
+		s.logger.Error("failed to create session token",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response := toAPIOrganization(org)
+	response.SessionToken = sessionToken
+	return openapi.ResponseWithHeaders(http.StatusCreated, etagHeaders(org.Version), response), nil
+}
+
+// getCallerOrg retrieves an organization that the caller is scoped to. Other organizations are reported as not found
+// so that their existence is not revealed. The returned status code describes the failure when an error is returned.
+func (s *OrganizationsAPIService) getCallerOrg(ctx context.Context, organizationID string) (*dal.Org, int, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return nil, http.StatusNotFound, errors.New("org not found")
+	}
+
+	if organizationID != orgID {
+		return nil, http.StatusNotFound, errors.New("organization not found")
+	}
+
+	org, err := s.orgClient.GetOrg(ctx, orgID)
+	if err != nil {
+		s.logger.Error("failed to get organization",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return nil, openapi.ErrorStatus(err), err
+	}
+	if org == nil {
+		return nil, http.StatusNotFound, errors.New("organization not found")
+	}
+
+	return org, http.StatusOK, nil
+}
+
+// deleteServices removes every service of an organization along with its API keys and actors. Each service is marked
+// as deleting before its API keys and actors are listed, and their deletes are then committed as one unit of work,
+// with the API keys first and the service last, so that a service is only removed once everything it owns is. A unit
+// of work that spans several transactions and fails part way returns a *dal.PartialCommitError, and the delete can be
+// retried to remove the rest.
+func (s *OrganizationsAPIService) deleteServices(ctx context.Context, orgID string) error {
+	services, err := dal.ListAll(func(page dal.Page) ([]dal.Service, string, error) {
+		return s.serviceClient.ListServicesByOrganization(ctx, orgID, page)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		// No API key or actor can be created in the service once it is deleting, so none is left out of its delete
+		version := service.Version
+		if !service.Deleting() {
+			err = s.serviceClient.MarkServiceDeleting(ctx, orgID, service.ServiceID, version)
+			if err != nil {
+				return fmt.Errorf("failed to mark service '%s' as deleting: %w", service.ServiceID, err)
+			}
+			version++
+		}
+
+		apiKeys, err := dal.ListAll(func(page dal.Page) ([]dal.APIKey, string, error) {
+			return s.apiKeyClient.ListAPIKeysByService(ctx, orgID, service.ServiceID, page)
+		})
+		if err != nil {
+			return err
+		}
+
+		actors, err := dal.ListAll(func(page dal.Page) ([]dal.Actor, string, error) {
+			return s.actorClient.ListActors(ctx, orgID, service.ServiceID, page)
+		})
+		if err != nil {
+			return err
+		}
+
+		unit := dal.NewUnitOfWork()
+		for _, apiKey := range apiKeys {
+			err = s.apiKeyClient.StageDeleteAPIKey(ctx, unit, orgID, service.ServiceID, apiKey.APIKeyID, apiKey.Version)
+			if err != nil {
+				return fmt.Errorf("failed to stage API key '%s' delete: %w", apiKey.APIKeyID, err)
+			}
+		}
+
+		for _, actor := range actors {
+			err = s.actorClient.StageDeleteActor(ctx, unit, orgID, service.ServiceID, actor.ExternalID, actor.Version)
+			if err != nil {
+				return fmt.Errorf("failed to stage actor '%s' delete: %w", actor.ExternalID, err)
+			}
+		}
+
+		err = s.serviceClient.StageDeleteService(ctx, unit, orgID, service.ServiceID, version)
+		if err != nil {
+			return fmt.Errorf("failed to stage service '%s' delete: %w", service.ServiceID, err)
+		}
+
+		err = s.transactionClient.Commit(ctx, unit)
+		if err != nil {
+			return fmt.Errorf("failed to delete service '%s': %w", service.ServiceID, err)
+		}
+	}
+
+	return nil
+}
+
+// normalizeDomain trims and lowercases a domain so that equal domains are stored the same way.
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+// validateOrganizationInput checks that an organization has a name and a plausible domain.
+func validateOrganizationInput(organizationInput openapi.OrganizationInput) error {
+	if strings.TrimSpace(organizationInput.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	domain := normalizeDomain(organizationInput.Domain)
+	if domain != "" && (!strings.Contains(domain, ".") || strings.ContainsAny(domain, " /:@")) {
+		return fmt.Errorf("invalid domain '%s'", organizationInput.Domain)
+	}
+
+	return nil
+}
+
+// toAPIOrganization converts a stored organization into its API representation.
+func toAPIOrganization(org *dal.Org) openapi.Organization {
+	return openapi.Organization{
+		Id:              org.OrgID,
+		Name:            org.Name,
+		Domain:          org.Domain,
+		StripeAccountId: org.StripeAccountId,
+		OwnerId:         org.OwnerID,
+	}
+}
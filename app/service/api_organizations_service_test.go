@@ -0,0 +1,256 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/payloadops/lanyard/app/auth"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestOrganizationsAPIService_CreateOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	// The caller does not need to be scoped to an organization yet
+	ctx := context.WithValue(context.Background(), "userID", "user1")
+
+	mockOrgClient.EXPECT().CreateOrg(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, org *dal.Org) error {
+		assert.Equal(t, "Acme", org.Name)
+		assert.Equal(t, "acme.com", org.Domain)
+		assert.Equal(t, "user1", org.OwnerID)
+		org.OrgID = "org1"
+		return nil
+	})
+
+	response, err := service.OrganizationsPost(ctx, openapi.OrganizationInput{Name: "Acme", Domain: " Acme.com "})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	created, ok := response.Body.(openapi.Organization)
+	assert.True(t, ok)
+	assert.Equal(t, "org1", created.Id)
+	assert.Equal(t, "user1", created.OwnerId)
+
+	// The session token makes the creator the owner of the new organization
+	claims := &auth.Claims{}
+	_, err = jwt.ParseWithClaims(created.SessionToken, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(testConfig.JWTSecret), nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "org1", claims.OrgID)
+	assert.Equal(t, "user1", claims.Subject)
+	assert.Equal(t, string(auth.RoleOwner), claims.Role)
+}
+
+func TestOrganizationsAPIService_CreateOrganization_Unauthenticated(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewOrganizationsAPIService(testConfig, mocks.NewMockOrgManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	response, err := service.OrganizationsPost(context.Background(), openapi.OrganizationInput{Name: "Acme"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestOrganizationsAPIService_CreateOrganization_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewOrganizationsAPIService(testConfig, mocks.NewMockOrgManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "userID", "user1")
+
+	tests := []struct {
+		name              string
+		organizationInput openapi.OrganizationInput
+	}{
+		{name: "Missing name", organizationInput: openapi.OrganizationInput{Domain: "acme.com"}},
+		{name: "Domain without dot", organizationInput: openapi.OrganizationInput{Name: "Acme", Domain: "acme"}},
+		{name: "Domain with scheme", organizationInput: openapi.OrganizationInput{Name: "Acme", Domain: "https://acme.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.OrganizationsPost(ctx, tt.organizationInput)
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
+
+func TestOrganizationsAPIService_CreateOrganization_DomainTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "userID", "user1")
+
+	mockOrgClient.EXPECT().CreateOrg(ctx, gomock.Any()).Return(dal.ErrDomainTaken)
+
+	response, err := service.OrganizationsPost(ctx, openapi.OrganizationInput{Name: "Acme", Domain: "acme.com"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestOrganizationsAPIService_DeleteOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mockServiceClient, mockActorClient, mockAPIKeyClient, mockTransactionClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// Each service is marked as deleting before it is removed with its API keys and actors in one unit of work. The
+	// second service was left deleting by a delete that failed part way
+	services := []dal.Service{{ServiceID: "serv1"}, {ServiceID: "serv2", Status: dal.ServiceStatusDeleting, Version: 3}}
+	gomock.InOrder(
+		mockOrgClient.EXPECT().GetOrg(ctx, "org1").Return(&dal.Org{OrgID: "org1"}, nil),
+		mockServiceClient.EXPECT().ListServicesByOrganization(ctx, "org1", gomock.Any()).Return(services, "", nil),
+		mockServiceClient.EXPECT().MarkServiceDeleting(ctx, "org1", "serv1", int64(0)).Return(nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", "serv1", gomock.Any()).Return([]dal.APIKey{{APIKeyID: "key1"}}, "", nil),
+		mockActorClient.EXPECT().ListActors(ctx, "org1", "serv1", gomock.Any()).Return([]dal.Actor{{ExternalID: "actor1"}}, "", nil),
+		mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil),
+		mockActorClient.EXPECT().StageDeleteActor(ctx, gomock.Any(), "org1", "serv1", "actor1", int64(0)).Return(nil),
+		mockServiceClient.EXPECT().StageDeleteService(ctx, gomock.Any(), "org1", "serv1", int64(1)).Return(nil),
+		mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", "serv2", gomock.Any()).Return(nil, "", nil),
+		mockActorClient.EXPECT().ListActors(ctx, "org1", "serv2", gomock.Any()).Return(nil, "", nil),
+		mockServiceClient.EXPECT().StageDeleteService(ctx, gomock.Any(), "org1", "serv2", int64(3)).Return(nil),
+		mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(nil),
+		mockOrgClient.EXPECT().DeleteOrg(ctx, "org1", int64(0)).Return(nil),
+	)
+
+	response, err := service.OrganizationsOrganizationIdDelete(ctx, "org1", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+}
+
+func TestOrganizationsAPIService_DeleteOrganization_CascadeFailed(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "Commit failed", err: errors.New("dynamodb error")},
+		{name: "Partial commit", err: &dal.PartialCommitError{Committed: 1, Total: 2, Err: errors.New("dynamodb error")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOrgClient := mocks.NewMockOrgManager(ctrl)
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+			service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mockServiceClient, mockActorClient, mockAPIKeyClient, mockTransactionClient, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+			// The organization and the remaining services are left in place when a service cannot be removed
+			mockOrgClient.EXPECT().GetOrg(ctx, "org1").Return(&dal.Org{OrgID: "org1"}, nil)
+			mockServiceClient.EXPECT().ListServicesByOrganization(ctx, "org1", gomock.Any()).Return([]dal.Service{{ServiceID: "serv1"}, {ServiceID: "serv2"}}, "", nil)
+			mockServiceClient.EXPECT().MarkServiceDeleting(ctx, "org1", "serv1", int64(0)).Return(nil)
+			mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", "serv1", gomock.Any()).Return([]dal.APIKey{{APIKeyID: "key1"}}, "", nil)
+			mockActorClient.EXPECT().ListActors(ctx, "org1", "serv1", gomock.Any()).Return(nil, "", nil)
+			mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", "serv1", "key1", int64(0)).Return(nil)
+			mockServiceClient.EXPECT().StageDeleteService(ctx, gomock.Any(), "org1", "serv1", int64(1)).Return(nil)
+			mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(tt.err)
+
+			response, err := service.OrganizationsOrganizationIdDelete(ctx, "org1", "")
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, http.StatusInternalServerError, response.Code)
+		})
+	}
+}
+
+func TestOrganizationsAPIService_GetOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockOrgClient.EXPECT().GetOrg(ctx, "org1").Return(&dal.Org{OrgID: "org1", Name: "Acme", Domain: "acme.com", OwnerID: "user1"}, nil)
+
+	response, err := service.OrganizationsOrganizationIdGet(ctx, "org1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, openapi.Organization{Id: "org1", Name: "Acme", Domain: "acme.com", OwnerId: "user1"}, response.Body)
+}
+
+func TestOrganizationsAPIService_GetOrganization_OtherOrg(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewOrganizationsAPIService(testConfig, mocks.NewMockOrgManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	response, err := service.OrganizationsOrganizationIdGet(ctx, "org2")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestOrganizationsAPIService_UpdateOrganization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockOrgClient.EXPECT().GetOrg(ctx, "org1").Return(&dal.Org{OrgID: "org1", Name: "Acme", Domain: "acme.com", OwnerID: "user1"}, nil)
+	mockOrgClient.EXPECT().UpdateOrg(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, org *dal.Org) error {
+		assert.Equal(t, "org1", org.OrgID)
+		assert.Equal(t, "Acme Inc", org.Name)
+		assert.Equal(t, "acme.io", org.Domain)
+		assert.Equal(t, "user1", org.OwnerID)
+		return nil
+	})
+
+	response, err := service.OrganizationsOrganizationIdPut(ctx, "org1", "", openapi.OrganizationInput{Name: "Acme Inc", Domain: "acme.io"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestOrganizationsAPIService_UpdateOrganization_DomainTaken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOrgClient := mocks.NewMockOrgManager(ctrl)
+	service := service.NewOrganizationsAPIService(testConfig, mockOrgClient, mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockOrgClient.EXPECT().GetOrg(ctx, "org1").Return(&dal.Org{OrgID: "org1", Name: "Acme"}, nil)
+	mockOrgClient.EXPECT().UpdateOrg(ctx, gomock.Any()).Return(dal.ErrDomainTaken)
+
+	response, err := service.OrganizationsOrganizationIdPut(ctx, "org1", "", openapi.OrganizationInput{Name: "Acme", Domain: "acme.io"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
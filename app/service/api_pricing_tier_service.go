@@ -0,0 +1,462 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"go.uber.org/zap"
+)
+
+// PricingTierAPIService is a service that implements the logic for the PricingTierAPIServicer
+// This service should implement the business logic for every endpoint for the PricingTierAPI API.
+type PricingTierAPIService struct {
+	tierClient    dal.TierManager
+	serviceClient dal.ServiceManager
+	actorClient   dal.ActorManager
+	logger        *zap.Logger
+}
+
+// NewPricingTierAPIService creates a default app service
+func NewPricingTierAPIService(tierClient dal.TierManager, serviceClient dal.ServiceManager, actorClient dal.ActorManager, logger *zap.Logger) openapi.PricingTierAPIServicer {
+	return &PricingTierAPIService{
+		tierClient:    tierClient,
+		serviceClient: serviceClient,
+		actorClient:   actorClient,
+		logger:        logger,
+	}
+}
+
+// ServicesServiceIdPricingTiersGet - Retrieve the pricing tiers of a service
+func (s *PricingTierAPIService) ServicesServiceIdPricingTiersGet(ctx context.Context, serviceId string, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	tiers, nextCursor, err := s.tierClient.ListTiers(ctx, orgID, serviceId, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list pricing tiers",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	responses := make([]openapi.PricingTier, len(tiers))
+	for i, tier := range tiers {
+		responses[i] = toAPIPricingTier(&tier)
+	}
+
+	return openapi.Response(http.StatusOK, openapi.PricingTierList{PricingTiers: responses, NextCursor: nextCursor}), nil
+}
+
+// ServicesServiceIdPricingTiersPost - Add a pricing tier to a service
+func (s *PricingTierAPIService) ServicesServiceIdPricingTiersPost(ctx context.Context, serviceId string, pricingTierInput openapi.PricingTierInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	err := validatePricingTierInput(pricingTierInput)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	taken, err := s.isTierNameTaken(ctx, orgID, serviceId, "", pricingTierInput.Name)
+	if err != nil {
+		s.logger.Error("failed to list pricing tiers",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if taken {
+		return openapi.Response(http.StatusConflict, nil), fmt.Errorf("pricing tier '%s' already exists", pricingTierInput.Name)
+	}
+
+	tier := &dal.Tier{
+		Name:                pricingTierInput.Name,
+		DefaultRequestLimit: int(pricingTierInput.DefaultMonthlyRequestLimit),
+		OveragePrice:        pricingTierInput.OveragePrice,
+	}
+
+	err = s.tierClient.CreateTier(ctx, orgID, serviceId, tier)
+	if err != nil {
+		s.logger.Error("failed to create pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusCreated, etagHeaders(tier.Version), toAPIPricingTier(tier)), nil
+}
+
+// ServicesServiceIdPricingTiersTierIdDelete - Remove a pricing tier from a service
+func (s *PricingTierAPIService) ServicesServiceIdPricingTiersTierIdDelete(ctx context.Context, serviceId string, tierId string, reassignTo string, ifMatch string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	if reassignTo == tierId {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("cannot reassign actors to the pricing tier being removed")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	tier, err := s.tierClient.GetTier(ctx, orgID, serviceId, tierId)
+	if err != nil {
+		s.logger.Error("failed to get pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if tier == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("pricing tier not found")
+	}
+	if !matchesETag(ifMatch, tier.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	// A retiring tier keeps the tier it retires to, so that a failed removal is finished by retrying it
+	if tier.Retiring() {
+		if reassignTo != "" && reassignTo != tier.ReassignTo {
+			return openapi.Response(http.StatusConflict, nil), fmt.Errorf("pricing tier is retiring to '%s'", tier.ReassignTo)
+		}
+		reassignTo = tier.ReassignTo
+	}
+
+	page := dal.Page{Limit: dal.MaxReassignedActors}
+	actors, cursor, err := s.actorClient.ListActorsByTier(ctx, orgID, serviceId, tierId, page)
+	if err != nil {
+		s.logger.Error("failed to list actors",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	version := tier.Version
+	if len(actors) > 0 && !tier.Retiring() {
+		if reassignTo == "" {
+			return openapi.Response(http.StatusConflict, nil), errors.New("pricing tier is assigned to actors")
+		}
+
+		target, err := s.tierClient.GetTier(ctx, orgID, serviceId, reassignTo)
+		if err != nil {
+			s.logger.Error("failed to get pricing tier",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		if target == nil || target.Retiring() {
+			return openapi.Response(http.StatusBadRequest, nil), fmt.Errorf("pricing tier '%s' not found or retiring", reassignTo)
+		}
+
+		// Once the tier is retiring no actor can be assigned to it, so the actors listed from then on are all of them
+		err = s.tierClient.RetireTier(ctx, orgID, serviceId, tierId, version, reassignTo)
+		if err != nil {
+			if isConflict(err) {
+				return conflictResponse(ifMatch, err)
+			}
+			if errors.Is(err, dal.ErrConflict) {
+				return openapi.Response(http.StatusBadRequest, nil), err
+			}
+			s.logger.Error("failed to retire pricing tier",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		version++
+
+		actors, cursor, err = s.actorClient.ListActorsByTier(ctx, orgID, serviceId, tierId, page)
+		if err != nil {
+			s.logger.Error("failed to list actors",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	// The actors are moved a batch at a time. A batch that fails leaves the tier retiring with the earlier batches
+	// moved, and the removal is finished by retrying it.
+	for len(actors) > 0 {
+		externalIDs := make([]string, 0, len(actors))
+		for _, actor := range actors {
+			externalIDs = append(externalIDs, actor.ExternalID)
+		}
+
+		err = s.tierClient.ReassignTierActors(ctx, orgID, serviceId, tierId, reassignTo, externalIDs)
+		if err != nil {
+			if isConflict(err) {
+				return conflictResponse(ifMatch, err)
+			}
+			s.logger.Error("failed to reassign pricing tier",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+
+		if cursor == "" {
+			break
+		}
+		page.Cursor = cursor
+		actors, cursor, err = s.actorClient.ListActorsByTier(ctx, orgID, serviceId, tierId, page)
+		if err != nil {
+			s.logger.Error("failed to list actors",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	err = s.tierClient.DeleteTier(ctx, orgID, serviceId, tierId, version)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to delete pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusNoContent, nil), nil
+}
+
+// ServicesServiceIdPricingTiersTierIdGet - Get the pricing tier for a service
+func (s *PricingTierAPIService) ServicesServiceIdPricingTiersTierIdGet(ctx context.Context, serviceId string, tierId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	tier, err := s.tierClient.GetTier(ctx, orgID, serviceId, tierId)
+	if err != nil {
+		s.logger.Error("failed to get pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if tier == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("pricing tier not found")
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(tier.Version), toAPIPricingTier(tier)), nil
+}
+
+// ServicesServiceIdPricingTiersTierIdPut - Update the pricing tier for a service
+func (s *PricingTierAPIService) ServicesServiceIdPricingTiersTierIdPut(ctx context.Context, serviceId string, tierId string, ifMatch string, pricingTierInput openapi.PricingTierInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	err := validatePricingTierInput(pricingTierInput)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	tier, err := s.tierClient.GetTier(ctx, orgID, serviceId, tierId)
+	if err != nil {
+		s.logger.Error("failed to get pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if tier == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("pricing tier not found")
+	}
+	if !matchesETag(ifMatch, tier.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	taken, err := s.isTierNameTaken(ctx, orgID, serviceId, tierId, pricingTierInput.Name)
+	if err != nil {
+		s.logger.Error("failed to list pricing tiers",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if taken {
+		return openapi.Response(http.StatusConflict, nil), fmt.Errorf("pricing tier '%s' already exists", pricingTierInput.Name)
+	}
+
+	tier.Name = pricingTierInput.Name
+	tier.DefaultRequestLimit = int(pricingTierInput.DefaultMonthlyRequestLimit)
+	tier.OveragePrice = pricingTierInput.OveragePrice
+
+	err = s.tierClient.UpdateTier(ctx, orgID, serviceId, tier)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to update pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(tier.Version), toAPIPricingTier(tier)), nil
+}
+
+// isTierNameTaken reports whether another tier of the service, ignoring the tier with the given ID, already has the
+// name. Names are compared case-insensitively so that "Pro" and "pro" cannot both exist.
+func (s *PricingTierAPIService) isTierNameTaken(ctx context.Context, orgID, serviceID, tierID, name string) (bool, error) {
+	tiers, err := dal.ListAll(func(page dal.Page) ([]dal.Tier, string, error) {
+		return s.tierClient.ListTiers(ctx, orgID, serviceID, page)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, tier := range tiers {
+		if tier.TierID != tierID && strings.EqualFold(tier.Name, name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// validatePricingTierInput checks that a pricing tier has a name and a usable request limit and price.
+func validatePricingTierInput(pricingTierInput openapi.PricingTierInput) error {
+	if strings.TrimSpace(pricingTierInput.Name) == "" {
+		return errors.New("name is required")
+	}
+	if pricingTierInput.DefaultMonthlyRequestLimit <= 0 {
+		return errors.New("defaultMonthlyRequestLimit must be positive")
+	}
+	if pricingTierInput.OveragePrice < 0 {
+		return errors.New("overagePrice must not be negative")
+	}
+	return nil
+}
+
+// toAPIPricingTier converts a stored tier into its API representation.
+func toAPIPricingTier(tier *dal.Tier) openapi.PricingTier {
+	return openapi.PricingTier{
+		Id:                         tier.TierID,
+		Name:                       tier.Name,
+		DefaultMonthlyRequestLimit: int32(tier.DefaultRequestLimit),
+		OveragePrice:               tier.OveragePrice,
+		Status:                     tierStatus(tier),
+	}
+}
+
+// tierStatus returns the status of a tier in its API representation. Tiers stored without a status are active.
+func tierStatus(tier *dal.Tier) string {
+	if tier.Status == "" {
+		return dal.TierStatusActive
+	}
+	return tier.Status
+}
@@ -0,0 +1,353 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestPricingTierAPIService_CreateTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	tierInput := openapi.PricingTierInput{Name: "Pro", DefaultMonthlyRequestLimit: 10000, OveragePrice: 0.01}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().ListTiers(ctx, "org1", "serv1", gomock.Any()).Return([]dal.Tier{{TierID: "tier0", Name: "Free"}}, "", nil)
+	mockTierClient.EXPECT().CreateTier(ctx, "org1", "serv1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID, serviceID string, tier *dal.Tier) error {
+		assert.Equal(t, "Pro", tier.Name)
+		assert.Equal(t, 10000, tier.DefaultRequestLimit)
+		assert.Equal(t, float32(0.01), tier.OveragePrice)
+		tier.TierID = "tier1"
+		return nil
+	})
+
+	response, err := service.ServicesServiceIdPricingTiersPost(ctx, "serv1", tierInput)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	assert.Equal(t, openapi.PricingTier{Id: "tier1", Name: "Pro", DefaultMonthlyRequestLimit: 10000, OveragePrice: 0.01, Status: "active"}, response.Body)
+}
+
+func TestPricingTierAPIService_CreateTier_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewPricingTierAPIService(mocks.NewMockTierManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	tests := []struct {
+		name      string
+		tierInput openapi.PricingTierInput
+	}{
+		{name: "Missing name", tierInput: openapi.PricingTierInput{Name: " ", DefaultMonthlyRequestLimit: 100}},
+		{name: "Zero limit", tierInput: openapi.PricingTierInput{Name: "Pro"}},
+		{name: "Negative limit", tierInput: openapi.PricingTierInput{Name: "Pro", DefaultMonthlyRequestLimit: -1}},
+		{name: "Negative price", tierInput: openapi.PricingTierInput{Name: "Pro", DefaultMonthlyRequestLimit: 100, OveragePrice: -0.5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.ServicesServiceIdPricingTiersPost(ctx, "serv1", tt.tierInput)
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
+
+func TestPricingTierAPIService_CreateTier_DuplicateName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().ListTiers(ctx, "org1", "serv1", gomock.Any()).Return([]dal.Tier{{TierID: "tier1", Name: "Pro"}}, "", nil)
+
+	response, err := service.ServicesServiceIdPricingTiersPost(ctx, "serv1", openapi.PricingTierInput{Name: "pro", DefaultMonthlyRequestLimit: 100})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1"}, nil)
+	mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{}, "", nil)
+	mockTierClient.EXPECT().DeleteTier(ctx, "org1", "serv1", "tier1", int64(0)).Return(nil)
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier_Assigned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1"}, nil)
+	mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+		{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+	}, "", nil)
+
+	// Without a tier to reassign to, a tier that is still assigned is kept
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier_Reassign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Version: 2}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier2").Return(&dal.Tier{TierID: "tier2"}, nil)
+
+	// The tier retires before its actors are listed again and moved a page at a time, however many there are, and is
+	// deleted at the version it retired at
+	gomock.InOrder(
+		mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+			{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+		}, "cursor1", nil),
+		mockTierClient.EXPECT().RetireTier(ctx, "org1", "serv1", "tier1", int64(2), "tier2").Return(nil),
+		mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+			{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+			{ExternalID: "actor3", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+		}, "cursor1", nil),
+		mockTierClient.EXPECT().ReassignTierActors(ctx, "org1", "serv1", "tier1", "tier2", []string{"actor1", "actor3"}).Return(nil),
+		mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Cursor: "cursor1", Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+			{ExternalID: "actor4", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+		}, "", nil),
+		mockTierClient.EXPECT().ReassignTierActors(ctx, "org1", "serv1", "tier1", "tier2", []string{"actor4"}).Return(nil),
+		mockTierClient.EXPECT().DeleteTier(ctx, "org1", "serv1", "tier1", int64(3)).Return(nil),
+	)
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "tier2", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier_Retiring(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	retiring := &dal.Tier{TierID: "tier1", Status: dal.TierStatusRetiring, ReassignTo: "tier2", Version: 3}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil).Times(2)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(retiring, nil).Times(2)
+
+	// Retrying the removal of a retiring tier moves its remaining actors to the tier it retires to
+	mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+		{ExternalID: "actor2", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+	}, "", nil)
+	mockTierClient.EXPECT().ReassignTierActors(ctx, "org1", "serv1", "tier1", "tier2", []string{"actor2"}).Return(nil)
+	mockTierClient.EXPECT().DeleteTier(ctx, "org1", "serv1", "tier1", int64(3)).Return(nil)
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+
+	// A retiring tier cannot retire to another tier
+	response, err = service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "tier3", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier_ReassignFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	actors := []dal.Actor{{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}}}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1"}, nil)
+	mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return(actors, "", nil).Times(2)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier2").Return(&dal.Tier{TierID: "tier2"}, nil)
+	mockTierClient.EXPECT().RetireTier(ctx, "org1", "serv1", "tier1", int64(0), "tier2").Return(nil)
+	mockTierClient.EXPECT().ReassignTierActors(ctx, "org1", "serv1", "tier1", "tier2", []string{"actor1"}).Return(errors.New("transaction canceled"))
+
+	// The tier is left retiring, and is not deleted
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "tier2", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestPricingTierAPIService_DeleteTier_InvalidReassign(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mockActorClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// Reassigning to the tier being removed is rejected up front
+	response, err := service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "tier1", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil).Times(2)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1"}, nil).Times(2)
+	mockActorClient.EXPECT().ListActorsByTier(ctx, "org1", "serv1", "tier1", dal.Page{Limit: dal.MaxReassignedActors}).Return([]dal.Actor{
+		{ExternalID: "actor1", BillingInfo: dal.BillingInfo{TierID: "tier1"}},
+	}, "", nil).Times(2)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "missing").Return(nil, nil)
+
+	response, err = service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "missing", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+
+	// Actors cannot be moved to a tier that is retiring itself
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier3").Return(&dal.Tier{TierID: "tier3", Status: dal.TierStatusRetiring}, nil)
+
+	response, err = service.ServicesServiceIdPricingTiersTierIdDelete(ctx, "serv1", "tier1", "tier3", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestPricingTierAPIService_GetTier_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(nil, nil)
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdGet(ctx, "serv1", "tier1")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestPricingTierAPIService_ListTiers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().ListTiers(ctx, "org1", "serv1", dal.Page{Limit: 2}).Return([]dal.Tier{
+		{TierID: "tier1", Name: "Free", DefaultRequestLimit: 100},
+		{TierID: "tier2", Name: "Pro", DefaultRequestLimit: 10000, OveragePrice: 0.01, Status: dal.TierStatusRetiring},
+	}, "cursor1", nil)
+
+	response, err := service.ServicesServiceIdPricingTiersGet(ctx, "serv1", "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, openapi.PricingTierList{
+		PricingTiers: []openapi.PricingTier{
+			{Id: "tier1", Name: "Free", DefaultMonthlyRequestLimit: 100, Status: "active"},
+			{Id: "tier2", Name: "Pro", DefaultMonthlyRequestLimit: 10000, OveragePrice: 0.01, Status: "retiring"},
+		},
+		NextCursor: "cursor1",
+	}, response.Body)
+}
+
+func TestPricingTierAPIService_UpdateTier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Name: "Pro", DefaultRequestLimit: 100}, nil)
+	// The tier keeps its own name without conflicting with itself
+	mockTierClient.EXPECT().ListTiers(ctx, "org1", "serv1", gomock.Any()).Return([]dal.Tier{{TierID: "tier1", Name: "Pro"}}, "", nil)
+	mockTierClient.EXPECT().UpdateTier(ctx, "org1", "serv1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID, serviceID string, tier *dal.Tier) error {
+		assert.Equal(t, "tier1", tier.TierID)
+		assert.Equal(t, 500, tier.DefaultRequestLimit)
+		return nil
+	})
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdPut(ctx, "serv1", "tier1", "", openapi.PricingTierInput{Name: "Pro", DefaultMonthlyRequestLimit: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+}
+
+func TestPricingTierAPIService_UpdateTier_DuplicateName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	service := service.NewPricingTierAPIService(mockTierClient, mockServiceClient, mocks.NewMockActorManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1"}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "tier1").Return(&dal.Tier{TierID: "tier1", Name: "Free"}, nil)
+	mockTierClient.EXPECT().ListTiers(ctx, "org1", "serv1", gomock.Any()).Return([]dal.Tier{{TierID: "tier1", Name: "Free"}, {TierID: "tier2", Name: "Pro"}}, "", nil)
+
+	response, err := service.ServicesServiceIdPricingTiersTierIdPut(ctx, "serv1", "tier1", "", openapi.PricingTierInput{Name: "Pro", DefaultMonthlyRequestLimit: 500})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusConflict, response.Code)
+}
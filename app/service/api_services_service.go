@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/apitoken"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/utils"
+	"go.uber.org/zap"
+)
+
+// ServicesAPIService is a service that implements the logic for the ServicesAPIServicer
+// This service should implement the business logic for every endpoint for the ServicesAPI API.
+type ServicesAPIService struct {
+	serviceClient     dal.ServiceManager
+	apiKeyClient      dal.APIKeyManager
+	transactionClient dal.TransactionManager
+	logger            *zap.Logger
+}
+
+// NewServicesAPIService creates a default app service
+func NewServicesAPIService(serviceClient dal.ServiceManager, apiKeyClient dal.APIKeyManager, transactionClient dal.TransactionManager, logger *zap.Logger) openapi.ServicesAPIServicer {
+	return &ServicesAPIService{
+		serviceClient:     serviceClient,
+		apiKeyClient:      apiKeyClient,
+		transactionClient: transactionClient,
+		logger:            logger,
+	}
+}
+
+// CreateService - Create a new service
+func (s *ServicesAPIService) CreateService(ctx context.Context, serviceInput openapi.ServiceInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	if serviceInput.MaxKeyTtlSeconds < 0 {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("maxKeyTtlSeconds must not be negative")
+	}
+
+	if serviceInput.KeyPrefix != "" {
+		if err := apitoken.ValidatePrefix(serviceInput.KeyPrefix); err != nil {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+	}
+
+	leakPolicy, err := resolveLeakPolicy(serviceInput.LeakPolicy)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	service := &dal.Service{
+		Name:             serviceInput.Name,
+		Description:      serviceInput.Description,
+		MaxKeyTTLSeconds: serviceInput.MaxKeyTtlSeconds,
+		KeyPrefix:        serviceInput.KeyPrefix,
+		LeakPolicy:       leakPolicy,
+	}
+
+	err = s.serviceClient.CreateService(ctx, orgID, service)
+	if err != nil {
+		s.logger.Error("failed to create service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response, err := toAPIService(service)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusCreated, etagHeaders(service.Version), response), nil
+}
+
+// DeleteService - Delete a specific service
+func (s *ServicesAPIService) DeleteService(ctx context.Context, serviceId string, ifMatch string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+	if !matchesETag(ifMatch, service.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	// Mark the service as deleting first, so that no API key can be created in it while its keys are listed and
+	// deleted. A delete that failed part way left the service deleting already
+	version := service.Version
+	if !service.Deleting() {
+		err = s.serviceClient.MarkServiceDeleting(ctx, orgID, serviceId, version)
+		if err != nil {
+			if isConflict(err) {
+				return conflictResponse(ifMatch, err)
+			}
+			s.logger.Error("failed to mark service as deleting",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		version++
+	}
+
+	// Revoke the service's API keys along with the service. The keys are committed first, so that a delete that spans
+	// several transactions and fails part way can be retried
+	apiKeys, err := dal.ListAll(func(page dal.Page) ([]dal.APIKey, string, error) {
+		return s.apiKeyClient.ListAPIKeysByService(ctx, orgID, serviceId, page)
+	})
+	if err != nil {
+		s.logger.Error("failed to list API keys",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	unit := dal.NewUnitOfWork()
+	for _, apiKey := range apiKeys {
+		err = s.apiKeyClient.StageDeleteAPIKey(ctx, unit, orgID, serviceId, apiKey.APIKeyID, apiKey.Version)
+		if err != nil {
+			s.logger.Error("failed to stage API key delete",
+				zap.String("requestID", requestID),
+				zap.String("keyID", apiKey.APIKeyID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+	}
+
+	err = s.serviceClient.StageDeleteService(ctx, unit, orgID, serviceId, version)
+	if err != nil {
+		s.logger.Error("failed to stage service delete",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	err = s.transactionClient.Commit(ctx, unit)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to delete service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.Response(http.StatusNoContent, nil), nil
+}
+
+// GetService - Retrieve a specific service
+func (s *ServicesAPIService) GetService(ctx context.Context, serviceId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	response, err := toAPIService(service)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(service.Version), response), nil
+}
+
+// ListServices - List all services
+func (s *ServicesAPIService) ListServices(ctx context.Context, cursor string, limit int32) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	page, err := toPage(cursor, limit)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	services, nextCursor, err := s.serviceClient.ListServicesByOrganization(ctx, orgID, page)
+	if err != nil {
+		if errors.Is(err, dal.ErrInvalidCursor) {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+		s.logger.Error("failed to list services",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	responses := make([]openapi.Service, len(services))
+	for i, service := range services {
+		response, err := toAPIService(&service)
+		if err != nil {
+			s.logger.Error("failed to parse timestamp",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		responses[i] = response
+	}
+
+	return openapi.Response(http.StatusOK, openapi.ServiceList{Services: responses, NextCursor: nextCursor}), nil
+}
+
+// UpdateService - Update a specific service
+func (s *ServicesAPIService) UpdateService(ctx context.Context, serviceId string, ifMatch string, serviceInput openapi.ServiceInput) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+	if !matchesETag(ifMatch, service.Version) {
+		return openapi.Response(http.StatusPreconditionFailed, nil), errPreconditionFailed
+	}
+
+	if serviceInput.MaxKeyTtlSeconds < 0 {
+		return openapi.Response(http.StatusBadRequest, nil), errors.New("maxKeyTtlSeconds must not be negative")
+	}
+
+	if serviceInput.KeyPrefix != "" {
+		if err := apitoken.ValidatePrefix(serviceInput.KeyPrefix); err != nil {
+			return openapi.Response(http.StatusBadRequest, nil), err
+		}
+	}
+
+	leakPolicy, err := resolveLeakPolicy(serviceInput.LeakPolicy)
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Lowering the maximum only applies to keys generated or updated afterwards, and a new key prefix only to the
+	// tokens of keys generated or rotated afterwards
+	service.Name = serviceInput.Name
+	service.Description = serviceInput.Description
+	service.MaxKeyTTLSeconds = serviceInput.MaxKeyTtlSeconds
+	service.KeyPrefix = serviceInput.KeyPrefix
+	service.LeakPolicy = leakPolicy
+
+	err = s.serviceClient.UpdateService(ctx, orgID, service)
+	if err != nil {
+		if isConflict(err) {
+			return conflictResponse(ifMatch, err)
+		}
+		s.logger.Error("failed to update service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	response, err := toAPIService(service)
+	if err != nil {
+		s.logger.Error("failed to parse timestamp",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	return openapi.ResponseWithHeaders(http.StatusOK, etagHeaders(service.Version), response), nil
+}
+
+// resolveLeakPolicy validates the leak policy of a service input and returns the policy to store.
+func resolveLeakPolicy(value string) (string, error) {
+	switch value {
+	case "":
+		return dal.LeakPolicyRevoke, nil
+	case dal.LeakPolicyRevoke, dal.LeakPolicyQuarantine:
+		return value, nil
+	default:
+		return "", fmt.Errorf("leakPolicy must be '%s' or '%s'", dal.LeakPolicyRevoke, dal.LeakPolicyQuarantine)
+	}
+}
+
+// toAPIService converts a stored service into its API representation.
+func toAPIService(service *dal.Service) (openapi.Service, error) {
+	createdAt, err := utils.ParseTimestamp(service.CreatedAt)
+	if err != nil {
+		return openapi.Service{}, err
+	}
+
+	updatedAt, err := utils.ParseTimestamp(service.UpdatedAt)
+	if err != nil {
+		return openapi.Service{}, err
+	}
+
+	// Services created before leak policies revoke leaked keys
+	leakPolicy := service.LeakPolicy
+	if leakPolicy == "" {
+		leakPolicy = dal.LeakPolicyRevoke
+	}
+
+	return openapi.Service{
+		Id:               service.ServiceID,
+		Name:             service.Name,
+		Description:      service.Description,
+		MaxKeyTtlSeconds: service.MaxKeyTTLSeconds,
+		KeyPrefix:        service.KeyPrefix,
+		LeakPolicy:       leakPolicy,
+		CreatedAt:        createdAt,
+		UpdatedAt:        updatedAt,
+	}, nil
+}
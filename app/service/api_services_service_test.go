@@ -0,0 +1,505 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestServicesAPIService_CreateService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	serviceInput := openapi.ServiceInput{
+		Name:             "Service1",
+		Description:      "Description1",
+		MaxKeyTtlSeconds: 86400,
+		KeyPrefix:        "acme",
+	}
+
+	mockServiceClient.EXPECT().CreateService(ctx, "org1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID string, svc *dal.Service) error {
+		svc.ServiceID = "serv1"
+		svc.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+		svc.UpdatedAt = svc.CreatedAt
+		return nil
+	})
+
+	response, err := service.CreateService(ctx, serviceInput)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, response.Code)
+	created, ok := response.Body.(openapi.Service)
+	assert.True(t, ok)
+	assert.Equal(t, "serv1", created.Id)
+	assert.Equal(t, serviceInput.Name, created.Name)
+	assert.Equal(t, serviceInput.Description, created.Description)
+	assert.Equal(t, serviceInput.MaxKeyTtlSeconds, created.MaxKeyTtlSeconds)
+	assert.Equal(t, serviceInput.KeyPrefix, created.KeyPrefix)
+	assert.Equal(t, dal.LeakPolicyRevoke, created.LeakPolicy)
+	assert.False(t, created.CreatedAt.IsZero())
+}
+
+func TestServicesAPIService_CreateService_NegativeMaxKeyTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	response, err := service.CreateService(ctx, openapi.ServiceInput{Name: "Service1", MaxKeyTtlSeconds: -1})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestServicesAPIService_CreateService_InvalidKeyPrefix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	for _, keyPrefix := range []string{"a", "Acme", "acme_live", "2fa"} {
+		response, err := service.CreateService(ctx, openapi.ServiceInput{Name: "Service1", KeyPrefix: keyPrefix})
+		assert.Error(t, err, keyPrefix)
+		assert.Equal(t, http.StatusBadRequest, response.Code, keyPrefix)
+	}
+}
+
+func TestServicesAPIService_CreateService_InvalidLeakPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	response, err := service.CreateService(ctx, openapi.ServiceInput{Name: "Service1", LeakPolicy: "ignore"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.Code)
+}
+
+func TestServicesAPIService_CreateService_OrgNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	response, err := service.CreateService(context.Background(), openapi.ServiceInput{Name: "Service1"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestServicesAPIService_DeleteService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mockTransactionClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	serviceID := "serv1"
+
+	// The service is marked as deleting before its keys are listed, so that no key is created in it meanwhile. The keys
+	// are staged before the service, and everything is committed together
+	gomock.InOrder(
+		mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{ServiceID: serviceID}, nil),
+		mockServiceClient.EXPECT().MarkServiceDeleting(ctx, "org1", serviceID, int64(0)).Return(nil),
+		mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", serviceID, gomock.Any()).Return([]dal.APIKey{{APIKeyID: "key1"}, {APIKeyID: "key2"}}, "", nil),
+		mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", serviceID, "key1", int64(0)).Return(nil),
+		mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", serviceID, "key2", int64(0)).Return(nil),
+		mockServiceClient.EXPECT().StageDeleteService(ctx, gomock.Any(), "org1", serviceID, int64(1)).Return(nil),
+		mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(nil),
+	)
+
+	response, err := service.DeleteService(ctx, serviceID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, response.Code)
+}
+
+func TestServicesAPIService_DeleteService_RevokeFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	serviceID := "serv1"
+
+	// Nothing is committed when the delete of a key cannot be staged
+	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(&dal.Service{ServiceID: serviceID}, nil)
+	mockServiceClient.EXPECT().MarkServiceDeleting(ctx, "org1", serviceID, int64(0)).Return(nil)
+	mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", serviceID, gomock.Any()).Return([]dal.APIKey{{APIKeyID: "key1"}}, "", nil)
+	mockAPIKeyClient.EXPECT().StageDeleteAPIKey(ctx, gomock.Any(), "org1", serviceID, "key1", int64(0)).Return(errors.New("dynamodb error"))
+
+	response, err := service.DeleteService(ctx, serviceID, "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, response.Code)
+}
+
+func TestServicesAPIService_DeleteService_CommitFailed(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		err     error
+		code    int
+	}{
+		{name: "Conflict with If-Match", ifMatch: `"2"`, err: &dal.ConflictError{Entity: "service", ID: "serv1", Version: 2}, code: http.StatusPreconditionFailed},
+		{name: "Conflict without If-Match", err: &dal.ConflictError{Entity: "service", ID: "serv1", Version: 2}, code: http.StatusConflict},
+		{name: "Partial commit", err: &dal.PartialCommitError{Committed: 1, Total: 2, Err: errors.New("dynamodb error")}, code: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+			mockTransactionClient := mocks.NewMockTransactionManager(ctrl)
+			service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mockTransactionClient, zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+			// The service was left deleting by a delete that failed part way, and is not marked again
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", Status: dal.ServiceStatusDeleting, Version: 2}, nil)
+			mockAPIKeyClient.EXPECT().ListAPIKeysByService(ctx, "org1", "serv1", gomock.Any()).Return(nil, "", nil)
+			mockServiceClient.EXPECT().StageDeleteService(ctx, gomock.Any(), "org1", "serv1", int64(2)).Return(nil)
+			mockTransactionClient.EXPECT().Commit(ctx, gomock.Any()).Return(tt.err)
+
+			response, err := service.DeleteService(ctx, "serv1", tt.ifMatch)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.code, response.Code)
+		})
+	}
+}
+
+func TestServicesAPIService_DeleteService_MarkFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// The service changed since it was read, and none of its keys is touched
+	conflict := &dal.ConflictError{Entity: "service", ID: "serv1", Version: 2}
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", Version: 2}, nil)
+	mockServiceClient.EXPECT().MarkServiceDeleting(ctx, "org1", "serv1", int64(2)).Return(conflict)
+
+	response, err := service.DeleteService(ctx, "serv1", `"2"`)
+	assert.ErrorIs(t, err, conflict)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+}
+
+func TestServicesAPIService_DeleteService_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.DeleteService(ctx, "serv1", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestServicesAPIService_GetService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	serviceID := "serv1"
+
+	svc := &dal.Service{
+		ServiceID:   serviceID,
+		Name:        "Service1",
+		Description: "Description1",
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+		UpdatedAt:   time.Now().UTC().Format(time.RFC3339),
+		Version:     3,
+	}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(svc, nil)
+
+	response, err := service.GetService(ctx, serviceID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, []string{`"3"`}, response.Headers["ETag"])
+	retrieved, ok := response.Body.(openapi.Service)
+	assert.True(t, ok)
+	assert.Equal(t, serviceID, retrieved.Id)
+	assert.Equal(t, "Service1", retrieved.Name)
+	assert.Equal(t, dal.LeakPolicyRevoke, retrieved.LeakPolicy)
+}
+
+func TestServicesAPIService_GetService_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.GetService(ctx, "serv1")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestServicesAPIService_GetService_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{name: "Throttled", err: fmt.Errorf("failed to get item from DynamoDB: %w", dal.ErrThrottled), code: http.StatusServiceUnavailable},
+		{name: "Other error", err: errors.New("dynamodb error"), code: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			service := service.NewServicesAPIService(mockServiceClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, tt.err)
+
+			response, err := service.GetService(ctx, "serv1")
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.code, response.Code)
+		})
+	}
+}
+
+func TestServicesAPIService_ListServices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	services := []dal.Service{
+		{ServiceID: "serv1", Name: "Service1"},
+		{ServiceID: "serv2", Name: "Service2"},
+	}
+
+	mockServiceClient.EXPECT().ListServicesByOrganization(ctx, "org1", dal.Page{}).Return(services, "", nil)
+
+	response, err := service.ListServices(ctx, "", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	listed, ok := response.Body.(openapi.ServiceList)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(listed.Services))
+	assert.Equal(t, "serv1", listed.Services[0].Id)
+	assert.Equal(t, "serv2", listed.Services[1].Id)
+	assert.Empty(t, listed.NextCursor)
+}
+
+func TestServicesAPIService_ListServices_Rejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		limit  int32
+		err    error
+	}{
+		{name: "Limit too large", limit: dal.MaxPageLimit + 1},
+		{name: "Negative limit", limit: -1},
+		{name: "Invalid cursor", cursor: "cursor1", err: dal.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			service := service.NewServicesAPIService(mockServiceClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+			if tt.err != nil {
+				mockServiceClient.EXPECT().ListServicesByOrganization(ctx, "org1", dal.Page{Cursor: tt.cursor}).Return(nil, "", tt.err)
+			}
+
+			response, err := service.ListServices(ctx, tt.cursor, tt.limit)
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
+
+func TestServicesAPIService_UpdateService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	serviceID := "serv1"
+	serviceInput := openapi.ServiceInput{
+		Name:             "New Name",
+		Description:      "New Description",
+		MaxKeyTtlSeconds: 3600,
+		LeakPolicy:       "quarantine",
+	}
+
+	svc := &dal.Service{
+		ServiceID:   serviceID,
+		Name:        "Old Name",
+		Description: "Old Description",
+	}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", serviceID).Return(svc, nil)
+	mockServiceClient.EXPECT().UpdateService(ctx, "org1", gomock.Any()).DoAndReturn(func(ctx context.Context, orgID string, updated *dal.Service) error {
+		assert.Equal(t, serviceID, updated.ServiceID)
+		assert.Equal(t, serviceInput.Name, updated.Name)
+		assert.Equal(t, serviceInput.Description, updated.Description)
+		assert.Equal(t, serviceInput.MaxKeyTtlSeconds, updated.MaxKeyTTLSeconds)
+		assert.Equal(t, dal.LeakPolicyQuarantine, updated.LeakPolicy)
+		return nil
+	})
+
+	response, err := service.UpdateService(ctx, serviceID, "", serviceInput)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	updated, ok := response.Body.(openapi.Service)
+	assert.True(t, ok)
+	assert.Equal(t, serviceInput.Name, updated.Name)
+	assert.Equal(t, serviceInput.Description, updated.Description)
+	assert.Equal(t, dal.LeakPolicyQuarantine, updated.LeakPolicy)
+}
+
+func TestServicesAPIService_UpdateService_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(nil, nil)
+
+	response, err := service.UpdateService(ctx, "serv1", "", openapi.ServiceInput{Name: "New Name"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestServicesAPIService_UpdateService_PreconditionFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", Version: 3}, nil)
+
+	response, err := service.UpdateService(ctx, "serv1", `"2"`, openapi.ServiceInput{Name: "New Name"})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+}
+
+func TestServicesAPIService_UpdateService_Conflict(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		code    int
+	}{
+		{name: "Without If-Match", ifMatch: "", code: http.StatusConflict},
+		{name: "With If-Match", ifMatch: `"3"`, code: http.StatusPreconditionFailed},
+		{name: "With any If-Match", ifMatch: "*", code: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockServiceClient := mocks.NewMockServiceManager(ctrl)
+			service := service.NewServicesAPIService(mockServiceClient, mocks.NewMockAPIKeyManager(ctrl), mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+			ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+			mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", Version: 3}, nil)
+			mockServiceClient.EXPECT().UpdateService(ctx, "org1", gomock.Any()).Return(&dal.ConflictError{Entity: "service", ID: "serv1", Version: 3})
+
+			response, err := service.UpdateService(ctx, "serv1", tt.ifMatch, openapi.ServiceInput{Name: "New Name"})
+			assert.Error(t, err)
+			assert.Equal(t, tt.code, response.Code)
+		})
+	}
+}
+
+func TestServicesAPIService_DeleteService_PreconditionFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewServicesAPIService(mockServiceClient, mockAPIKeyClient, mocks.NewMockTransactionManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// The keys of the service are not revoked when the precondition does not hold.
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{ServiceID: "serv1", Version: 3}, nil)
+
+	response, err := service.DeleteService(ctx, "serv1", `W/"3", "4"`)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusPreconditionFailed, response.Code)
+}
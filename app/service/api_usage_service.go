@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/usage"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxUsagePeriods represents the maximum number of calendar months covered by a usage report
+	MaxUsagePeriods = 24
+)
+
+// UsageAPIService is a service that implements the logic for the UsageAPIServicer
+// This service should implement the business logic for every endpoint for the UsageAPI API.
+type UsageAPIService struct {
+	usageClient   dal.UsageManager
+	serviceClient dal.ServiceManager
+	actorClient   dal.ActorManager
+	tierClient    dal.TierManager
+	apiKeyClient  dal.APIKeyManager
+	logger        *zap.Logger
+}
+
+// NewUsageAPIService creates a default app service
+func NewUsageAPIService(usageClient dal.UsageManager, serviceClient dal.ServiceManager, actorClient dal.ActorManager, tierClient dal.TierManager, apiKeyClient dal.APIKeyManager, logger *zap.Logger) openapi.UsageAPIServicer {
+	return &UsageAPIService{
+		usageClient:   usageClient,
+		serviceClient: serviceClient,
+		actorClient:   actorClient,
+		tierClient:    tierClient,
+		apiKeyClient:  apiKeyClient,
+		logger:        logger,
+	}
+}
+
+// GetActorUsage - Get the usage of an actor
+func (s *UsageAPIService) GetActorUsage(ctx context.Context, serviceId string, actorExternalId string, from string, to string, keyId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	periods, err := usagePeriods(from, to, time.Now())
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	// Deleted actors are reported too, since their usage in the periods before they were deleted is still billed
+	actor, err := s.actorClient.GetActorIncludingDeleted(ctx, orgID, serviceId, actorExternalId)
+	if err != nil {
+		s.logger.Error("failed to get actor",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if actor == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("actor not found")
+	}
+
+	subject, subjectID := dal.UsageSubjectActor, actorExternalId
+	if keyId != "" {
+		apiKey, err := s.getAPIKey(ctx, orgID, serviceId, keyId)
+		if err != nil {
+			s.logger.Error("failed to get API key",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		if apiKey == nil || apiKey.ActorID != actorExternalId {
+			return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
+		}
+		subject, subjectID = dal.UsageSubjectAPIKey, keyId
+	}
+
+	price, err := s.overagePrice(ctx, orgID, serviceId, actor)
+	if err != nil {
+		s.logger.Error("failed to get pricing tier",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+
+	report := openapi.UsageReport{
+		ServiceId:       serviceId,
+		ActorExternalId: actorExternalId,
+		KeyId:           keyId,
+		From:            periods[0],
+		To:              periods[len(periods)-1],
+	}
+
+	for _, period := range periods {
+		entry := openapi.UsagePeriod{Period: period}
+		counter, err := s.usageClient.GetUsage(ctx, orgID, serviceId, period, subject, subjectID)
+		if err != nil {
+			s.logger.Error("failed to get usage",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		if counter != nil {
+			entry.Requests = counter.Count
+			entry.OverageRequests = counter.Overage
+			entry.OverageCost = float64(counter.Overage) * price
+		}
+		addUsagePeriod(&report, entry)
+	}
+
+	return openapi.Response(http.StatusOK, report), nil
+}
+
+// GetServiceUsage - Get the usage of a service
+func (s *UsageAPIService) GetServiceUsage(ctx context.Context, serviceId string, from string, to string, keyId string) (openapi.ImplResponse, error) {
+	requestID := middleware.GetReqID(ctx)
+	orgID, ok := ctx.Value("orgID").(string)
+	if !ok || orgID == "" {
+		s.logger.Error("orgID not present in context",
+			zap.String("requestID", requestID),
+		)
+		return openapi.Response(http.StatusNotFound, nil), errors.New("org not found")
+	}
+
+	periods, err := usagePeriods(from, to, time.Now())
+	if err != nil {
+		return openapi.Response(http.StatusBadRequest, nil), err
+	}
+
+	// Check if the service exists
+	service, err := s.serviceClient.GetService(ctx, orgID, serviceId)
+	if err != nil {
+		s.logger.Error("failed to get service",
+			zap.String("requestID", requestID),
+			zap.Error(err),
+		)
+		return openapi.ErrorResponse(err)
+	}
+	if service == nil {
+		return openapi.Response(http.StatusNotFound, nil), errors.New("service not found")
+	}
+
+	var apiKey *dal.APIKey
+	if keyId != "" {
+		apiKey, err = s.getAPIKey(ctx, orgID, serviceId, keyId)
+		if err != nil {
+			s.logger.Error("failed to get API key",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		if apiKey == nil {
+			return openapi.Response(http.StatusNotFound, nil), errors.New("API key not found")
+		}
+	}
+
+	report := openapi.UsageReport{
+		ServiceId: serviceId,
+		KeyId:     keyId,
+		From:      periods[0],
+		To:        periods[len(periods)-1],
+	}
+
+	// Overage prices are looked up once per actor across all periods
+	prices := make(map[string]float64)
+	for _, period := range periods {
+		var entry openapi.UsagePeriod
+		if apiKey != nil {
+			entry, err = s.keyUsagePeriod(ctx, orgID, serviceId, period, apiKey, prices)
+		} else {
+			entry, err = s.serviceUsagePeriod(ctx, orgID, serviceId, period, prices)
+		}
+		if err != nil {
+			s.logger.Error("failed to get usage",
+				zap.String("requestID", requestID),
+				zap.Error(err),
+			)
+			return openapi.ErrorResponse(err)
+		}
+		addUsagePeriod(&report, entry)
+	}
+
+	return openapi.Response(http.StatusOK, report), nil
+}
+
+// keyUsagePeriod reads the usage of a single API key in a period.
+func (s *UsageAPIService) keyUsagePeriod(ctx context.Context, orgID, serviceID, period string, apiKey *dal.APIKey, prices map[string]float64) (openapi.UsagePeriod, error) {
+	entry := openapi.UsagePeriod{Period: period}
+	counter, err := s.usageClient.GetUsage(ctx, orgID, serviceID, period, dal.UsageSubjectAPIKey, apiKey.APIKeyID)
+	if err != nil {
+		return entry, err
+	}
+	if counter == nil {
+		return entry, nil
+	}
+
+	price, err := s.actorOveragePrice(ctx, orgID, serviceID, apiKey.ActorID, prices)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Requests = counter.Count
+	entry.OverageRequests = counter.Overage
+	entry.OverageCost = float64(counter.Overage) * price
+	return entry, nil
+}
+
+// serviceUsagePeriod sums the usage of every API key and actor of a service in a period. Every request is counted
+// against its API key, while overage only accrues to actors, so requests are summed over keys and overage over actors.
+func (s *UsageAPIService) serviceUsagePeriod(ctx context.Context, orgID, serviceID, period string, prices map[string]float64) (openapi.UsagePeriod, error) {
+	entry := openapi.UsagePeriod{Period: period}
+
+	keys, err := s.usageClient.ListUsage(ctx, orgID, serviceID, period, dal.UsageSubjectAPIKey)
+	if err != nil {
+		return entry, err
+	}
+	for _, counter := range keys {
+		entry.Requests += counter.Count
+	}
+
+	actors, err := s.usageClient.ListUsage(ctx, orgID, serviceID, period, dal.UsageSubjectActor)
+	if err != nil {
+		return entry, err
+	}
+	for _, counter := range actors {
+		if counter.Overage == 0 {
+			continue
+		}
+
+		price, err := s.actorOveragePrice(ctx, orgID, serviceID, counter.SubjectID, prices)
+		if err != nil {
+			return entry, err
+		}
+
+		entry.OverageRequests += counter.Overage
+		entry.OverageCost += float64(counter.Overage) * price
+	}
+
+	return entry, nil
+}
+
+// getAPIKey retrieves an API key, returning nil if it does not belong to the service. Deleted keys are returned
+// so that their past usage can still be reported.
+func (s *UsageAPIService) getAPIKey(ctx context.Context, orgID, serviceID, keyID string) (*dal.APIKey, error) {
+	apiKey, err := s.apiKeyClient.GetAPIKeyIncludingDeleted(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == nil || apiKey.OrgID != orgID || apiKey.ServiceID != serviceID {
+		return nil, nil
+	}
+
+	return apiKey, nil
+}
+
+// actorOveragePrice returns the overage price of an actor's pricing tier, memoizing it in prices. Deleted actors
+// keep the price of their tier, so that their overage is still billed.
+func (s *UsageAPIService) actorOveragePrice(ctx context.Context, orgID, serviceID, actorID string, prices map[string]float64) (float64, error) {
+	if actorID == "" {
+		return 0, nil
+	}
+	if price, ok := prices[actorID]; ok {
+		return price, nil
+	}
+
+	actor, err := s.actorClient.GetActorIncludingDeleted(ctx, orgID, serviceID, actorID)
+	if err != nil {
+		return 0, err
+	}
+
+	price, err := s.overagePrice(ctx, orgID, serviceID, actor)
+	if err != nil {
+		return 0, err
+	}
+
+	prices[actorID] = price
+	return price, nil
+}
+
+// overagePrice returns the overage price of an actor's pricing tier, or zero if it has none. Costs are computed
+// with the current price, as prices are not recorded alongside usage.
+func (s *UsageAPIService) overagePrice(ctx context.Context, orgID, serviceID string, actor *dal.Actor) (float64, error) {
+	if actor == nil || actor.BillingInfo.TierID == "" {
+		return 0, nil
+	}
+
+	tier, err := s.tierClient.GetTier(ctx, orgID, serviceID, actor.BillingInfo.TierID)
+	if err != nil {
+		return 0, err
+	}
+	if tier == nil {
+		return 0, nil
+	}
+
+	// Widen through the shortest decimal representation so that prices like 0.01 stay exact
+	return strconv.ParseFloat(strconv.FormatFloat(float64(tier.OveragePrice), 'f', -1, 32), 64)
+}
+
+// usagePeriods resolves the calendar months covered by a usage report. Both bounds default to the current month.
+func usagePeriods(from, to string, now time.Time) ([]string, error) {
+	end := now
+	if to != "" {
+		parsed, err := usage.ParsePeriod(to)
+		if err != nil {
+			return nil, err
+		}
+		end = parsed
+	}
+
+	start := end
+	if from != "" {
+		parsed, err := usage.ParsePeriod(from)
+		if err != nil {
+			return nil, err
+		}
+		start = parsed
+	}
+
+	periods := usage.Periods(start, end)
+	if len(periods) == 0 {
+		return nil, errors.New("from must not be after to")
+	}
+	if len(periods) > MaxUsagePeriods {
+		return nil, fmt.Errorf("usage reports cannot cover more than %d months", MaxUsagePeriods)
+	}
+
+	return periods, nil
+}
+
+// addUsagePeriod appends a period to a usage report and adds it to the report's totals.
+func addUsagePeriod(report *openapi.UsageReport, entry openapi.UsagePeriod) {
+	entry.OverageCost = roundCost(entry.OverageCost)
+	report.Periods = append(report.Periods, entry)
+	report.Requests += entry.Requests
+	report.OverageRequests += entry.OverageRequests
+	report.OverageCost = roundCost(report.OverageCost + entry.OverageCost)
+}
+
+// roundCost rounds a cost to six decimal places to hide floating point error.
+func roundCost(cost float64) float64 {
+	return math.Round(cost*1e6) / 1e6
+}
@@ -0,0 +1,232 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/openapi"
+	"github.com/payloadops/lanyard/app/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestUsageAPIService_GetActorUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewUsageAPIService(mockUsageClient, mockServiceClient, mockActorClient, mockTierClient, mockAPIKeyClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+	actor := &dal.Actor{ActorID: "actor1", BillingInfo: dal.BillingInfo{TierID: "pro"}}
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(actor, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "pro").Return(&dal.Tier{Name: "pro", OveragePrice: 0.01}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 120, Overage: 20}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-02", dal.UsageSubjectActor, "actor1").Return(nil, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-03", dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 130, Overage: 30}, nil)
+
+	response, err := service.GetActorUsage(ctx, "serv1", "actor1", "2024-01", "2024-03", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	report, ok := response.Body.(openapi.UsageReport)
+	assert.True(t, ok)
+	assert.Equal(t, "actor1", report.ActorExternalId)
+	assert.Equal(t, "2024-01", report.From)
+	assert.Equal(t, "2024-03", report.To)
+	assert.Equal(t, int64(250), report.Requests)
+	assert.Equal(t, int64(50), report.OverageRequests)
+	assert.Equal(t, 0.5, report.OverageCost)
+	assert.Equal(t, []openapi.UsagePeriod{
+		{Period: "2024-01", Requests: 120, OverageRequests: 20, OverageCost: 0.2},
+		{Period: "2024-02"},
+		{Period: "2024-03", Requests: 130, OverageRequests: 30, OverageCost: 0.3},
+	}, report.Periods)
+}
+
+func TestUsageAPIService_GetActorUsage_ByKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewUsageAPIService(mockUsageClient, mockServiceClient, mockActorClient, mockTierClient, mockAPIKeyClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil).Times(2)
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1"}, nil).Times(2)
+	mockAPIKeyClient.EXPECT().GetAPIKeyIncludingDeleted(ctx, "key1").Return(&dal.APIKey{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv1", ActorID: "actor1"}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKeyIncludingDeleted(ctx, "key2").Return(&dal.APIKey{APIKeyID: "key2", OrgID: "org1", ServiceID: "serv1", ActorID: "actor2"}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectAPIKey, "key1").Return(&dal.Usage{Count: 7}, nil)
+
+	response, err := service.GetActorUsage(ctx, "serv1", "actor1", "2024-01", "2024-01", "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	report := response.Body.(openapi.UsageReport)
+	assert.Equal(t, "key1", report.KeyId)
+	assert.Equal(t, int64(7), report.Requests)
+
+	// Keys of other actors are not found
+	response, err = service.GetActorUsage(ctx, "serv1", "actor1", "2024-01", "2024-01", "key2")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestUsageAPIService_GetActorUsage_DeletedKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewUsageAPIService(mockUsageClient, mockServiceClient, mockActorClient, mocks.NewMockTierManager(ctrl), mockAPIKeyClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// The past usage of a deleted key is still reported
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1"}, nil)
+	mockAPIKeyClient.EXPECT().GetAPIKeyIncludingDeleted(ctx, "key1").Return(&dal.APIKey{APIKeyID: "key1", OrgID: "org1", ServiceID: "serv1", ActorID: "actor1", Deleted: true}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectAPIKey, "key1").Return(&dal.Usage{Count: 7}, nil)
+
+	response, err := service.GetActorUsage(ctx, "serv1", "actor1", "2024-01", "2024-01", "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	report := response.Body.(openapi.UsageReport)
+	assert.Equal(t, "key1", report.KeyId)
+	assert.Equal(t, int64(7), report.Requests)
+}
+
+func TestUsageAPIService_GetActorUsage_DeletedActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	service := service.NewUsageAPIService(mockUsageClient, mockServiceClient, mockActorClient, mockTierClient, mocks.NewMockAPIKeyManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	// An actor offboarded during the period still has its usage and overage billed
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", Deleted: true, BillingInfo: dal.BillingInfo{TierID: "pro"}}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "pro").Return(&dal.Tier{Name: "pro", OveragePrice: 0.5}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 12, Overage: 2}, nil)
+
+	response, err := service.GetActorUsage(ctx, "serv1", "actor1", "2024-01", "2024-01", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	report := response.Body.(openapi.UsageReport)
+	assert.Equal(t, int64(12), report.Requests)
+	assert.Equal(t, int64(2), report.OverageRequests)
+	assert.Equal(t, 1.0, report.OverageCost)
+}
+
+func TestUsageAPIService_GetActorUsage_ActorNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	service := service.NewUsageAPIService(mocks.NewMockUsageManager(ctrl), mockServiceClient, mockActorClient, mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(nil, nil)
+
+	response, err := service.GetActorUsage(ctx, "serv1", "actor1", "", "", "")
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, response.Code)
+}
+
+func TestUsageAPIService_GetServiceUsage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockServiceClient := mocks.NewMockServiceManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	mockAPIKeyClient := mocks.NewMockAPIKeyManager(ctrl)
+	service := service.NewUsageAPIService(mockUsageClient, mockServiceClient, mockActorClient, mockTierClient, mockAPIKeyClient, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	mockServiceClient.EXPECT().GetService(ctx, "org1", "serv1").Return(&dal.Service{}, nil)
+	mockUsageClient.EXPECT().ListUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectAPIKey).Return([]dal.Usage{
+		{SubjectID: "key1", Count: 100},
+		{SubjectID: "key2", Count: 50},
+		{SubjectID: "key3", Count: 10},
+	}, nil)
+	mockUsageClient.EXPECT().ListUsage(ctx, "org1", "serv1", "2024-01", dal.UsageSubjectActor).Return([]dal.Usage{
+		{SubjectID: "actor1", Count: 150, Overage: 40},
+		{SubjectID: "actor2", Count: 10},
+	}, nil)
+	mockUsageClient.EXPECT().ListUsage(ctx, "org1", "serv1", "2024-02", dal.UsageSubjectAPIKey).Return([]dal.Usage{
+		{SubjectID: "key1", Count: 60},
+	}, nil)
+	mockUsageClient.EXPECT().ListUsage(ctx, "org1", "serv1", "2024-02", dal.UsageSubjectActor).Return([]dal.Usage{
+		{SubjectID: "actor1", Count: 60, Overage: 10},
+	}, nil)
+
+	// The actor's price is only looked up once
+	mockActorClient.EXPECT().GetActorIncludingDeleted(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", BillingInfo: dal.BillingInfo{TierID: "pro"}}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "pro").Return(&dal.Tier{Name: "pro", OveragePrice: 0.05}, nil)
+
+	response, err := service.GetServiceUsage(ctx, "serv1", "2024-01", "2024-02", "")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.Code)
+	report := response.Body.(openapi.UsageReport)
+	assert.Equal(t, int64(220), report.Requests)
+	assert.Equal(t, int64(50), report.OverageRequests)
+	assert.Equal(t, 2.5, report.OverageCost)
+	assert.Equal(t, []openapi.UsagePeriod{
+		{Period: "2024-01", Requests: 160, OverageRequests: 40, OverageCost: 2},
+		{Period: "2024-02", Requests: 60, OverageRequests: 10, OverageCost: 0.5},
+	}, report.Periods)
+}
+
+func TestUsageAPIService_GetServiceUsage_InvalidRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	service := service.NewUsageAPIService(mocks.NewMockUsageManager(ctrl), mocks.NewMockServiceManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockTierManager(ctrl), mocks.NewMockAPIKeyManager(ctrl), zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), "orgID", "org1")
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{name: "Invalid from", from: "2024-1", to: "2024-02"},
+		{name: "Invalid to", from: "2024-01", to: "February"},
+		{name: "Reversed", from: "2024-03", to: "2024-02"},
+		{name: "Too long", from: "2020-01", to: "2024-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := service.GetServiceUsage(ctx, "serv1", tt.from, tt.to, "")
+			assert.Error(t, err)
+			assert.Equal(t, http.StatusBadRequest, response.Code)
+		})
+	}
+}
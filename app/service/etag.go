@@ -0,0 +1,58 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/openapi"
+)
+
+// errPreconditionFailed is returned when the If-Match header of a request does not match the current version of the
+// entity it writes.
+var errPreconditionFailed = errors.New("precondition failed: If-Match does not match the current ETag")
+
+// etag returns the entity tag of a version of an entity.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// etagHeaders returns the headers of a response that represents a version of an entity.
+func etagHeaders(version int64) map[string][]string {
+	return map[string][]string{"ETag": {etag(version)}}
+}
+
+// matchesETag reports whether the If-Match header of a request matches a version of an entity. A missing header and
+// "*" match every version. Weak entity tags never match, as If-Match uses the strong comparison.
+func matchesETag(header string, version int64) bool {
+	if header == "" {
+		return true
+	}
+
+	tag := etag(version)
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// isConflict reports whether a write failed because the entity it writes was changed or deleted concurrently.
+func isConflict(err error) bool {
+	var conflict *dal.ConflictError
+	return errors.As(err, &conflict)
+}
+
+// conflictResponse is the response to a write that failed because the entity it writes was changed or deleted
+// concurrently. The precondition of a write made with If-Match no longer holds, so it fails with a 412, while other
+// writes fail with a 409 and can be retried.
+func conflictResponse(header string, err error) (openapi.ImplResponse, error) {
+	if header != "" {
+		return openapi.Response(http.StatusPreconditionFailed, nil), err
+	}
+	return openapi.ErrorResponse(err)
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/payloadops/lanyard/app/dal"
+)
+
+// toPage validates the cursor and limit query parameters of a list operation. A limit of zero selects the default.
+func toPage(cursor string, limit int32) (dal.Page, error) {
+	if limit < 0 || limit > dal.MaxPageLimit {
+		return dal.Page{}, fmt.Errorf("limit must be between 1 and %d", dal.MaxPageLimit)
+	}
+	return dal.Page{Cursor: cursor, Limit: int(limit)}, nil
+}
@@ -0,0 +1,395 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/payloadops/lanyard/app/cache"
+	"github.com/payloadops/lanyard/app/dal"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultFlushInterval represents how often pending usage is written to the database.
+	DefaultFlushInterval = 10 * time.Second
+
+	// cacheTTL represents how long an idle usage counter is kept in the cache.
+	cacheTTL = time.Hour
+
+	// planTTL represents how long the request limit of an actor is kept before its actor and tier are read again.
+	planTTL = time.Minute
+
+	// consumeScript counts a request against a cached counter unless the counter has reached the limit in ARGV[1]
+	// and ARGV[2] does not allow overage, and returns the count before the request. Counters that have not been
+	// seeded from the database yet return -1, so that a partial count is never mistaken for the full one.
+	consumeScript = `
+		local used = redis.call('GET', KEYS[1])
+		if not used then
+			return -1
+		end
+		used = tonumber(used)
+		if used < tonumber(ARGV[1]) or ARGV[2] == '1' then
+			redis.call('INCR', KEYS[1])
+			redis.call('EXPIRE', KEYS[1], ARGV[3])
+		end
+		return used
+	`
+)
+
+// errNotSeeded is returned when a usage counter is missing from the cache.
+var errNotSeeded = errors.New("usage counter is not seeded")
+
+// Decision represents the outcome of comparing an actor's usage against its monthly request limit.
+type Decision struct {
+	Allowed bool
+	// Overage is set when the limit has been reached but the actor's tier bills for additional requests.
+	Overage bool
+	Usage   int64
+	// Limit is zero when the actor has no monthly request limit.
+	Limit int64
+}
+
+// counter identifies a single usage counter.
+type counter struct {
+	orgID     string
+	serviceID string
+	period    string
+	subject   dal.UsageSubject
+	subjectID string
+}
+
+// tally holds the requests counted against a counter and how many of them were overage.
+type tally struct {
+	count   int64
+	overage int64
+}
+
+// actorKey identifies the actor a plan belongs to.
+type actorKey struct {
+	orgID     string
+	serviceID string
+	actorID   string
+}
+
+// plan holds the monthly request limit of an actor and whether its tier bills requests over it as overage.
+type plan struct {
+	limit     int64
+	overage   bool
+	expiresAt time.Time
+}
+
+// decide compares the usage of an actor against its plan.
+func (p plan) decide(used int64) Decision {
+	decision := Decision{Allowed: true, Usage: used, Limit: p.limit}
+	if used >= p.limit {
+		decision.Overage = p.overage
+		decision.Allowed = p.overage
+	}
+
+	return decision
+}
+
+// cacheKey generates the cache key holding the running count of the counter.
+func (c counter) cacheKey() string {
+	return "Usage#Org#" + c.orgID + "Service#" + c.serviceID + "Period#" + c.period + "#" + string(c.subject) + "#" + c.subjectID
+}
+
+// Meter counts successful authorizations per actor and API key in calendar month buckets. Increments are
+// buffered in memory and written to the database in batches by Flush, while the running totals used for
+// limit checks are kept in the cache. Counts may lag behind by up to one flush interval per instance, and
+// changes to the limit of an actor or its tier take effect within planTTL.
+type Meter struct {
+	usageClient dal.UsageManager
+	actorClient dal.ActorManager
+	tierClient  dal.TierManager
+	cache       cache.Cache
+	logger      *zap.Logger
+
+	mu      sync.Mutex
+	pending map[counter]tally
+	plans   map[actorKey]plan
+	now     func() time.Time
+}
+
+// NewMeter creates a new Meter.
+func NewMeter(usageClient dal.UsageManager, actorClient dal.ActorManager, tierClient dal.TierManager, cache cache.Cache, logger *zap.Logger) *Meter {
+	return &Meter{
+		usageClient: usageClient,
+		actorClient: actorClient,
+		tierClient:  tierClient,
+		cache:       cache,
+		logger:      logger,
+		pending:     make(map[counter]tally),
+		plans:       make(map[actorKey]plan),
+		now:         time.Now,
+	}
+}
+
+// Consume counts a successful authorization against the actor, if any, and the API key in the current period,
+// unless the actor has reached its monthly request limit, falling back to the default limit of its pricing tier.
+// Requests over the limit are counted as overage when the tier has an overage price and denied otherwise. The
+// limit is checked and the request counted in one step, so that parallel requests cannot together overshoot it.
+// Actors without a limit, and keys without an actor, are always allowed.
+func (m *Meter) Consume(ctx context.Context, orgID, serviceID, actorID, keyID string) (Decision, error) {
+	period := Period(m.now())
+	key := counter{orgID: orgID, serviceID: serviceID, period: period, subject: dal.UsageSubjectAPIKey, subjectID: keyID}
+	if actorID == "" {
+		m.record(false, key)
+		return Decision{Allowed: true}, nil
+	}
+
+	actor := counter{orgID: orgID, serviceID: serviceID, period: period, subject: dal.UsageSubjectActor, subjectID: actorID}
+	p, err := m.plan(ctx, orgID, serviceID, actorID)
+	if err != nil {
+		return Decision{}, err
+	}
+	if p.limit <= 0 {
+		m.record(false, actor, key)
+		return Decision{Allowed: true}, nil
+	}
+
+	decision, err := m.consume(ctx, actor, p)
+	if err != nil {
+		return Decision{}, err
+	}
+	if decision.Allowed {
+		m.record(decision.Overage, key)
+	}
+
+	return decision, nil
+}
+
+// Flush writes pending usage to the database. Counters that fail to be written are kept for the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = make(map[counter]tally)
+	m.mu.Unlock()
+
+	var errs []error
+	for c, t := range pending {
+		err := m.usageClient.IncrementUsage(ctx, c.orgID, c.serviceID, c.period, c.subject, c.subjectID, t.count, t.overage)
+		if err != nil {
+			m.mu.Lock()
+			retry := m.pending[c]
+			retry.count += t.count
+			retry.overage += t.overage
+			m.pending[c] = retry
+			m.mu.Unlock()
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run flushes pending usage every interval until the context is cancelled, after which it flushes one last time.
+func (m *Meter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				m.logger.Error("failed to flush usage", zap.Error(err))
+			}
+			m.prunePlans()
+		case <-ctx.Done():
+			if err := m.Flush(context.WithoutCancel(ctx)); err != nil {
+				m.logger.Error("failed to flush usage", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// plan returns the request limit of an actor, reading the actor and its pricing tier at most once per planTTL.
+// Unknown actors have no limit.
+func (m *Meter) plan(ctx context.Context, orgID, serviceID, actorID string) (plan, error) {
+	key := actorKey{orgID: orgID, serviceID: serviceID, actorID: actorID}
+	now := m.now()
+
+	m.mu.Lock()
+	p, ok := m.plans[key]
+	m.mu.Unlock()
+	if ok && now.Before(p.expiresAt) {
+		return p, nil
+	}
+
+	actor, err := m.actorClient.GetActor(ctx, orgID, serviceID, actorID)
+	if err != nil {
+		return plan{}, err
+	}
+
+	p = plan{expiresAt: now.Add(planTTL)}
+	if actor != nil {
+		var tier *dal.Tier
+		if actor.BillingInfo.TierID != "" {
+			tier, err = m.tierClient.GetTier(ctx, orgID, serviceID, actor.BillingInfo.TierID)
+			if err != nil {
+				return plan{}, err
+			}
+		}
+
+		p.limit = int64(actor.MonthlyRequestLimit)
+		if p.limit <= 0 && tier != nil {
+			p.limit = int64(tier.DefaultRequestLimit)
+		}
+		p.overage = tier != nil && tier.OveragePrice > 0
+	}
+
+	m.mu.Lock()
+	m.plans[key] = p
+	m.mu.Unlock()
+
+	return p, nil
+}
+
+// prunePlans drops the plans that have expired, so that actors that stopped making requests are not kept.
+func (m *Meter) prunePlans() {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, p := range m.plans {
+		if !now.Before(p.expiresAt) {
+			delete(m.plans, key)
+		}
+	}
+}
+
+// consume checks a counter against the plan of its actor and counts the request if it is allowed. The check and
+// the increment are made atomically by the cache, which is seeded from the database when the counter is missing.
+// When the cache cannot evaluate scripts, they are made under the lock of this instance instead.
+func (m *Meter) consume(ctx context.Context, c counter, p plan) (Decision, error) {
+	used, err := m.consumeCached(ctx, c, p)
+	if errors.Is(err, errNotSeeded) {
+		err = m.seed(ctx, c)
+		if err != nil {
+			return Decision{}, err
+		}
+		used, err = m.consumeCached(ctx, c, p)
+	}
+	if err != nil {
+		if !errors.Is(err, cache.ErrNotSupported) {
+			m.logger.Warn("failed to evaluate usage script, falling back to local usage",
+				zap.String("requestID", middleware.GetReqID(ctx)),
+				zap.Error(err),
+			)
+		}
+
+		return m.consumeLocal(ctx, c, p)
+	}
+
+	decision := p.decide(used)
+	if decision.Allowed {
+		m.record(decision.Overage, c)
+	}
+
+	return decision, nil
+}
+
+// consumeCached counts a request against the cached running count of a counter if the plan allows it, and
+// returns the count before the request. It returns errNotSeeded when the counter is missing from the cache.
+func (m *Meter) consumeCached(ctx context.Context, c counter, p plan) (int64, error) {
+	overage := "0"
+	if p.overage {
+		overage = "1"
+	}
+
+	value, err := m.cache.Eval(ctx, consumeScript, []string{c.cacheKey()}, p.limit, overage, int(cacheTTL.Seconds()))
+	if err != nil {
+		return 0, err
+	}
+
+	used, ok := value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected usage script result: %v", value)
+	}
+	if used < 0 {
+		return 0, errNotSeeded
+	}
+
+	return used, nil
+}
+
+// consumeLocal counts a request against the stored count of a counter and the requests pending on this instance
+// if the plan allows it. Requests counted by other instances since their last flush are not seen.
+func (m *Meter) consumeLocal(ctx context.Context, c counter, p plan) (Decision, error) {
+	count, err := m.stored(ctx, c)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	decision := p.decide(count + m.pending[c].count)
+	if decision.Allowed {
+		m.add(decision.Overage, c)
+	}
+
+	return decision, nil
+}
+
+// seed caches the stored count of a counter together with the requests pending on this instance. A counter seeded
+// by another instance in the meantime is kept, since it may already hold requests that are not stored yet.
+func (m *Meter) seed(ctx context.Context, c counter) error {
+	count, err := m.stored(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	count += m.pending[c].count
+	m.mu.Unlock()
+
+	_, err = m.cache.SetNX(ctx, c.cacheKey(), strconv.FormatInt(count, 10), cacheTTL)
+	if err != nil {
+		m.logger.Warn("failed to cache usage",
+			zap.String("requestID", middleware.GetReqID(ctx)),
+			zap.Error(err),
+		)
+	}
+
+	return nil
+}
+
+// stored returns the count of a counter in the database.
+func (m *Meter) stored(ctx context.Context, c counter) (int64, error) {
+	usage, err := m.usageClient.GetUsage(ctx, c.orgID, c.serviceID, c.period, c.subject, c.subjectID)
+	if err != nil {
+		return 0, err
+	}
+	if usage == nil {
+		return 0, nil
+	}
+
+	return usage.Count, nil
+}
+
+// record adds a request to the pending counts of the counters, as overage if requested.
+func (m *Meter) record(overage bool, counters ...counter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add(overage, counters...)
+}
+
+// add adds a request to the pending counts of the counters. The caller must hold m.mu.
+func (m *Meter) add(overage bool, counters ...counter) {
+	for _, c := range counters {
+		t := m.pending[c]
+		t.count++
+		if overage {
+			t.overage++
+		}
+		m.pending[c] = t
+	}
+}
@@ -0,0 +1,239 @@
+package usage_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/cache"
+	cachemocks "github.com/payloadops/lanyard/app/cache/mocks"
+	"github.com/payloadops/lanyard/app/dal"
+	"github.com/payloadops/lanyard/app/dal/mocks"
+	"github.com/payloadops/lanyard/app/usage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestMeter_Consume(t *testing.T) {
+	tests := []struct {
+		name            string
+		actor           *dal.Actor
+		tier            *dal.Tier
+		usage           *dal.Usage
+		expectedAllowed bool
+		expectedOverage bool
+		expectedLimit   int64
+	}{
+		{
+			name:            "Unknown actor",
+			actor:           nil,
+			expectedAllowed: true,
+		},
+		{
+			name:            "No limit",
+			actor:           &dal.Actor{ActorID: "actor1"},
+			expectedAllowed: true,
+		},
+		{
+			name:            "Under actor limit",
+			actor:           &dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 10},
+			usage:           &dal.Usage{Count: 9},
+			expectedAllowed: true,
+			expectedLimit:   10,
+		},
+		{
+			name:            "Over actor limit",
+			actor:           &dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 10},
+			usage:           &dal.Usage{Count: 10},
+			expectedAllowed: false,
+			expectedLimit:   10,
+		},
+		{
+			name:            "Over tier default limit",
+			actor:           &dal.Actor{ActorID: "actor1", BillingInfo: dal.BillingInfo{TierID: "free"}},
+			tier:            &dal.Tier{Name: "free", DefaultRequestLimit: 5},
+			usage:           &dal.Usage{Count: 5},
+			expectedAllowed: false,
+			expectedLimit:   5,
+		},
+		{
+			name:            "Over limit with overage price",
+			actor:           &dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 10, BillingInfo: dal.BillingInfo{TierID: "pro"}},
+			tier:            &dal.Tier{Name: "pro", DefaultRequestLimit: 5, OveragePrice: 0.01},
+			usage:           &dal.Usage{Count: 12},
+			expectedAllowed: true,
+			expectedOverage: true,
+			expectedLimit:   10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockUsageClient := mocks.NewMockUsageManager(ctrl)
+			mockActorClient := mocks.NewMockActorManager(ctrl)
+			mockTierClient := mocks.NewMockTierManager(ctrl)
+			meter := usage.NewMeter(mockUsageClient, mockActorClient, mockTierClient, cache.NewNoopCache(), zap.NewNop())
+
+			ctx := context.Background()
+			mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(tt.actor, nil)
+			if tt.actor != nil && tt.actor.BillingInfo.TierID != "" {
+				mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", tt.actor.BillingInfo.TierID).Return(tt.tier, nil)
+			}
+			if tt.expectedLimit > 0 {
+				mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", gomock.Any(), dal.UsageSubjectActor, "actor1").Return(tt.usage, nil)
+			}
+
+			decision, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedAllowed, decision.Allowed)
+			assert.Equal(t, tt.expectedOverage, decision.Overage)
+			assert.Equal(t, tt.expectedLimit, decision.Limit)
+		})
+	}
+}
+
+func TestMeter_Consume_NoActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mocks.NewMockActorManager(ctrl), mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+
+	decision, err := meter.Consume(context.Background(), "org1", "serv1", "", "key1")
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+func TestMeter_Consume_Cached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockCache := cachemocks.NewMockCache(ctrl)
+	meter := usage.NewMeter(mocks.NewMockUsageManager(ctrl), mockActorClient, mocks.NewMockTierManager(ctrl), mockCache, zap.NewNop())
+
+	ctx := context.Background()
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 10}, nil)
+	mockCache.EXPECT().Eval(ctx, gomock.Any(), gomock.Any(), int64(10), "0", gomock.Any()).Return(int64(10), nil)
+
+	decision, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, int64(10), decision.Usage)
+}
+
+func TestMeter_Consume_Seed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockCache := cachemocks.NewMockCache(ctrl)
+	meter := usage.NewMeter(mockUsageClient, mockActorClient, mocks.NewMockTierManager(ctrl), mockCache, zap.NewNop())
+
+	ctx := context.Background()
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 10}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", gomock.Any(), dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 4}, nil)
+
+	// The counter was seeded by another instance, which counted requests since, so its value is kept
+	gomock.InOrder(
+		mockCache.EXPECT().Eval(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(-1), nil),
+		mockCache.EXPECT().SetNX(ctx, gomock.Any(), "4", time.Hour).Return(false, nil),
+		mockCache.EXPECT().Eval(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(7), nil),
+	)
+
+	decision, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Equal(t, int64(7), decision.Usage)
+}
+
+func TestMeter_Consume_IncludesPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	meter := usage.NewMeter(mockUsageClient, mockActorClient, mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+
+	ctx := context.Background()
+
+	// The actor is read once and its limit reused
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 5}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", gomock.Any(), dal.UsageSubjectActor, "actor1").Return(&dal.Usage{Count: 3}, nil).Times(3)
+
+	for _, expectedUsage := range []int64{3, 4} {
+		decision, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+		assert.NoError(t, err)
+		assert.True(t, decision.Allowed)
+		assert.Equal(t, expectedUsage, decision.Usage)
+	}
+
+	decision, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+	assert.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, int64(5), decision.Usage)
+}
+
+func TestMeter_Flush(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	mockActorClient := mocks.NewMockActorManager(ctrl)
+	mockTierClient := mocks.NewMockTierManager(ctrl)
+	meter := usage.NewMeter(mockUsageClient, mockActorClient, mockTierClient, cache.NewNoopCache(), zap.NewNop())
+
+	ctx := context.Background()
+	period := usage.Period(time.Now())
+
+	// The second request reaches the limit and is counted as overage
+	mockActorClient.EXPECT().GetActor(ctx, "org1", "serv1", "actor1").Return(&dal.Actor{ActorID: "actor1", MonthlyRequestLimit: 1, BillingInfo: dal.BillingInfo{TierID: "pro"}}, nil)
+	mockTierClient.EXPECT().GetTier(ctx, "org1", "serv1", "pro").Return(&dal.Tier{Name: "pro", OveragePrice: 0.01}, nil)
+	mockUsageClient.EXPECT().GetUsage(ctx, "org1", "serv1", period, dal.UsageSubjectActor, "actor1").Return(nil, nil).Times(2)
+
+	for i := 0; i < 2; i++ {
+		_, err := meter.Consume(ctx, "org1", "serv1", "actor1", "key1")
+		assert.NoError(t, err)
+	}
+	_, err := meter.Consume(ctx, "org1", "serv1", "", "key2")
+	assert.NoError(t, err)
+
+	mockUsageClient.EXPECT().IncrementUsage(ctx, "org1", "serv1", period, dal.UsageSubjectActor, "actor1", int64(2), int64(1)).Return(nil)
+	mockUsageClient.EXPECT().IncrementUsage(ctx, "org1", "serv1", period, dal.UsageSubjectAPIKey, "key1", int64(2), int64(1)).Return(nil)
+	mockUsageClient.EXPECT().IncrementUsage(ctx, "org1", "serv1", period, dal.UsageSubjectAPIKey, "key2", int64(1), int64(0)).Return(nil)
+
+	assert.NoError(t, meter.Flush(ctx))
+
+	// Nothing is pending after a successful flush
+	assert.NoError(t, meter.Flush(ctx))
+}
+
+func TestMeter_Flush_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUsageClient := mocks.NewMockUsageManager(ctrl)
+	meter := usage.NewMeter(mockUsageClient, mocks.NewMockActorManager(ctrl), mocks.NewMockTierManager(ctrl), cache.NewNoopCache(), zap.NewNop())
+
+	ctx := context.Background()
+	period := usage.Period(time.Now())
+	_, err := meter.Consume(ctx, "org1", "serv1", "", "key1")
+	assert.NoError(t, err)
+
+	gomock.InOrder(
+		mockUsageClient.EXPECT().IncrementUsage(ctx, "org1", "serv1", period, dal.UsageSubjectAPIKey, "key1", int64(1), int64(0)).Return(errors.New("dynamodb error")),
+		mockUsageClient.EXPECT().IncrementUsage(ctx, "org1", "serv1", period, dal.UsageSubjectAPIKey, "key1", int64(2), int64(0)).Return(nil),
+	)
+
+	assert.Error(t, meter.Flush(ctx))
+
+	// Failed counts are retried along with new ones
+	_, err = meter.Consume(ctx, "org1", "serv1", "", "key1")
+	assert.NoError(t, err)
+	assert.NoError(t, meter.Flush(ctx))
+}
@@ -0,0 +1,37 @@
+package usage
+
+import (
+	"fmt"
+	"time"
+)
+
+// periodLayout represents the format of a usage period.
+const periodLayout = "2006-01"
+
+// Period returns the calendar month, in UTC, that usage at the given time is metered in.
+func Period(t time.Time) string {
+	return t.UTC().Format(periodLayout)
+}
+
+// ParsePeriod parses a calendar month formatted as YYYY-MM into the time it starts at.
+func ParsePeriod(period string) (time.Time, error) {
+	t, err := time.Parse(periodLayout, period)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid period '%s', expected YYYY-MM", period)
+	}
+
+	return t, nil
+}
+
+// Periods returns every calendar month from the period of from up to and including the period of to.
+func Periods(from, to time.Time) []string {
+	start := time.Date(from.UTC().Year(), from.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.UTC().Year(), to.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	var periods []string
+	for t := start; !t.After(end); t = t.AddDate(0, 1, 0) {
+		periods = append(periods, Period(t))
+	}
+
+	return periods
+}
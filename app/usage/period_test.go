@@ -0,0 +1,31 @@
+package usage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/payloadops/lanyard/app/usage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriod(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	assert.Equal(t, "2024-05", usage.Period(time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2024-05", usage.Period(time.Date(2024, 6, 1, 1, 0, 0, 0, loc)))
+}
+
+func TestParsePeriod(t *testing.T) {
+	start, err := usage.ParsePeriod("2024-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), start)
+
+	_, err = usage.ParsePeriod("2024-02-01")
+	assert.Error(t, err)
+}
+
+func TestPeriods(t *testing.T) {
+	from := time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2023-11", "2023-12", "2024-01", "2024-02"}, usage.Periods(from, to))
+	assert.Empty(t, usage.Periods(to, from))
+}
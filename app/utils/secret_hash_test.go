@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSecret(t *testing.T) {
+	t.Run("VerifyMatchingSecret", func(t *testing.T) {
+		hash, err := HashSecret("secret", "pepper")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, SecretHashVersion+"$"))
+		assert.NotContains(t, hash, "secret")
+		assert.True(t, VerifySecretHash("secret", hash, "pepper"))
+	})
+
+	t.Run("RejectWrongSecret", func(t *testing.T) {
+		hash, err := HashSecret("secret", "pepper")
+		assert.NoError(t, err)
+		assert.False(t, VerifySecretHash("other", hash, "pepper"))
+	})
+
+	t.Run("RejectWrongPepper", func(t *testing.T) {
+		hash, err := HashSecret("secret", "pepper")
+		assert.NoError(t, err)
+		assert.False(t, VerifySecretHash("secret", hash, "other"))
+	})
+
+	t.Run("SaltDiffersPerHash", func(t *testing.T) {
+		first, err := HashSecret("secret", "pepper")
+		assert.NoError(t, err)
+		second, err := HashSecret("secret", "pepper")
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("RejectMalformedHash", func(t *testing.T) {
+		assert.False(t, VerifySecretHash("secret", "secret", "pepper"))
+		assert.False(t, VerifySecretHash("secret", "v1$!!$!!", "pepper"))
+		assert.False(t, VerifySecretHash("secret", "v0$abc$def", "pepper"))
+	})
+}
+
+func TestIsSecretHash(t *testing.T) {
+	hash, err := HashSecret("secret", "pepper")
+	assert.NoError(t, err)
+	assert.True(t, IsSecretHash(hash))
+	assert.False(t, IsSecretHash("plaintext-secret"))
+}
@@ -17,7 +17,7 @@ const REPLICATIONS_REGIONS: string[] = [
 export class DynamoStack extends cdk.Stack {
   constructor(scope: Construct, id: string, props?: DynamoStackProps) {
     super(scope, id, props);
-    new dynamodb.Table(this, 'ServicesTable', {
+    const servicesTable = new dynamodb.Table(this, 'ServicesTable', {
         tableName: "Services",
         partitionKey: { name: 'pk', type: dynamodb.AttributeType.STRING},
         sortKey: { name: 'sk', type: dynamodb.AttributeType.STRING},
@@ -26,7 +26,21 @@ export class DynamoStack extends cdk.Stack {
         tableClass: dynamodb.TableClass.STANDARD,
         // removalPolicy: cdk.RemovalPolicy.RETAIN
       })
-      
+
+    // Sparse index of the blocked IPs, the only items with a BlocklistPK
+    servicesTable.addGlobalSecondaryIndex({
+      indexName: "Blocked-IP-Index",
+      partitionKey: { name: 'BlocklistPK', type: dynamodb.AttributeType.STRING},
+      sortKey: { name: 'sk', type: dynamodb.AttributeType.STRING},
+    })
+
+    // Sparse index of the actors assigned to a pricing tier, the only items with a TierPK
+    servicesTable.addGlobalSecondaryIndex({
+      indexName: "Tier-Index",
+      partitionKey: { name: 'TierPK', type: dynamodb.AttributeType.STRING},
+      sortKey: { name: 'sk', type: dynamodb.AttributeType.STRING},
+    })
+
     const apiKeysTable = new dynamodb.Table(this, 'APIKeysTable', {
       tableName: "APIKeys",
       partitionKey: { name: 'pk', type: dynamodb.AttributeType.STRING},
@@ -35,5 +49,22 @@ export class DynamoStack extends cdk.Stack {
       tableClass: dynamodb.TableClass.STANDARD,
       // removalPolicy: cdk.RemovalPolicy.RETAIN
     })
+
+    apiKeysTable.addGlobalSecondaryIndex({
+      indexName: "Org-Service-Index",
+      partitionKey: { name: 'GSI1PK', type: dynamodb.AttributeType.STRING},
+    })
+
+    apiKeysTable.addGlobalSecondaryIndex({
+      indexName: "Org-Service-Actor-Index",
+      partitionKey: { name: 'GSI2PK', type: dynamodb.AttributeType.STRING},
+    })
+
+    // Sparse index of the keys due to expire, the only items with an ExpiryPK
+    apiKeysTable.addGlobalSecondaryIndex({
+      indexName: "Expiry-Index",
+      partitionKey: { name: 'ExpiryPK', type: dynamodb.AttributeType.STRING},
+      sortKey: { name: 'ExpirySK', type: dynamodb.AttributeType.STRING},
+    })
   }
 }
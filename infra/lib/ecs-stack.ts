@@ -53,6 +53,8 @@ export class EcsStack extends cdk.Stack {
       generateSecretString: {
         secretStringTemplate: JSON.stringify({
           JWT_SECRET: 'CHANGE_ME',
+          API_KEY_SECRET_PEPPER: 'CHANGE_ME',
+          PAGINATION_CURSOR_SECRET: 'CHANGE_ME',
         }),
         generateStringKey: 'unused',
       },
@@ -91,6 +93,8 @@ export class EcsStack extends cdk.Stack {
         },
         secrets: {
           "JWT_SECRET": ecs.Secret.fromSecretsManager(ecsSecret, "JWT_SECRET"),
+          "API_KEY_SECRET_PEPPER": ecs.Secret.fromSecretsManager(ecsSecret, "API_KEY_SECRET_PEPPER"),
+          "PAGINATION_CURSOR_SECRET": ecs.Secret.fromSecretsManager(ecsSecret, "PAGINATION_CURSOR_SECRET"),
         },
         taskRole: ecsTaskRole,
         executionRole: ecsExecutionRole,